@@ -0,0 +1,231 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+// Package bulk parses and analyzes many scripts concurrently, e.g. the scripts of all users
+// of a server for an audit before a migration.
+//
+// A failing script doesn't affect the others: read and syntax errors, and panics of the
+// analysis, are reported in the result of the script.
+package bulk
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"gosieve/src/rfc5228"
+)
+
+// Extension is the file name extension of scripts found by Find
+const Extension = ".sieve"
+
+// Find returns the names of the scripts in a file system in lexical order: the regular
+// files ending with Extension
+func Find(fsys fs.FS) ([]string, error) {
+	var names []string
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() && path.Ext(name) == Extension {
+			names = append(names, name)
+		}
+		return nil
+	})
+	return names, err
+}
+
+// Run calls fn for 0 up to n on jobs goroutines (GOMAXPROCS if jobs is not positive) and
+// returns the errors by index; a panic of fn is returned as its error
+func Run(n, jobs int, fn func(i int) error) []error {
+	if jobs <= 0 {
+		jobs = runtime.GOMAXPROCS(0)
+	}
+	errs := make([]error, n)
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < jobs && w < n; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				errs[i] = call(i, fn)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	return errs
+}
+
+func call(i int, fn func(i int) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return fn(i)
+}
+
+// Options control the analysis of Analyze
+type Options struct {
+	Jobs    int  // the number of scripts analyzed at the same time; GOMAXPROCS if not positive
+	Strict  bool // parse in strict mode
+	Options []rfc5228.Option
+}
+
+// Result is the analysis of a script
+type Result struct {
+	Name     string               `json:"name"`
+	Warnings []rfc5228.Warning    `json:"warnings,omitempty"`
+	Stats    *rfc5228.ScriptStats `json:"stats,omitempty"`
+	Err      error                `json:"-"` // the script couldn't be read, parsed or analyzed
+}
+
+// Analyze parses, validates and summarizes (see rfc5228.Stats) the named scripts of a file
+// system; the results are in the order of the names
+func Analyze(fsys fs.FS, names []string, opts Options) []Result {
+	var mode rfc5228.Mode
+	if opts.Strict {
+		mode |= rfc5228.ModeStrict
+	}
+	results := make([]Result, len(names))
+	errs := Run(len(names), opts.Jobs, func(i int) error {
+		content, err := fs.ReadFile(fsys, names[i])
+		if err != nil {
+			return err
+		}
+		tree, err := rfc5228.Parse(names[i], string(content), mode, opts.Options...)
+		if err != nil {
+			return err
+		}
+		results[i].Warnings = rfc5228.Validate(tree, opts.Options...)
+		results[i].Stats = rfc5228.Stats(tree)
+		return nil
+	})
+	for i, err := range errs {
+		results[i].Name, results[i].Err = names[i], err
+	}
+	return results
+}
+
+// Report aggregates the results of Analyze over all scripts
+type Report struct {
+	Scripts         int                  `json:"scripts"`
+	Failed          int                  `json:"failed"`          // the scripts that couldn't be analyzed
+	Errors          map[rfc5228.Code]int `json:"errors"`          // the scripts failing with each syntax error
+	Warnings        map[rfc5228.Code]int `json:"warnings"`        // the number of each warning
+	Actions         map[string]int       `json:"actions"`         // the number of each action
+	Tests           map[string]int       `json:"tests"`           // the number of each test
+	Capabilities    map[string]int       `json:"capabilities"`    // the scripts requiring each capability
+	RedirectScripts int                  `json:"redirectScripts"` // the scripts with a redirect
+}
+
+// Summarize aggregates results
+func Summarize(results []Result) *Report {
+	r := &Report{
+		Errors:       map[rfc5228.Code]int{},
+		Warnings:     map[rfc5228.Code]int{},
+		Actions:      map[string]int{},
+		Tests:        map[string]int{},
+		Capabilities: map[string]int{},
+	}
+	for _, result := range results {
+		r.Scripts++
+		if result.Err != nil {
+			r.Failed++
+			var syntax *rfc5228.SyntaxError
+			if errors.As(result.Err, &syntax) {
+				r.Errors[syntax.Code]++
+			}
+			continue
+		}
+		for _, w := range result.Warnings {
+			r.Warnings[w.Code]++
+		}
+		for name, n := range result.Stats.Actions {
+			r.Actions[name] += n
+		}
+		for name, n := range result.Stats.Tests {
+			r.Tests[name] += n
+		}
+		for _, capability := range result.Stats.Capabilities {
+			r.Capabilities[capability]++
+		}
+		if len(result.Stats.RedirectTargets) > 0 {
+			r.RedirectScripts++
+		}
+	}
+	return r
+}
+
+// WriteText writes the report as a plain text summary with the counts in descending order
+func (r *Report) WriteText(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "scripts: %d, failed: %d, with redirect: %d\n", r.Scripts, r.Failed, r.RedirectScripts)
+	section := func(title string, counts map[string]int) {
+		if len(counts) == 0 {
+			return
+		}
+		keys := make([]string, 0, len(counts))
+		for key := range counts {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			if counts[keys[i]] != counts[keys[j]] {
+				return counts[keys[i]] > counts[keys[j]]
+			}
+			return keys[i] < keys[j]
+		})
+		fmt.Fprintf(&b, "\n%s:\n", title)
+		for _, key := range keys {
+			fmt.Fprintf(&b, "  %-24s %d\n", key, counts[key])
+		}
+	}
+	section("errors", codeCounts(r.Errors))
+	section("warnings", codeCounts(r.Warnings))
+	section("actions", r.Actions)
+	section("tests", r.Tests)
+	section("capabilities", r.Capabilities)
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func codeCounts(counts map[rfc5228.Code]int) map[string]int {
+	result := map[string]int{}
+	for code, n := range counts {
+		result[string(code)] = n
+	}
+	return result
+}
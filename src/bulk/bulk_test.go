@@ -0,0 +1,113 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package bulk
+
+import (
+	"bytes"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+)
+
+func TestFind(t *testing.T) {
+	fsys := fstest.MapFS{
+		"users/alice/active.sieve": {Data: []byte("keep;\r\n")},
+		"users/bob/active.sieve":   {Data: []byte("keep;\r\n")},
+		"users/bob/notes.txt":      {Data: []byte("notes")},
+		"global.sieve":             {Data: []byte("keep;\r\n")},
+	}
+	names, err := Find(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "global.sieve users/alice/active.sieve users/bob/active.sieve"; strings.Join(names, " ") != expected {
+		t.Errorf("expected %s, got %v", expected, names)
+	}
+}
+
+func TestRun(t *testing.T) {
+	var active, peak int32
+	errs := Run(50, 4, func(i int) error {
+		n := atomic.AddInt32(&active, 1)
+		defer atomic.AddInt32(&active, -1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		if i == 7 {
+			panic("boom")
+		}
+		return nil
+	})
+	if peak > 4 {
+		t.Errorf("expected at most 4 concurrent calls, got %d", peak)
+	}
+	for i, err := range errs {
+		if (err != nil) != (i == 7) {
+			t.Errorf("%d: unexpected error %v", i, err)
+		}
+	}
+	if errs[7].Error() != "panic: boom" {
+		t.Errorf("expected the panic as error, got %v", errs[7])
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	fsys := fstest.MapFS{
+		"alice.sieve": {Data: []byte("require \"fileinto\";\r\nif true {\r\n  fileinto \"Archive\";\r\n}\r\n")},
+		"bob.sieve":   {Data: []byte("redirect \"bob@example.org\";\r\n")},
+		"carol.sieve": {Data: []byte("keep\r\n")},
+	}
+	names, err := Find(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := Analyze(fsys, append(names, "dave.sieve"), Options{Jobs: 2})
+	if len(results) != 4 || results[0].Name != "alice.sieve" || results[3].Name != "dave.sieve" {
+		t.Fatalf("unexpected results %+v", results)
+	}
+	if results[0].Err != nil || len(results[0].Warnings) == 0 || results[0].Stats.Actions["fileinto"] != 1 {
+		t.Errorf("unexpected result %+v", results[0])
+	}
+	if results[2].Err == nil || results[3].Err == nil {
+		t.Errorf("expected errors for carol and dave, got %v and %v", results[2].Err, results[3].Err)
+	}
+
+	report := Summarize(results)
+	if report.Scripts != 4 || report.Failed != 2 || report.RedirectScripts != 1 || report.Capabilities["fileinto"] != 1 || len(report.Errors) != 1 {
+		t.Errorf("unexpected report %+v", report)
+	}
+	var b bytes.Buffer
+	if err := report.WriteText(&b); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(b.String(), "scripts: 4, failed: 2, with redirect: 1\n") || !strings.Contains(b.String(), "\nactions:\n") {
+		t.Errorf("unexpected text report %q", b.String())
+	}
+}
@@ -0,0 +1,223 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+// Package bundle exports and imports the sieve scripts of an account as a portable
+// archive, e.g. to migrate accounts between servers.
+//
+// A bundle is a tar archive holding a manifest and the scripts:
+//
+//	manifest.json
+//	scripts/<name>.sieve
+//
+// The manifest names the active script and records, per script, its size, SHA-256
+// checksum and the capabilities it requires. Scripts referenced by an included script
+// (RFC 6609) are bundled like any other script.
+package bundle
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+
+	"gosieve/src/rfc5228"
+)
+
+// Version is the manifest format version written by Export
+const Version = 1
+
+const manifestFile = "manifest.json"
+
+// MaxScriptSize is the largest script Import accepts
+var MaxScriptSize int64 = 1 << 20
+
+// Bundle holds the scripts of an account
+type Bundle struct {
+	Active  string            // name of the active script, empty if none is active
+	Scripts map[string]string // script sources by name
+}
+
+// Manifest describes the content of a bundle
+type Manifest struct {
+	Version int      `json:"version"`
+	Active  string   `json:"active,omitempty"`
+	Scripts []Script `json:"scripts"`
+}
+
+// Script describes a bundled script
+type Script struct {
+	Name     string   `json:"name"`
+	File     string   `json:"file"`
+	Size     int      `json:"size"`
+	SHA256   string   `json:"sha256"`
+	Requires []string `json:"requires"` // capabilities required by the script; empty if it doesn't parse
+}
+
+func validName(name string) error {
+	if name == "" || strings.ContainsAny(name, "/\\\x00") || strings.HasPrefix(name, ".") {
+		return fmt.Errorf("invalid script name %q", name)
+	}
+	return nil
+}
+
+// requires returns the capabilities required by a script in order of appearance
+func requires(name, source string) []string {
+	tree, err := rfc5228.Parse(name, source, 0)
+	if err != nil {
+		// scripts using extensions unknown to the parser are still bundled as is
+		return []string{}
+	}
+	capabilities := []string{}
+	seen := map[string]bool{}
+	for _, require := range tree.Requires() {
+		for _, c := range require.Capabilities {
+			if !seen[c] {
+				seen[c] = true
+				capabilities = append(capabilities, c)
+			}
+		}
+	}
+	return capabilities
+}
+
+func checksum(source string) string {
+	sum := sha256.Sum256([]byte(source))
+	return hex.EncodeToString(sum[:])
+}
+
+// Export writes a bundle as a tar archive
+func Export(w io.Writer, b *Bundle) error {
+	if _, ok := b.Scripts[b.Active]; b.Active != "" && !ok {
+		return fmt.Errorf("active script %q is not part of the bundle", b.Active)
+	}
+
+	names := make([]string, 0, len(b.Scripts))
+	for name := range b.Scripts {
+		if err := validName(name); err != nil {
+			return err
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	manifest := Manifest{Version: Version, Active: b.Active, Scripts: []Script{}}
+	for _, name := range names {
+		source := b.Scripts[name]
+		manifest.Scripts = append(manifest.Scripts, Script{
+			Name:     name,
+			File:     path.Join("scripts", name+".sieve"),
+			Size:     len(source),
+			SHA256:   checksum(source),
+			Requires: requires(name, source),
+		})
+	}
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tw := tar.NewWriter(w)
+	if err := writeFile(tw, manifestFile, data); err != nil {
+		return err
+	}
+	for _, script := range manifest.Scripts {
+		if err := writeFile(tw, script.File, []byte(b.Scripts[script.Name])); err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
+
+func writeFile(tw *tar.Writer, name string, data []byte) error {
+	if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o600, Size: int64(len(data)), Typeflag: tar.TypeReg}); err != nil {
+		return err
+	}
+	_, err := tw.Write(data)
+	return err
+}
+
+// Import reads a bundle written by Export; the content of every script is verified
+// against the manifest
+func Import(r io.Reader) (*Bundle, *Manifest, error) {
+	files := map[string][]byte{}
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			return nil, nil, fmt.Errorf("unexpected entry %s", hdr.Name)
+		}
+		if hdr.Size > MaxScriptSize {
+			return nil, nil, fmt.Errorf("%s exceeds maximum size of %d bytes", hdr.Name, MaxScriptSize)
+		}
+		data, err := io.ReadAll(io.LimitReader(tr, MaxScriptSize))
+		if err != nil {
+			return nil, nil, err
+		}
+		files[hdr.Name] = data
+	}
+
+	data, ok := files[manifestFile]
+	if !ok {
+		return nil, nil, fmt.Errorf("missing %s", manifestFile)
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", manifestFile, err)
+	}
+	if manifest.Version < 1 || manifest.Version > Version {
+		return nil, nil, fmt.Errorf("unsupported bundle version %d", manifest.Version)
+	}
+
+	b := &Bundle{Active: manifest.Active, Scripts: map[string]string{}}
+	for _, script := range manifest.Scripts {
+		if err := validName(script.Name); err != nil {
+			return nil, nil, err
+		}
+		data, ok := files[script.File]
+		if !ok {
+			return nil, nil, fmt.Errorf("missing %s for script %s", script.File, script.Name)
+		}
+		if len(data) != script.Size || checksum(string(data)) != script.SHA256 {
+			return nil, nil, fmt.Errorf("checksum mismatch for script %s", script.Name)
+		}
+		b.Scripts[script.Name] = string(data)
+	}
+	if _, ok := b.Scripts[b.Active]; b.Active != "" && !ok {
+		return nil, nil, fmt.Errorf("active script %q is not part of the bundle", b.Active)
+	}
+	return b, &manifest, nil
+}
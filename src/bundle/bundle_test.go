@@ -0,0 +1,103 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package bundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestExportImport(t *testing.T) {
+	b := &Bundle{
+		Active: "main",
+		Scripts: map[string]string{
+			"main":     "require [\"fileinto\", \"envelope\"];\r\nrequire \"fileinto\";\r\nkeep;\r\n",
+			"vacation": "# uses an extension the parser doesn't know\r\nvacation \"away\";\r\n",
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := Export(&buf, b); err != nil {
+		t.Fatal(err)
+	}
+
+	imported, manifest, err := Import(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(b, imported) {
+		t.Errorf("expected %v, got %v", b, imported)
+	}
+	if len(manifest.Scripts) != 2 || !reflect.DeepEqual(manifest.Scripts[0].Requires, []string{"fileinto", "envelope"}) {
+		t.Errorf("unexpected manifest %+v", manifest)
+	}
+}
+
+func TestExportInvalid(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Export(&buf, &Bundle{Active: "missing", Scripts: map[string]string{}}); err == nil {
+		t.Errorf("expected error for missing active script")
+	}
+	if err := Export(&buf, &Bundle{Scripts: map[string]string{"../x": "keep;"}}); err == nil {
+		t.Errorf("expected error for invalid script name")
+	}
+}
+
+func TestImportTampered(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Export(&buf, &Bundle{Scripts: map[string]string{"main": "keep;\r\n"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// rewrite the archive with a modified script
+	var tampered bytes.Buffer
+	tr := tar.NewReader(&buf)
+	tw := tar.NewWriter(&tampered)
+	for {
+		hdr, err := tr.Next()
+		if err != nil {
+			break
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hdr.Name == "scripts/main.sieve" {
+			data = []byte("stop;\r\n")
+		}
+		if err := writeFile(tw, hdr.Name, data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tw.Close()
+
+	if _, _, err := Import(&tampered); err == nil {
+		t.Errorf("expected checksum error")
+	}
+}
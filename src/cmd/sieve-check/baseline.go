@@ -0,0 +1,127 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"gosieve/src/rfc5228"
+)
+
+// baselineEntry is a recorded finding; Count is the number of findings with the same key
+type baselineEntry struct {
+	File  string       `json:"file"`
+	Code  rfc5228.Code `json:"code"`
+	Line  string       `json:"line"`
+	Count int          `json:"count"`
+}
+
+// baselineFile is the file format of a baseline
+type baselineFile struct {
+	Version  int             `json:"version"`
+	Findings []baselineEntry `json:"findings"`
+}
+
+// baselineKey identifies a finding independent of its position and the locale of its message
+type baselineKey struct {
+	file string
+	code rfc5228.Code
+	line string
+}
+
+func (f finding) key() baselineKey {
+	return baselineKey{f.file, f.code, f.line}
+}
+
+// baseline holds the number of recorded findings of each key
+type baseline map[baselineKey]int
+
+func readBaseline(path string) (baseline, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file baselineFile
+	if err := json.Unmarshal(content, &file); err != nil {
+		return nil, fmt.Errorf("baseline %s: %w", path, err)
+	}
+	if file.Version != 1 {
+		return nil, fmt.Errorf("baseline %s: unsupported version %d", path, file.Version)
+	}
+	b := baseline{}
+	for _, entry := range file.Findings {
+		b[baselineKey{entry.File, entry.Code, entry.Line}] += entry.Count
+	}
+	return b, nil
+}
+
+// writeBaseline records the findings, sorted so that the file diffs well under version control
+func writeBaseline(path string, findings []finding) error {
+	b := baseline{}
+	for _, f := range findings {
+		b[f.key()]++
+	}
+	file := baselineFile{Version: 1, Findings: []baselineEntry{}}
+	for key, count := range b {
+		file.Findings = append(file.Findings, baselineEntry{key.file, key.code, key.line, count})
+	}
+	sort.Slice(file.Findings, func(i, j int) bool {
+		a, b := file.Findings[i], file.Findings[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Code != b.Code {
+			return a.Code < b.Code
+		}
+		return a.Line < b.Line
+	})
+	content, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(content, '\n'), 0o644)
+}
+
+// filter returns the findings that are not recorded: when a key is recorded n times, the
+// first n findings with that key are left out
+func (b baseline) filter(findings []finding) []finding {
+	remaining := baseline{}
+	for key, count := range b {
+		remaining[key] = count
+	}
+	var result []finding
+	for _, f := range findings {
+		if remaining[f.key()] > 0 {
+			remaining[f.key()]--
+			continue
+		}
+		result = append(result, f)
+	}
+	return result
+}
@@ -0,0 +1,344 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+// Command sieve-check checks scripts for syntax errors and warnings, e.g. in CI for a
+// repository of scripts.
+//
+//	sieve-check [-strict] [-locale nl] [-suppress SIEVE0104,SIEVE0105] [-format text|sarif]
+//		[-fail-on warning|error|none] [-warnings-as-errors] [-baseline file [-update-baseline]]
+//		[-recursive] [-jobs n] [-summary] [-fix] file...
+//
+// Findings are written to standard output, as `file:line:column: code: message` lines or as a
+// SARIF 2.1.0 log for code scanning dashboards. The exit status is 0 without findings at or
+// above the -fail-on severity, 1 with warnings, 2 with errors and 3 if a file can't be read or
+// the arguments are invalid. Syntax errors are errors, and so are warnings with
+// -warnings-as-errors; hints and infos (see rfc5228.Severity) never fail the check.
+//
+// A baseline records the findings of a repository of scripts, so that a linter can be adopted
+// without fixing them first: with -update-baseline the findings are written to the baseline
+// file, otherwise the findings recorded in it are left out. Findings are recorded by file, code
+// and the text of their line, so they still match after lines are inserted above them.
+//
+// With -recursive the scripts (*.sieve) in directories and their subdirectories are checked,
+// e.g. the scripts of all users of a server; -jobs sets the number of scripts checked at the
+// same time and -summary writes totals per finding, action, test and capability to standard
+// error. A script that fails doesn't stop the check of the others.
+//
+// With -fix the require commands of each script are fixed up before it is checked (see
+// rfc5228.FixRequires): capabilities used but not required are added and capabilities
+// required but not used are removed. A script is only rewritten if its require commands
+// change, and a script that can't be parsed is left as it is.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gosieve/src/bulk"
+
+	"gosieve/src/rfc5228"
+)
+
+// finding is a syntax error or warning in a file
+type finding struct {
+	file     string
+	position rfc5228.Position
+	related  []rfc5228.Position
+	code     rfc5228.Code
+	severity rfc5228.Severity
+	message  string
+	line     string // the text of the line of the finding, without surrounding whitespace
+}
+
+// The exit statuses of sieve-check
+const (
+	exitClean   = 0
+	exitWarning = 1
+	exitError   = 2
+	exitFailure = 3
+)
+
+// severities maps the -fail-on values to the lowest exit status that fails the check
+var severities = map[string]int{"warning": exitWarning, "error": exitError, "none": exitFailure}
+
+// status returns the exit status of the findings; hints and infos don't fail a check
+func status(findings []finding) int {
+	status := exitClean
+	for _, f := range findings {
+		if f.severity == rfc5228.SeverityError {
+			return exitError
+		}
+		if f.severity == rfc5228.SeverityWarning {
+			status = exitWarning
+		}
+	}
+	return status
+}
+
+// config holds the options of a check
+type config struct {
+	strict   bool
+	locale   string
+	suppress []rfc5228.Code
+	format   string
+	failOn   string
+	werror   bool // report warnings as errors
+	fix      bool // fix up the require commands of the scripts
+}
+
+func (c config) mode() rfc5228.Mode {
+	if c.strict {
+		return rfc5228.ModeStrict
+	}
+	return 0
+}
+
+func (c config) options() []rfc5228.Option {
+	opts := []rfc5228.Option{rfc5228.WithSuppressed(c.suppress...)}
+	if c.werror {
+		opts = append(opts, rfc5228.WithWarningsAsErrors())
+	}
+	if c.locale != "" {
+		opts = append(opts, rfc5228.WithLocale(c.locale))
+	}
+	return opts
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run checks the files named by the arguments and returns the exit status
+func run(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("sieve-check", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	var c config
+	var suppress, baselinePath string
+	var update, recursive, summary bool
+	var jobs int
+	flags.BoolVar(&c.strict, "strict", false, "parse in strict mode")
+	flags.StringVar(&c.locale, "locale", "", "language of the messages, e.g. nl")
+	flags.StringVar(&suppress, "suppress", "", "comma separated codes of warnings to leave out")
+	flags.StringVar(&c.format, "format", "text", "output format: text or sarif")
+	flags.StringVar(&c.failOn, "fail-on", "warning", "lowest severity that fails the check: warning, error or none")
+	flags.BoolVar(&c.werror, "warnings-as-errors", false, "report warnings as errors")
+	flags.StringVar(&baselinePath, "baseline", "", "file of findings to leave out")
+	flags.BoolVar(&update, "update-baseline", false, "write the findings to the baseline file")
+	flags.BoolVar(&recursive, "recursive", false, "check the scripts in directories and their subdirectories")
+	flags.IntVar(&jobs, "jobs", 0, "number of scripts checked at the same time; the number of CPUs if 0")
+	flags.BoolVar(&summary, "summary", false, "write totals of the findings and the scripts to standard error")
+	flags.BoolVar(&c.fix, "fix", false, "fix up the require commands of the scripts before checking them")
+	if err := flags.Parse(args); err != nil {
+		return exitFailure
+	}
+	if c.format != "text" && c.format != "sarif" {
+		fmt.Fprintf(stderr, "unknown format %q\n", c.format)
+		return exitFailure
+	}
+	if _, ok := severities[c.failOn]; !ok {
+		fmt.Fprintf(stderr, "unknown severity %q\n", c.failOn)
+		return exitFailure
+	}
+	if update && baselinePath == "" {
+		fmt.Fprintln(stderr, "-update-baseline requires -baseline")
+		return exitFailure
+	}
+	if flags.NArg() == 0 {
+		fmt.Fprintln(stderr, "usage: sieve-check [flags] file...")
+		return exitFailure
+	}
+	for _, code := range strings.Split(suppress, ",") {
+		if code = strings.TrimSpace(code); code != "" {
+			c.suppress = append(c.suppress, rfc5228.Code(code))
+		}
+	}
+
+	files, err := scripts(flags.Args(), recursive)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitFailure
+	}
+	checked := make([][]finding, len(files))
+	results := make([]bulk.Result, len(files))
+	errs := bulk.Run(len(files), jobs, func(i int) error {
+		content, err := os.ReadFile(files[i])
+		if err != nil {
+			return err
+		}
+		if c.fix {
+			if content, err = fix(files[i], content, c); err != nil {
+				return err
+			}
+		}
+		checked[i], results[i] = check(files[i], string(content), c)
+		return nil
+	})
+	failed := false
+	var findings []finding
+	for i, err := range errs {
+		if err != nil {
+			// a file that can't be checked doesn't stop the check of the others
+			if _, ok := err.(*fs.PathError); ok {
+				fmt.Fprintln(stderr, err)
+			} else {
+				fmt.Fprintf(stderr, "%s: %v\n", files[i], err)
+			}
+			results[i] = bulk.Result{Name: files[i], Err: err}
+			failed = true
+		}
+		findings = append(findings, checked[i]...)
+	}
+	if summary {
+		if err := bulk.Summarize(results).WriteText(stderr); err != nil {
+			return exitFailure
+		}
+	}
+
+	if update {
+		if err := writeBaseline(baselinePath, findings); err != nil {
+			fmt.Fprintln(stderr, err)
+			return exitFailure
+		}
+		return exitClean
+	}
+	if baselinePath != "" {
+		b, err := readBaseline(baselinePath)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return exitFailure
+		}
+		findings = b.filter(findings)
+	}
+
+	if c.format == "sarif" {
+		err = writeSARIF(stdout, findings)
+	} else {
+		err = writeText(stdout, findings)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitFailure
+	}
+	if failed {
+		return exitFailure
+	}
+	if status := status(findings); status >= severities[c.failOn] {
+		return status
+	}
+	return exitClean
+}
+
+// scripts returns the files named by the arguments; with recursive set, a directory names
+// the scripts in it and its subdirectories
+func scripts(args []string, recursive bool) ([]string, error) {
+	var files []string
+	for _, arg := range args {
+		if info, err := os.Stat(arg); !recursive || err != nil || !info.IsDir() {
+			files = append(files, arg)
+			continue
+		}
+		names, err := bulk.Find(os.DirFS(arg))
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			files = append(files, filepath.Join(arg, filepath.FromSlash(name)))
+		}
+	}
+	return files, nil
+}
+
+// fix rewrites a script with its require commands fixed up and returns its new content; a
+// script that can't be parsed is left to check to report
+func fix(file string, content []byte, c config) ([]byte, error) {
+	tree, err := rfc5228.Parse(file, string(content), c.mode(), c.options()...)
+	if err != nil {
+		return content, nil
+	}
+	fixed, err := rfc5228.FixRequires(tree)
+	if err != nil || fixed == string(content) {
+		return content, err
+	}
+	info, err := os.Stat(file)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(file, []byte(fixed), info.Mode().Perm()); err != nil {
+		return nil, err
+	}
+	return []byte(fixed), nil
+}
+
+// check parses and validates a script; a script that can't be parsed has a single finding.
+// The result summarizes the script for -summary.
+func check(file, content string, c config) ([]finding, bulk.Result) {
+	source := rfc5228.NewSourceFile(file, content)
+	tree, err := rfc5228.Parse(file, content, c.mode(), c.options()...)
+	var syntax *rfc5228.SyntaxError
+	if errors.As(err, &syntax) {
+		return []finding{{file: file, position: source.Position(syntax.Pos), code: syntax.Code, severity: rfc5228.SeverityError, message: syntax.Message, line: line(content, syntax.Pos)}}, bulk.Result{Name: file, Err: err}
+	} else if err != nil {
+		return []finding{{file: file, position: source.Position(0), severity: rfc5228.SeverityError, message: err.Error(), line: line(content, 0)}}, bulk.Result{Name: file, Err: err}
+	}
+
+	result := bulk.Result{Name: file, Warnings: rfc5228.Validate(tree, c.options()...), Stats: rfc5228.Stats(tree)}
+	var findings []finding
+	for _, w := range result.Warnings {
+		f := finding{file: file, position: source.Position(w.Pos), code: w.Code, severity: w.Severity, message: w.Message, line: line(content, w.Pos)}
+		for _, pos := range w.Related {
+			f.related = append(f.related, source.Position(pos))
+		}
+		findings = append(findings, f)
+	}
+	return findings, result
+}
+
+// line returns the text of the line holding a position, without surrounding whitespace
+func line(content string, pos rfc5228.Pos) string {
+	if int(pos) > len(content) {
+		pos = rfc5228.Pos(len(content))
+	}
+	start := strings.LastIndexByte(content[:pos], '\n') + 1
+	end := len(content)
+	if i := strings.IndexByte(content[pos:], '\n'); i >= 0 {
+		end = int(pos) + i
+	}
+	return strings.TrimSpace(content[start:end])
+}
+
+func writeText(w io.Writer, findings []finding) error {
+	for _, f := range findings {
+		if _, err := fmt.Fprintf(w, "%s:%s: %s: %s\n", f.file, f.position, f.code, f.message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,221 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func script(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestText(t *testing.T) {
+	clean := script(t, "clean.sieve", "keep;\r\n")
+	var stdout, stderr bytes.Buffer
+	if status := run([]string{clean}, &stdout, &stderr); status != 0 || stdout.Len() != 0 {
+		t.Errorf("expected no findings, got %d: %q %q", status, stdout.String(), stderr.String())
+	}
+
+	file := script(t, "warning.sieve", "if true {\r\n  keep;\r\n}\r\n")
+	stdout.Reset()
+	if status := run([]string{file}, &stdout, &stderr); status != 1 {
+		t.Errorf("expected status 1, got %d", status)
+	}
+	if want := file + ":1:4: SIEVE0104: "; !strings.HasPrefix(stdout.String(), want) {
+		t.Errorf("expected %q, got %q", want, stdout.String())
+	}
+
+	stdout.Reset()
+	if status := run([]string{"-suppress", "SIEVE0104", file}, &stdout, &stderr); status != 0 {
+		t.Errorf("expected suppressed warning, got %d: %q", status, stdout.String())
+	}
+	if status := run([]string{"-format", "xml", file}, &stdout, &stderr); status != 3 {
+		t.Errorf("expected status 3 for an unknown format, got %d", status)
+	}
+	if status := run([]string{filepath.Join(t.TempDir(), "missing.sieve")}, &stdout, &stderr); status != 3 {
+		t.Errorf("expected status 3 for a missing file, got %d", status)
+	}
+}
+
+func TestFailOn(t *testing.T) {
+	warning := script(t, "warning.sieve", "if true {\r\n  keep;\r\n}\r\n")
+	syntax := script(t, "syntax.sieve", "keep\r\n")
+	for _, test := range []struct {
+		failOn string
+		werror bool
+		files  []string
+		status int
+	}{
+		{"warning", false, []string{warning}, 1},
+		{"warning", false, []string{warning, syntax}, 2},
+		{"error", false, []string{warning}, 0},
+		{"error", false, []string{syntax}, 2},
+		{"none", false, []string{warning, syntax}, 0},
+		{"warning", true, []string{warning}, 2},
+		{"error", true, []string{warning}, 2},
+	} {
+		var stdout, stderr bytes.Buffer
+		args := []string{"-fail-on", test.failOn}
+		if test.werror {
+			args = append(args, "-warnings-as-errors")
+		}
+		if status := run(append(args, test.files...), &stdout, &stderr); status != test.status {
+			t.Errorf("-fail-on %s -warnings-as-errors=%v %v: expected status %d, got %d", test.failOn, test.werror, test.files, test.status, status)
+		}
+	}
+}
+
+func TestBaseline(t *testing.T) {
+	file := script(t, "legacy.sieve", "if true {\r\n  keep;\r\n}\r\n")
+	baseline := filepath.Join(t.TempDir(), "baseline.json")
+	var stdout, stderr bytes.Buffer
+	if status := run([]string{"-baseline", baseline, file}, &stdout, &stderr); status != 3 {
+		t.Errorf("expected status 3 for a missing baseline, got %d", status)
+	}
+	if status := run([]string{"-baseline", baseline, "-update-baseline", file}, &stdout, &stderr); status != 0 {
+		t.Fatalf("expected the baseline to be written, got %d: %q", status, stderr.String())
+	}
+	if status := run([]string{"-baseline", baseline, file}, &stdout, &stderr); status != 0 || stdout.Len() != 0 {
+		t.Errorf("expected recorded findings to be left out, got %d: %q", status, stdout.String())
+	}
+
+	// lines inserted above a recorded finding, and a new finding
+	if err := os.WriteFile(file, []byte("# legacy\r\nif true {\r\n  keep;\r\n}\r\nif false {\r\n  keep;\r\n}\r\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if status := run([]string{"-baseline", baseline, file}, &stdout, &stderr); status != 1 {
+		t.Errorf("expected the new finding to fail the check, got %d", status)
+	}
+	if lines := strings.Split(strings.TrimSpace(stdout.String()), "\n"); len(lines) != 1 || !strings.HasPrefix(lines[0], file+":5:4: ") {
+		t.Errorf("expected only the new finding, got %q", stdout.String())
+	}
+}
+
+func TestSARIF(t *testing.T) {
+	warning := script(t, "warning.sieve", "if true {\r\n  keep;\r\n}\r\n")
+	syntax := script(t, "syntax.sieve", "keep\r\n")
+	var stdout, stderr bytes.Buffer
+	if status := run([]string{"-format", "sarif", warning, syntax}, &stdout, &stderr); status != 2 {
+		t.Fatalf("expected status 2, got %d: %q", status, stderr.String())
+	}
+
+	var log sarifLog
+	if err := json.Unmarshal(stdout.Bytes(), &log); err != nil {
+		t.Fatal(err)
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 {
+		t.Fatalf("unexpected log %+v", log)
+	}
+	run := log.Runs[0]
+	if run.Tool.Driver.Name != "sieve-check" || len(run.Results) != 2 || len(run.Tool.Driver.Rules) != 2 {
+		t.Fatalf("unexpected run %+v", run)
+	}
+
+	result := run.Results[0]
+	if result.RuleID != "SIEVE0104" || result.Level != "warning" || *result.RuleIndex != 0 || run.Tool.Driver.Rules[0].ID != "SIEVE0104" {
+		t.Errorf("unexpected result %+v", result)
+	}
+	region := result.Locations[0].PhysicalLocation.Region
+	if uri := result.Locations[0].PhysicalLocation.ArtifactLocation.URI; uri != filepath.ToSlash(warning) || region.StartLine != 1 || region.StartColumn != 4 {
+		t.Errorf("unexpected location %s %+v", uri, region)
+	}
+	if result := run.Results[1]; result.Level != "error" || *result.RuleIndex != 1 {
+		t.Errorf("expected a syntax error, got %+v", result)
+	}
+}
+
+func TestRecursive(t *testing.T) {
+	root := t.TempDir()
+	for name, content := range map[string]string{
+		"alice/active.sieve": "if true {\r\n  keep;\r\n}\r\n",
+		"bob/active.sieve":   "redirect \"bob@example.org\";\r\n",
+		"bob/notes.txt":      "not a script",
+		"carol/active.sieve": "keep\r\n",
+	} {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var stdout, stderr bytes.Buffer
+	if status := run([]string{"-recursive", "-jobs", "2", "-summary", root}, &stdout, &stderr); status != 2 {
+		t.Errorf("expected status 2 for the syntax error, got %d: %q", status, stderr.String())
+	}
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], filepath.Join(root, "alice", "active.sieve")+":") || !strings.HasPrefix(lines[1], filepath.Join(root, "carol", "active.sieve")+":") {
+		t.Errorf("expected the findings of alice and carol in order, got %q", stdout.String())
+	}
+	if !strings.HasPrefix(stderr.String(), "scripts: 3, failed: 1, with redirect: 1\n") {
+		t.Errorf("unexpected summary %q", stderr.String())
+	}
+
+	stdout.Reset()
+	if status := run([]string{root}, &stdout, &stderr); status != 3 {
+		t.Errorf("expected status 3 for a directory without -recursive, got %d", status)
+	}
+}
+
+func TestFix(t *testing.T) {
+	missing := script(t, "missing.sieve", "fileinto \"Archive\";\r\n")
+	unused := script(t, "unused.sieve", "require [\"fileinto\", \"vacation\"];\r\nfileinto \"Archive\";\r\n")
+	syntax := script(t, "syntax.sieve", "keep\r\n")
+	var stdout, stderr bytes.Buffer
+	if status := run([]string{missing, unused}, &stdout, &stderr); status != 1 {
+		t.Errorf("expected status 1 before -fix, got %d: %q", status, stdout.String())
+	}
+	stdout.Reset()
+	if status := run([]string{"-fix", missing, unused, syntax}, &stdout, &stderr); status != 2 || strings.Contains(stdout.String(), "SIEVE01") {
+		t.Errorf("expected only the syntax error, got %d: %q %q", status, stdout.String(), stderr.String())
+	}
+	for file, want := range map[string]string{
+		missing: "require [\"fileinto\"];\r\nfileinto \"Archive\";\r\n",
+		unused:  "require [\"fileinto\"];\r\nfileinto \"Archive\";\r\n",
+		syntax:  "keep\r\n",
+	} {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(content) != want {
+			t.Errorf("%s: expected %q, got %q", filepath.Base(file), want, content)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/url"
+	"path/filepath"
+
+	"gosieve/src/rfc5228"
+)
+
+// The subset of the SARIF 2.1.0 object model (https://docs.oasis-open.org/sarif/sarif/v2.1.0/)
+// written by sieve-check
+type (
+	sarifLog struct {
+		Schema  string     `json:"$schema"`
+		Version string     `json:"version"`
+		Runs    []sarifRun `json:"runs"`
+	}
+	sarifRun struct {
+		Tool       sarifTool     `json:"tool"`
+		ColumnKind string        `json:"columnKind"`
+		Results    []sarifResult `json:"results"`
+	}
+	sarifTool struct {
+		Driver sarifDriver `json:"driver"`
+	}
+	sarifDriver struct {
+		Name           string      `json:"name"`
+		InformationURI string      `json:"informationUri"`
+		Rules          []sarifRule `json:"rules"`
+	}
+	sarifRule struct {
+		ID                   string             `json:"id"`
+		DefaultConfiguration sarifConfiguration `json:"defaultConfiguration"`
+	}
+	sarifConfiguration struct {
+		Level string `json:"level"`
+	}
+	sarifResult struct {
+		RuleID           string          `json:"ruleId,omitempty"`
+		RuleIndex        *int            `json:"ruleIndex,omitempty"`
+		Level            string          `json:"level"`
+		Message          sarifMessage    `json:"message"`
+		Locations        []sarifLocation `json:"locations"`
+		RelatedLocations []sarifLocation `json:"relatedLocations,omitempty"`
+	}
+	sarifMessage struct {
+		Text string `json:"text"`
+	}
+	sarifLocation struct {
+		ID               *int                  `json:"id,omitempty"`
+		PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+	}
+	sarifPhysicalLocation struct {
+		ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+		Region           sarifRegion           `json:"region"`
+	}
+	sarifArtifactLocation struct {
+		URI string `json:"uri"`
+	}
+	sarifRegion struct {
+		StartLine   int `json:"startLine"`
+		StartColumn int `json:"startColumn"`
+	}
+)
+
+// level returns the SARIF level of a severity; hints and infos are notes
+func level(severity rfc5228.Severity) string {
+	switch severity {
+	case rfc5228.SeverityError:
+		return "error"
+	case rfc5228.SeverityWarning:
+		return "warning"
+	}
+	return "note"
+}
+
+// location returns the SARIF location of a position in a file; columns are counted in UTF-16
+// code units, the default column kind of SARIF
+func location(file string, position rfc5228.Position) sarifLocation {
+	line, column := position.Line, position.UTF16Column
+	if line == 0 {
+		line, column = 1, 1
+	}
+	return sarifLocation{PhysicalLocation: sarifPhysicalLocation{
+		ArtifactLocation: sarifArtifactLocation{URI: (&url.URL{Path: filepath.ToSlash(file)}).String()},
+		Region:           sarifRegion{StartLine: line, StartColumn: column},
+	}}
+}
+
+// writeSARIF writes the findings as a SARIF log with a single run
+func writeSARIF(w io.Writer, findings []finding) error {
+	driver := sarifDriver{Name: "sieve-check", InformationURI: "https://github.com/digitalmisfits/gosieve", Rules: []sarifRule{}}
+	rules := map[rfc5228.Code]int{}
+	results := []sarifResult{}
+	for _, f := range findings {
+		result := sarifResult{
+			RuleID:    string(f.code),
+			Level:     level(f.severity),
+			Message:   sarifMessage{Text: f.message},
+			Locations: []sarifLocation{location(f.file, f.position)},
+		}
+		if f.code != "" {
+			index, ok := rules[f.code]
+			if !ok {
+				index = len(driver.Rules)
+				rules[f.code] = index
+				driver.Rules = append(driver.Rules, sarifRule{ID: string(f.code), DefaultConfiguration: sarifConfiguration{Level: level(rfc5228.SeverityOf(f.code))}})
+			}
+			result.RuleIndex = &index
+		}
+		for i, position := range f.related {
+			related := location(f.file, position)
+			id := i + 1
+			related.ID = &id
+			result.RelatedLocations = append(result.RelatedLocations, related)
+		}
+		results = append(results, result)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{{Tool: sarifTool{Driver: driver}, ColumnKind: "utf16CodeUnits", Results: results}},
+	})
+}
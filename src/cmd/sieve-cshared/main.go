@@ -0,0 +1,90 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+// Command sieve-cshared exports the validator with a C interface, for use from mail servers
+// that are not written in Go.
+//
+// Build with `go build -buildmode=c-shared -o libgosieve.so ./src/cmd/sieve-cshared`, which
+// also generates libgosieve.h. For example (C):
+//
+//	char *result = ValidateScript(script, 0);
+//	// {"warnings":[{"pos":3,"message":"..."}]} or {"error":"..."}
+//	FreeString(result);
+package main
+
+/*
+#include <stdlib.h>
+*/
+import "C"
+
+import (
+	"encoding/json"
+	"unsafe"
+
+	"gosieve/src/rfc5228"
+)
+
+// ValidateScript parses and validates a NUL terminated script, strict selects strict mode.
+// It returns a JSON document that the caller must release with FreeString.
+//
+//export ValidateScript
+func ValidateScript(script *C.char, strict C.int) *C.char {
+	var mode rfc5228.Mode
+	if strict != 0 {
+		mode |= rfc5228.ModeStrict
+	}
+	return C.CString(validate(C.GoString(script), mode))
+}
+
+// FreeString releases a string returned by ValidateScript
+//
+//export FreeString
+func FreeString(s *C.char) {
+	C.free(unsafe.Pointer(s))
+}
+
+func validate(script string, mode rfc5228.Mode) string {
+	result := map[string]any{}
+
+	tree, err := rfc5228.Parse("script", script, mode)
+	if err != nil {
+		result["error"] = err.Error()
+	} else {
+		warnings := rfc5228.Validate(tree)
+		if warnings == nil {
+			warnings = []rfc5228.Warning{}
+		}
+		result["warnings"] = warnings
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return `{"error":"internal error"}`
+	}
+	return string(data)
+}
+
+// main is required for -buildmode=c-shared
+func main() {}
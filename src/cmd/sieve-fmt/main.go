@@ -0,0 +1,181 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+// Command sieve-fmt formats scripts, like gofmt.
+//
+//	sieve-fmt [-w] [-l] [-config file] [file...]
+//
+// Without files the script on standard input is formatted. The style is read from the
+// nearest `.sievefmt` file in the directory of a script or one of its parents (see
+// rfc5228.ParseFormatOptions), unless -config names one; without one the default style is
+// used. The formatted script is written to standard output, or back to the file with -w;
+// -l lists the files whose formatting differs instead. The exit status is 1 if a script
+// can't be read or parsed and 2 if the arguments are invalid.
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"gosieve/src/rfc5228"
+)
+
+// configName is the name of the file holding the formatting options
+const configName = ".sievefmt"
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run formats the files named by the arguments and returns the exit status
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("sieve-fmt", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	write := flags.Bool("w", false, "write the result to the file instead of standard output")
+	list := flags.Bool("l", false, "list the files whose formatting differs")
+	config := flags.String("config", "", "file with the formatting options, instead of the nearest "+configName)
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	var fixed *rfc5228.FormatOptions
+	if *config != "" {
+		opts, err := readConfig(*config)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 2
+		}
+		fixed = &opts
+	}
+	options := func(dir string) (rfc5228.FormatOptions, error) {
+		if fixed != nil {
+			return *fixed, nil
+		}
+		return findConfig(dir)
+	}
+
+	if flags.NArg() == 0 {
+		content, err := io.ReadAll(stdin)
+		if err == nil {
+			var opts rfc5228.FormatOptions
+			if opts, err = options("."); err == nil {
+				err = format("<stdin>", content, opts, stdout)
+			}
+		}
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+		return 0
+	}
+
+	status := 0
+	for _, file := range flags.Args() {
+		if err := formatFile(file, options, *write, *list, stdout); err != nil {
+			fmt.Fprintln(stderr, err)
+			status = 1
+		}
+	}
+	return status
+}
+
+func formatFile(file string, options func(dir string) (rfc5228.FormatOptions, error), write, list bool, stdout io.Writer) error {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	opts, err := options(filepath.Dir(file))
+	if err != nil {
+		return err
+	}
+	var formatted bytes.Buffer
+	if err := format(file, content, opts, &formatted); err != nil {
+		return err
+	}
+
+	switch {
+	case list:
+		if !bytes.Equal(content, formatted.Bytes()) {
+			_, err = fmt.Fprintln(stdout, file)
+		}
+	case write:
+		if !bytes.Equal(content, formatted.Bytes()) {
+			err = os.WriteFile(file, formatted.Bytes(), 0o644)
+		}
+	default:
+		_, err = stdout.Write(formatted.Bytes())
+	}
+	return err
+}
+
+func format(name string, content []byte, opts rfc5228.FormatOptions, w io.Writer) error {
+	tree, err := rfc5228.Parse(name, string(content), 0)
+	if err != nil {
+		return err
+	}
+	formatted, err := rfc5228.Format(tree, opts)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	_, err = io.WriteString(w, formatted)
+	return err
+}
+
+func readConfig(path string) (rfc5228.FormatOptions, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return rfc5228.FormatOptions{}, err
+	}
+	opts, err := rfc5228.ParseFormatOptions(string(content))
+	if err != nil {
+		return opts, fmt.Errorf("%s: %w", path, err)
+	}
+	return opts, nil
+}
+
+// findConfig reads the nearest configuration file in dir or one of its parents
+func findConfig(dir string) (rfc5228.FormatOptions, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return rfc5228.FormatOptions{}, err
+	}
+	for {
+		opts, err := readConfig(filepath.Join(dir, configName))
+		if !errors.Is(err, fs.ErrNotExist) {
+			return opts, err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return rfc5228.DefaultFormatOptions(), nil
+		}
+		dir = parent
+	}
+}
@@ -0,0 +1,95 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const unformatted = "if header :is \"from\" [\"a@example.org\", \"b@example.org\"] { keep; }\r\n"
+
+func TestConfigDiscovery(t *testing.T) {
+	root := t.TempDir()
+	nested := filepath.Join(root, "team", "rules")
+	if err := os.MkdirAll(nested, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, configName), []byte("indent_style = tab\nbrace_style = next_line\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(nested, "filter.sieve")
+	if err := os.WriteFile(file, []byte(unformatted), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if status := run([]string{file}, nil, &stdout, &stderr); status != 0 {
+		t.Fatalf("expected status 0, got %d: %q", status, stderr.String())
+	}
+	if expected := "if header :is \"from\" [\"a@example.org\", \"b@example.org\"]\r\n{\r\n\tkeep;\r\n}\r\n"; stdout.String() != expected {
+		t.Errorf("expected %q, got %q", expected, stdout.String())
+	}
+
+	stdout.Reset()
+	if status := run([]string{"-l", file}, nil, &stdout, &stderr); status != 0 || stdout.String() != file+"\n" {
+		t.Errorf("expected %s to be listed, got %d: %q", file, status, stdout.String())
+	}
+	if status := run([]string{"-w", file}, nil, &stdout, &stderr); status != 0 {
+		t.Fatalf("expected status 0, got %d: %q", status, stderr.String())
+	}
+	stdout.Reset()
+	if status := run([]string{"-l", file}, nil, &stdout, &stderr); status != 0 || stdout.Len() != 0 {
+		t.Errorf("expected the written file to be formatted, got %d: %q", status, stdout.String())
+	}
+}
+
+func TestStdin(t *testing.T) {
+	config := filepath.Join(t.TempDir(), "style")
+	if err := os.WriteFile(config, []byte("indent_size = 4\nmax_line_length = 40\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var stdout, stderr bytes.Buffer
+	if status := run([]string{"-config", config}, strings.NewReader(unformatted), &stdout, &stderr); status != 0 {
+		t.Fatalf("expected status 0, got %d: %q", status, stderr.String())
+	}
+	if expected := "if header :is \"from\" [\r\n    \"a@example.org\",\r\n    \"b@example.org\"\r\n] {\r\n    keep;\r\n}\r\n"; stdout.String() != expected {
+		t.Errorf("expected %q, got %q", expected, stdout.String())
+	}
+
+	if status := run(nil, strings.NewReader("keep\r\n"), &stdout, &stderr); status != 1 {
+		t.Errorf("expected status 1 for a syntax error, got %d", status)
+	}
+	if err := os.WriteFile(config, []byte("indent = 4\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if status := run([]string{"-config", config}, strings.NewReader(unformatted), &stdout, &stderr); status != 2 {
+		t.Errorf("expected status 2 for an invalid configuration, got %d", status)
+	}
+}
@@ -0,0 +1,192 @@
+//go:build js && wasm
+
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+// Command sieve-wasm exposes the parser, validator and formatter to JavaScript.
+//
+// Build with `GOOS=js GOARCH=wasm go build -o gosieve.wasm ./src/cmd/sieve-wasm` and load it
+// with wasm_exec.js; it registers a global `gosieve` object:
+//
+//	gosieve.parse(script, strict)   // => {tree: {...}} or {error: "..."}
+//	gosieve.validate(script)        // => {warnings: [{pos, message}, ...]} or {error: "..."}
+//	gosieve.format(script, options) // => {script: "..."} or {error: "..."}
+//
+// The options of format are optional, as are their fields, which default to those of
+// rfc5228.DefaultFormatOptions: {indentWidth: 2, useTabs: false, maxLineLength: 80,
+// listWrap: "auto" | "always" | "never", braceStyle: "same_line" | "next_line"}.
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"syscall/js"
+
+	"gosieve/src/rfc5228"
+)
+
+func main() {
+	js.Global().Set("gosieve", js.ValueOf(map[string]any{
+		"parse":    js.FuncOf(parse),
+		"validate": js.FuncOf(validate),
+		"format":   js.FuncOf(format),
+	}))
+
+	// keep the exported functions alive
+	select {}
+}
+
+// toJS converts a value to a JavaScript object by a round trip through JSON
+func toJS(v any) js.Value {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return failure(err)
+	}
+	return js.Global().Get("JSON").Call("parse", string(data))
+}
+
+func failure(err error) js.Value {
+	return js.ValueOf(map[string]any{"error": err.Error()})
+}
+
+func parse(_ js.Value, args []js.Value) any {
+	if len(args) < 1 {
+		return js.ValueOf(map[string]any{"error": "missing script"})
+	}
+	if args[0].Type() != js.TypeString {
+		return js.ValueOf(map[string]any{"error": "script is not a string"})
+	}
+
+	var mode rfc5228.Mode
+	if len(args) > 1 && args[1].Truthy() {
+		mode |= rfc5228.ModeStrict
+	}
+
+	tree, err := rfc5228.Parse("script", args[0].String(), mode)
+	if err != nil {
+		return failure(err)
+	}
+	return toJS(map[string]any{"tree": tree})
+}
+
+func validate(_ js.Value, args []js.Value) any {
+	if len(args) < 1 {
+		return js.ValueOf(map[string]any{"error": "missing script"})
+	}
+	if args[0].Type() != js.TypeString {
+		return js.ValueOf(map[string]any{"error": "script is not a string"})
+	}
+
+	tree, err := rfc5228.Parse("script", args[0].String(), 0)
+	if err != nil {
+		return failure(err)
+	}
+
+	warnings := rfc5228.Validate(tree)
+	if warnings == nil {
+		warnings = []rfc5228.Warning{}
+	}
+	return toJS(map[string]any{"warnings": warnings})
+}
+
+func format(_ js.Value, args []js.Value) any {
+	if len(args) < 1 {
+		return js.ValueOf(map[string]any{"error": "missing script"})
+	}
+	if args[0].Type() != js.TypeString {
+		return js.ValueOf(map[string]any{"error": "script is not a string"})
+	}
+
+	opts := rfc5228.DefaultFormatOptions()
+	if len(args) > 1 && !args[1].IsUndefined() && !args[1].IsNull() {
+		if args[1].Type() != js.TypeObject {
+			return js.ValueOf(map[string]any{"error": "options is not an object"})
+		}
+		if err := formatOptions(args[1], &opts); err != nil {
+			return failure(err)
+		}
+	}
+
+	tree, err := rfc5228.Parse("script", args[0].String(), 0)
+	if err != nil {
+		return failure(err)
+	}
+	script, err := rfc5228.Format(tree, opts)
+	if err != nil {
+		return failure(err)
+	}
+	return js.ValueOf(map[string]any{"script": script})
+}
+
+// formatOptions sets the fields of opts given in a JavaScript object
+func formatOptions(v js.Value, opts *rfc5228.FormatOptions) error {
+	if field := v.Get("indentWidth"); !field.IsUndefined() {
+		if field.Type() != js.TypeNumber {
+			return fmt.Errorf("indentWidth is not a number")
+		}
+		opts.IndentWidth = field.Int()
+	}
+	if field := v.Get("useTabs"); !field.IsUndefined() {
+		opts.UseTabs = field.Truthy()
+	}
+	if field := v.Get("maxLineLength"); !field.IsUndefined() {
+		if field.Type() != js.TypeNumber {
+			return fmt.Errorf("maxLineLength is not a number")
+		}
+		opts.MaxLineLength = field.Int()
+	}
+	if opts.IndentWidth < 0 || opts.MaxLineLength < 0 {
+		return fmt.Errorf("negative indentWidth or maxLineLength")
+	}
+	if field := v.Get("listWrap"); !field.IsUndefined() {
+		if field.Type() != js.TypeString {
+			return fmt.Errorf("listWrap is not a string")
+		}
+		switch field.String() {
+		case "auto":
+			opts.ListWrap = rfc5228.ListWrapAuto
+		case "always":
+			opts.ListWrap = rfc5228.ListWrapAlways
+		case "never":
+			opts.ListWrap = rfc5228.ListWrapNever
+		default:
+			return fmt.Errorf("unknown listWrap %q", field.String())
+		}
+	}
+	if field := v.Get("braceStyle"); !field.IsUndefined() {
+		if field.Type() != js.TypeString {
+			return fmt.Errorf("braceStyle is not a string")
+		}
+		switch field.String() {
+		case "same_line":
+			opts.BraceStyle = rfc5228.BraceSameLine
+		case "next_line":
+			opts.BraceStyle = rfc5228.BraceNextLine
+		default:
+			return fmt.Errorf("unknown braceStyle %q", field.String())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,240 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+// Command sieved serves the parser, validator and formatter over HTTP with JSON requests and responses.
+//
+//	POST /parse     {"script": "...", "strict": false, "extensions": ["fileinto"], "max_size": 65536, "locale": "nl", "suppress": ["SIEVE0104"]}
+//	POST /validate  (same request)
+//	POST /format    (same request, with "format": {"indent_width": 2, "use_tabs": false, "max_line_length": 80, "list_wrap": "auto", "brace_style": "same_line"})
+//
+// /parse responds with {"tree": {...}}, /validate with {"warnings": [...]}, /format with
+// {"script": "..."}; failures are reported as {"error": "..."}, syntax errors as {"error": "...", "code": "SIEVE0004", "pos": 12, "line": 2, "column": 5}.
+// When extensions is given, requiring any other capability is an error. max_size may lower, but
+// not raise, the size limit of the server; a larger script is the syntax error SIEVE0024.
+// locale selects the language of error and warning messages and suppress lists the codes of
+// warnings to leave out. The fields of format are optional and default to those of
+// rfc5228.DefaultFormatOptions; list_wrap is auto, always or never, brace_style same_line or
+// next_line.
+//
+// Only HTTP with JSON is served: there is no gRPC endpoint, and scripts can't be evaluated
+// against a message, as the module has no interpreter.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+
+	"gosieve/src/rfc5228"
+)
+
+// request is the JSON body shared by all endpoints
+type request struct {
+	Script     string         `json:"script"`
+	Strict     bool           `json:"strict"`
+	Extensions []string       `json:"extensions"`
+	MaxSize    int            `json:"max_size"`
+	Locale     string         `json:"locale"`
+	Suppress   []rfc5228.Code `json:"suppress"`
+	Format     formatRequest  `json:"format"`
+}
+
+// formatRequest holds the style of /format; fields left out keep their default
+type formatRequest struct {
+	IndentWidth   *int   `json:"indent_width"`
+	UseTabs       *bool  `json:"use_tabs"`
+	MaxLineLength *int   `json:"max_line_length"`
+	ListWrap      string `json:"list_wrap"`
+	BraceStyle    string `json:"brace_style"`
+}
+
+// options returns the format options of a request
+func (f formatRequest) options() (rfc5228.FormatOptions, error) {
+	opts := rfc5228.DefaultFormatOptions()
+	if f.IndentWidth != nil {
+		opts.IndentWidth = *f.IndentWidth
+	}
+	if f.UseTabs != nil {
+		opts.UseTabs = *f.UseTabs
+	}
+	if f.MaxLineLength != nil {
+		opts.MaxLineLength = *f.MaxLineLength
+	}
+	if opts.IndentWidth < 0 || opts.MaxLineLength < 0 {
+		return opts, errors.New("negative indent_width or max_line_length")
+	}
+	switch f.ListWrap {
+	case "", "auto":
+	case "always":
+		opts.ListWrap = rfc5228.ListWrapAlways
+	case "never":
+		opts.ListWrap = rfc5228.ListWrapNever
+	default:
+		return opts, fmt.Errorf("unknown list_wrap %q", f.ListWrap)
+	}
+	switch f.BraceStyle {
+	case "", "same_line":
+	case "next_line":
+		opts.BraceStyle = rfc5228.BraceNextLine
+	default:
+		return opts, fmt.Errorf("unknown brace_style %q", f.BraceStyle)
+	}
+	return opts, nil
+}
+
+// options returns the parser and validator options of a request
+func (req request) options() []rfc5228.Option {
+	var opts []rfc5228.Option
+	if req.Locale != "" {
+		opts = append(opts, rfc5228.WithLocale(req.Locale))
+	}
+	if len(req.Suppress) > 0 {
+		opts = append(opts, rfc5228.WithSuppressed(req.Suppress...))
+	}
+	return opts
+}
+
+type server struct {
+	maxSize int // maximum script size in bytes
+}
+
+func main() {
+	listen := flag.String("listen", "localhost:4190", "address to listen on")
+	maxSize := flag.Int("max-size", 1<<20, "maximum script size in bytes")
+	flag.Parse()
+
+	log.Fatal(http.ListenAndServe(*listen, newServer(*maxSize).handler()))
+}
+
+func newServer(maxSize int) *server {
+	return &server{maxSize: maxSize}
+}
+
+func (s *server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/parse", s.serve(func(tree *rfc5228.Tree, req request) any {
+		return map[string]any{"tree": tree}
+	}))
+	mux.HandleFunc("/validate", s.serve(func(tree *rfc5228.Tree, req request) any {
+		warnings := rfc5228.Validate(tree, req.options()...)
+		if warnings == nil {
+			warnings = []rfc5228.Warning{}
+		}
+		return map[string]any{"warnings": warnings}
+	}))
+	mux.HandleFunc("/format", s.serve(func(tree *rfc5228.Tree, req request) any {
+		opts, err := req.Format.options()
+		if err != nil {
+			return err
+		}
+		script, err := rfc5228.Format(tree, opts)
+		if err != nil {
+			return err
+		}
+		return map[string]any{"script": script}
+	}))
+	return mux
+}
+
+// serve decodes and parses the script of a request and responds with the result of fn,
+// which may be an error
+func (s *server) serve(fn func(tree *rfc5228.Tree, req request) any) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			respond(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+			return
+		}
+
+		// a byte of a script takes up to 6 bytes in JSON (a control character as \u0000); allow
+		// 4096 bytes for the other fields
+		var req request
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, int64(6*s.maxSize+4096))).Decode(&req); err != nil {
+			respond(w, http.StatusBadRequest, fmt.Errorf("invalid request: %w", err))
+			return
+		}
+
+		tree, err := s.parse(req)
+		if err != nil {
+			respond(w, http.StatusUnprocessableEntity, err)
+			return
+		}
+		result := fn(tree, req)
+		if err, ok := result.(error); ok {
+			respond(w, http.StatusUnprocessableEntity, err)
+			return
+		}
+		respond(w, http.StatusOK, result)
+	}
+}
+
+// parse parses the script of a request, honoring its limits and extension set
+func (s *server) parse(req request) (*rfc5228.Tree, error) {
+	maxSize := s.maxSize
+	if req.MaxSize > 0 && req.MaxSize < maxSize {
+		maxSize = req.MaxSize
+	}
+
+	var mode rfc5228.Mode
+	if req.Strict {
+		mode |= rfc5228.ModeStrict
+	}
+	tree, err := rfc5228.Parse("script", req.Script, mode, append(req.options(), rfc5228.WithMaxSize(maxSize))...)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Extensions != nil {
+		supported := map[string]bool{}
+		for _, ext := range req.Extensions {
+			supported[ext] = true
+		}
+		for _, require := range tree.Requires() {
+			for _, capability := range require.Capabilities {
+				if !supported[capability] {
+					return nil, fmt.Errorf("unsupported capability %q at %d", capability, require.Pos)
+				}
+			}
+		}
+	}
+	return tree, nil
+}
+
+// respond writes v as JSON; errors are written as {"error": "..."}
+func respond(w http.ResponseWriter, status int, v any) {
+	var syntax *rfc5228.SyntaxError
+	if err, ok := v.(error); ok && errors.As(err, &syntax) {
+		v = map[string]any{"error": err.Error(), "code": syntax.Code, "pos": syntax.Pos, "line": syntax.Line, "column": syntax.Column}
+	} else if ok {
+		v = map[string]any{"error": err.Error()}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("write response: %s", err)
+	}
+}
@@ -0,0 +1,128 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func post(t *testing.T, handler http.Handler, path, body string) (int, map[string]any) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+
+	var result map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	return rec.Code, result
+}
+
+func TestValidate(t *testing.T) {
+	handler := newServer(1024).handler()
+
+	status, result := post(t, handler, "/validate", `{"script": "if true {\r\n  keep;\r\n}\r\n"}`)
+	if status != http.StatusOK {
+		t.Fatalf("unexpected status %d: %v", status, result)
+	}
+	if warnings := result["warnings"].([]any); len(warnings) != 1 {
+		t.Errorf("expected 1 warning, got %v", warnings)
+	}
+
+	_, result = post(t, handler, "/validate", `{"script": "if true {\r\n  keep;\r\n}\r\n", "locale": "nl"}`)
+	if warning := result["warnings"].([]any)[0].(map[string]any); warning["message"] != "`if`-voorwaarde is altijd waar" || warning["code"] != "SIEVE0104" {
+		t.Errorf("unexpected warning %v", warning)
+	}
+
+	_, result = post(t, handler, "/validate", `{"script": "if true {\r\n  keep;\r\n}\r\n", "suppress": ["SIEVE0104"]}`)
+	if warnings := result["warnings"].([]any); len(warnings) != 0 {
+		t.Errorf("expected no warnings, got %v", warnings)
+	}
+
+	status, result = post(t, handler, "/parse", `{"script": "keep\r\n"}`)
+	if status != http.StatusUnprocessableEntity || result["code"] != "SIEVE0004" || result["pos"] != 4.0 || result["line"] != 1.0 || result["column"] != 5.0 {
+		t.Errorf("unexpected response %d: %v", status, result)
+	}
+
+	status, result = post(t, handler, "/parse", `{"script": "keep;\r\n"}`)
+	if status != http.StatusOK || result["tree"] == nil {
+		t.Errorf("unexpected response %d: %v", status, result)
+	}
+}
+
+func TestLimitsAndExtensions(t *testing.T) {
+	handler := newServer(1024).handler()
+
+	for body, expected := range map[string]int{
+		`{"script": "require \"fileinto\";\r\n", "extensions": ["fileinto"]}`: http.StatusOK,
+		`{"script": "require \"fileinto\";\r\n", "extensions": []}`:           http.StatusUnprocessableEntity,
+		`{"script": "keep;\r\n", "max_size": 4}`:                              http.StatusUnprocessableEntity,
+		`{"script": "if {"}`:                                                  http.StatusUnprocessableEntity,
+		`{"script": `:                                                         http.StatusBadRequest,
+	} {
+		if status, result := post(t, handler, "/validate", body); status != expected {
+			t.Errorf("%s: expected status %d, got %d: %v", body, expected, status, result)
+		}
+	}
+
+	// a script of the size limit fits in the request even if every byte is escaped
+	escaped := `{"script": "#` + strings.Repeat(`\u0001`, 1023) + `"}`
+	if status, result := post(t, handler, "/validate", escaped); status != http.StatusOK {
+		t.Errorf("expected an escaped script to be accepted, got %d: %v", status, result)
+	}
+
+	if _, result := post(t, handler, "/parse", `{"script": "keep;\r\n", "max_size": 4}`); result["code"] != "SIEVE0024" {
+		t.Errorf("expected the size limit of the parser, got %v", result)
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/validate", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected status %d, got %d", http.StatusMethodNotAllowed, rec.Code)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	handler := newServer(1024).handler()
+
+	status, result := post(t, handler, "/format", `{"script": "if true{keep;}"}`)
+	if status != http.StatusOK || result["script"] != "if true {\r\n  keep;\r\n}\r\n" {
+		t.Errorf("unexpected response %d: %v", status, result)
+	}
+	status, result = post(t, handler, "/format", `{"script": "if true{keep;}", "format": {"use_tabs": true, "brace_style": "next_line"}}`)
+	if status != http.StatusOK || result["script"] != "if true\r\n{\r\n\tkeep;\r\n}\r\n" {
+		t.Errorf("unexpected response %d: %v", status, result)
+	}
+	for _, body := range []string{`{"script": "keep"}`, `{"script": "keep;", "format": {"list_wrap": "sometimes"}}`, `{"script": "keep;", "format": {"indent_width": -1}}`} {
+		if status, result := post(t, handler, "/format", body); status != http.StatusUnprocessableEntity {
+			t.Errorf("%s: expected status %d, got %d: %v", body, http.StatusUnprocessableEntity, status, result)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// FileBackend stores every revision as a JSON file in a directory per script:
+//
+//	<Dir>/<name>/000001.json
+type FileBackend struct {
+	Dir string
+}
+
+// validName rejects script names that would escape the directory of the backend
+func validName(name string) error {
+	if name == "" || strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, ".") {
+		return fmt.Errorf("invalid script name %q", name)
+	}
+	return nil
+}
+
+func (b *FileBackend) Append(name string, rev Revision) error {
+	if err := validName(name); err != nil {
+		return err
+	}
+	dir := filepath.Join(b.Dir, name)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(rev)
+	if err != nil {
+		return err
+	}
+
+	// O_EXCL makes sure an existing revision is never overwritten
+	f, err := os.OpenFile(filepath.Join(dir, fmt.Sprintf("%06d.json", rev.Number)), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (b *FileBackend) Revisions(name string) ([]Revision, error) {
+	if err := validName(name); err != nil {
+		return nil, err
+	}
+	files, err := filepath.Glob(filepath.Join(b.Dir, name, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	revs := make([]Revision, 0, len(files))
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		var rev Revision
+		if err := json.Unmarshal(data, &rev); err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		revs = append(revs, rev)
+	}
+	sort.Slice(revs, func(i, j int) bool { return revs[i].Number < revs[j].Number })
+	return revs, nil
+}
+
+// MemoryBackend keeps revisions in memory, e.g. for tests
+type MemoryBackend struct {
+	mu      sync.Mutex
+	scripts map[string][]Revision
+}
+
+func (b *MemoryBackend) Append(name string, rev Revision) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.scripts == nil {
+		b.scripts = map[string][]Revision{}
+	}
+	b.scripts[name] = append(b.scripts[name], rev)
+	return nil
+}
+
+func (b *MemoryBackend) Revisions(name string) ([]Revision, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]Revision(nil), b.scripts[name]...), nil
+}
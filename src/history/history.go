@@ -0,0 +1,193 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+// Package history stores revisions of sieve scripts and answers structural questions
+// about them: what changed between two revisions and which revision introduced a rule.
+package history
+
+import (
+	"fmt"
+	"time"
+
+	"gosieve/src/rfc5228"
+)
+
+// Revision is a stored version of a script
+type Revision struct {
+	Number  int       `json:"number"` // 1 for the first revision of a script
+	Time    time.Time `json:"time"`
+	Author  string    `json:"author"`
+	Message string    `json:"message"`
+	Script  string    `json:"script"`
+}
+
+// Backend persists revisions; implementations exist for the file system (FileBackend)
+// and memory (MemoryBackend), other stores (e.g. SQL) can be plugged in
+type Backend interface {
+	// Append stores a revision of the named script
+	Append(name string, rev Revision) error
+	// Revisions returns the revisions of the named script, oldest first
+	Revisions(name string) ([]Revision, error)
+}
+
+// History records and inspects script revisions
+type History struct {
+	Backend Backend
+	Now     func() time.Time // clock; time.Now if nil
+}
+
+// New returns a history on top of a backend
+func New(backend Backend) *History {
+	return &History{Backend: backend}
+}
+
+// Commit stores a new revision of the named script; scripts that don't parse are rejected
+func (h *History) Commit(name, script, author, message string) (Revision, error) {
+	if _, err := rfc5228.Parse(name, script, 0); err != nil {
+		return Revision{}, err
+	}
+
+	revs, err := h.Backend.Revisions(name)
+	if err != nil {
+		return Revision{}, err
+	}
+
+	now := time.Now
+	if h.Now != nil {
+		now = h.Now
+	}
+	rev := Revision{Number: len(revs) + 1, Time: now(), Author: author, Message: message, Script: script}
+	if err := h.Backend.Append(name, rev); err != nil {
+		return Revision{}, err
+	}
+	return rev, nil
+}
+
+// ChangeKind identifies the type of a structural change
+type ChangeKind int
+
+const (
+	Removed ChangeKind = iota // the command exists in the old revision only
+	Added                     // the command exists in the new revision only
+)
+
+func (k ChangeKind) String() string {
+	if k == Added {
+		return "added"
+	}
+	return "removed"
+}
+
+// Change is a top-level command that was added or removed between two revisions
+type Change struct {
+	Kind    ChangeKind
+	Command rfc5228.Command // node in the old (Removed) or new (Added) tree
+}
+
+// Diff compares the top-level commands of two scripts by meaning rather than by text:
+// layout, comments and identifier case are ignored (see rfc5228.FingerprintCommand).
+// Removed commands are listed first, both in lexical order.
+func Diff(old, new string) ([]Change, error) {
+	a, err := rfc5228.Parse("old", old, 0)
+	if err != nil {
+		return nil, err
+	}
+	b, err := rfc5228.Parse("new", new, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	count := map[string]int{}
+	for _, node := range b.Commands() {
+		count[rfc5228.FingerprintCommand(node)]++
+	}
+
+	var changes []Change
+	for _, node := range a.Commands() {
+		f := rfc5228.FingerprintCommand(node)
+		if count[f] > 0 {
+			count[f]-- // unchanged
+			continue
+		}
+		changes = append(changes, Change{Kind: Removed, Command: node})
+	}
+
+	// whatever remains counted only exists in the new script
+	for _, node := range b.Commands() {
+		f := rfc5228.FingerprintCommand(node)
+		if count[f] > 0 {
+			count[f]--
+			changes = append(changes, Change{Kind: Added, Command: node})
+		}
+	}
+	return changes, nil
+}
+
+// Attribution links a top-level command of the latest revision to the revision that introduced it
+type Attribution struct {
+	Command  rfc5228.Command
+	Revision Revision
+}
+
+// Blame returns, for each top-level command of the latest revision of the named script
+// for which match returns true, the revision since which the command is present unchanged
+func (h *History) Blame(name string, match func(rfc5228.Command) bool) ([]Attribution, error) {
+	revs, err := h.Backend.Revisions(name)
+	if err != nil {
+		return nil, err
+	}
+	if len(revs) == 0 {
+		return nil, fmt.Errorf("no revisions of script %s", name)
+	}
+
+	// the set of command fingerprints of each revision
+	present := make([]map[string]bool, len(revs))
+	var latest *rfc5228.Tree
+	for i, rev := range revs {
+		tree, err := rfc5228.Parse(name, rev.Script, 0)
+		if err != nil {
+			return nil, fmt.Errorf("revision %d: %w", rev.Number, err)
+		}
+		present[i] = map[string]bool{}
+		for _, node := range tree.Commands() {
+			present[i][rfc5228.FingerprintCommand(node)] = true
+		}
+		latest = tree
+	}
+
+	var attributions []Attribution
+	for _, node := range latest.Commands() {
+		if !match(node) {
+			continue
+		}
+		f := rfc5228.FingerprintCommand(node)
+		i := len(revs) - 1
+		for i > 0 && present[i-1][f] {
+			i--
+		}
+		attributions = append(attributions, Attribution{Command: node, Revision: revs[i]})
+	}
+	return attributions, nil
+}
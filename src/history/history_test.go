@@ -0,0 +1,118 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package history
+
+import (
+	"testing"
+	"time"
+
+	"gosieve/src/rfc5228"
+)
+
+const (
+	revision1 = "require \"fileinto\";\r\nkeep;\r\n"
+	revision2 = "require \"fileinto\";\r\nif address :is \"from\" \"spammer@example.com\" {\r\n  discard;\r\n}\r\nkeep;\r\n"
+	revision3 = "# reformatted\r\nREQUIRE \"fileinto\";\r\nif address :is \"from\" \"spammer@example.com\" { discard; }\r\nredirect \"me@example.org\";\r\n"
+)
+
+func TestHistory(t *testing.T) {
+	for name, backend := range map[string]Backend{
+		"file":   &FileBackend{Dir: t.TempDir()},
+		"memory": &MemoryBackend{},
+	} {
+		t.Run(name, func(t *testing.T) {
+			h := New(backend)
+			h.Now = func() time.Time { return time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC) }
+
+			for i, script := range []string{revision1, revision2, revision3} {
+				rev, err := h.Commit("user", script, "admin", "update")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if rev.Number != i+1 {
+					t.Errorf("expected revision %d, got %d", i+1, rev.Number)
+				}
+			}
+			if _, err := h.Commit("user", "if {", "admin", "broken"); err == nil {
+				t.Errorf("expected error for invalid script")
+			}
+
+			// which revision introduced the rule that discards mail from spammer@example.com?
+			attributions, err := h.Blame("user", func(node rfc5228.Command) bool {
+				n, ok := node.(*rfc5228.IfNode)
+				if !ok {
+					return false
+				}
+				for _, list := range n.Test.StringLists() {
+					for _, s := range list {
+						if s == "spammer@example.com" {
+							return true
+						}
+					}
+				}
+				return false
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(attributions) != 1 || attributions[0].Revision.Number != 2 {
+				t.Errorf("expected the rule to be introduced in revision 2, got %v", attributions)
+			}
+		})
+	}
+}
+
+func TestDiff(t *testing.T) {
+	changes, err := Diff(revision2, revision3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 2 {
+		t.Fatalf("expected 2 changes, got %v", changes)
+	}
+	if _, ok := changes[0].Command.(*rfc5228.KeepNode); !ok || changes[0].Kind != Removed {
+		t.Errorf("expected keep to be removed, got %s %T", changes[0].Kind, changes[0].Command)
+	}
+	if _, ok := changes[1].Command.(*rfc5228.RedirectNode); !ok || changes[1].Kind != Added {
+		t.Errorf("expected redirect to be added, got %s %T", changes[1].Kind, changes[1].Command)
+	}
+}
+
+func TestFileBackendNames(t *testing.T) {
+	backend := &FileBackend{Dir: t.TempDir()}
+	for _, name := range []string{"", "../escape", ".hidden", `a\b`} {
+		if err := backend.Append(name, Revision{Number: 1}); err == nil {
+			t.Errorf("%q: expected error", name)
+		}
+	}
+
+	if err := backend.Append("user", Revision{Number: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := backend.Append("user", Revision{Number: 1}); err == nil {
+		t.Errorf("expected error when overwriting a revision")
+	}
+}
@@ -0,0 +1,196 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+// Package apitest checks the exported API of a package against golden export data, so that
+// a breaking change to it fails the tests of the package instead of the builds of its users.
+//
+// The golden file holds a line per exported constant, variable, function, type, struct field
+// and method, in the form of the package's own types; parameter names are left out, as they
+// can change without breaking callers. A change that only adds lines is compatible, a removed
+// or changed line is breaking. Run the tests with -update-api to accept the current API.
+package apitest
+
+import (
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update-api", false, "write the exported API to the golden files")
+
+// Check compares the exported API of the package in dir to the golden file; lines missing
+// from the API are reported as breaking changes and new lines as additions to accept.
+func Check(t *testing.T, dir, golden string) {
+	t.Helper()
+	api, err := API(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(golden), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(golden, []byte(strings.Join(api, "\n")+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	data, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("%v; run the tests with -update-api to create it", err)
+	}
+
+	current := map[string]bool{}
+	for _, line := range api {
+		current[line] = true
+	}
+	recorded := map[string]bool{}
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		recorded[line] = true
+		if !current[line] {
+			t.Errorf("breaking change: %s", line)
+		}
+	}
+	for _, line := range api {
+		if !recorded[line] {
+			t.Errorf("addition to the API: %s; run the tests with -update-api to accept it", line)
+		}
+	}
+}
+
+// API returns the sorted lines of the exported API of the package in dir, test files left out
+func API(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, entry := range entries {
+		if name := entry.Name(); !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(fset, filepath.Join(dir, entry.Name()), nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no package in %s", dir)
+	}
+
+	config := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	pkg, err := config.Check(dir, fset, files, nil)
+	if err != nil {
+		return nil, err
+	}
+	qualifier := types.RelativeTo(pkg)
+
+	var api []string
+	scope := pkg.Scope()
+	for _, name := range scope.Names() {
+		if !token.IsExported(name) {
+			continue
+		}
+		switch obj := scope.Lookup(name).(type) {
+		case *types.Const:
+			api = append(api, fmt.Sprintf("const %s %s", name, types.TypeString(obj.Type(), qualifier)))
+		case *types.Var:
+			api = append(api, fmt.Sprintf("var %s %s", name, types.TypeString(obj.Type(), qualifier)))
+		case *types.Func:
+			api = append(api, fmt.Sprintf("func %s%s", name, signature(obj.Type().(*types.Signature), qualifier)))
+		case *types.TypeName:
+			api = append(api, typeAPI(obj, qualifier)...)
+		}
+	}
+	sort.Strings(api)
+	return api, nil
+}
+
+// typeAPI returns the lines of an exported type: its kind, exported fields and methods
+func typeAPI(obj *types.TypeName, qualifier types.Qualifier) []string {
+	name := obj.Name()
+	if obj.IsAlias() {
+		return []string{fmt.Sprintf("type %s = %s", name, types.TypeString(obj.Type(), qualifier))}
+	}
+
+	var api []string
+	switch underlying := obj.Type().Underlying().(type) {
+	case *types.Struct:
+		api = append(api, fmt.Sprintf("type %s struct", name))
+		for i := 0; i < underlying.NumFields(); i++ {
+			if field := underlying.Field(i); field.Exported() {
+				api = append(api, fmt.Sprintf("field %s.%s %s", name, field.Name(), types.TypeString(field.Type(), qualifier)))
+			}
+		}
+	case *types.Interface:
+		// every method of an interface is part of its API, as implementations need them all
+		api = append(api, fmt.Sprintf("type %s interface", name))
+		for i := 0; i < underlying.NumMethods(); i++ {
+			method := underlying.Method(i)
+			api = append(api, fmt.Sprintf("method %s.%s%s", name, method.Name(), signature(method.Type().(*types.Signature), qualifier)))
+		}
+		return api
+	default:
+		api = append(api, fmt.Sprintf("type %s %s", name, types.TypeString(underlying, qualifier)))
+	}
+
+	methods := types.NewMethodSet(types.NewPointer(obj.Type()))
+	for i := 0; i < methods.Len(); i++ {
+		method := methods.At(i).Obj().(*types.Func)
+		if !method.Exported() || len(methods.At(i).Index()) > 1 {
+			continue // promoted methods are listed with the embedded type
+		}
+		receiver := name
+		if _, ok := method.Type().(*types.Signature).Recv().Type().(*types.Pointer); ok {
+			receiver = "*" + name
+		}
+		api = append(api, fmt.Sprintf("method (%s) %s%s", receiver, method.Name(), signature(method.Type().(*types.Signature), qualifier)))
+	}
+	return api
+}
+
+// signature formats the parameters and results of a function without their names
+func signature(sig *types.Signature, qualifier types.Qualifier) string {
+	unnamed := func(tuple *types.Tuple) *types.Tuple {
+		vars := make([]*types.Var, tuple.Len())
+		for i := range vars {
+			vars[i] = types.NewParam(token.NoPos, nil, "", tuple.At(i).Type())
+		}
+		return types.NewTuple(vars...)
+	}
+	s := types.TypeString(types.NewSignatureType(nil, nil, nil, unnamed(sig.Params()), unnamed(sig.Results()), sig.Variadic()), qualifier)
+	return strings.TrimPrefix(s, "func")
+}
@@ -0,0 +1,660 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+// Package lexer scans sieve scripts (RFC 5228) into items for the parser of package rfc5228.
+// It is internal to gosieve: its items are positions in the input, and its errors are codes
+// that package rfc5228 maps to its diagnostics.
+package lexer
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Item represents a token or input string returned from the scanner.
+//
+// An Item holds positions only, so that the token stream of a large script doesn't hold a
+// string header per token; its text is sliced from the input when needed (see Value).
+type Item struct {
+	Type ItemType // The type of this item.
+	Pos  int      // The starting position, in bytes, of this item in the input string.
+	End  int      // The position just after this item.
+}
+
+// Value returns the text of the item in the input it was scanned from
+func (i Item) Value(input string) string {
+	if i.Type == ItemEOF {
+		return "EOF"
+	}
+	return input[i.Pos:i.End]
+}
+
+// ItemType identifies the type of lex items.
+type ItemType int
+
+const (
+	ItemError ItemType = iota // error occurred; see Lexer.Code
+	ItemEOF
+	ItemComment
+	ItemIdentifier
+	ItemEnd
+	ItemString
+	ItemNumeric
+	ItemStringListOpen
+	ItemStringListClose
+	ItemTestListOpen
+	ItemTestListClose
+	ItemBlockOpen
+	ItemBlockClose
+	ItemComma
+	ItemTag // `:` identifier; the value includes the colon
+)
+
+// Code identifies the error that ended a scan.
+type Code int
+
+const (
+	NoError Code = iota
+	UnexpectedRune
+	UnexpectedCR
+	DanglingLF
+	UnexpectedSlash
+	UnterminatedComment
+	ExpectedAlpha
+	ExpectedQuote
+	UnterminatedString
+	UnsupportedEscape
+	DanglingCR
+	UnexpectedCharacter
+	ExpectedTextMarker
+	ExpectedCRLF
+	UnterminatedMultiline
+	ExpectedBracket
+	ExpectedColon
+	ExpectedDigit
+	ExpectedParen
+	ExpectedBrace
+)
+
+// TextMarker starts a multi-line string; it is matched ignoring case
+const TextMarker = "text:"
+
+// EOF is returned by next at the end of the input.
+const EOF = -1
+
+// stateFn represents the state of the scanner as a function that returns the next state.
+type stateFn func(*Lexer) stateFn
+
+// Lexer holds the state of the scanner.
+type Lexer struct {
+	input string // the string being scanned
+	start int    // start position of this token
+	pos   int    // current position in the input
+	width int    // width of the last rune read; 0 at the end of the input
+	item  Item   // item to return to the caller
+	code  Code   // code of the error, once an error item is returned
+}
+
+// thisItem returns the item at the current input point with the specified type
+// and advances the input.
+func (l *Lexer) thisItem(t ItemType) Item {
+	i := Item{Type: t, Pos: l.start, End: l.pos}
+	l.start = l.pos
+	return i
+}
+
+func (l *Lexer) emitItem(i Item) stateFn {
+	l.item = i
+	return nil
+}
+
+// emit passes the trailing input as an item back to the parser.
+func (l *Lexer) emit(t ItemType) stateFn {
+	return l.emitItem(l.thisItem(t))
+}
+
+// next advances the position past the decoded rune
+func (l *Lexer) next() rune {
+
+	// if we read past the end of the input we've reached the end of the file
+	if l.pos >= len(l.input) {
+		l.width = 0
+		return EOF
+	}
+
+	// decode the string into a rune (utf-8 code points) and advance the position
+	r, size := utf8.DecodeRuneInString(l.input[l.pos:])
+	l.width = size
+	l.pos += size
+	return r
+}
+
+func (l *Lexer) acceptExact(r rune) bool {
+	if next := l.next(); next == r {
+		return true
+	}
+	l.backup()
+	return false
+}
+
+func (l *Lexer) acceptAny(runes []rune) bool {
+	r := l.next()
+	for _, v := range runes {
+		if r == v {
+			return true
+		}
+	}
+	l.backup()
+	return false
+}
+
+// acceptRunSequence consumes s if the input continues with it
+func (l *Lexer) acceptRunSequence(s string) bool {
+	if !l.isExactPrefix(s) {
+		return false
+	}
+	l.pos += len(s)
+	_, l.width = utf8.DecodeLastRuneInString(s)
+	return true
+}
+
+// acceptRunStringSequence acceptRunStringSequence a run of runes from the valid set
+func (l *Lexer) acceptRunAny(valid string) {
+	for strings.ContainsRune(valid, l.next()) {
+		// consumed
+	}
+	l.backup()
+}
+
+// backup steps back over the rune read by next; it doesn't move at the end of the input,
+// where next didn't advance
+func (l *Lexer) backup() stateFn {
+	l.pos -= l.width
+	l.width = 0
+	return nil
+}
+
+// ignore skips over the pending input before this point
+func (l *Lexer) ignore() {
+	l.start = l.pos
+}
+
+// peek does return but does not accept a rune from the input
+func (l *Lexer) peek() rune {
+	r := l.next()
+	l.backup()
+	return r
+}
+
+// isExactPrefix tests if the input continues with prefix; this method does not accept any tokens (peek only).
+// The comparison is bounded by the length of the prefix, so it never scans or slices past the end of the input.
+func (l *Lexer) isExactPrefix(prefix string) bool {
+	if l.pos < 0 || l.pos > len(l.input) {
+		return false
+	}
+	return strings.HasPrefix(l.input[l.pos:], prefix)
+}
+
+// isNotExactPrefix is the inverse of isExactPrefix
+func (l *Lexer) isNotExactPrefix(prefix string) bool {
+	return !l.isExactPrefix(prefix)
+}
+
+// isNotExactPrefixFold is isNotExactPrefix ignoring ASCII case, for the case-insensitive literals of the grammar
+func (l *Lexer) isNotExactPrefixFold(prefix string) bool {
+	if l.pos < 0 || l.pos+len(prefix) > len(l.input) {
+		return true
+	}
+	return !strings.EqualFold(l.input[l.pos:l.pos+len(prefix)], prefix)
+}
+
+// Next returns the next item from the input; after an ItemError, Code returns the error.
+func (l *Lexer) Next() Item {
+	l.item = Item{Type: ItemEOF, Pos: l.pos, End: l.pos}
+
+	state := lexStart
+	for {
+		state = state(l)
+		if state == nil {
+			return l.item
+		}
+	}
+}
+
+// errorf returns an error token and terminates the scan by passing
+// back a nil pointer that will be the next state, terminating l.next.
+// The code of the error is kept in l.code.
+func (l *Lexer) errorf(code Code) stateFn {
+	l.item = Item{Type: ItemError, Pos: l.start, End: l.start}
+	l.code = code
+	l.start = 0
+	l.pos = 0
+	l.input = l.input[:0]
+	return nil
+}
+
+// isWhitespace tests if a rune is a (part of a) whitespace character
+//
+// Whitespace is used to separate items.  Whitespace is made up of
+// tabs, newlines (CRLF, never just '\r' or '\n'), and the space character.
+//
+// Comments are semantically equivalent to whitespace and can be used anyplace that whitespace is
+// (with one exception in multi-line strings, as described in the grammar).
+func isWhitespace(r rune) bool {
+	return r == ' ' ||
+		r == '\t' ||
+		r == '\r' ||
+		r == '\n' ||
+		r == '/' ||
+		r == '#' ||
+		r == byteOrderMark
+}
+
+// byteOrderMark is skipped like whitespace between tokens: at the start of a script saved by
+// an editor that writes one, and within a script concatenated from such files
+const byteOrderMark = '\uFEFF'
+
+// isOctetFiltered tests if a rune consists of octets other than NUL and the given filters
+//
+// Runes beyond 0xFF (and utf8.RuneError for invalid input) are made up of octets in the
+// range 0x80-0xFF and are therefore accepted.
+func isOctetFiltered(r rune, filters ...rune) bool {
+	if r < 0x01 {
+		return false
+	}
+	for _, f := range filters {
+		if f == r {
+			return false
+		}
+	}
+	return true
+}
+
+// IsAlpha reports whether r is an alphabetic, or underscore.
+func IsAlpha(r rune) bool {
+	return r == '_' || unicode.IsLetter(r)
+}
+
+// IsDigit reports whether r is a digit.
+func IsDigit(r rune) bool {
+	return unicode.IsDigit(r)
+}
+
+func isAlphaNumeric(r rune) bool {
+	return IsAlpha(r) || IsDigit(r)
+}
+
+// New returns a lexer for input. Trailing NULs, as left by reading a script from a fixed-size
+// buffer, end the input; a NUL elsewhere is an error.
+func New(input string) *Lexer {
+	return &Lexer{
+		input: strings.TrimRight(input, "\x00"),
+		start: 0,
+		pos:   0,
+		width: 0,
+	}
+}
+
+// Input returns the input being scanned, without its trailing NULs.
+func (l *Lexer) Input() string {
+	return l.input
+}
+
+// Code returns the code of the error, once Next returned an ItemError.
+func (l *Lexer) Code() Code {
+	return l.code
+}
+
+// Seek continues the scan at pos, e.g. to scan a region of the input in place.
+func (l *Lexer) Seek(pos int) {
+	l.start, l.pos, l.width = pos, pos, 0
+}
+
+func lexStart(l *Lexer) stateFn {
+	for {
+		switch r := l.peek(); {
+		case r == EOF:
+			return nil
+		case isWhitespace(r):
+			return lexWhitespace
+		case IsAlpha(r) && l.isNotExactPrefixFold(TextMarker):
+			return lexIdentifier
+		case r == '"':
+			return lexQuotedString
+		case IsAlpha(r):
+			return lexMultiline
+		case r == '[':
+			return lexStringList
+		case r == ',':
+			l.next() // we only peeked `r`, so we need to absorb it
+			return l.emit(ItemComma)
+		case r == ']':
+			return lexStringList
+		case r == ':':
+			return lexTag
+		case IsDigit(r):
+			return lexNumeric
+		case r == '(':
+			return lexTestList
+		case r == ')':
+			return lexTestList
+		case r == ';':
+			l.next() // we only peeked `r`, so we need to absorb it
+			return l.emit(ItemEnd)
+		case r == '{':
+			return lexBlock
+		case r == '}':
+			return lexBlock
+		default:
+			return l.errorf(UnexpectedRune)
+		}
+	}
+}
+
+// lexWhitespace scans whitespace.
+func lexWhitespace(l *Lexer) stateFn {
+	for {
+		switch r := l.next(); {
+		case r == EOF:
+			return nil
+		case r == ' ' || r == '\t' || r == byteOrderMark:
+			l.ignore()
+		case r == '\r':
+			if next := l.next(); next != '\n' {
+				return l.errorf(UnexpectedCR)
+			}
+			l.ignore()
+		case r == '\n':
+			return l.errorf(DanglingLF)
+		case r == '#':
+			return lexHashComment
+		case r == '/':
+			if next := l.next(); next != '*' {
+				return l.errorf(UnexpectedSlash)
+			}
+			return lexBracketComment
+		default:
+			l.backup() // restore non matching rune
+			return lexStart
+		}
+	}
+}
+
+// lexBracketComment scans a bracket-comment.
+func lexBracketComment(l *Lexer) stateFn {
+	for {
+		switch r := l.next(); {
+		case r == EOF:
+			return l.errorf(UnterminatedComment)
+		case isOctetFiltered(r, '\r', '\n', '*'):
+			// absorb.
+		case r == '\r':
+			if next := l.next(); next != '\n' {
+				return l.errorf(UnexpectedCR)
+			}
+		case r == '*':
+			if next := l.next(); next != '/' {
+				l.backup() // restore rune
+			} else {
+				return l.emit(ItemComment)
+			}
+		default:
+			return l.errorf(UnexpectedRune)
+		}
+	}
+}
+
+// lexHashComment scans a hash comment
+func lexHashComment(l *Lexer) stateFn {
+	for {
+		switch r := l.next(); {
+		case r == EOF:
+			// a hash comment on the last line of a script without a trailing CRLF
+			return l.emit(ItemComment)
+		case isOctetFiltered(r, '\r', '\n'):
+			// absorb.
+		case r == '\r':
+			// we don't want to include the trailing CRLF in the token value
+			// as we already consume '\r' and we don't know if the next character is going to be '\n',
+			// we need to peek '\n', if the next char is indeed '\n', we can backup the token stream
+			// and let the whitespace state absorb the CRLF
+			if l.isExactPrefix("\n") {
+				l.backup()
+				return l.emit(ItemComment)
+			} else {
+				return l.errorf(UnexpectedCR)
+			}
+		default:
+			return l.errorf(UnexpectedRune)
+		}
+	}
+}
+
+// lexIdentifier scans an identifier
+func lexIdentifier(l *Lexer) stateFn {
+	return lexName(l, ItemIdentifier)
+}
+
+// lexName scans the (alph)anumeric run of an identifier or tag and emits it as typ
+func lexName(l *Lexer, typ ItemType) stateFn {
+	if r := l.next(); !IsAlpha(r) {
+		return l.errorf(ExpectedAlpha)
+	}
+
+	for {
+		switch r := l.next(); {
+		case r == EOF:
+			// an identifier or tag at the end of the input is complete
+			return l.emit(typ)
+		case isAlphaNumeric(r):
+			// absorb.
+		default:
+			l.backup()
+			return l.emit(typ)
+		}
+	}
+}
+
+// lexQuotedString scans a quoted string
+func lexQuotedString(l *Lexer) stateFn {
+	if l.acceptExact('"') == false {
+		return l.errorf(ExpectedQuote)
+	}
+
+	for {
+		switch r := l.next(); {
+		case r == EOF:
+			return l.errorf(UnterminatedString)
+		case isOctetFiltered(r, '\r', '\n', '"', '\\'):
+			// absorb
+		case r == '\\':
+			{
+				// quoted-special
+				switch next := l.next(); {
+				case next == EOF:
+					return l.errorf(UnterminatedString)
+				case next == '"':
+					// absorb
+				case next == '\\':
+					// absorb
+				default:
+					/*
+						TODO
+							Scripts SHOULD NOT escape other characters with a backslash.
+							An undefined escape sequence (such as "\a" in a context where "a" has
+							no special meaning) is interpreted as if there were no backslash (in
+							this case, "\a" is just "a"), though that may be changed by
+							extensions.
+					*/
+					return l.errorf(UnsupportedEscape)
+				}
+			}
+		case r == '\r':
+			if l.acceptExact('\n') == false {
+				return l.errorf(DanglingCR)
+			}
+		case r == '"':
+			return l.emit(ItemString)
+		default:
+			return l.errorf(UnexpectedCharacter)
+		}
+	}
+}
+
+// lexMultiline scans a multi-line string
+//
+//	multi-line = "text:" *(SP / HTAB) (hash-comment / CRLF) *(multiline-literal / multiline-dotstart) "." CRLF
+//
+// This is the one place where comments are not equivalent to whitespace: only a hash comment may
+// follow the marker, and a `#` or `/*` on the lines of the literal is part of its content.
+func lexMultiline(l *Lexer) stateFn {
+	const endSequence = ".\r\n"
+
+	// text: (case-insensitive)
+	if l.isNotExactPrefixFold(TextMarker) {
+		return l.errorf(ExpectedTextMarker)
+	}
+	l.pos += len(TextMarker)
+
+	// *(SP / '\t)
+	l.acceptRunAny(" \t")
+
+	// [hash-comment]; its CRLF is accepted below
+	if l.acceptExact('#') {
+	comment:
+		for {
+			switch r := l.next(); {
+			case r == EOF:
+				break comment
+			case isOctetFiltered(r, '\r', '\n'):
+				// absorb
+			default:
+				l.backup()
+				break comment
+			}
+		}
+	}
+
+	// CRLF
+	if l.acceptRunSequence("\r\n") == false {
+		return l.errorf(ExpectedCRLF)
+	}
+
+	// prematurely check if the end sequence was found
+	// this is equivalent to an empty multi-line string
+	if l.acceptRunSequence(endSequence) {
+		return l.emit(ItemString)
+	}
+
+	for {
+		switch r := l.next(); {
+		case r == EOF:
+			// the `.` line may lack its CRLF at the end of the input
+			if strings.HasSuffix(l.input[l.start:l.pos], "\r\n.") {
+				return l.emit(ItemString)
+			}
+			return l.errorf(UnterminatedMultiline)
+		case isOctetFiltered(r, '\r', '\n'):
+			// absorb
+		case r == '\r':
+			if l.acceptExact('\n') == false {
+				return l.errorf(UnexpectedCR)
+			}
+			if l.acceptRunSequence(endSequence) {
+				return l.emit(ItemString)
+			}
+		default:
+			return l.errorf(UnexpectedRune)
+		}
+	}
+}
+
+// lexStringList scans a string-list open or close tag
+func lexStringList(l *Lexer) stateFn {
+	switch r := l.next(); {
+	case r == '[':
+		return l.emit(ItemStringListOpen)
+	case r == ']':
+		return l.emit(ItemStringListClose)
+	}
+	return l.errorf(ExpectedBracket)
+}
+
+// lexTag scans a tag
+func lexTag(l *Lexer) stateFn {
+	if !l.acceptExact(':') {
+		return l.errorf(ExpectedColon)
+	}
+	return lexName(l, ItemTag)
+}
+
+// lexNumeric scans a numerical value (digit w/ optional quantifier)
+func lexNumeric(l *Lexer) stateFn {
+	//    number             = 1*DIGIT [ QUANTIFIER ]
+
+	if !IsDigit(l.peek()) {
+		return l.errorf(ExpectedDigit)
+	}
+
+iter:
+	for {
+		switch r := l.next(); {
+		case r == EOF:
+			break iter
+		case IsDigit(r):
+			//absorb
+		default:
+			l.backup()
+			break iter
+		}
+	}
+
+	// accept optional QUANTIFIER
+	l.acceptAny([]rune{'K', 'M', 'G'})
+	return l.emit(ItemNumeric)
+}
+
+// lexTestList scans an test-list open and closing tag
+func lexTestList(l *Lexer) stateFn {
+	switch r := l.next(); {
+	case r == '(':
+		return l.emit(ItemTestListOpen)
+	case r == ')':
+		return l.emit(ItemTestListClose)
+	}
+	return l.errorf(ExpectedParen)
+}
+
+// lexBlock scans an test-list open and closing tag
+func lexBlock(l *Lexer) stateFn {
+	switch r := l.next(); {
+	case r == '{':
+		return l.emit(ItemBlockOpen)
+	case r == '}':
+		return l.emit(ItemBlockClose)
+	}
+	return l.errorf(ExpectedBrace)
+}
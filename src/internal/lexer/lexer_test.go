@@ -0,0 +1,51 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package lexer
+
+import (
+	"testing"
+)
+
+func TestLexerPrefixAtEOF(t *testing.T) {
+	for _, input := range []string{"", "i", "inpu", "input", "\r", "text:\r\n.\r", "\xff", "\xef\xbf"} {
+		l := New(input)
+		for _, prefix := range []string{TextMarker, "\r\n", ".\r\n", "�"} {
+			if l.isExactPrefix(prefix) != (len(input) >= len(prefix) && input[:len(prefix)] == prefix) {
+				t.Errorf("%q: unexpected result for prefix %q", input, prefix)
+			}
+		}
+		l.pos = len(input)
+		if l.isExactPrefix("x") || l.acceptRunSequence("\r\n") || !l.isExactPrefix("") {
+			t.Errorf("%q: unexpected result at the end of the input", input)
+		}
+		// no input makes the scanner panic
+		for l := New(input); ; {
+			if i := l.Next(); i.Type == ItemEOF || i.Type == ItemError {
+				break
+			}
+		}
+	}
+}
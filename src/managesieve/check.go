@@ -0,0 +1,87 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package managesieve
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"gosieve/src/rfc5228"
+	"gosieve/src/scripts"
+)
+
+// CheckScript checks a script for CHECKSCRIPT (RFC 5804, section 2.12). A script that
+// can't be parsed, or has findings of rfc5228.SeverityError, yields a NO response listing
+// every finding; other findings yield an OK response with the WARNINGS code. The findings
+// are rendered a line each, e.g. "line 2, column 5: warning: SIEVE0104: ...", for clients
+// to show to the user.
+func CheckScript(name, content string, mode rfc5228.Mode, opts ...rfc5228.Option) *Response {
+	tree, err := rfc5228.Parse(name, content, mode, opts...)
+	if err != nil {
+		return ErrorResponse(err)
+	}
+	warnings := rfc5228.Validate(tree, opts...)
+	if len(warnings) == 0 {
+		return &Response{Status: "OK"}
+	}
+	resp := &Response{Status: "OK", Code: "WARNINGS"}
+	lines := make([]string, len(warnings))
+	for i, w := range warnings {
+		if w.Severity == rfc5228.SeverityError {
+			resp = &Response{Status: "NO"}
+		}
+		lines[i] = finding(w.Line, w.Column, w.Severity, w.Code, w.Message)
+	}
+	resp.Text = strings.Join(lines, "\r\n")
+	return resp
+}
+
+// ErrorResponse returns the NO response for an error of a script store or of parsing a
+// script: a *scripts.Veto keeps its response code, and scripts.ErrNotFound and
+// scripts.ErrActive get NONEXISTENT and ACTIVE
+func ErrorResponse(err error) *Response {
+	var resp *Response
+	var veto *scripts.Veto
+	var syntax *rfc5228.SyntaxError
+	switch {
+	case errors.As(err, &resp):
+		return resp
+	case errors.As(err, &veto):
+		return &Response{Status: "NO", Code: veto.Code, Text: veto.Message}
+	case errors.As(err, &syntax):
+		return &Response{Status: "NO", Text: finding(syntax.Line, syntax.Column, rfc5228.SeverityError, syntax.Code, syntax.Message)}
+	case errors.Is(err, scripts.ErrNotFound):
+		return &Response{Status: "NO", Code: "NONEXISTENT", Text: err.Error()}
+	case errors.Is(err, scripts.ErrActive):
+		return &Response{Status: "NO", Code: "ACTIVE", Text: err.Error()}
+	}
+	return &Response{Status: "NO", Text: err.Error()}
+}
+
+func finding(line, column int, severity rfc5228.Severity, code rfc5228.Code, message string) string {
+	return fmt.Sprintf("line %d, column %d: %s: %s: %s", line, column, severity, code, message)
+}
@@ -0,0 +1,78 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package managesieve
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"gosieve/src/rfc5228"
+	"gosieve/src/scripts"
+)
+
+func TestCheckScript(t *testing.T) {
+	if resp := CheckScript("clean", "keep;\r\n", rfc5228.ModeStrict); resp.String() != "OK" {
+		t.Errorf("expected OK, got %q", resp)
+	}
+
+	resp := CheckScript("warnings", "if true {\r\n  keep;\r\n}\r\nif false {\r\n  keep;\r\n}\r\n", rfc5228.ModeStrict)
+	lines := strings.Split(resp.Text, "\r\n")
+	if resp.Status != "OK" || resp.Code != "WARNINGS" || len(lines) < 2 {
+		t.Fatalf("expected several warnings, got %q", resp)
+	}
+	if !strings.HasPrefix(lines[0], "line 1, column 4: ") || !strings.Contains(lines[0], ": SIEVE0104: ") {
+		t.Errorf("unexpected finding %q", lines[0])
+	}
+	if !strings.HasPrefix(resp.String(), fmt.Sprintf("OK (WARNINGS) {%d}\r\n", len(resp.Text))) {
+		t.Errorf("expected the findings in a literal, got %q", resp)
+	}
+
+	resp = CheckScript("errors", "if true {\r\n  keep;\r\n}\r\n", rfc5228.ModeStrict, rfc5228.WithWarningsAsErrors())
+	if resp.Status != "NO" || !strings.Contains(resp.Text, "error: SIEVE0104") {
+		t.Errorf("expected the warning as an error, got %q", resp)
+	}
+	resp = CheckScript("syntax", "keep;\r\nkeep\r\n", rfc5228.ModeStrict)
+	if resp.Status != "NO" || !strings.HasPrefix(resp.Text, "line 2, column 5: error: SIEVE0004: ") {
+		t.Errorf("expected the syntax error, got %q", resp)
+	}
+}
+
+func TestErrorResponse(t *testing.T) {
+	for _, test := range []struct {
+		err      error
+		expected string
+	}{
+		{&scripts.Veto{Code: "QUOTA/MAXSCRIPTS", Message: "too many scripts"}, `NO (QUOTA/MAXSCRIPTS) "too many scripts"`},
+		{fmt.Errorf("get: %w", scripts.ErrNotFound), `NO (NONEXISTENT) "get: ` + scripts.ErrNotFound.Error() + `"`},
+		{scripts.ErrActive, `NO (ACTIVE) "` + scripts.ErrActive.Error() + `"`},
+		{&Response{Status: "BYE", Text: "Shutting down"}, `BYE "Shutting down"`},
+	} {
+		if resp := ErrorResponse(test.err).String(); resp != test.expected {
+			t.Errorf("%v: expected %q, got %q", test.err, test.expected, resp)
+		}
+	}
+}
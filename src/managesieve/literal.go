@@ -0,0 +1,97 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package managesieve
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"gosieve/src/rfc5228"
+)
+
+// Limits are the limits a server puts on the scripts of clients
+type Limits struct {
+	MaxScriptSize int64 // the size of the largest script in bytes; 0 for no limit
+}
+
+// NewLimits returns the limits of the parser options a server checks scripts with, so that
+// it refuses a script larger than rfc5228.WithMaxSize allows before reading it
+func NewLimits(opts ...rfc5228.Option) Limits {
+	return Limits{MaxScriptSize: int64(rfc5228.MaxSize(opts...))}
+}
+
+// Capabilities returns the capability lines that advertise the limits, e.g.
+// `"MAXSCRIPTSIZE" "65536"`
+func (l Limits) Capabilities() []string {
+	if l.MaxScriptSize <= 0 {
+		return nil
+	}
+	return []string{quote("MAXSCRIPTSIZE") + " " + quote(strconv.FormatInt(l.MaxScriptSize, 10))}
+}
+
+// ReadLiteral reads the literal of a client, e.g. the script of PUTSCRIPT, whose header
+// "{n+}" ends the line the server read (RFC 5804, section 4). A literal larger than
+// MaxScriptSize is discarded as it arrives, without buffering it, and refused with a NO
+// response with the QUOTA/MAXSIZE code; the connection can be used for the next command.
+// Without a limit, only the bytes that arrive are buffered, whatever size the client states.
+func (l Limits) ReadLiteral(r *bufio.Reader, header string) (string, error) {
+	size, ok := literalSize(header)
+	if !ok {
+		return "", fmt.Errorf("managesieve: invalid literal %q", header)
+	}
+	if header[len(header)-2] != '+' {
+		// clients only send non-synchronizing literals; the data follows without waiting
+		// for the server, so the connection can't continue
+		return "", fmt.Errorf("managesieve: synchronizing literal %q", header)
+	}
+	if l.MaxScriptSize > 0 && size > l.MaxScriptSize {
+		if _, err := io.CopyN(io.Discard, r, size); err != nil {
+			return "", err
+		}
+		return "", &Response{Status: "NO", Code: "QUOTA/MAXSIZE", Text: fmt.Sprintf("script of %d bytes exceeds the limit of %d bytes", size, l.MaxScriptSize)}
+	}
+	if size > math.MaxInt {
+		return "", fmt.Errorf("managesieve: literal %q too large", header)
+	}
+	// the size is the client's word; without a limit, only buffer the bytes that arrive
+	var b strings.Builder
+	if n, err := io.Copy(&b, io.LimitReader(r, size)); err != nil {
+		return "", err
+	} else if n < size {
+		return "", io.ErrUnexpectedEOF
+	}
+	return b.String(), nil
+}
+
+// WriteLiteral writes s as the non-synchronizing literal of a client
+func WriteLiteral(w io.Writer, s string) error {
+	_, err := io.WriteString(w, "{"+strconv.Itoa(len(s))+"+}\r\n"+s)
+	return err
+}
@@ -0,0 +1,88 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package managesieve
+
+import (
+	"bufio"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gosieve/src/rfc5228"
+)
+
+func TestLiteral(t *testing.T) {
+	var b strings.Builder
+	script := "keep;\r\n"
+	if err := WriteLiteral(&b, script); err != nil {
+		t.Fatal(err)
+	}
+	b.WriteString("\r\nNOOP\r\n")
+	r := bufio.NewReader(strings.NewReader(b.String()))
+	header, _ := readLine(r)
+	limits := NewLimits(rfc5228.WithMaxSize(7))
+	if s, err := limits.ReadLiteral(r, header); err != nil || s != script {
+		t.Errorf("unexpected literal %q %v", s, err)
+	}
+
+	// a literal larger than the limit is skipped, so that the next command can be read
+	r = bufio.NewReader(strings.NewReader("PUTSCRIPT \"big\" {8+}\r\nkeep;\r\n\r\nNOOP\r\n"))
+	readLine(r)
+	_, err := limits.ReadLiteral(r, "{8+}")
+	var resp *Response
+	if !errors.As(err, &resp) || resp.Code != "QUOTA/MAXSIZE" {
+		t.Errorf("expected QUOTA/MAXSIZE, got %v", err)
+	}
+	if line, _ := readLine(r); line != "" {
+		t.Errorf("expected the end of the command, got %q", line)
+	}
+	if line, _ := readLine(r); line != "NOOP" {
+		t.Errorf("expected the next command, got %q", line)
+	}
+
+	for _, header := range []string{"{7}", "{+}", "{-1+}", "7+}"} {
+		if _, err := (Limits{}).ReadLiteral(bufio.NewReader(strings.NewReader(script)), header); err == nil {
+			t.Errorf("%q: expected an error", header)
+		}
+	}
+	if _, err := (Limits{}).ReadLiteral(bufio.NewReader(strings.NewReader("keep")), "{7+}"); err == nil {
+		t.Errorf("expected an error for a short literal")
+	}
+	// without a limit, a size the client made up isn't allocated up front
+	for _, header := range []string{"{9223372036854775807+}", "{99999999999999999999+}"} {
+		if _, err := (Limits{}).ReadLiteral(bufio.NewReader(strings.NewReader(script)), header); err == nil {
+			t.Errorf("%q: expected an error", header)
+		}
+	}
+
+	if capabilities := limits.Capabilities(); !reflect.DeepEqual(capabilities, []string{`"MAXSCRIPTSIZE" "7"`}) {
+		t.Errorf("unexpected capabilities %v", capabilities)
+	}
+	if capabilities := (Limits{}).Capabilities(); capabilities != nil {
+		t.Errorf("unexpected capabilities %v", capabilities)
+	}
+}
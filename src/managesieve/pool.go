@@ -0,0 +1,235 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package managesieve
+
+import (
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// ErrPoolClosed is returned by a closed Pool
+var ErrPoolClosed = errors.New("managesieve: pool closed")
+
+// Conn is an authenticated connection of a client
+type Conn interface {
+	// Noop sends NOOP (RFC 5804, section 2.13), e.g. to keep an idle connection open
+	Noop() error
+	Close() error
+}
+
+type idleConn struct {
+	conn  Conn
+	since time.Time
+}
+
+// Pool keeps authenticated connections per account for reuse, so that a backend serving
+// many users doesn't pay a TLS and SASL handshake per request. A connection is returned to
+// the pool with Put, or used and returned by Do.
+type Pool struct {
+	Dial        func(account string) (Conn, error) // connects and authenticates as account
+	MaxIdle     int                                // idle connections kept per account; 0 for 1
+	IdleTimeout time.Duration                      // idle connections are closed after it; 0 for no limit
+	Retries     int                                // reconnects for an idempotent operation on a broken connection
+
+	mu     sync.Mutex
+	idle   map[string][]idleConn
+	closed bool
+	now    func() time.Time
+}
+
+// Get returns an idle connection of account, or a new one
+func (p *Pool) Get(account string) (Conn, error) {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil, ErrPoolClosed
+	}
+	var expired []Conn
+	var conn Conn
+	for conn == nil && len(p.idle[account]) > 0 {
+		conns := p.idle[account]
+		idle := conns[len(conns)-1]
+		p.idle[account] = conns[:len(conns)-1]
+		if p.expired(idle) {
+			expired = append(expired, idle.conn)
+		} else {
+			conn = idle.conn
+		}
+	}
+	p.mu.Unlock()
+	for _, c := range expired {
+		c.Close()
+	}
+	if conn != nil {
+		return conn, nil
+	}
+	return p.Dial(account)
+}
+
+// Put returns a connection of account to the pool; err is the error of its last use, and
+// a connection broken by it is closed instead
+func (p *Pool) Put(account string, conn Conn, err error) {
+	if err != nil && broken(err) {
+		conn.Close()
+		return
+	}
+	p.mu.Lock()
+	if p.closed || len(p.idle[account]) >= p.maxIdle() {
+		p.mu.Unlock()
+		conn.Close()
+		return
+	}
+	if p.idle == nil {
+		p.idle = make(map[string][]idleConn)
+	}
+	p.idle[account] = append(p.idle[account], idleConn{conn: conn, since: p.time()})
+	p.mu.Unlock()
+}
+
+// Do runs op on a connection of account. An idempotent operation, e.g. LISTSCRIPTS or
+// GETSCRIPT, that fails on a broken connection is retried on a new one up to Retries times;
+// other operations aren't, as the server may have run them before the connection broke.
+func (p *Pool) Do(account string, idempotent bool, op func(Conn) error) error {
+	for attempt := 0; ; attempt++ {
+		conn, err := p.Get(account)
+		if err != nil {
+			return err
+		}
+		err = op(conn)
+		p.Put(account, conn, err)
+		if err == nil || !broken(err) || !idempotent || attempt >= p.Retries {
+			return err
+		}
+	}
+}
+
+// Keepalive sends NOOP on the idle connections every interval until stop is closed, and
+// closes those that have expired or fail; the errors are passed to onError, which may be
+// nil to ignore them
+func (p *Pool) Keepalive(interval time.Duration, stop <-chan struct{}, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			for _, err := range p.ping() {
+				if onError != nil {
+					onError(err)
+				}
+			}
+		}
+	}
+}
+
+// ping sends NOOP on the idle connections; they are taken from the pool meanwhile, so that
+// Get doesn't hand out a connection in use
+func (p *Pool) ping() []error {
+	p.mu.Lock()
+	idle := p.idle
+	p.idle = nil
+	p.mu.Unlock()
+
+	var errs []error
+	for account, conns := range idle {
+		for _, idle := range conns {
+			if p.expired(idle) {
+				idle.conn.Close()
+				continue
+			}
+			if err := idle.conn.Noop(); err != nil {
+				idle.conn.Close()
+				errs = append(errs, err)
+				continue
+			}
+			// a Put during the round may have filled the pool of the account meanwhile
+			p.mu.Lock()
+			if p.closed || len(p.idle[account]) >= p.maxIdle() {
+				p.mu.Unlock()
+				idle.conn.Close()
+				continue
+			}
+			if p.idle == nil {
+				p.idle = make(map[string][]idleConn)
+			}
+			p.idle[account] = append(p.idle[account], idle)
+			p.mu.Unlock()
+		}
+	}
+	return errs
+}
+
+// Close closes the idle connections; connections in use are closed when they're returned
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	idle := p.idle
+	p.idle = nil
+	p.closed = true
+	p.mu.Unlock()
+
+	var first error
+	for _, conns := range idle {
+		for _, idle := range conns {
+			if err := idle.conn.Close(); err != nil && first == nil {
+				first = err
+			}
+		}
+	}
+	return first
+}
+
+func (p *Pool) maxIdle() int {
+	if p.MaxIdle <= 0 {
+		return 1
+	}
+	return p.MaxIdle
+}
+
+func (p *Pool) expired(idle idleConn) bool {
+	return p.IdleTimeout > 0 && p.time().Sub(idle.since) >= p.IdleTimeout
+}
+
+func (p *Pool) time() time.Time {
+	if p.now != nil {
+		return p.now()
+	}
+	return time.Now()
+}
+
+// broken reports whether err leaves a connection unusable, as opposed to a NO response; after
+// a BYE response the server closes the connection (RFC 5804, section 1.3)
+func broken(err error) bool {
+	var netErr net.Error
+	var resp *Response
+	if errors.As(err, &resp) {
+		return resp.Status == "BYE"
+	}
+	return errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, net.ErrClosed) || errors.As(err, &netErr)
+}
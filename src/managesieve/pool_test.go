@@ -0,0 +1,172 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package managesieve
+
+import (
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+type testConn struct {
+	id     int
+	closed bool
+	noop   error
+	onNoop func() // called by Noop, e.g. to use the pool meanwhile
+}
+
+func (c *testConn) Noop() error {
+	if c.onNoop != nil {
+		c.onNoop()
+	}
+	return c.noop
+}
+
+func (c *testConn) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestPool(t *testing.T) {
+	var dialed []*testConn
+	now := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	pool := &Pool{
+		Dial: func(account string) (Conn, error) {
+			conn := &testConn{id: len(dialed)}
+			dialed = append(dialed, conn)
+			return conn, nil
+		},
+		IdleTimeout: time.Minute,
+		Retries:     1,
+		now:         func() time.Time { return now },
+	}
+
+	var used []int
+	use := func(conn Conn) error {
+		used = append(used, conn.(*testConn).id)
+		return nil
+	}
+	for i := 0; i < 2; i++ {
+		if err := pool.Do("alice", true, use); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := pool.Do("bob", true, use); err != nil {
+		t.Fatal(err)
+	}
+	if len(dialed) != 2 || used[0] != 0 || used[1] != 0 || used[2] != 1 {
+		t.Errorf("expected a connection per account to be reused, got %v", used)
+	}
+
+	// a broken connection is retried for idempotent operations only
+	calls := 0
+	fail := func(conn Conn) error {
+		calls++
+		if calls == 1 {
+			return io.EOF
+		}
+		return nil
+	}
+	if err := pool.Do("alice", true, fail); err != nil || calls != 2 || !dialed[0].closed || len(dialed) != 3 {
+		t.Errorf("expected a retry on a new connection, got %v after %d calls", err, calls)
+	}
+	calls = 0
+	if err := pool.Do("alice", false, fail); !errors.Is(err, io.EOF) || calls != 1 {
+		t.Errorf("expected no retry, got %v after %d calls", err, calls)
+	}
+	rejected := errors.New("NO")
+	if err := pool.Do("bob", true, func(Conn) error { return rejected }); err != rejected || dialed[1].closed {
+		t.Errorf("expected the connection to be kept after a response, got %v", err)
+	}
+
+	// keepalive drops expired and failing connections
+	dialed[1].noop = io.EOF
+	if errs := pool.ping(); len(errs) != 1 || !dialed[1].closed {
+		t.Errorf("expected the failing connection to be closed, got %v", errs)
+	}
+	if err := pool.Do("alice", true, use); err != nil || len(dialed) != 4 {
+		t.Fatalf("expected a new connection, got %d: %v", len(dialed), err)
+	}
+	now = now.Add(time.Minute)
+	if conn, err := pool.Get("alice"); err != nil || conn.(*testConn).id != 4 || !dialed[3].closed {
+		t.Errorf("expected the expired connection to be replaced, got %v", err)
+	}
+
+	if err := pool.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pool.Get("alice"); err != ErrPoolClosed {
+		t.Errorf("expected ErrPoolClosed, got %v", err)
+	}
+}
+
+func TestPoolKeepaliveMaxIdle(t *testing.T) {
+	pool := &Pool{MaxIdle: 1}
+	returned := &testConn{id: 1}
+	pinged := &testConn{onNoop: func() { pool.Put("alice", returned, nil) }}
+	pool.Put("alice", pinged, nil)
+
+	// the connection returned during the round fills the pool, so the pinged one is closed
+	if errs := pool.ping(); len(errs) != 0 {
+		t.Fatal(errs)
+	}
+	if len(pool.idle["alice"]) != 1 || !pinged.closed || returned.closed {
+		t.Errorf("expected at most MaxIdle idle connections, got %d", len(pool.idle["alice"]))
+	}
+}
+
+func TestPoolBye(t *testing.T) {
+	var dialed []*testConn
+	pool := &Pool{
+		Dial: func(account string) (Conn, error) {
+			conn := &testConn{id: len(dialed)}
+			dialed = append(dialed, conn)
+			return conn, nil
+		},
+		Retries: 1,
+	}
+
+	// the server closes the connection after BYE, so an idempotent operation is retried
+	calls := 0
+	bye := &Response{Status: "BYE", Text: "Shutting down"}
+	err := pool.Do("alice", true, func(Conn) error {
+		calls++
+		if calls == 1 {
+			return bye
+		}
+		return nil
+	})
+	if err != nil || calls != 2 || !dialed[0].closed || dialed[1].closed {
+		t.Errorf("expected a retry on a new connection, got %v after %d calls", err, calls)
+	}
+	if err := pool.Do("alice", false, func(Conn) error { return bye }); err != bye || !dialed[1].closed {
+		t.Errorf("expected the connection to be closed after BYE, got %v", err)
+	}
+	if err := pool.Do("alice", false, func(Conn) error { return &Response{Status: "NO"} }); err == nil || dialed[2].closed {
+		t.Errorf("expected the connection to be kept after NO, got %v", err)
+	}
+}
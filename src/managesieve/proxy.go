@@ -0,0 +1,245 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package managesieve
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// proxySignature starts a header of version 2 of the PROXY protocol
+var proxySignature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+// ProxyHeader is the header of the PROXY protocol of HAProxy, which a load balancer sends
+// ahead of a connection to pass on the addresses of the client
+type ProxyHeader struct {
+	Version     int      // 1 for the text, 2 for the binary format
+	Source      net.Addr // the client; nil when the balancer didn't pass on addresses (LOCAL, UNKNOWN)
+	Destination net.Addr
+}
+
+// ReadProxyHeader reads the header of version 1 or 2 of the PROXY protocol
+func ReadProxyHeader(r *bufio.Reader) (*ProxyHeader, error) {
+	start, err := r.Peek(len(proxySignature))
+	if err != nil {
+		return nil, fmt.Errorf("proxy: %w", err)
+	}
+	if bytes.Equal(start, proxySignature) {
+		return readProxyV2(r)
+	}
+	if bytes.HasPrefix(start, []byte("PROXY ")) {
+		return readProxyV1(r)
+	}
+	return nil, errors.New("proxy: no PROXY protocol header")
+}
+
+// readProxyV1 reads a text header, e.g. "PROXY TCP4 192.0.2.1 192.0.2.2 56324 4190\r\n"
+func readProxyV1(r *bufio.Reader) (*ProxyHeader, error) {
+	var line []byte
+	for len(line) < 107 && !bytes.HasSuffix(line, []byte("\r\n")) {
+		c, err := r.ReadByte()
+		if err != nil {
+			return nil, fmt.Errorf("proxy: %w", err)
+		}
+		line = append(line, c)
+	}
+	if !bytes.HasSuffix(line, []byte("\r\n")) {
+		return nil, errors.New("proxy: header too long")
+	}
+	fields := strings.Split(string(line[:len(line)-2]), " ")
+	header := &ProxyHeader{Version: 1}
+	if len(fields) >= 2 && fields[1] == "UNKNOWN" {
+		return header, nil
+	}
+	if len(fields) != 6 || (fields[1] != "TCP4" && fields[1] != "TCP6") {
+		return nil, fmt.Errorf("proxy: invalid header %q", line)
+	}
+	var err error
+	if header.Source, err = proxyAddr(fields[2], fields[4]); err == nil {
+		header.Destination, err = proxyAddr(fields[3], fields[5])
+	}
+	if err != nil {
+		return nil, fmt.Errorf("proxy: invalid header %q", line)
+	}
+	return header, nil
+}
+
+func proxyAddr(host, port string) (net.Addr, error) {
+	ip := net.ParseIP(host)
+	p, err := strconv.ParseUint(port, 10, 16)
+	if ip == nil || err != nil {
+		return nil, errors.New("invalid address")
+	}
+	return &net.TCPAddr{IP: ip, Port: int(p)}, nil
+}
+
+// readProxyV2 reads a binary header; its TLVs are skipped
+func readProxyV2(r *bufio.Reader) (*ProxyHeader, error) {
+	var fixed [16]byte
+	if _, err := io.ReadFull(r, fixed[:]); err != nil {
+		return nil, fmt.Errorf("proxy: %w", err)
+	}
+	body := make([]byte, binary.BigEndian.Uint16(fixed[14:]))
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, fmt.Errorf("proxy: %w", err)
+	}
+	header := &ProxyHeader{Version: 2}
+	if fixed[12]>>4 != 2 {
+		return nil, fmt.Errorf("proxy: unsupported version %d", fixed[12]>>4)
+	}
+	switch fixed[12] & 0xf {
+	case 0: // LOCAL, e.g. a health check of the balancer
+		return header, nil
+	case 1: // PROXY
+	default:
+		return nil, fmt.Errorf("proxy: unsupported command %d", fixed[12]&0xf)
+	}
+	size := 0
+	switch fixed[13] {
+	case 0x11: // TCP over IPv4
+		size = net.IPv4len
+	case 0x21: // TCP over IPv6
+		size = net.IPv6len
+	default:
+		return header, nil
+	}
+	if len(body) < 2*size+4 {
+		return nil, errors.New("proxy: short address block")
+	}
+	header.Source = &net.TCPAddr{IP: net.IP(body[:size]), Port: int(binary.BigEndian.Uint16(body[2*size:]))}
+	header.Destination = &net.TCPAddr{IP: net.IP(body[size : 2*size]), Port: int(binary.BigEndian.Uint16(body[2*size+2:]))}
+	return header, nil
+}
+
+// DefaultProxyTimeout is the time for reading the PROXY protocol header unless
+// ProxyListener.Timeout is set
+const DefaultProxyTimeout = 10 * time.Second
+
+// ProxyListener is a listener behind a load balancer that sends the PROXY protocol. The
+// connections it accepts report the client as their remote address. The header is read on
+// the first Read, Write or RemoteAddr of a connection, so that a client that sends nothing
+// holds up its own connection only; a connection without a valid header fails and is
+// closed.
+type ProxyListener struct {
+	Listener net.Listener
+	Timeout  time.Duration // the time for reading the header; 0 for DefaultProxyTimeout
+}
+
+func (l *ProxyListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	timeout := l.Timeout
+	if timeout <= 0 {
+		timeout = DefaultProxyTimeout
+	}
+	return &proxyConn{Conn: conn, r: bufio.NewReader(conn), timeout: timeout}, nil
+}
+
+func (l *ProxyListener) Close() error {
+	return l.Listener.Close()
+}
+
+func (l *ProxyListener) Addr() net.Addr {
+	return l.Listener.Addr()
+}
+
+// proxyConn reads the header on first use, and then the data buffered with it before the
+// rest of the connection
+type proxyConn struct {
+	net.Conn
+	r       *bufio.Reader
+	timeout time.Duration
+
+	once     sync.Once
+	mu       sync.Mutex
+	deadline time.Time // the read deadline set by the user of the connection
+	remote   net.Addr
+	err      error
+}
+
+// header reads the header, with its own deadline
+func (c *proxyConn) header() error {
+	c.once.Do(func() {
+		c.Conn.SetReadDeadline(time.Now().Add(c.timeout))
+		header, err := ReadProxyHeader(c.r)
+		c.mu.Lock()
+		c.Conn.SetReadDeadline(c.deadline)
+		c.mu.Unlock()
+		if err != nil {
+			c.err = err
+			c.Conn.Close()
+			return
+		}
+		c.remote = header.Source
+	})
+	return c.err
+}
+
+func (c *proxyConn) Read(b []byte) (int, error) {
+	if err := c.header(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(b)
+}
+
+func (c *proxyConn) Write(b []byte) (int, error) {
+	if err := c.header(); err != nil {
+		return 0, err
+	}
+	return c.Conn.Write(b)
+}
+
+func (c *proxyConn) RemoteAddr() net.Addr {
+	if c.header() == nil && c.remote != nil {
+		return c.remote
+	}
+	return c.Conn.RemoteAddr()
+}
+
+func (c *proxyConn) SetDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.deadline = t
+	return c.Conn.SetDeadline(t)
+}
+
+func (c *proxyConn) SetReadDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.deadline = t
+	return c.Conn.SetReadDeadline(t)
+}
@@ -0,0 +1,114 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package managesieve
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadProxyHeader(t *testing.T) {
+	v2 := "\r\n\r\n\x00\r\nQUIT\n\x21\x11\x00\x0f" + // PROXY, TCP over IPv4, 12 bytes of addresses and a TLV
+		"\xc0\x00\x02\x01\xc0\x00\x02\x02\xdc\x04\x10\x5e" + "\x04\x00\x00" + "AUTHENTICATE"
+	for _, test := range []struct {
+		header  string
+		version int
+		source  string
+	}{
+		{"PROXY TCP4 192.0.2.1 192.0.2.2 56324 4190\r\nAUTHENTICATE", 1, "192.0.2.1:56324"},
+		{"PROXY TCP6 2001:db8::1 2001:db8::2 56324 4190\r\nAUTHENTICATE", 1, "[2001:db8::1]:56324"},
+		{"PROXY UNKNOWN\r\nAUTHENTICATE", 1, ""},
+		{v2, 2, "192.0.2.1:56324"},
+		{"\r\n\r\n\x00\r\nQUIT\n\x20\x00\x00\x00AUTHENTICATE", 2, ""},
+	} {
+		r := bufio.NewReader(strings.NewReader(test.header))
+		header, err := ReadProxyHeader(r)
+		if err != nil {
+			t.Errorf("%q: %v", test.header, err)
+			continue
+		}
+		source := ""
+		if header.Source != nil {
+			source = header.Source.String()
+		}
+		if header.Version != test.version || source != test.source {
+			t.Errorf("%q: unexpected header %+v", test.header, header)
+		}
+		if rest, _ := io.ReadAll(r); string(rest) != "AUTHENTICATE" {
+			t.Errorf("%q: unexpected data after the header %q", test.header, rest)
+		}
+	}
+	for _, header := range []string{"AUTHENTICATE \"PLAIN\"\r\n", "PROXY TCP4 192.0.2.1\r\n", "PROXY TCP4 " + strings.Repeat("1", 100) + "\r\n"} {
+		if _, err := ReadProxyHeader(bufio.NewReader(strings.NewReader(header))); err == nil {
+			t.Errorf("%q: expected an error", header)
+		}
+	}
+}
+
+func TestProxyListener(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip(err)
+	}
+	proxy := &ProxyListener{Listener: listener, Timeout: 100 * time.Millisecond}
+	defer proxy.Close()
+
+	// a silent client, one without a header and one behind the balancer
+	for _, data := range []string{"", "NOOP\r\n\r\n\r\n", "PROXY TCP4 192.0.2.1 192.0.2.2 56324 4190\r\nNOOP\r\n"} {
+		client, err := net.Dial("tcp", listener.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer client.Close()
+		client.Write([]byte(data))
+	}
+	var conns []net.Conn
+	for i := 0; i < 3; i++ {
+		conn, err := proxy.Accept()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		conns = append(conns, conn)
+	}
+
+	if addr := conns[2].RemoteAddr().String(); addr != "192.0.2.1:56324" {
+		t.Errorf("expected the address of the client, got %s", addr)
+	}
+	if line, err := bufio.NewReader(conns[2]).ReadString('\n'); err != nil || line != "NOOP\r\n" {
+		t.Errorf("unexpected data %q %v", line, err)
+	}
+	if _, err := conns[1].Write([]byte("OK\r\n")); err == nil {
+		t.Error("expected an error without a header")
+	}
+	if _, err := conns[0].Read(make([]byte, 1)); err == nil {
+		t.Error("expected the header to time out")
+	}
+}
@@ -0,0 +1,289 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package managesieve
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	maxQuoted          = 1024    // the longest quoted string
+	maxResponseLiteral = 1 << 20 // the longest literal read in a response
+)
+
+// DefaultPort is the port of ManageSieve (RFC 5804, section 1.8)
+const DefaultPort = "4190"
+
+// Response is the OK, NO or BYE response that ends the reply to a command (RFC 5804,
+// section 1.3). A NO or BYE response is an error, so that it can be returned as one.
+type Response struct {
+	Status string   // "OK", "NO" or "BYE"
+	Code   string   // the response code, e.g. "REFERRAL" or "QUOTA/MAXSIZE"; empty for none
+	Args   []string // the arguments of the response code
+	Text   string   // the human-readable text; empty for none
+}
+
+func (r *Response) Error() string {
+	if r.Text == "" {
+		return "managesieve: " + r.Status + " " + r.Code
+	}
+	return "managesieve: " + r.Text
+}
+
+// String formats the response as it is sent, without the trailing CRLF; a text of several
+// lines is sent as a literal
+func (r *Response) String() string {
+	var b strings.Builder
+	b.WriteString(r.Status)
+	if r.Code != "" {
+		b.WriteString(" (" + r.Code)
+		for _, arg := range r.Args {
+			b.WriteString(" ")
+			if r.Code == "REFERRAL" {
+				b.WriteString(arg)
+			} else {
+				b.WriteString(quote(arg))
+			}
+		}
+		b.WriteString(")")
+	}
+	if r.Text != "" {
+		b.WriteString(" " + quoteOrLiteral(r.Text))
+	}
+	return b.String()
+}
+
+// Referral returns the server a BYE response with the REFERRAL code refers to
+// (RFC 5804, section 1.3); the port defaults to DefaultPort
+func (r *Response) Referral() (string, error) {
+	if r.Code != "REFERRAL" || len(r.Args) != 1 {
+		return "", errors.New("managesieve: not a referral")
+	}
+	u, err := url.Parse(r.Args[0])
+	if err != nil {
+		return "", fmt.Errorf("managesieve: invalid referral: %w", err)
+	}
+	if u.Scheme != "sieve" || u.Hostname() == "" {
+		return "", fmt.Errorf("managesieve: invalid referral %q", r.Args[0])
+	}
+	port := u.Port()
+	if port == "" {
+		port = DefaultPort
+	}
+	return net.JoinHostPort(u.Hostname(), port), nil
+}
+
+// ReadResponse reads an OK, NO or BYE response
+func ReadResponse(r *bufio.Reader) (*Response, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+	p := &responseParser{r: r, line: line}
+	resp := &Response{Status: strings.ToUpper(p.atom())}
+	if resp.Status != "OK" && resp.Status != "NO" && resp.Status != "BYE" {
+		return nil, fmt.Errorf("managesieve: unexpected response %q", line)
+	}
+	if p.space() && p.peek() == '(' {
+		p.pos++
+		resp.Code = strings.ToUpper(p.atom())
+		for p.err == nil && p.space() {
+			if resp.Code == "REFERRAL" {
+				resp.Args = append(resp.Args, p.until(')'))
+			} else {
+				resp.Args = append(resp.Args, p.string())
+			}
+		}
+		if p.err == nil && p.peek() != ')' {
+			p.fail()
+		}
+		p.pos++
+		p.space()
+	}
+	if p.err == nil && p.pos < len(p.line) {
+		resp.Text = p.string()
+	}
+	if p.err == nil && p.pos < len(p.line) {
+		p.fail()
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	return resp, nil
+}
+
+// Follow connects to addr with dial and follows the referrals of the servers up to hops
+// times. A dial that meets a BYE response returns it as the error.
+func Follow(addr string, hops int, dial func(addr string) (Conn, error)) (Conn, error) {
+	for hop := 0; ; hop++ {
+		conn, err := dial(addr)
+		var resp *Response
+		if err == nil || !errors.As(err, &resp) || resp.Code != "REFERRAL" {
+			return conn, err
+		}
+		if hop >= hops {
+			return nil, fmt.Errorf("managesieve: too many referrals from %s", addr)
+		}
+		if addr, err = resp.Referral(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// responseParser parses the tokens of a response line
+type responseParser struct {
+	r    *bufio.Reader // the lines after a literal are read from r
+	line string
+	pos  int
+	err  error
+}
+
+func (p *responseParser) peek() byte {
+	if p.pos < len(p.line) {
+		return p.line[p.pos]
+	}
+	return 0
+}
+
+func (p *responseParser) space() bool {
+	if p.peek() != ' ' {
+		return false
+	}
+	p.pos++
+	return true
+}
+
+func (p *responseParser) atom() string {
+	start := p.pos
+	for p.pos < len(p.line) && strings.IndexByte(" ()\"{", p.line[p.pos]) < 0 {
+		p.pos++
+	}
+	return p.line[start:p.pos]
+}
+
+func (p *responseParser) until(end byte) string {
+	start := p.pos
+	for p.pos < len(p.line) && p.line[p.pos] != end && p.line[p.pos] != ' ' {
+		p.pos++
+	}
+	return p.line[start:p.pos]
+}
+
+// string parses a quoted string or a literal
+func (p *responseParser) string() string {
+	if p.peek() == '{' {
+		return p.literal()
+	}
+	if p.peek() != '"' {
+		p.fail()
+		return ""
+	}
+	var b strings.Builder
+	for p.pos++; p.pos < len(p.line); p.pos++ {
+		switch c := p.line[p.pos]; c {
+		case '"':
+			p.pos++
+			return b.String()
+		case '\\':
+			p.pos++
+			if p.pos < len(p.line) {
+				b.WriteByte(p.line[p.pos])
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	p.fail()
+	return ""
+}
+
+// literal parses a literal, "{n}" at the end of the line followed by n octets, and
+// continues with the line after it
+func (p *responseParser) literal() string {
+	size, ok := literalSize(p.line[p.pos:])
+	if !ok || size > maxResponseLiteral {
+		p.fail()
+		return ""
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(p.r, data); err != nil {
+		p.err = err
+		return ""
+	}
+	if p.line, p.err = readLine(p.r); p.err != nil {
+		return ""
+	}
+	p.pos = 0
+	return string(data)
+}
+
+func (p *responseParser) fail() {
+	if p.err == nil {
+		p.err = fmt.Errorf("managesieve: invalid response %q at %d", p.line, p.pos)
+	}
+}
+
+// readLine reads a line ending in CRLF, without it
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasSuffix(line, "\r\n") {
+		return "", fmt.Errorf("managesieve: line without CRLF %q", line)
+	}
+	return line[:len(line)-2], nil
+}
+
+// quoteOrLiteral formats s as a quoted string, or as a literal if it can't be quoted
+// (RFC 5804, section 4)
+func quoteOrLiteral(s string) string {
+	if len(s) > maxQuoted || strings.ContainsAny(s, "\r\n\x00") {
+		return "{" + strconv.Itoa(len(s)) + "}\r\n" + s
+	}
+	return quote(s)
+}
+
+// quote formats s as a quoted string
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// literalSize parses "{n}" or "{n+}", which must end s, and returns n
+func literalSize(s string) (int64, bool) {
+	if len(s) < 3 || s[0] != '{' || s[len(s)-1] != '}' {
+		return 0, false
+	}
+	size, err := strconv.ParseInt(strings.TrimSuffix(s[1:len(s)-1], "+"), 10, 64)
+	return size, err == nil && size >= 0 && s[1] != '+' && s[1] != '-'
+}
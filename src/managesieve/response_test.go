@@ -0,0 +1,93 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package managesieve
+
+import (
+	"bufio"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadResponse(t *testing.T) {
+	for _, test := range []struct {
+		line     string
+		expected Response
+	}{
+		{"OK\r\n", Response{Status: "OK"}},
+		{"ok \"Logged in.\"\r\n", Response{Status: "OK", Text: "Logged in."}},
+		{"NO (QUOTA/MAXSIZE) \"Script too \\\"big\\\"\"\r\n", Response{Status: "NO", Code: "QUOTA/MAXSIZE", Text: `Script too "big"`}},
+		{"NO (TAG \"a1\")\r\n", Response{Status: "NO", Code: "TAG", Args: []string{"a1"}}},
+		{"NO (WARNINGS) {12}\r\nline1\r\nline2\r\n", Response{Status: "NO", Code: "WARNINGS", Text: "line1\r\nline2"}},
+		{"BYE (REFERRAL sieve://sieve2.example.org) \"Try elsewhere\"\r\n", Response{Status: "BYE", Code: "REFERRAL", Args: []string{"sieve://sieve2.example.org"}, Text: "Try elsewhere"}},
+	} {
+		resp, err := ReadResponse(bufio.NewReader(strings.NewReader(test.line)))
+		if err != nil {
+			t.Errorf("%q: %v", test.line, err)
+			continue
+		}
+		if !reflect.DeepEqual(*resp, test.expected) {
+			t.Errorf("%q: expected %+v, got %+v", test.line, test.expected, *resp)
+		}
+		if line := resp.String() + "\r\n"; !strings.EqualFold(line, test.line) {
+			t.Errorf("%q: formatted as %q", test.line, line)
+		}
+	}
+	for _, line := range []string{"MAYBE\r\n", "NO (QUOTA\r\n", "NO \"unterminated\r\n", "OK\n", "OK \"a\" \"b\"\r\n", "NO {20}\r\nshort\r\n", "NO {x}\r\n"} {
+		if _, err := ReadResponse(bufio.NewReader(strings.NewReader(line))); err == nil {
+			t.Errorf("%q: expected an error", line)
+		}
+	}
+}
+
+func TestFollow(t *testing.T) {
+	servers := map[string]error{
+		"sieve1.example.org:4190": &Response{Status: "BYE", Code: "REFERRAL", Args: []string{"sieve://sieve2.example.org:2000"}},
+		"sieve2.example.org:2000": &Response{Status: "BYE", Code: "REFERRAL", Args: []string{"sieve://sieve3.example.org"}},
+		"sieve3.example.org:4190": nil,
+	}
+	var dialed []string
+	dial := func(addr string) (Conn, error) {
+		dialed = append(dialed, addr)
+		if err := servers[addr]; err != nil {
+			return nil, err
+		}
+		return &testConn{}, nil
+	}
+	if conn, err := Follow("sieve1.example.org:4190", 2, dial); err != nil || conn == nil || len(dialed) != 3 || dialed[2] != "sieve3.example.org:4190" {
+		t.Errorf("expected the referrals to be followed, got %v %v", dialed, err)
+	}
+	dialed = nil
+	if _, err := Follow("sieve1.example.org:4190", 1, dial); err == nil || len(dialed) != 2 {
+		t.Errorf("expected too many referrals, got %v %v", dialed, err)
+	}
+	bye := &Response{Status: "BYE", Text: "Shutting down"}
+	servers["sieve3.example.org:4190"] = bye
+	if _, err := Follow("sieve3.example.org:4190", 2, dial); !errors.Is(err, bye) {
+		t.Errorf("expected the BYE response, got %v", err)
+	}
+}
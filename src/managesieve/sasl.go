@@ -0,0 +1,184 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+// Package managesieve holds the parts of the ManageSieve protocol (RFC 5804) that a client
+// or a server for the script stores of package scripts builds on.
+package managesieve
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// Mechanism is the client side of a SASL mechanism (RFC 4422) for the AUTHENTICATE
+// command. Challenges and responses are the decoded bytes; the protocol encodes them in
+// base64.
+type Mechanism interface {
+	// Start returns the name of the mechanism and the initial response
+	Start() (name string, ir []byte, err error)
+	// Next returns the response to a challenge of the server
+	Next(challenge []byte) ([]byte, error)
+}
+
+// TokenSource returns an OAuth 2.0 access token; refresh is set when the server rejected
+// the previous token, so that a new one must be fetched instead of a cached one
+type TokenSource func(refresh bool) (string, error)
+
+// OAuthError is the error a server sends in a challenge when it rejects a token (RFC 7628,
+// section 3.2.2)
+type OAuthError struct {
+	Status  string `json:"status"`
+	Scope   string `json:"scope,omitempty"`
+	Schemes string `json:"schemes,omitempty"`
+}
+
+func (e *OAuthError) Error() string {
+	return "oauth: " + e.Status
+}
+
+// Plain is the PLAIN mechanism (RFC 4616)
+type Plain struct {
+	Identity string // the authorization identity; empty to act as Username
+	Username string
+	Password string
+}
+
+func (m *Plain) Start() (string, []byte, error) {
+	return "PLAIN", []byte(m.Identity + "\x00" + m.Username + "\x00" + m.Password), nil
+}
+
+func (m *Plain) Next(challenge []byte) ([]byte, error) {
+	return nil, errors.New("sasl: unexpected challenge for PLAIN")
+}
+
+// OAuthBearer is the OAUTHBEARER mechanism (RFC 7628). After a rejected token, Err holds
+// the error of the server and the next Start fetches a new token, so that a client retries
+// the authentication by starting the mechanism again.
+type OAuthBearer struct {
+	Username string
+	Host     string // the host and port the client connected to; optional
+	Port     int
+	Token    TokenSource
+	Err      *OAuthError // the error of the last rejected token
+
+	refresh bool
+}
+
+func (m *OAuthBearer) Start() (string, []byte, error) {
+	token, err := m.token()
+	if err != nil {
+		return "", nil, err
+	}
+	ir := "n,"
+	if m.Username != "" {
+		ir += "a=" + escapeSASLName(m.Username)
+	}
+	ir += ",\x01"
+	if m.Host != "" {
+		ir += "host=" + m.Host + "\x01"
+	}
+	if m.Port != 0 {
+		ir += "port=" + strconv.Itoa(m.Port) + "\x01"
+	}
+	ir += "auth=Bearer " + token + "\x01\x01"
+	return "OAUTHBEARER", []byte(ir), nil
+}
+
+// Next answers the error challenge of a rejected token with the dummy response that ends
+// the exchange (RFC 7628, section 3.2.3)
+func (m *OAuthBearer) Next(challenge []byte) ([]byte, error) {
+	err := m.reject(challenge)
+	if err != nil {
+		return nil, err
+	}
+	return []byte("\x01"), nil
+}
+
+func (m *OAuthBearer) token() (string, error) {
+	if m.Token == nil {
+		return "", errors.New("sasl: no token source")
+	}
+	refresh := m.refresh
+	m.refresh = false
+	return m.Token(refresh)
+}
+
+func (m *OAuthBearer) reject(challenge []byte) error {
+	var e OAuthError
+	if err := json.Unmarshal(challenge, &e); err != nil {
+		return fmt.Errorf("sasl: invalid error challenge: %w", err)
+	}
+	m.Err = &e
+	m.refresh = e.Status == "invalid_token" || e.Status == "401"
+	return nil
+}
+
+// XOAuth2 is the XOAUTH2 mechanism of Google and Microsoft, which predates OAUTHBEARER.
+// Like OAuthBearer, it fetches a new token after a rejected one.
+type XOAuth2 struct {
+	Username string
+	Token    TokenSource
+	Err      *OAuthError // the error of the last rejected token
+
+	bearer OAuthBearer
+}
+
+func (m *XOAuth2) Start() (string, []byte, error) {
+	m.bearer.Token = m.Token
+	token, err := m.bearer.token()
+	if err != nil {
+		return "", nil, err
+	}
+	return "XOAUTH2", []byte("user=" + m.Username + "\x01auth=Bearer " + token + "\x01\x01"), nil
+}
+
+// Next answers the error challenge of a rejected token with the empty response that ends
+// the exchange
+func (m *XOAuth2) Next(challenge []byte) ([]byte, error) {
+	err := m.bearer.reject(challenge)
+	m.Err = m.bearer.Err
+	if err != nil {
+		return nil, err
+	}
+	return []byte{}, nil
+}
+
+// escapeSASLName escapes a name in the GS2 header (RFC 5801, section 4)
+func escapeSASLName(name string) string {
+	escaped := make([]byte, 0, len(name))
+	for i := 0; i < len(name); i++ {
+		switch name[i] {
+		case ',':
+			escaped = append(escaped, "=2C"...)
+		case '=':
+			escaped = append(escaped, "=3D"...)
+		default:
+			escaped = append(escaped, name[i])
+		}
+	}
+	return string(escaped)
+}
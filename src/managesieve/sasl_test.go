@@ -0,0 +1,96 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package managesieve
+
+import (
+	"testing"
+)
+
+func TestPlain(t *testing.T) {
+	name, ir, err := (&Plain{Username: "alice", Password: "secret"}).Start()
+	if err != nil || name != "PLAIN" || string(ir) != "\x00alice\x00secret" {
+		t.Errorf("unexpected start %s %q %v", name, ir, err)
+	}
+}
+
+func TestOAuth(t *testing.T) {
+	var refreshed []bool
+	tokens := func(refresh bool) (string, error) {
+		refreshed = append(refreshed, refresh)
+		if refresh {
+			return "fresh", nil
+		}
+		return "cached", nil
+	}
+	for _, test := range []struct {
+		mechanism Mechanism
+		name      string
+		ir        []string
+		response  string
+	}{
+		{
+			&OAuthBearer{Username: "alice=a,b", Host: "sieve.example.org", Port: 4190, Token: tokens},
+			"OAUTHBEARER",
+			[]string{
+				"n,a=alice=3Da=2Cb,\x01host=sieve.example.org\x01port=4190\x01auth=Bearer cached\x01\x01",
+				"n,a=alice=3Da=2Cb,\x01host=sieve.example.org\x01port=4190\x01auth=Bearer fresh\x01\x01",
+			},
+			"\x01",
+		},
+		{
+			&XOAuth2{Username: "alice", Token: tokens},
+			"XOAUTH2",
+			[]string{"user=alice\x01auth=Bearer cached\x01\x01", "user=alice\x01auth=Bearer fresh\x01\x01"},
+			"",
+		},
+	} {
+		refreshed = nil
+		for i, want := range test.ir {
+			name, ir, err := test.mechanism.Start()
+			if err != nil || name != test.name || string(ir) != want {
+				t.Errorf("%s: unexpected start %d %s %q %v", test.name, i, name, ir, err)
+			}
+			if i == 0 {
+				response, err := test.mechanism.Next([]byte(`{"status":"invalid_token","scope":"sieve"}`))
+				if err != nil || string(response) != test.response {
+					t.Errorf("%s: unexpected response %q %v", test.name, response, err)
+				}
+			}
+		}
+		if len(refreshed) != 2 || refreshed[0] || !refreshed[1] {
+			t.Errorf("%s: expected a refresh after the rejected token, got %v", test.name, refreshed)
+		}
+		if _, err := test.mechanism.Next([]byte("not json")); err == nil {
+			t.Errorf("%s: expected an error for an invalid challenge", test.name)
+		}
+	}
+
+	bearer := &OAuthBearer{Token: tokens}
+	bearer.Next([]byte(`{"status":"insufficient_scope","scope":"sieve"}`))
+	if bearer.Err == nil || bearer.Err.Scope != "sieve" || bearer.refresh {
+		t.Errorf("expected no refresh for an insufficient scope, got %+v", bearer.Err)
+	}
+}
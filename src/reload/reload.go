@@ -0,0 +1,195 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+// Package reload keeps the parsed script of a user up to date with its source, e.g. a file
+// edited by the user or a script uploaded to a store: a ScriptManager parses and validates a
+// changed script and only replaces the script it serves if the new one passes, so a broken
+// upload never takes the rules of a user offline.
+package reload
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"gosieve/src/rfc5228"
+)
+
+// Source provides the text of a script
+type Source interface {
+	// Load returns the script and a version that changes whenever the script does, e.g. the
+	// modification time of a file or the revision of a stored script
+	Load() (script, version string, err error)
+}
+
+// FileSource is the script in a file; its version is the size and modification time
+type FileSource string
+
+func (f FileSource) Load() (string, string, error) {
+	info, err := os.Stat(string(f))
+	if err != nil {
+		return "", "", err
+	}
+	content, err := os.ReadFile(string(f))
+	if err != nil {
+		return "", "", err
+	}
+	return string(content), fmt.Sprintf("%d-%d", info.Size(), info.ModTime().UnixNano()), nil
+}
+
+// FuncSource is a script returned by a callback, e.g. of a store
+type FuncSource func() (script, version string, err error)
+
+func (f FuncSource) Load() (string, string, error) {
+	return f()
+}
+
+// Script is a version of a script that passed the gate of its manager
+type Script struct {
+	Tree     *rfc5228.Tree
+	Warnings []rfc5228.Warning
+	Version  string    // the version reported by the source
+	Loaded   time.Time // the time the version was loaded
+}
+
+// Options control how a ScriptManager parses and accepts scripts
+type Options struct {
+	Strict  bool // parse in strict mode
+	Options []rfc5228.Option
+
+	// Gate rejects a script by the warnings of the validator; if nil, scripts with warnings
+	// of rfc5228.SeverityError (see rfc5228.WithWarningsAsErrors) are rejected
+	Gate func(warnings []rfc5228.Warning) error
+}
+
+// ScriptManager serves the last version of a script that parsed and passed the gate
+type ScriptManager struct {
+	name   string
+	source Source
+	opts   Options
+
+	mu       sync.Mutex // serializes reloads
+	rejected string     // the last version that was rejected, so that it isn't parsed again
+	now      func() time.Time
+
+	currentMu sync.RWMutex // guards current, which is read while a reload runs
+	current   *Script
+}
+
+// New returns a manager of the named script of a source; it fails if the first version of
+// the script can't be loaded or is rejected, as there is no earlier version to serve
+func New(name string, source Source, opts Options) (*ScriptManager, error) {
+	m := &ScriptManager{name: name, source: source, opts: opts, now: time.Now}
+	if _, err := m.Reload(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Script returns the current script; it is safe to call while a reload runs
+func (m *ScriptManager) Script() *Script {
+	m.currentMu.RLock()
+	defer m.currentMu.RUnlock()
+	return m.current
+}
+
+// Reload loads the script of the source and, if its version changed, parses and validates
+// it and replaces the current script. It reports whether the script was replaced; a script
+// that can't be loaded, parsed or passes the gate is an error and leaves the current one in
+// place.
+func (m *ScriptManager) Reload() (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	script, version, err := m.source.Load()
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", m.name, err)
+	}
+	if current := m.Script(); current != nil && current.Version == version {
+		return false, nil
+	}
+	if version != "" && version == m.rejected {
+		return false, nil
+	}
+
+	next, err := m.accept(script, version)
+	if err != nil {
+		m.rejected = version
+		return false, fmt.Errorf("%s: version %s rejected: %w", m.name, version, err)
+	}
+	m.currentMu.Lock()
+	m.current = next
+	m.currentMu.Unlock()
+	return true, nil
+}
+
+// accept parses and validates a version of the script and applies the gate
+func (m *ScriptManager) accept(script, version string) (*Script, error) {
+	var mode rfc5228.Mode
+	if m.opts.Strict {
+		mode |= rfc5228.ModeStrict
+	}
+	tree, err := rfc5228.Parse(m.name, script, mode, m.opts.Options...)
+	if err != nil {
+		return nil, err
+	}
+	warnings := rfc5228.Validate(tree, m.opts.Options...)
+	gate := m.opts.Gate
+	if gate == nil {
+		gate = rejectErrors
+	}
+	if err := gate(warnings); err != nil {
+		return nil, err
+	}
+	return &Script{Tree: tree, Warnings: warnings, Version: version, Loaded: m.now()}, nil
+}
+
+// rejectErrors is the default gate: it rejects scripts with warnings of SeverityError
+func rejectErrors(warnings []rfc5228.Warning) error {
+	for _, w := range warnings {
+		if w.Severity == rfc5228.SeverityError {
+			return fmt.Errorf("%d:%d: %s: %s", w.Line, w.Column, w.Code, w.Message)
+		}
+	}
+	return nil
+}
+
+// Watch reloads the script every interval until stop is closed; the errors of reloads are
+// passed to onError, which may be nil to ignore them
+func (m *ScriptManager) Watch(interval time.Duration, stop <-chan struct{}, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if _, err := m.Reload(); err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,134 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package reload
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gosieve/src/rfc5228"
+)
+
+func TestScriptManager(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "user.sieve")
+	write := func(script string, modified time.Time) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(script), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, modified, modified); err != nil {
+			t.Fatal(err)
+		}
+	}
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	write("keep;\r\n", start)
+	m, err := New("user", FileSource(path), Options{Options: []rfc5228.Option{rfc5228.WithWarningsAsErrors(rfc5228.CodeRedirectAddress)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := m.Script()
+	if len(first.Tree.Commands()) != 1 {
+		t.Fatalf("unexpected script %v", first.Tree.Commands())
+	}
+	if replaced, err := m.Reload(); replaced || err != nil {
+		t.Errorf("unexpected reload of an unchanged script: %v (%v)", replaced, err)
+	}
+
+	for i, test := range []struct {
+		script   string
+		replaced bool
+		commands int
+	}{
+		{"discard;\r\nstop;\r\n", true, 2},
+		{"if {\r\n", false, 2},                     // syntax error
+		{"redirect \"postmaster\";\r\n", false, 2}, // rejected by the gate
+		{"keep;\r\nkeep;\r\nkeep;\r\n", true, 3},
+	} {
+		write(test.script, start.Add(time.Duration(i+1)*time.Minute))
+		replaced, err := m.Reload()
+		if replaced != test.replaced || (err == nil) != test.replaced {
+			t.Errorf("%q: unexpected reload %v (%v)", test.script, replaced, err)
+		}
+		if commands := len(m.Script().Tree.Commands()); commands != test.commands {
+			t.Errorf("%q: expected %d commands, got %d", test.script, test.commands, commands)
+		}
+	}
+
+	// a rejected version isn't parsed and reported again
+	write("if {\r\n", start.Add(time.Hour))
+	if _, err := m.Reload(); err == nil {
+		t.Error("expected an error for a syntax error")
+	}
+	if replaced, err := m.Reload(); replaced || err != nil {
+		t.Errorf("unexpected reload of a rejected version: %v (%v)", replaced, err)
+	}
+
+	failing := FuncSource(func() (string, string, error) { return "", "", errors.New("unavailable") })
+	if _, err := New("user", failing, Options{}); err == nil {
+		t.Error("expected an error for a script that can't be loaded")
+	}
+	if _, err := New("user", FuncSource(func() (string, string, error) { return "if {", "1", nil }), Options{}); err == nil {
+		t.Error("expected an error for a first version that doesn't parse")
+	}
+}
+
+func TestScriptManagerWatch(t *testing.T) {
+	version := make(chan string, 1)
+	version <- "1"
+	current := "1"
+	source := FuncSource(func() (string, string, error) {
+		select {
+		case current = <-version:
+		default:
+		}
+		return "keep;\r\n", current, nil
+	})
+	m, err := New("user", source, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		m.Watch(time.Millisecond, stop, nil)
+		close(done)
+	}()
+	version <- "2"
+	deadline := time.Now().Add(5 * time.Second)
+	for m.Script().Version != "2" && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	close(stop)
+	<-done
+	if m.Script().Version != "2" {
+		t.Errorf("expected version 2, got %s", m.Script().Version)
+	}
+}
@@ -0,0 +1,197 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	maxLocalPartLength = 64  // RFC 5321, section 4.5.3.1.1
+	maxDomainLength    = 255 // RFC 5321, section 4.5.3.1.2
+)
+
+// SplitAddress splits an addr-spec into its local part and domain.
+//
+// Internationalized addresses are supported: the local part and the domain may hold
+// UTF-8 characters (RFC 6531, RFC 6532), so U-labels are accepted next to A-labels.
+// The local part is returned as written, i.e. a quoted local part keeps its quotes.
+func SplitAddress(addr string) (local, domain string, err error) {
+	if !utf8.ValidString(addr) {
+		return "", "", fmt.Errorf("invalid UTF-8 in address %q", addr)
+	}
+
+	at := strings.LastIndexByte(addr, '@')
+	if at < 0 {
+		return "", "", fmt.Errorf("missing `@` in address %q", addr)
+	}
+	local, domain = addr[:at], addr[at+1:]
+
+	if !isLocalPart(local) || len(local) > maxLocalPartLength {
+		return "", "", fmt.Errorf("invalid local part in address %q", addr)
+	}
+	if !isDomain(domain) || len(domain) > maxDomainLength {
+		return "", "", fmt.Errorf("invalid domain in address %q", addr)
+	}
+	return local, domain, nil
+}
+
+// isAtext reports whether r may be part of an atom (RFC 5322, section 3.2.3; RFC 6532, section 3.2)
+func isAtext(r rune) bool {
+	return r >= utf8.RuneSelf ||
+		r >= 'a' && r <= 'z' ||
+		r >= 'A' && r <= 'Z' ||
+		r >= '0' && r <= '9' ||
+		strings.ContainsRune("!#$%&'*+-/=?^_`{|}~", r)
+}
+
+// isLocalPart reports whether s is a dot-atom or a quoted-string
+func isLocalPart(s string) bool {
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		return isQuotedLocalPart(s[1 : len(s)-1])
+	}
+	return isDotAtom(s, isAtext)
+}
+
+// isQuotedLocalPart reports whether s is the content of a quoted-string
+func isQuotedLocalPart(s string) bool {
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\\':
+			// quoted-pair
+			if i++; i == len(s) || s[i] < ' ' || s[i] == 0x7F {
+				return false
+			}
+		case c == '"' || c < ' ' && c != '\t' || c == 0x7F:
+			return false
+		}
+	}
+	return true
+}
+
+// isDotAtom reports whether s is a non-empty sequence of atoms separated by single dots
+func isDotAtom(s string, valid func(r rune) bool) bool {
+	if s == "" {
+		return false
+	}
+	for _, atom := range strings.Split(s, ".") {
+		if atom == "" || strings.IndexFunc(atom, func(r rune) bool { return !valid(r) }) >= 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// isDomain reports whether s is a domain of (A- or U-) labels or an address literal
+func isDomain(s string) bool {
+	if strings.HasPrefix(s, "[") && strings.HasSuffix(s, "]") {
+		return len(s) > 2 && strings.IndexFunc(s[1:len(s)-1], func(r rune) bool {
+			return r <= ' ' || r > '~' || r == '[' || r == ']' || r == '\\'
+		}) < 0
+	}
+
+	valid := isDotAtom(s, func(r rune) bool {
+		return r >= utf8.RuneSelf ||
+			r >= 'a' && r <= 'z' ||
+			r >= 'A' && r <= 'Z' ||
+			r >= '0' && r <= '9' ||
+			r == '-'
+	})
+	if !valid {
+		return false
+	}
+
+	ascii, err := DomainToASCII(s)
+	if err != nil {
+		return false
+	}
+	for _, label := range strings.Split(ascii, ".") {
+		if len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+	}
+	return true
+}
+
+// AddressPart identifies the part of an address that is compared (RFC 5228, section 2.7.4)
+type AddressPart int
+
+const (
+	AddressAll       AddressPart = iota // :all, the default
+	AddressLocalPart                    // :localpart
+	AddressDomain                       // :domain
+)
+
+// AddressPart returns the address-part given by the tags of an address or envelope test
+func (n *TestNode) AddressPart() AddressPart {
+	switch {
+	case n.HasTag(":localpart"):
+		return AddressLocalPart
+	case n.HasTag(":domain"):
+		return AddressDomain
+	default:
+		return AddressAll
+	}
+}
+
+// ExtractAddressPart returns the given part of an addr-spec.
+//
+// If idna is set the domain is normalized to its lower-cased ASCII form (A-labels), so that
+// `bücher.example` and `xn--bcher-kva.example` compare equal; keys compared to the
+// result should be normalized with NormalizeDomain.
+func ExtractAddressPart(addr string, part AddressPart, idna bool) (string, error) {
+	local, domain, err := SplitAddress(addr)
+	if err != nil {
+		return "", err
+	}
+	if idna {
+		domain = NormalizeDomain(domain)
+	}
+
+	switch part {
+	case AddressLocalPart:
+		return local, nil
+	case AddressDomain:
+		return domain, nil
+	default:
+		return local + "@" + domain, nil
+	}
+}
+
+// NormalizeDomain returns the lower-cased ASCII form of a domain (see DomainToASCII);
+// a domain that can't be converted is returned unchanged
+func NormalizeDomain(domain string) string {
+	if strings.HasPrefix(domain, "[") {
+		return domain
+	}
+	ascii, err := DomainToASCII(domain)
+	if err != nil {
+		return domain
+	}
+	return ascii
+}
@@ -0,0 +1,112 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import "testing"
+
+func TestSplitAddress(t *testing.T) {
+	for addr, expected := range map[string][2]string{
+		"user@example.com":             {"user", "example.com"},
+		"first.last+tag@example.com":   {"first.last+tag", "example.com"},
+		`"john doe"@example.com`:       {`"john doe"`, "example.com"},
+		"用户@例子.广告":                     {"用户", "例子.广告"},
+		"δοκιμή@xn--bcher-kva.example": {"δοκιμή", "xn--bcher-kva.example"},
+		"user@[192.0.2.1]":             {"user", "[192.0.2.1]"},
+	} {
+		local, domain, err := SplitAddress(addr)
+		if err != nil {
+			t.Errorf("%s: unexpected error %s", addr, err)
+			continue
+		}
+		if local != expected[0] || domain != expected[1] {
+			t.Errorf("%s: expected %v, got [%s %s]", addr, expected, local, domain)
+		}
+	}
+
+	for _, addr := range []string{
+		"",
+		"user",
+		"@example.com",
+		"user@",
+		"us er@example.com",
+		"user..name@example.com",
+		"user@-example.com",
+		"user@exa_mple.com",
+		"Name <user@example.com>",
+		"user@example.com\r\n",
+		"\xffuser@example.com",
+	} {
+		if _, _, err := SplitAddress(addr); err == nil {
+			t.Errorf("%q: expected error", addr)
+		}
+	}
+}
+
+func TestRedirectAddress(t *testing.T) {
+	input := "redirect \"not an address\";\r\nredirect \"用户@例子.广告\";\r\n"
+
+	if _, err := Parse("test", input, ModeStrict); err == nil {
+		t.Errorf("expected error for invalid address in strict mode")
+	}
+
+	warnings := Validate(parse(t, input))
+	if len(warnings) != 1 || warnings[0].Pos != 0 {
+		t.Errorf("expected a single warning for the invalid address, got %v", warnings)
+	}
+}
+
+func TestExtractAddressPart(t *testing.T) {
+	for _, expected := range []struct {
+		part   AddressPart
+		idna   bool
+		result string
+	}{
+		{AddressAll, false, "User@Bücher.Example"},
+		{AddressLocalPart, false, "User"},
+		{AddressDomain, false, "Bücher.Example"},
+		{AddressDomain, true, "xn--bcher-kva.example"},
+		{AddressAll, true, "User@xn--bcher-kva.example"},
+	} {
+		result, err := ExtractAddressPart("User@Bücher.Example", expected.part, expected.idna)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result != expected.result {
+			t.Errorf("expected %s, got %s", expected.result, result)
+		}
+	}
+
+	if NormalizeDomain("bücher.example") != NormalizeDomain("XN--BCHER-KVA.example") {
+		t.Errorf("expected U-label and A-label forms to normalize equally")
+	}
+}
+
+func TestAddressPartTag(t *testing.T) {
+	tree := parse(t, "if address :DOMAIN :is \"from\" \"bücher.example\" {\r\n  keep;\r\n}\r\n")
+	if part := tree.Commands()[0].(*IfNode).Test.AddressPart(); part != AddressDomain {
+		t.Errorf("expected domain address part, got %d", part)
+	}
+}
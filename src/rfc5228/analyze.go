@@ -0,0 +1,250 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import (
+	"sort"
+	"strings"
+)
+
+// testCapabilities maps tests defined by extensions to the capability that defines them
+var testCapabilities = map[string]string{
+	ENVELOPE:                   "envelope",
+	"body":                     "body",
+	"currentdate":              "date",
+	"date":                     "date",
+	"duplicate":                "duplicate",
+	"environment":              "environment",
+	"hasflag":                  "imap4flags",
+	"ihave":                    "ihave",
+	"mailboxexists":            "mailbox",
+	"metadata":                 "mboxmetadata",
+	"metadataexists":           "mboxmetadata",
+	"notify_method_capability": "enotify",
+	"servermetadata":           "servermetadata",
+	"servermetadataexists":     "servermetadata",
+	"spamtest":                 "spamtest",
+	"string":                   "variables",
+	"valid_ext_list":           "extlists",
+	"valid_notify_method":      "enotify",
+	"virustest":                "virustest",
+}
+
+// commandCapabilities maps commands defined by extensions to the capabilities that define them
+var commandCapabilities = map[string][]string{
+	"addflag":      {"imap4flags"},
+	"addheader":    {"editheader"},
+	"break":        {"foreverypart"},
+	"deleteheader": {"editheader"},
+	"enclose":      {"enclose"},
+	"ereject":      {"ereject"},
+	"error":        {"ihave"},
+	"extracttext":  {"extracttext"},
+	"fileinto":     {"fileinto"},
+	"foreverypart": {"foreverypart"},
+	"global":       {"include", "variables"}, // RFC 6609, section 3.3
+	"include":      {"include"},
+	"notify":       {"enotify"},
+	"reject":       {"reject"},
+	"removeflag":   {"imap4flags"},
+	"replace":      {"replace"},
+	"return":       {"include"},
+	"set":          {"variables"},
+	"setflag":      {"imap4flags"},
+	"vacation":     {"vacation"},
+}
+
+// tagCapabilities maps tagged arguments defined by extensions to the capability that defines them
+var tagCapabilities = map[string]string{
+	":count":    "relational",
+	":value":    "relational",
+	":regex":    "regex",
+	":user":     "subaddress",
+	":detail":   "subaddress",
+	":index":    "index",
+	":last":     "index",
+	":list":     "extlists",
+	":mime":     "mime",
+	":anychild": "mime",
+	":percent":  "spamtestplus",
+	":zone":     "date",
+
+	// tags of commands
+	":copy":       "copy",
+	":create":     "mailbox",
+	":fcc":        "fcc",
+	":flags":      "imap4flags",
+	":seconds":    "vacation-seconds",
+	":specialuse": "special-use",
+}
+
+// builtinComparators are the comparators every implementation supports without a require (RFC 5228, section 2.7.3)
+var builtinComparators = map[string]bool{"i;octet": true, "i;ascii-casemap": true}
+
+// Analysis is the capability usage of a script
+type Analysis struct {
+	used       map[string][]Pos // positions of the tests and tags using a capability
+	required   map[string][]Pos // positions of the require commands requiring a capability
+	constructs map[Pos]string   // names of the commands, tests and tags using a capability
+}
+
+// Requirement explains why a script needs a capability: a construct that uses it, e.g. for a
+// UI that offers to add the capability to the require command
+type Requirement struct {
+	Capability string `json:"capability"`
+	Pos        Pos    `json:"pos"`       // The starting position, in bytes, of the construct in the input string.
+	Construct  string `json:"construct"` // The name of the command, test or tag as written, e.g. `:copy`.
+}
+
+// Analyze reports the capabilities a script uses, derived from the commands, tests and tags
+// defined by extensions, and those it requires. Commands of extensions are only parsed outside
+// strict mode (see GenericCommandNode).
+func Analyze(tree *Tree) *Analysis {
+	a := &Analysis{used: map[string][]Pos{}, required: map[string][]Pos{}, constructs: map[Pos]string{}}
+	for _, require := range tree.Requires() {
+		for _, capability := range require.Capabilities {
+			a.required[capability] = append(a.required[capability], require.Pos)
+		}
+	}
+	var walk func(commands []Command)
+	walk = func(commands []Command) {
+		for _, node := range commands {
+			switch n := node.(type) {
+			case *IfNode:
+				for _, test := range n.Conditions() {
+					a.test(test)
+				}
+				for _, block := range n.Blocks() {
+					walk(block.Commands())
+				}
+			case *GenericCommandNode:
+				for _, capability := range commandCapabilities[strings.ToLower(n.Name)] {
+					a.use(capability, n.Pos, n.Name)
+				}
+				a.arguments(n.Arguments)
+				for _, test := range n.Tests {
+					a.test(test)
+				}
+				if n.Block != nil {
+					walk(n.Block.Commands())
+				}
+			}
+		}
+	}
+	walk(tree.Commands())
+	return a
+}
+
+func (a *Analysis) use(capability string, pos Pos, construct string) {
+	a.used[capability] = append(a.used[capability], pos)
+	a.constructs[pos] = construct
+}
+
+func (a *Analysis) test(test *TestNode) {
+	if capability, ok := testCapabilities[strings.ToLower(test.Name)]; ok {
+		a.use(capability, test.Pos, test.Name)
+	}
+	a.arguments(test.Arguments)
+	for _, t := range test.Tests {
+		a.test(t)
+	}
+}
+
+func (a *Analysis) arguments(args []Argument) {
+	for i, arg := range args {
+		tag, ok := arg.(*TagNode)
+		if !ok {
+			continue
+		}
+		name := strings.ToLower(tag.Name)
+		if capability, ok := tagCapabilities[name]; ok {
+			a.use(capability, tag.Pos, tag.Name)
+		}
+		// :comparator <comparator-name: string>
+		if name == ":comparator" && i+1 < len(args) {
+			if s, ok := args[i+1].(*StringNode); ok && !builtinComparators[strings.ToLower(s.Text)] {
+				a.use("comparator-"+s.Text, tag.Pos, tag.Name)
+			}
+		}
+	}
+}
+
+// CapabilitiesUsed returns the minimal set of capabilities the script must require, sorted
+func (a *Analysis) CapabilitiesUsed() []string {
+	return sortedKeys(a.used, nil)
+}
+
+// Uses returns the positions of the tests and tags that use a capability in lexical order
+func (a *Analysis) Uses(capability string) []Pos {
+	uses := append([]Pos{}, a.used[capability]...)
+	sort.Slice(uses, func(i, j int) bool { return uses[i] < uses[j] })
+	return uses
+}
+
+// Explain returns the constructs that use a capability in lexical order
+func (a *Analysis) Explain(capability string) []Requirement {
+	var requirements []Requirement
+	for _, pos := range a.Uses(capability) {
+		requirements = append(requirements, Requirement{Capability: capability, Pos: pos, Construct: a.constructs[pos]})
+	}
+	return requirements
+}
+
+// Missing returns the capabilities the script uses but doesn't require, sorted
+func (a *Analysis) Missing() []string {
+	return sortedKeys(a.used, a.required)
+}
+
+// Unused returns the capabilities the script requires but doesn't use, sorted
+func (a *Analysis) Unused() []string {
+	return sortedKeys(a.required, a.used)
+}
+
+// sortedKeys returns the keys of m that are not keys of exclude, sorted
+func sortedKeys(m, exclude map[string][]Pos) []string {
+	keys := []string{}
+	for key := range m {
+		if _, ok := exclude[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// missing warns about the capabilities the script uses but doesn't require, at the first
+// construct using each; the others are related
+func (v *validator) missing(tree *Tree) {
+	analysis := Analyze(tree)
+	for _, capability := range analysis.Missing() {
+		requirements := analysis.Explain(capability)
+		var related []Pos
+		for _, r := range requirements[1:] {
+			related = append(related, r.Pos)
+		}
+		v.relatedf(requirements[0].Pos, related, CodeMissingCapability, requirements[0].Construct, capability)
+	}
+}
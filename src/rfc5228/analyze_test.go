@@ -0,0 +1,75 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAnalyzeCapabilities(t *testing.T) {
+	tree := parse(t, "require [\"envelope\", \"fileinto\", \"relational\"];\r\n"+
+		"if allof (envelope :user \"to\" \"me\", header :count \"ge\" :comparator \"i;ascii-numeric\" \"received\" \"3\") {\r\n"+
+		"  if not string :comparator \"i;octet\" \"a\" \"b\" {\r\n    keep;\r\n  }\r\n}\r\n")
+	a := Analyze(tree)
+
+	if used := a.CapabilitiesUsed(); !reflect.DeepEqual(used, []string{"comparator-i;ascii-numeric", "envelope", "relational", "subaddress", "variables"}) {
+		t.Errorf("unexpected capabilities %v", used)
+	}
+	if missing := a.Missing(); !reflect.DeepEqual(missing, []string{"comparator-i;ascii-numeric", "subaddress", "variables"}) {
+		t.Errorf("unexpected missing capabilities %v", missing)
+	}
+	if unused := a.Unused(); !reflect.DeepEqual(unused, []string{"fileinto"}) {
+		t.Errorf("unexpected unused capabilities %v", unused)
+	}
+	if uses := a.Uses("envelope"); !reflect.DeepEqual(uses, []Pos{59}) {
+		t.Errorf("unexpected uses %v", uses)
+	}
+}
+
+func TestAnalyzeExplain(t *testing.T) {
+	tree := parse(t, "require \"fileinto\";\r\n"+
+		"if header :regex \"subject\" \"^\\\\[x\\\\]\" {\r\n"+
+		"  fileinto :copy \"x\";\r\n"+
+		"}\r\n"+
+		"fileinto :copy \"y\";\r\n")
+
+	expected := []Requirement{{Capability: "copy", Pos: 73, Construct: ":copy"}, {Capability: "copy", Pos: 97, Construct: ":copy"}}
+	if explained := Analyze(tree).Explain("copy"); !reflect.DeepEqual(explained, expected) {
+		t.Errorf("unexpected requirements %v", explained)
+	}
+
+	var missing []Warning
+	for _, w := range Validate(tree) {
+		if w.Code == CodeMissingCapability {
+			missing = append(missing, w)
+		}
+	}
+	if len(missing) != 2 || missing[0].Message != "`:regex` needs capability `regex`, which isn't required" ||
+		missing[1].Line != 3 || !reflect.DeepEqual(missing[1].Related, []Pos{97}) || missing[1].Args[1] != "copy" {
+		t.Errorf("unexpected warnings %v", missing)
+	}
+}
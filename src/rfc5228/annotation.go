@@ -0,0 +1,220 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import (
+	"fmt"
+	"strings"
+)
+
+// annotationMarker starts the text of a hash comment holding an annotation: `#@ id=spam enabled=true`
+const annotationMarker = "@"
+
+// AnnotationField is a `name=value` pair of an annotation
+type AnnotationField struct {
+	Name  string
+	Value string
+}
+
+// Annotation is the structured metadata of a rule, written as a hash comment preceding the rule:
+//
+//	#@ id=spam-filter enabled=true title="Junk mail"
+//
+// Values that are empty or hold whitespace, `"` or `\` are quoted, with `\` escaping `"` and `\`.
+// Fields keep their order, so rewriting an annotation doesn't reorder it.
+type Annotation []AnnotationField
+
+// Get returns the value of a field
+func (a Annotation) Get(name string) (string, bool) {
+	for _, field := range a {
+		if field.Name == name {
+			return field.Value, true
+		}
+	}
+	return "", false
+}
+
+// Set returns the annotation with a field replaced, or appended if it isn't present
+func (a Annotation) Set(name, value string) Annotation {
+	result := append(Annotation{}, a...)
+	for i, field := range result {
+		if field.Name == name {
+			result[i].Value = value
+			return result
+		}
+	}
+	return append(result, AnnotationField{name, value})
+}
+
+// String returns the annotation as a hash comment
+func (a Annotation) String() string {
+	var b strings.Builder
+	b.WriteString("#" + annotationMarker)
+	for _, field := range a {
+		b.WriteString(" " + field.Name + "=")
+		if field.Value != "" && !strings.ContainsAny(field.Value, " \t\"\\") {
+			b.WriteString(field.Value)
+			continue
+		}
+		b.WriteByte('"')
+		for i := 0; i < len(field.Value); i++ {
+			if field.Value[i] == '"' || field.Value[i] == '\\' {
+				b.WriteByte('\\')
+			}
+			b.WriteByte(field.Value[i])
+		}
+		b.WriteByte('"')
+	}
+	return b.String()
+}
+
+// validate reports a field that can't be written in a hash comment
+func (a Annotation) validate() error {
+	for _, field := range a {
+		if field.Name == "" || strings.ContainsAny(field.Name, " \t\r\n=\"\\") {
+			return fmt.Errorf("invalid annotation name %q", field.Name)
+		}
+		if strings.ContainsAny(field.Value, "\r\n") {
+			return fmt.Errorf("annotation value of %s contains a line break", field.Name)
+		}
+	}
+	return nil
+}
+
+// ParseAnnotation parses the text of a hash comment (without the `#`) as an annotation;
+// ok is false if the text doesn't start with `@` or is malformed
+func ParseAnnotation(text string) (a Annotation, ok bool) {
+	s, ok := strings.CutPrefix(text, annotationMarker)
+	if !ok {
+		return nil, false
+	}
+	a = Annotation{}
+	for {
+		s = strings.TrimLeft(s, " \t")
+		if s == "" {
+			return a, true
+		}
+		i := strings.IndexAny(s, "= \t")
+		if i <= 0 || s[i] != '=' {
+			return nil, false
+		}
+		name := s[:i]
+		s = s[i+1:]
+
+		var value strings.Builder
+		if strings.HasPrefix(s, "\"") {
+			closed := false
+			for i = 1; i < len(s) && !closed; i++ {
+				switch {
+				case s[i] == '\\' && i+1 < len(s):
+					i++
+					value.WriteByte(s[i])
+				case s[i] == '"':
+					closed = true
+				default:
+					value.WriteByte(s[i])
+				}
+			}
+			if !closed || i < len(s) && s[i] != ' ' && s[i] != '\t' {
+				return nil, false
+			}
+			s = s[i:]
+		} else {
+			end := strings.IndexAny(s, " \t")
+			if end < 0 {
+				end = len(s)
+			}
+			value.WriteString(s[:end])
+			s = s[end:]
+		}
+		a = append(a, AnnotationField{name, value.String()})
+	}
+}
+
+// Annotation returns the annotation held by a hash comment
+func (n *CommentNode) Annotation() (Annotation, bool) {
+	if n.Kind != CommentHash {
+		return nil, false
+	}
+	return ParseAnnotation(n.Text)
+}
+
+// annotationComment returns the last annotation comment preceding a command
+func (t *Tree) annotationComment(command Command) (*CommentNode, Annotation) {
+	var comment *CommentNode
+	var annotation Annotation
+	for _, c := range t.CommentsOf(command) {
+		if a, ok := c.Annotation(); ok && !c.Trailing && c.Pos < command.Position() {
+			comment, annotation = c, a
+		}
+	}
+	return comment, annotation
+}
+
+// AnnotationOf returns the annotation of a command: that of the last annotation comment
+// preceding it, or nil if there is none
+func (t *Tree) AnnotationOf(command Command) Annotation {
+	_, annotation := t.annotationComment(command)
+	return annotation
+}
+
+// FindAnnotated returns the first command, in lexical order, whose annotation has a field
+// with the given value (e.g. id=spam-filter), or nil if there is none
+func (t *Tree) FindAnnotated(name, value string) Command {
+	seen := map[Command]bool{}
+	for _, comment := range t.Comments {
+		if comment.Command == nil || seen[comment.Command] {
+			continue
+		}
+		seen[comment.Command] = true
+		if v, ok := t.AnnotationOf(comment.Command).Get(name); ok && v == value {
+			return comment.Command
+		}
+	}
+	return nil
+}
+
+// Annotate returns the edit of the source of the tree that sets the annotation of a command:
+// the annotation comment preceding the command is replaced, or a new one is inserted on its own
+// line before the command with the indentation of the command. Apply the edit with Reparse.
+func (t *Tree) Annotate(command Command, annotation Annotation) (Edit, error) {
+	if err := annotation.validate(); err != nil {
+		return Edit{}, err
+	}
+	if t.Source == nil {
+		return Edit{}, fmt.Errorf("tree %s has no source", t.Name)
+	}
+	if comment, _ := t.annotationComment(command); comment != nil {
+		return Edit{Start: comment.Pos, End: comment.End(), Text: annotation.String()}, nil
+	}
+
+	pos := command.Position()
+	start := pos - Pos(t.Source.Position(pos).Column-1)
+	if indent := t.Source.Content[start:pos]; strings.Trim(indent, " \t") == "" {
+		return Edit{Start: start, End: start, Text: indent + annotation.String() + "\r\n"}, nil
+	}
+	return Edit{Start: pos, End: pos, Text: annotation.String() + "\r\n"}, nil
+}
@@ -0,0 +1,107 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseAnnotation(t *testing.T) {
+	for _, test := range []struct {
+		text     string
+		expected Annotation
+	}{
+		{"@", Annotation{}},
+		{"@ id=spam enabled=true", Annotation{{"id", "spam"}, {"enabled", "true"}}},
+		{"@ title=\"Junk \\\"mail\\\"\" \tempty=\"\"", Annotation{{"title", "Junk \"mail\""}, {"empty", ""}}},
+		{" id=spam", nil},
+		{"@ id", nil},
+		{"@ =x", nil},
+		{"@ title=\"open", nil},
+		{"@ title=\"a\"b", nil},
+	} {
+		a, ok := ParseAnnotation(test.text)
+		if ok != (test.expected != nil) || !reflect.DeepEqual(a, test.expected) {
+			t.Errorf("%q: expected %v, got %v", test.text, test.expected, a)
+		}
+		if ok {
+			if b, _ := ParseAnnotation(a.String()[1:]); !reflect.DeepEqual(a, b) {
+				t.Errorf("%q: %s doesn't round-trip", test.text, a)
+			}
+		}
+	}
+}
+
+func TestAnnotate(t *testing.T) {
+	input := "#@ id=spam enabled=true\r\nif header :contains \"subject\" \"spam\" {\r\n  discard;\r\n}\r\nif size :over 1M {\r\n  # big\r\n  keep;\r\n}\r\n"
+	tree := parse(t, input)
+	spam := tree.FindAnnotated("id", "spam")
+	if spam != tree.Commands()[0] {
+		t.Fatalf("unexpected command %v", spam)
+	}
+
+	// replace the existing annotation
+	edit, err := tree.Annotate(spam, tree.AnnotationOf(spam).Set("enabled", "false"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	input, tree = reparse(t, tree, input, edit)
+	if value, _ := tree.AnnotationOf(tree.Commands()[0]).Get("enabled"); value != "false" {
+		t.Errorf("unexpected annotation %v", tree.AnnotationOf(tree.Commands()[0]))
+	}
+
+	// insert an annotation with the indentation of the command
+	keep := tree.Commands()[1].(*IfNode).Body.Commands()[0]
+	if edit, err = tree.Annotate(keep, Annotation{{"id", "big"}}); err != nil {
+		t.Fatal(err)
+	}
+	input, tree = reparse(t, tree, input, edit)
+	expected := "#@ id=spam enabled=false\r\nif header :contains \"subject\" \"spam\" {\r\n  discard;\r\n}\r\nif size :over 1M {\r\n  # big\r\n  #@ id=big\r\n  keep;\r\n}\r\n"
+	if input != expected {
+		t.Errorf("unexpected script %q", input)
+	}
+	if tree.FindAnnotated("id", "big") != tree.Commands()[1].(*IfNode).Body.Commands()[0] {
+		t.Errorf("annotated command not found")
+	}
+
+	if _, err := tree.Annotate(keep, Annotation{{"id", "a\r\nb"}}); err == nil {
+		t.Errorf("expected an error for a line break")
+	}
+}
+
+func reparse(t *testing.T, tree *Tree, input string, edit Edit) (string, *Tree) {
+	t.Helper()
+	edited, err := edit.Apply(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree, err = Reparse(tree, input, edit, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return edited, tree
+}
@@ -0,0 +1,169 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+// arenaBlock is the number of nodes of a type an empty arena allocates at once
+const arenaBlock = 64
+
+// Arena allocates the nodes of the trees parsed with WithArena in blocks, so that parsing many
+// scripts in a loop (bulk analysis, a ManageSieve server) makes few allocations: after Release
+// the blocks are reused for the next script. The most numerous nodes (tests, arguments,
+// generic commands and blocks) are allocated from the arena, other nodes as usual.
+//
+// The trees parsed since the last Release, and their nodes, become invalid when the arena is
+// released: they are overwritten by the nodes of the next script. An arena must only be used
+// by one parser at a time.
+type Arena struct {
+	tests    []TestNode
+	strings  []StringNode
+	lists    []StringListNode
+	tags     []TagNode
+	numbers  []NumberNode
+	generics []GenericCommandNode
+	commands []CommandsNode
+
+	// the number of nodes used of each block
+	nTests, nStrings, nLists, nTags, nNumbers, nGenerics, nCommands int
+}
+
+// NewArena returns an empty arena
+func NewArena() *Arena {
+	return &Arena{}
+}
+
+// WithArena allocates the nodes of the parsed tree from an arena
+func WithArena(a *Arena) Option {
+	return func(o *options) {
+		o.arena = a
+	}
+}
+
+// Release makes the blocks of the arena available for the next script; the nodes allocated
+// so far must no longer be used
+func (a *Arena) Release() {
+	for i := 0; i < a.nTests; i++ {
+		a.tests[i] = TestNode{}
+	}
+	for i := 0; i < a.nStrings; i++ {
+		a.strings[i] = StringNode{}
+	}
+	for i := 0; i < a.nLists; i++ {
+		a.lists[i] = StringListNode{}
+	}
+	for i := 0; i < a.nTags; i++ {
+		a.tags[i] = TagNode{}
+	}
+	for i := 0; i < a.nNumbers; i++ {
+		a.numbers[i] = NumberNode{}
+	}
+	for i := 0; i < a.nGenerics; i++ {
+		a.generics[i] = GenericCommandNode{}
+	}
+	for i := 0; i < a.nCommands; i++ {
+		a.commands[i] = CommandsNode{}
+	}
+	a.nTests, a.nStrings, a.nLists, a.nTags, a.nNumbers, a.nGenerics, a.nCommands = 0, 0, 0, 0, 0, 0, 0
+}
+
+// A full block is replaced by one twice its size; the nodes in the full block stay valid, and
+// after Release only the largest block is reused, so the blocks grow to fit the largest script.
+
+func (a *Arena) test() *TestNode {
+	if a == nil {
+		return &TestNode{}
+	}
+	if a.nTests == len(a.tests) {
+		a.tests, a.nTests = make([]TestNode, 2*len(a.tests)+arenaBlock), 0
+	}
+	a.nTests++
+	return &a.tests[a.nTests-1]
+}
+
+func (a *Arena) string() *StringNode {
+	if a == nil {
+		return &StringNode{}
+	}
+	if a.nStrings == len(a.strings) {
+		a.strings, a.nStrings = make([]StringNode, 2*len(a.strings)+arenaBlock), 0
+	}
+	a.nStrings++
+	return &a.strings[a.nStrings-1]
+}
+
+func (a *Arena) stringList() *StringListNode {
+	if a == nil {
+		return &StringListNode{}
+	}
+	if a.nLists == len(a.lists) {
+		a.lists, a.nLists = make([]StringListNode, 2*len(a.lists)+arenaBlock), 0
+	}
+	a.nLists++
+	return &a.lists[a.nLists-1]
+}
+
+func (a *Arena) tag() *TagNode {
+	if a == nil {
+		return &TagNode{}
+	}
+	if a.nTags == len(a.tags) {
+		a.tags, a.nTags = make([]TagNode, 2*len(a.tags)+arenaBlock), 0
+	}
+	a.nTags++
+	return &a.tags[a.nTags-1]
+}
+
+func (a *Arena) number() *NumberNode {
+	if a == nil {
+		return &NumberNode{}
+	}
+	if a.nNumbers == len(a.numbers) {
+		a.numbers, a.nNumbers = make([]NumberNode, 2*len(a.numbers)+arenaBlock), 0
+	}
+	a.nNumbers++
+	return &a.numbers[a.nNumbers-1]
+}
+
+func (a *Arena) genericCommand() *GenericCommandNode {
+	if a == nil {
+		return &GenericCommandNode{}
+	}
+	if a.nGenerics == len(a.generics) {
+		a.generics, a.nGenerics = make([]GenericCommandNode, 2*len(a.generics)+arenaBlock), 0
+	}
+	a.nGenerics++
+	return &a.generics[a.nGenerics-1]
+}
+
+func (a *Arena) commandsNode() *CommandsNode {
+	if a == nil {
+		return &CommandsNode{}
+	}
+	if a.nCommands == len(a.commands) {
+		a.commands, a.nCommands = make([]CommandsNode, 2*len(a.commands)+arenaBlock), 0
+	}
+	a.nCommands++
+	return &a.commands[a.nCommands-1]
+}
@@ -0,0 +1,57 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import "testing"
+
+func TestArena(t *testing.T) {
+	script := GenerateScript(CorpusOptions{Rules: 50, ListLength: 20, Seed: 1})
+	expected := Fingerprint(parse(t, script))
+
+	arena := NewArena()
+	for i := 0; i < 3; i++ {
+		tree, err := Parse("arena", script, 0, WithArena(arena))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if Fingerprint(tree) != expected {
+			t.Errorf("%d: the tree differs from the tree parsed without arena", i)
+		}
+		arena.Release()
+	}
+
+	// after the first script the blocks of the arena are reused
+	without := testing.AllocsPerRun(10, func() {
+		_, _ = Parse("arena", script, 0)
+	})
+	with := testing.AllocsPerRun(10, func() {
+		_, _ = Parse("arena", script, 0, WithArena(arena))
+		arena.Release()
+	})
+	if with >= without*3/4 {
+		t.Errorf("expected fewer allocations with an arena: %v with, %v without", with, without)
+	}
+}
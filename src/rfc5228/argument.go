@@ -0,0 +1,396 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Argument is an argument of a test: a *TagNode, *StringNode, *StringListNode or *NumberNode;
+// the nested tests of a test are held by TestNode.Tests
+type Argument interface {
+	Node
+	argument()
+}
+
+func (*TagNode) argument()        {}
+func (*StringNode) argument()     {}
+func (*StringListNode) argument() {}
+func (*NumberNode) argument()     {}
+
+// ArgumentKind identifies the kind of an argument in a schema
+type ArgumentKind int
+
+const (
+	ArgNone       ArgumentKind = iota // no argument, e.g. the value of a tag that doesn't take one
+	ArgString                         // a string
+	ArgStringList                     // a string-list; a single string is accepted as a list of one
+	ArgNumber                         // a number
+	ArgTest                           // a single test
+	ArgTestList                       // a test-list
+)
+
+func (k ArgumentKind) String() string {
+	switch k {
+	case ArgString:
+		return "string"
+	case ArgStringList:
+		return "string-list"
+	case ArgNumber:
+		return "number"
+	case ArgTest:
+		return "test"
+	case ArgTestList:
+		return "test-list"
+	default:
+		return "none"
+	}
+}
+
+// TagSchema describes a tagged argument
+type TagSchema struct {
+	Group    string       // tags of the same group exclude each other, e.g. "match-type"; the tag itself if empty
+	Value    ArgumentKind // the kind of the argument following the tag, e.g. the name of :comparator
+	Required bool         // a tag of the group must be given, e.g. :over or :under of size
+}
+
+// TestSchema describes the arguments of a test, or of a command (see RegisterCommandSchema):
+// tags, in any order, followed by the positional arguments and the nested tests
+type TestSchema struct {
+	Tags       map[string]TagSchema // tags by lower-case name, including the colon
+	Positional []ArgumentKind       // kinds of the arguments after the tags, in order
+	Tests      ArgumentKind         // ArgNone, ArgTest or ArgTestList
+}
+
+// tag groups shared by the tests of RFC 5228 and the extensions refining them
+var (
+	comparatorTags = map[string]TagSchema{
+		":comparator": {Group: "comparator", Value: ArgString},
+	}
+	matchTypeTags = map[string]TagSchema{
+		":is":       {Group: "match-type"},
+		":contains": {Group: "match-type"},
+		":matches":  {Group: "match-type"},
+		":regex":    {Group: "match-type"},                   // draft-ietf-sieve-regex
+		":count":    {Group: "match-type", Value: ArgString}, // RFC 5231
+		":value":    {Group: "match-type", Value: ArgString}, // RFC 5231
+		":list":     {Group: "match-type"},                   // RFC 6134
+	}
+	addressPartTags = map[string]TagSchema{
+		":all":       {Group: "address-part"},
+		":localpart": {Group: "address-part"},
+		":domain":    {Group: "address-part"},
+		":user":      {Group: "address-part"}, // RFC 5233
+		":detail":    {Group: "address-part"}, // RFC 5233
+	}
+	indexTags = map[string]TagSchema{ // RFC 5260
+		":index": {Value: ArgNumber},
+		":last":  {},
+	}
+	mimeTags = map[string]TagSchema{ // RFC 5703
+		":mime":        {},
+		":anychild":    {},
+		":type":        {Group: "mime-option"},
+		":subtype":     {Group: "mime-option"},
+		":contenttype": {Group: "mime-option"},
+		":param":       {Group: "mime-option", Value: ArgStringList},
+	}
+)
+
+// tags merges tag groups
+func tags(groups ...map[string]TagSchema) map[string]TagSchema {
+	merged := map[string]TagSchema{}
+	for _, group := range groups {
+		for name, tag := range group {
+			merged[name] = tag
+		}
+	}
+	return merged
+}
+
+var (
+	testSchemasMu sync.RWMutex
+	testSchemas   = map[string]*TestSchema{
+		// address [COMPARATOR] [ADDRESS-PART] [MATCH-TYPE] <header-list: string-list> <key-list: string-list>
+		ADDRESS: {Tags: tags(comparatorTags, addressPartTags, matchTypeTags, indexTags, mimeTags), Positional: []ArgumentKind{ArgStringList, ArgStringList}},
+		// envelope [COMPARATOR] [ADDRESS-PART] [MATCH-TYPE] <envelope-part: string-list> <key-list: string-list>
+		ENVELOPE: {Tags: tags(comparatorTags, addressPartTags, matchTypeTags), Positional: []ArgumentKind{ArgStringList, ArgStringList}},
+		// header [COMPARATOR] [MATCH-TYPE] <header-names: string-list> <key-list: string-list>
+		HEADER: {Tags: tags(comparatorTags, matchTypeTags, indexTags, mimeTags), Positional: []ArgumentKind{ArgStringList, ArgStringList}},
+		// exists <header-names: string-list>
+		EXISTS: {Tags: tags(mimeTags), Positional: []ArgumentKind{ArgStringList}},
+		// size <":over" / ":under"> <limit: number>
+		SIZE:  {Tags: map[string]TagSchema{":over": {Group: "size", Required: true}, ":under": {Group: "size", Required: true}}, Positional: []ArgumentKind{ArgNumber}},
+		TRUE:  {},
+		FALSE: {},
+		NOT:   {Tests: ArgTest},
+		ALLOF: {Tests: ArgTestList},
+		ANYOF: {Tests: ArgTestList},
+		// environment [COMPARATOR] [MATCH-TYPE] <name: string> <key-list: string-list> (RFC 5183)
+		"environment": {Tags: tags(comparatorTags, matchTypeTags), Positional: []ArgumentKind{ArgString, ArgStringList}},
+	}
+)
+
+// RegisterTestSchema adds or replaces the schema of a test, e.g. of an extension, so that its
+// arguments can be bound with Bind and are validated by the parser in strict mode
+func RegisterTestSchema(name string, schema *TestSchema) {
+	testSchemasMu.Lock()
+	defer testSchemasMu.Unlock()
+	testSchemas[strings.ToLower(name)] = schema
+}
+
+// LookupTestSchema returns the schema of a test by its (case-insensitive) name
+func LookupTestSchema(name string) (*TestSchema, bool) {
+	testSchemasMu.RLock()
+	defer testSchemasMu.RUnlock()
+	schema, ok := testSchemas[strings.ToLower(name)]
+	return schema, ok
+}
+
+var (
+	commandSchemasMu sync.RWMutex
+	commandSchemas   = map[string]*TestSchema{
+		"vacation": vacationSchema,
+	}
+)
+
+// RegisterCommandSchema adds or replaces the schema of a command of an extension (see
+// GenericCommandNode), so that its arguments can be bound with Bind and are validated by
+// the parser in strict mode
+func RegisterCommandSchema(name string, schema *TestSchema) {
+	commandSchemasMu.Lock()
+	defer commandSchemasMu.Unlock()
+	commandSchemas[strings.ToLower(name)] = schema
+}
+
+// LookupCommandSchema returns the schema of a command by its (case-insensitive) name
+func LookupCommandSchema(name string) (*TestSchema, bool) {
+	commandSchemasMu.RLock()
+	defer commandSchemasMu.RUnlock()
+	schema, ok := commandSchemas[strings.ToLower(name)]
+	return schema, ok
+}
+
+// Arguments are the arguments of a test or command bound to its schema
+type Arguments struct {
+	groups     map[string]*TagNode // the tag given for each group
+	values     map[string]Argument // the values of the tags taking one, by lower-case tag name
+	positional []Argument
+}
+
+// Bind binds the arguments of the test to the schema of the test (see RegisterTestSchema)
+func (n *TestNode) Bind() (*Arguments, error) {
+	schema, ok := LookupTestSchema(n.Name)
+	if !ok {
+		return nil, fmt.Errorf("unknown test %s", n.Name)
+	}
+	return n.BindSchema(schema)
+}
+
+// BindSchema binds the arguments of the test to a schema: every tag must be defined by the schema
+// and given at most once per group, a group of required tags must be given, a tag taking a
+// value must be followed by a value of its kind, and the positional arguments and nested
+// tests must match those of the schema
+func (n *TestNode) BindSchema(schema *TestSchema) (*Arguments, error) {
+	return bind(n.Arguments, n.Tests, schema)
+}
+
+// Bind binds the arguments of the command to the schema of the command (see
+// RegisterCommandSchema)
+func (n *GenericCommandNode) Bind() (*Arguments, error) {
+	schema, ok := LookupCommandSchema(n.Name)
+	if !ok {
+		return nil, fmt.Errorf("unknown command %s", n.Name)
+	}
+	return n.BindSchema(schema)
+}
+
+// BindSchema binds the arguments of the command to a schema, like TestNode.BindSchema; the
+// block of the command isn't part of the schema
+func (n *GenericCommandNode) BindSchema(schema *TestSchema) (*Arguments, error) {
+	return bind(n.Arguments, n.Tests, schema)
+}
+
+func bind(arguments []Argument, tests []*TestNode, schema *TestSchema) (*Arguments, error) {
+	a := &Arguments{groups: map[string]*TagNode{}, values: map[string]Argument{}}
+	i := 0
+	for ; i < len(arguments); i++ {
+		tag, ok := arguments[i].(*TagNode)
+		if !ok {
+			break
+		}
+		name := strings.ToLower(tag.Name)
+		spec, ok := schema.Tags[name]
+		if !ok {
+			return nil, fmt.Errorf("unexpected tag %s", tag.Name)
+		}
+		group := spec.Group
+		if group == "" {
+			group = name
+		}
+		if other, ok := a.groups[group]; ok {
+			return nil, fmt.Errorf("tag %s conflicts with %s", tag.Name, other.Name)
+		}
+		a.groups[group] = tag
+		if spec.Value == ArgNone {
+			continue
+		}
+		if i+1 == len(arguments) || !matchesKind(arguments[i+1], spec.Value) {
+			return nil, fmt.Errorf("tag %s expects a %s", tag.Name, spec.Value)
+		}
+		i++
+		a.values[name] = arguments[i]
+	}
+	if missing := missingGroup(schema, a.groups); missing != nil {
+		return nil, fmt.Errorf("expected one of the tags %s", strings.Join(missing, ", "))
+	}
+
+	positional := arguments[i:]
+	if len(positional) != len(schema.Positional) {
+		return nil, fmt.Errorf("expected %d positional arguments, got %d", len(schema.Positional), len(positional))
+	}
+	for j, arg := range positional {
+		if !matchesKind(arg, schema.Positional[j]) {
+			return nil, fmt.Errorf("argument %d: expected a %s", j+1, schema.Positional[j])
+		}
+	}
+	a.positional = positional
+
+	switch {
+	case schema.Tests == ArgNone && len(tests) > 0:
+		return nil, fmt.Errorf("unexpected tests")
+	case schema.Tests == ArgTest && len(tests) != 1:
+		return nil, fmt.Errorf("expected a single test")
+	}
+	return a, nil
+}
+
+// missingGroup returns the sorted tags of the first required group, by name, that isn't
+// given; nil if all are
+func missingGroup(schema *TestSchema, given map[string]*TagNode) []string {
+	groups := map[string][]string{}
+	for name, spec := range schema.Tags {
+		group := spec.Group
+		if group == "" {
+			group = name
+		}
+		if _, ok := given[group]; spec.Required && !ok {
+			groups[group] = append(groups[group], name)
+		}
+	}
+	if len(groups) == 0 {
+		return nil
+	}
+	var first string
+	for group := range groups {
+		if first == "" || group < first {
+			first = group
+		}
+	}
+	sort.Strings(groups[first])
+	return groups[first]
+}
+
+// matchesKind reports whether an argument is of a kind; a string is a string-list of one
+func matchesKind(arg Argument, kind ArgumentKind) bool {
+	switch arg.(type) {
+	case *StringNode:
+		return kind == ArgString || kind == ArgStringList
+	case *StringListNode:
+		return kind == ArgStringList
+	case *NumberNode:
+		return kind == ArgNumber
+	}
+	return false
+}
+
+// Tag returns the lower-case name of the tag given for a group (e.g. ":is" for "match-type"),
+// or the empty string if the group isn't given
+func (a *Arguments) Tag(group string) string {
+	if tag, ok := a.groups[group]; ok {
+		return strings.ToLower(tag.Name)
+	}
+	return ""
+}
+
+// Has reports whether a tag is given; tags are case-insensitive
+func (a *Arguments) Has(tag string) bool {
+	for _, t := range a.groups {
+		if isKeyword(t.Name, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// Value returns the value of a tag taking one (e.g. the name of :comparator), or nil if the tag isn't given
+func (a *Arguments) Value(tag string) Argument {
+	return a.values[strings.ToLower(tag)]
+}
+
+// Comparator returns the comparator of the test: the value of :comparator, or the default
+// i;ascii-casemap (RFC 5228, section 2.7.3)
+func (a *Arguments) Comparator() string {
+	if s, ok := a.Value(":comparator").(*StringNode); ok {
+		return s.Text
+	}
+	return ComparatorASCIICasemap
+}
+
+// String returns the positional argument i if it is a string
+func (a *Arguments) String(i int) (string, bool) {
+	if i < len(a.positional) {
+		if s, ok := a.positional[i].(*StringNode); ok {
+			return s.Text, true
+		}
+	}
+	return "", false
+}
+
+// StringList returns the positional argument i as a string-list; a string is returned as a list of one
+func (a *Arguments) StringList(i int) []string {
+	if i < len(a.positional) {
+		switch arg := a.positional[i].(type) {
+		case *StringNode:
+			return []string{arg.Text}
+		case *StringListNode:
+			return arg.Strings
+		}
+	}
+	return nil
+}
+
+// Number returns the positional argument i if it is a number
+func (a *Arguments) Number(i int) (*NumberNode, bool) {
+	if i < len(a.positional) {
+		n, ok := a.positional[i].(*NumberNode)
+		return n, ok
+	}
+	return nil, false
+}
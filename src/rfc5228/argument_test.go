@@ -0,0 +1,133 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBind(t *testing.T) {
+	tree := parse(t, "if address :comparator \"i;octet\" :DOMAIN :is [\"from\", \"sender\"] \"example.com\" {\r\n  keep;\r\n}\r\n")
+	args, err := tree.Commands()[0].(*IfNode).Test.Bind()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if args.Tag("address-part") != ":domain" || args.Tag("match-type") != ":is" || args.Tag("index") != "" || !args.Has(":Domain") {
+		t.Errorf("unexpected tags %+v", args.groups)
+	}
+	if args.Comparator() != "i;octet" {
+		t.Errorf("unexpected comparator %s", args.Comparator())
+	}
+	// the comparator name isn't a positional argument
+	if names, keys := args.StringList(0), args.StringList(1); !reflect.DeepEqual(names, []string{"from", "sender"}) || !reflect.DeepEqual(keys, []string{"example.com"}) {
+		t.Errorf("unexpected arguments %v %v", names, keys)
+	}
+	if _, ok := args.String(0); ok {
+		t.Errorf("expected a string-list")
+	}
+
+	tree = parse(t, "if size :over 10K {\r\n  discard;\r\n}\r\n")
+	args, err = tree.Commands()[0].(*IfNode).Test.Bind()
+	if number, ok := args.Number(0); err != nil || !ok || number.Value != 10*1024 || args.Tag("size") != ":over" {
+		t.Errorf("unexpected arguments %+v (%v)", args, err)
+	}
+}
+
+func TestBindErrors(t *testing.T) {
+	for test, expected := range map[string]string{
+		"header :is :contains \"a\" \"b\"":   "tag :contains conflicts with :is",
+		"header :comparator :is \"a\" \"b\"": "tag :comparator expects a string",
+		"header :all \"a\" \"b\"":            "unexpected tag :all",
+		"header \"a\"":                       "expected 2 positional arguments, got 1",
+		"size :over \"1\"":                   "argument 1: expected a number",
+		"size 100":                           "expected one of the tags :over, :under",
+		"environment [\"a\", \"b\"] \"c\"":   "argument 1: expected a string",
+		"unknown \"a\"":                      "unknown test unknown",
+	} {
+		tree := parse(t, "if "+test+" {\r\n  keep;\r\n}\r\n")
+		if _, err := tree.Commands()[0].(*IfNode).Test.Bind(); err == nil || err.Error() != expected {
+			t.Errorf("%s: expected %q, got %v", test, expected, err)
+		}
+	}
+}
+
+func TestRegisterTestSchema(t *testing.T) {
+	// spamtest [:percent] [COMPARATOR] [MATCH-TYPE] <value: string> (RFC 5235)
+	RegisterTestSchema("spamtest", &TestSchema{
+		Tags:       tags(map[string]TagSchema{":percent": {}}, comparatorTags, matchTypeTags),
+		Positional: []ArgumentKind{ArgString},
+	})
+	defer func() {
+		testSchemasMu.Lock()
+		delete(testSchemas, "spamtest")
+		testSchemasMu.Unlock()
+	}()
+
+	script := "if spamtest :percent :value \"ge\" :comparator \"i;ascii-numeric\" \"50\" {\r\n  discard;\r\n}\r\n"
+	if _, err := Parse("test", script, ModeStrict); err != nil {
+		t.Errorf("unexpected error %s", err)
+	}
+	_, err := Parse("test", strings.Replace(script, "\"50\"", "[\"50\", \"60\"]", 1), ModeStrict)
+	var syntax *SyntaxError
+	if !errors.As(err, &syntax) || syntax.Code != CodeInvalidArguments || syntax.Message != "`spamtest` at 1:4: argument 1: expected a string" {
+		t.Errorf("unexpected error %v", err)
+	}
+	// arguments are only validated in strict mode
+	if _, err := Parse("test", strings.Replace(script, "\"50\"", "[\"50\", \"60\"]", 1), 0); err != nil {
+		t.Errorf("unexpected error %s", err)
+	}
+}
+
+func TestRegisterCommandSchema(t *testing.T) {
+	// addflag [<variablename: string>] <list-of-flags: string-list> (RFC 5232)
+	RegisterCommandSchema("addflag", &TestSchema{Positional: []ArgumentKind{ArgStringList}})
+	defer func() {
+		commandSchemasMu.Lock()
+		delete(commandSchemas, "addflag")
+		commandSchemasMu.Unlock()
+	}()
+
+	tree := parse(t, "require \"imap4flags\";\r\naddflag \"\\\\Seen\";\r\naddflag :copy \"\\\\Seen\";\r\n")
+	args, err := tree.Commands()[1].(*GenericCommandNode).Bind()
+	if err != nil || !reflect.DeepEqual(args.StringList(0), []string{"\\Seen"}) {
+		t.Errorf("unexpected arguments %v (%v)", args, err)
+	}
+	if _, err := tree.Commands()[2].(*GenericCommandNode).Bind(); err == nil || err.Error() != "unexpected tag :copy" {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	// vacation has a schema of its own
+	tree = parse(t, "require \"vacation\";\r\nvacation :days 1 :seconds 60 \"away\";\r\n")
+	if _, err := tree.Commands()[1].(*GenericCommandNode).Bind(); err == nil || err.Error() != "tag :seconds conflicts with :days" {
+		t.Errorf("unexpected error %v", err)
+	}
+	if _, err := (&GenericCommandNode{Name: "unknown"}).Bind(); err == nil || err.Error() != "unknown command unknown" {
+		t.Errorf("unexpected error %v", err)
+	}
+}
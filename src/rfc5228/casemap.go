@@ -0,0 +1,99 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+// The i;ascii-casemap comparator (RFC 4790, section 9.2) folds only the ASCII letters; these
+// functions implement it without allocating, unlike strings.ToLower and strings.EqualFold,
+// which also fold non-ASCII letters.
+
+// asciiFold maps an octet to lower case if it is an ASCII letter
+var asciiFold = func() (table [256]byte) {
+	for i := range table {
+		table[i] = byte(i)
+		if 'A' <= i && i <= 'Z' {
+			table[i] += 'a' - 'A'
+		}
+	}
+	return table
+}()
+
+// equalFoldASCII reports whether a and b are equal with the i;ascii-casemap comparator
+func equalFoldASCII(a, b string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := 0; i < len(a); i++ {
+		if asciiFold[a[i]] != asciiFold[b[i]] {
+			return false
+		}
+	}
+	return true
+}
+
+// compareFoldASCII orders a and b with the i;ascii-casemap comparator
+func compareFoldASCII(a, b string) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if ca, cb := asciiFold[a[i]], asciiFold[b[i]]; ca != cb {
+			if ca < cb {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case len(a) < len(b):
+		return -1
+	case len(a) > len(b):
+		return 1
+	}
+	return 0
+}
+
+// containsFoldASCII reports whether substr is within s with the i;ascii-casemap comparator
+func containsFoldASCII(s, substr string) bool {
+	for i := 0; i+len(substr) <= len(s); i++ {
+		if equalFoldASCII(s[i:i+len(substr)], substr) {
+			return true
+		}
+	}
+	return false
+}
+
+// asciiLower folds the ASCII letters of s to lower case, as the i;ascii-casemap comparator does;
+// other characters, including non-ASCII letters, are unchanged. s is returned as is if it
+// holds no upper case ASCII letters.
+func asciiLower(s string) string {
+	for i := 0; i < len(s); i++ {
+		if asciiFold[s[i]] != s[i] {
+			b := []byte(s)
+			for j := i; j < len(b); j++ {
+				b[j] = asciiFold[b[j]]
+			}
+			return string(b)
+		}
+	}
+	return s
+}
@@ -0,0 +1,80 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCasemap(t *testing.T) {
+	for _, test := range []struct {
+		a, b             string
+		equal, contained bool
+		compare          int
+	}{
+		{"Subject", "subJECT", true, true, 0},
+		{"X-Spam-Flag", "spam", false, true, 1},
+		{"abc", "abd", false, false, -1},
+		{"ab", "abc", false, false, -1},
+		// only ASCII letters are folded
+		{"É", "é", false, false, -1},
+		{"", "", true, true, 0},
+	} {
+		if equalFoldASCII(test.a, test.b) != test.equal {
+			t.Errorf("%q %q: expected equal %t", test.a, test.b, test.equal)
+		}
+		if containsFoldASCII(test.a, test.b) != test.contained {
+			t.Errorf("%q %q: expected contained %t", test.a, test.b, test.contained)
+		}
+		if c := compareFoldASCII(test.a, test.b); c != test.compare {
+			t.Errorf("%q %q: expected %d, got %d", test.a, test.b, test.compare, c)
+		}
+	}
+	if s := asciiLower("MiXeD É"); s != "mixed É" {
+		t.Errorf("unexpected result %q", s)
+	}
+}
+
+func BenchmarkEqualFoldASCII(b *testing.B) {
+	upper := strings.ToUpper(benchmarkHeaders[0])
+	for i := 0; i < b.N; i++ {
+		for _, header := range benchmarkHeaders {
+			equalFoldASCII(header, upper)
+			containsFoldASCII(header, "EXAMPLE.COM")
+		}
+	}
+}
+
+func BenchmarkToLower(b *testing.B) {
+	upper := strings.ToUpper(benchmarkHeaders[0])
+	for i := 0; i < b.N; i++ {
+		for _, header := range benchmarkHeaders {
+			_ = strings.ToLower(header) == strings.ToLower(upper)
+			strings.Contains(strings.ToLower(header), strings.ToLower("EXAMPLE.COM"))
+		}
+	}
+}
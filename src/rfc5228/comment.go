@@ -0,0 +1,147 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import (
+	"sort"
+	"strings"
+)
+
+// CommentKind distinguishes hash comments from bracket comments
+type CommentKind int
+
+const (
+	CommentHash    CommentKind = iota // `#` up to the end of the line
+	CommentBracket                    // `/*` up to `*/`, may span lines
+)
+
+// CommentNode is a comment of the script. Comments are equivalent to whitespace, so they are
+// not part of the command tree but kept in Tree.Comments, each attached to the nearest command.
+type CommentNode struct {
+	NodeType
+	Pos
+	Kind     CommentKind
+	Raw      string  // the comment as written in the script, including its delimiters
+	Text     string  // the comment without its delimiters
+	Command  Command // the command the comment is attached to; nil for a script without commands
+	Trailing bool    // the comment follows Command on the line on which Command starts
+}
+
+func (t *Tree) newComment(pos Pos, raw string) *CommentNode {
+	node := &CommentNode{NodeType: NodeComment, Pos: pos, Raw: raw, Text: commentText(raw)}
+	if !strings.HasPrefix(raw, "#") {
+		node.Kind = CommentBracket
+	}
+	return node
+}
+
+func (n *CommentNode) Type() NodeType {
+	return n.NodeType
+}
+
+func (n *CommentNode) Position() Pos {
+	return n.Pos
+}
+
+// End returns the position just after the comment; the CRLF ending a hash comment is not part of it
+func (n *CommentNode) End() Pos {
+	return n.Pos + Pos(len(n.Raw))
+}
+
+// Field splits a comment of the form `name: value` (e.g. `# rule: vacation-summer`) into its
+// trimmed name and value; ok is false if the comment has no such form
+func (n *CommentNode) Field() (name, value string, ok bool) {
+	name, value, ok = strings.Cut(n.Text, ":")
+	name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+	if !ok || name == "" || strings.ContainsAny(name, " \t\r\n") {
+		return "", "", false
+	}
+	return name, value, true
+}
+
+// commentText removes the delimiters of a comment: the `#` of a hash comment or the `/*`
+// and `*/` of a bracket comment
+func commentText(raw string) string {
+	if strings.HasPrefix(raw, "#") {
+		return raw[1:]
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(raw, "/*"), "*/")
+}
+
+// CommentsOf returns the comments attached to a command in lexical order
+func (t *Tree) CommentsOf(command Command) []*CommentNode {
+	var comments []*CommentNode
+	for _, comment := range t.Comments {
+		if comment.Command == command {
+			comments = append(comments, comment)
+		}
+	}
+	return comments
+}
+
+// attachComments attaches every comment to the nearest command: the command starting on the
+// same line before it (a trailing comment), else the first command after it (a leading
+// comment, e.g. the doc comment of a rule) or, at the end of a block or the script, the
+// last command before it. Commands of blocks are candidates as well as top-level commands.
+func (t *Tree) attachComments() {
+	var commands []Command
+	var walk func(block []Command)
+	walk = func(block []Command) {
+		for _, node := range block {
+			commands = append(commands, node)
+			switch n := node.(type) {
+			case *IfNode:
+				for _, b := range n.Blocks() {
+					walk(b.Commands())
+				}
+			case *GenericCommandNode:
+				if n.Block != nil {
+					walk(n.Block.Commands())
+				}
+			}
+		}
+	}
+	walk(t.Commands())
+	sort.SliceStable(commands, func(i, j int) bool { return commands[i].Position() < commands[j].Position() })
+
+	for _, comment := range t.Comments {
+		comment.Command, comment.Trailing = nil, false
+		next := sort.Search(len(commands), func(i int) bool { return commands[i].Position() > comment.Pos })
+		if next > 0 {
+			previous := commands[next-1]
+			if t.Source.Position(previous.Position()).Line == t.Source.Position(comment.Pos).Line {
+				comment.Command, comment.Trailing = previous, true
+				continue
+			}
+		}
+		switch {
+		case next < len(commands):
+			comment.Command = commands[next]
+		case next > 0:
+			comment.Command = commands[next-1]
+		}
+	}
+}
@@ -0,0 +1,68 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import (
+	"testing"
+)
+
+func TestComments(t *testing.T) {
+	tree := parse(t, "# rule: spam\r\nif header :contains \"subject\" \"spam\" { /* junk */\r\n  discard; # drop it\r\n  # done\r\n}\r\n"+
+		"keep;\r\n# the end\r\n")
+	if len(tree.Comments) != 5 {
+		t.Fatalf("unexpected comments %v", tree.Comments)
+	}
+
+	node := tree.Commands()[0].(*IfNode)
+	discard, keep := node.Body.Commands()[0], tree.Commands()[1]
+	for i, expected := range []struct {
+		kind     CommentKind
+		text     string
+		command  Command
+		trailing bool
+	}{
+		{CommentHash, " rule: spam", node, false},
+		{CommentBracket, " junk ", node, true},
+		{CommentHash, " drop it", discard, true},
+		{CommentHash, " done", keep, false},
+		{CommentHash, " the end", keep, false},
+	} {
+		comment := tree.Comments[i]
+		if comment.Kind != expected.kind || comment.Text != expected.text || comment.Command != expected.command || comment.Trailing != expected.trailing {
+			t.Errorf("%d: unexpected comment %+v", i, comment)
+		}
+	}
+	if comments := tree.CommentsOf(keep); len(comments) != 2 {
+		t.Errorf("unexpected comments of keep %v", comments)
+	}
+
+	if name, value, ok := tree.Comments[0].Field(); !ok || name != "rule" || value != "spam" {
+		t.Errorf("unexpected field %q %q", name, value)
+	}
+	if _, _, ok := tree.Comments[2].Field(); ok {
+		t.Errorf("unexpected field in %q", tree.Comments[2].Text)
+	}
+}
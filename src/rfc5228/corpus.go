@@ -0,0 +1,124 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+)
+
+// CorpusOptions sizes a script of GenerateScript
+type CorpusOptions struct {
+	Rules      int   // number of rules (if commands)
+	ListLength int   // number of keys of the longest string-lists, e.g. of a blocklist
+	Seed       int64 // seed of the random choices; the same options give the same script
+}
+
+// GenerateScript synthesizes a large script shaped like those of real users, e.g. to benchmark
+// the lexer, parser and validator: rules filing mailing lists, spam and newsletters into
+// folders, blocklists of senders, forwards and flags, each with a `# rule:` comment. Names
+// and addresses are made up of words and the example domains of RFC 2606, so the corpus holds
+// no personal data. The script is valid and requires the capabilities it uses.
+func GenerateScript(opts CorpusOptions) string {
+	g := &corpusGenerator{r: rand.New(rand.NewSource(opts.Seed)), listLength: opts.ListLength}
+	if g.listLength < 1 {
+		g.listLength = 1
+	}
+	var b strings.Builder
+	b.WriteString("require [\"fileinto\", \"imap4flags\"];\r\n")
+	for i := 0; i < opts.Rules; i++ {
+		b.WriteString("\r\n")
+		b.WriteString(g.rule(i))
+	}
+	return b.String()
+}
+
+var corpusWords = []string{
+	"alpha", "board", "build", "cloud", "daily", "devel", "events", "finance", "golang", "hiring",
+	"infra", "jobs", "kernel", "lunch", "market", "news", "ops", "photos", "release", "sales",
+	"security", "support", "team", "travel", "weekly",
+}
+
+var corpusDomains = []string{"example.com", "example.org", "example.net"}
+
+type corpusGenerator struct {
+	r          *rand.Rand
+	listLength int
+	n          int // number of addresses so far, to make every address unique
+}
+
+func (g *corpusGenerator) word() string {
+	return corpusWords[g.r.Intn(len(corpusWords))]
+}
+
+// folder returns a capitalized word
+func (g *corpusGenerator) folder() string {
+	word := g.word()
+	return strings.ToUpper(word[:1]) + word[1:]
+}
+
+func (g *corpusGenerator) address() string {
+	g.n++
+	return fmt.Sprintf("%s.%s%d@%s", g.word(), g.word(), g.n, corpusDomains[g.r.Intn(len(corpusDomains))])
+}
+
+// list returns a string-list of n values of a generator
+func (g *corpusGenerator) list(n int, value func() string) string {
+	values := make([]string, n)
+	for i := range values {
+		values[i] = `"` + value() + `"`
+	}
+	return "[" + strings.Join(values, ", ") + "]"
+}
+
+func (g *corpusGenerator) rule(i int) string {
+	name := fmt.Sprintf("%s-%d", g.word(), i)
+	folder := g.folder() + "/" + g.folder()
+	var test, actions string
+	switch g.r.Intn(6) {
+	case 0: // mailing list
+		test = fmt.Sprintf("header :contains \"list-id\" \"<%s-%d.lists.%s>\"", g.word(), i, corpusDomains[g.r.Intn(len(corpusDomains))])
+		actions = fmt.Sprintf("  fileinto \"Lists/%s\";\r\n  stop;\r\n", folder)
+	case 1: // spam
+		test = fmt.Sprintf("anyof (header :is \"x-spam-flag\" \"YES\", header :matches \"subject\" [\"*[SPAM %d]*\", \"*%s*\"])", i, g.word())
+		actions = "  fileinto \"Junk\";\r\n  stop;\r\n"
+	case 2: // blocklist
+		test = "address :is \"from\" " + g.list(1+g.r.Intn(g.listLength), g.address)
+		actions = "  discard;\r\n  stop;\r\n"
+	case 3: // newsletters
+		test = fmt.Sprintf("allof (address :domain :is \"from\" %s, exists \"list-unsubscribe\", not header :contains \"subject\" \"%s %d\")",
+			g.list(1+g.r.Intn(4), func() string { return corpusDomains[g.r.Intn(len(corpusDomains))] }), g.word(), i)
+		actions = fmt.Sprintf("  addflag \"\\\\Seen\";\r\n  fileinto \"%s\";\r\n", folder)
+	case 4: // forward
+		test = fmt.Sprintf("header :contains [\"to\", \"cc\"] %s", g.list(1+g.r.Intn(g.listLength/4+1), g.address))
+		actions = fmt.Sprintf("  redirect \"%s\";\r\n", g.address())
+	default: // large messages
+		test = fmt.Sprintf("allof (size :over %dK, header :matches \"subject\" \"*%s %d*\")", 100+g.r.Intn(900), g.word(), i)
+		actions = fmt.Sprintf("  fileinto \"Large/%s\";\r\n", folder)
+	}
+	return fmt.Sprintf("# rule: %s\r\nif %s {\r\n%s}\r\n", name, test, actions)
+}
@@ -0,0 +1,124 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import "testing"
+
+// benchmarkScript is a script of a user with many rules
+var benchmarkScript = GenerateScript(CorpusOptions{Rules: 500, ListLength: 200, Seed: 1})
+
+func TestGenerateScript(t *testing.T) {
+	tree, err := Parse("corpus", benchmarkScript, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(tree.Commands()); n != 501 {
+		t.Errorf("expected 501 commands, got %d", n)
+	}
+	// Validate is quadratic in the number of rules, so a smaller script is validated
+	if tree, err = Parse("corpus", GenerateScript(CorpusOptions{Rules: 100, ListLength: 20, Seed: 1}), 0); err != nil {
+		t.Fatal(err)
+	}
+	if warnings := Validate(tree); len(warnings) != 0 {
+		t.Errorf("unexpected warnings %v", warnings)
+	}
+	if GenerateScript(CorpusOptions{Rules: 500, ListLength: 200, Seed: 1}) != benchmarkScript {
+		t.Errorf("the same options give another script")
+	}
+	if GenerateScript(CorpusOptions{Rules: 500, ListLength: 200, Seed: 2}) == benchmarkScript {
+		t.Errorf("another seed gives the same script")
+	}
+}
+
+func BenchmarkTokenize(b *testing.B) {
+	b.SetBytes(int64(len(benchmarkScript)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := Tokenize("corpus", benchmarkScript); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParse(b *testing.B) {
+	b.SetBytes(int64(len(benchmarkScript)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := Parse("corpus", benchmarkScript, 0); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParseArena(b *testing.B) {
+	b.SetBytes(int64(len(benchmarkScript)))
+	b.ReportAllocs()
+	arena := NewArena()
+	for i := 0; i < b.N; i++ {
+		if _, err := Parse("corpus", benchmarkScript, 0, WithArena(arena)); err != nil {
+			b.Fatal(err)
+		}
+		arena.Release()
+	}
+}
+
+func BenchmarkValidate(b *testing.B) {
+	tree, err := Parse("corpus", benchmarkScript, 0)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Validate(tree)
+	}
+}
+
+// BenchmarkCompileMatches measures the preparation of a script for evaluation
+func BenchmarkCompileMatches(b *testing.B) {
+	tree, err := Parse("corpus", benchmarkScript, 0)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := CompileMatches(tree); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkFormat(b *testing.B) {
+	tree, err := Parse("corpus", benchmarkScript, 0)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := Format(tree, DefaultFormatOptions()); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
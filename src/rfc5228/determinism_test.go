@@ -0,0 +1,64 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// TestDeterministicOutput runs the functions producing ordered output several times: the
+// iteration order of maps differs between runs, so output depending on it changes
+func TestDeterministicOutput(t *testing.T) {
+	script := GenerateScript(CorpusOptions{Rules: 40, ListLength: 10, Seed: 3}) +
+		"require [\"vacation\", \"envelope\", \"reject\", \"ereject\"];\r\n" +
+		"if header :is \"x-spam\" \"yes\" {\r\n  discard;\r\n}\r\n" +
+		"if envelope :all :comparator \"i;octet\" :is \"from\" \"a@example.com\" {\r\n  reject \"no\";\r\n}\r\n"
+	tree := parse(t, script)
+	facts := Facts{Headers: map[string]bool{"X-Spam": true, "x-spam": false, "X-SPAM": true}}
+
+	run := func() string {
+		var b strings.Builder
+		warnings, _ := json.Marshal(Validate(tree))
+		stats, _ := json.Marshal(Stats(tree))
+		analysis := Analyze(tree)
+		formatted, _ := Format(tree, DefaultFormatOptions())
+		fixed, _ := FixRequires(tree)
+		downgraded, downgrades, _ := Downgrade(tree, []string{"fileinto"})
+		specialized, specializations := Specialize(tree, facts)
+		fmt.Fprintln(&b, string(warnings), string(stats), analysis.CapabilitiesUsed(), analysis.Missing(), analysis.Unused())
+		fmt.Fprintln(&b, formatted, fixed, downgraded, downgrades, Fingerprint(specialized), specializations, Codes())
+		return b.String()
+	}
+	expected := run()
+	for i := 0; i < 20; i++ {
+		if output := run(); output != expected {
+			t.Fatalf("run %d gives another output", i)
+		}
+	}
+}
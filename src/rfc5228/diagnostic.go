@@ -0,0 +1,470 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Code is the stable identifier of a diagnostic; the message of a diagnostic may change
+// between releases and depends on the locale, the code does not. Codes are never reused:
+// SIEVE0001-0049 are syntax errors of the parser, SIEVE0050-0099 syntax errors of the lexer
+// and SIEVE0100 and up are warnings. Codes of custom validation passes (see RegisterValidation)
+// must not start with SIEVE and are warnings.
+type Code string
+
+// Syntax errors reported by the parser
+const (
+	CodeUnexpectedToken      Code = "SIEVE0001"
+	CodeOrphanElse           Code = "SIEVE0002" // elsif or else without a preceding if
+	CodeUnknownCommand       Code = "SIEVE0003"
+	CodeExpectedEnd          Code = "SIEVE0004" // missing `;`
+	CodeUnexpectedStart      Code = "SIEVE0005" // a command doesn't start with an identifier
+	CodeExpectedString       Code = "SIEVE0006"
+	CodeExpectedStringList   Code = "SIEVE0007" // missing `,` or `]` in a string-list
+	CodeMalformedString      Code = "SIEVE0008"
+	CodeRequireNotFirst      Code = "SIEVE0009" // require after another command (strict mode)
+	CodeInvalidAddress       Code = "SIEVE0010" // invalid redirect address (strict mode)
+	CodeNumberOutOfRange     Code = "SIEVE0011"
+	CodeExpectedTest         Code = "SIEVE0012"
+	CodeNoArguments          Code = "SIEVE0013" // arguments for true or false
+	CodeExpectedSingleTest   Code = "SIEVE0014" // not without exactly one test
+	CodeExpectedTestList     Code = "SIEVE0015" // allof or anyof without a test-list
+	CodeEmptyTestList        Code = "SIEVE0016"
+	CodeExpectedTestListOpen Code = "SIEVE0017"
+	CodeExpectedTestListEnd  Code = "SIEVE0018" // missing `,` or `)` in a test-list
+	CodeExpectedBlockOpen    Code = "SIEVE0019"
+	CodeExpectedBlockClose   Code = "SIEVE0020"
+	CodeReservedIdentifier   Code = "SIEVE0021" // a command name used as a test (strict mode)
+	CodeInvalidArguments     Code = "SIEVE0022" // arguments not matching the schema of a test (strict mode)
+	CodeTooDeep              Code = "SIEVE0023" // tests and blocks nested deeper than the limit (see WithMaxDepth)
+	CodeTooLarge             Code = "SIEVE0024" // script larger than the limit (see WithMaxSize)
+)
+
+// Syntax errors reported by the lexer
+const (
+	CodeUnexpectedRune        Code = "SIEVE0050"
+	CodeUnexpectedCR          Code = "SIEVE0051" // CR not followed by LF
+	CodeDanglingLF            Code = "SIEVE0052" // LF not preceded by CR
+	CodeUnexpectedSlash       Code = "SIEVE0053" // `/` not starting a bracket comment
+	CodeExpectedAlpha         Code = "SIEVE0054" // identifier not starting with a letter or `_`
+	CodeExpectedQuote         Code = "SIEVE0055"
+	CodeUnsupportedEscape     Code = "SIEVE0056" // backslash other than `\"` or `\\` in a quoted string
+	CodeDanglingCR            Code = "SIEVE0057" // CR not followed by LF in a quoted string
+	CodeUnexpectedCharacter   Code = "SIEVE0058" // invalid character in a quoted string
+	CodeExpectedTextMarker    Code = "SIEVE0059"
+	CodeExpectedCRLF          Code = "SIEVE0060" // missing line break after the text marker
+	CodeExpectedBracket       Code = "SIEVE0061"
+	CodeExpectedColon         Code = "SIEVE0062"
+	CodeExpectedDigit         Code = "SIEVE0063"
+	CodeExpectedParen         Code = "SIEVE0064"
+	CodeExpectedBrace         Code = "SIEVE0065"
+	CodeUnterminatedComment   Code = "SIEVE0066" // bracket comment without `*/`
+	CodeUnterminatedString    Code = "SIEVE0067" // quoted string without closing `"`
+	CodeUnterminatedMultiline Code = "SIEVE0068" // multi-line string without the `.` line
+)
+
+// Warnings
+const (
+	CodeRequirePlacement Code = "SIEVE0101" // require after another command
+	CodeRedirectAddress  Code = "SIEVE0102" // invalid redirect address
+	CodeUnreachable      Code = "SIEVE0103" // elsif or else after a condition that is always true
+	CodeAlwaysTrue       Code = "SIEVE0104"
+	CodeAlwaysFalse      Code = "SIEVE0105"
+	CodeShadowed         Code = "SIEVE0106" // elsif condition implies an earlier condition
+	CodeShadowedByStop   Code = "SIEVE0107" // condition implies that of an earlier rule that ends with stop
+	CodeContradiction    Code = "SIEVE0108" // allof with tests that can't both be true
+	CodeBlockNeverRuns   Code = "SIEVE0109" // block removed by Specialize
+
+	CodeCapabilityRequires    Code = "SIEVE0110" // capability required without a capability it depends on
+	CodeCapabilityRequiresAny Code = "SIEVE0111" // capability required without any of the capabilities it works with
+	CodeCapabilityConflict    Code = "SIEVE0112" // capabilities that can't be required together
+	CodeNoTranslation         Code = "SIEVE0113" // unsupported capability left by Downgrade
+	CodeUntranslatableRegex   Code = "SIEVE0114" // regular expression without a :matches equivalent
+	CodeDuplicateCapability   Code = "SIEVE0115" // capability required more than once
+	CodeMissingCapability     Code = "SIEVE0116" // capability used but not required; see Analysis.Explain
+)
+
+// DefaultLocale is the locale of diagnostics unless WithLocale is given
+const DefaultLocale = "en"
+
+// An Option configures parsing, tokenizing and validation
+type Option func(*options)
+
+type options struct {
+	locale     string
+	suppressed map[Code]bool
+	comments   bool // Tokenize includes comment tokens
+	maxDepth   int  // limit of nested tests and blocks of the parser
+	maxSize    int  // limit of the size of a script in bytes; no limit if 0
+	arena      *Arena
+	asErrors   map[Code]bool // warnings reported as errors (see WithWarningsAsErrors)
+	allErrors  bool          // all warnings are reported as errors
+}
+
+func newOptions(opts []Option) options {
+	o := options{locale: DefaultLocale, comments: true, maxDepth: DefaultMaxDepth}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithLocale renders the messages of diagnostics in the language of a BCP 47 language tag,
+// e.g. "nl" or "de-CH". A tag without a catalog falls back to its base language and then to
+// DefaultLocale, as does a message missing from a catalog.
+func WithLocale(tag string) Option {
+	return func(o *options) {
+		o.locale = tag
+	}
+}
+
+// WithSuppressed drops the warnings with the given codes, e.g. to enforce a policy that allows
+// constructs a warning is reported for; syntax errors can't be suppressed
+func WithSuppressed(codes ...Code) Option {
+	return func(o *options) {
+		if o.suppressed == nil {
+			o.suppressed = map[Code]bool{}
+		}
+		for _, code := range codes {
+			o.suppressed[code] = true
+		}
+	}
+}
+
+// warn appends a warning to warnings unless it is suppressed
+func (o options) warn(warnings []Warning, source *SourceFile, pos Pos, related []Pos, code Code, args ...any) []Warning {
+	if o.suppressed[code] {
+		return warnings
+	}
+	position := source.Position(pos)
+	return append(warnings, Warning{
+		Pos:      pos,
+		Line:     position.Line,
+		Column:   position.Column,
+		Code:     code,
+		Severity: o.severity(code),
+		Message:  Localize(o.locale, code, args...),
+		Related:  related,
+		Args:     args,
+	})
+}
+
+// Codes returns the codes of all diagnostics in ascending order
+func Codes() []Code {
+	codes := make([]Code, 0, len(catalogEN))
+	for code := range catalogEN {
+		codes = append(codes, code)
+	}
+	sort.Slice(codes, func(i, j int) bool { return codes[i] < codes[j] })
+	return codes
+}
+
+// IsWarning reports whether a code identifies a warning rather than a syntax error
+func IsWarning(code Code) bool {
+	return !strings.HasPrefix(string(code), "SIEVE") || code >= "SIEVE0100"
+}
+
+var (
+	catalogsMu sync.RWMutex
+	catalogs   = map[string]map[Code]string{
+		"en": catalogEN,
+		"nl": catalogNL,
+		"de": catalogDE,
+	}
+)
+
+// RegisterCatalog adds or replaces the messages for a language tag. Messages are fmt
+// format strings receiving the arguments of the diagnostic in the order of the English
+// message; explicit argument indexes (e.g. `%[2]s`) can be used to reorder them.
+func RegisterCatalog(tag string, messages map[Code]string) {
+	catalogsMu.Lock()
+	defer catalogsMu.Unlock()
+	catalog := map[Code]string{}
+	for code, message := range catalogs[normalizeTag(tag)] {
+		catalog[code] = message
+	}
+	for code, message := range messages {
+		catalog[code] = message
+	}
+	catalogs[normalizeTag(tag)] = catalog
+}
+
+func normalizeTag(tag string) string {
+	return strings.ToLower(strings.ReplaceAll(tag, "_", "-"))
+}
+
+// Localize renders the message of a diagnostic for a language tag
+func Localize(tag string, code Code, args ...any) string {
+	catalogsMu.RLock()
+	defer catalogsMu.RUnlock()
+
+	tag = normalizeTag(tag)
+	for _, candidate := range []string{tag, strings.SplitN(tag, "-", 2)[0], DefaultLocale} {
+		if format, ok := catalogs[candidate][code]; ok {
+			return fmt.Sprintf(format, args...)
+		}
+	}
+	return fmt.Sprintf("%s %v", code, args)
+}
+
+// SyntaxError is the error returned for a script that can't be parsed
+type SyntaxError struct {
+	Pos     Pos    // The position, in bytes, of the offending token in the input string.
+	Line    int    // The line of the offending token, starting at 1.
+	Column  int    // The column, in bytes, of the offending token, starting at 1.
+	Code    Code   // The stable identifier of the error.
+	Message string // The description of the error in the requested locale.
+	Args    []any  // The arguments of the message, e.g. to render it in another locale.
+}
+
+func newSyntaxError(locale string, source *SourceFile, pos Pos, code Code, args ...any) *SyntaxError {
+	position := source.Position(pos)
+	return &SyntaxError{Pos: pos, Line: position.Line, Column: position.Column, Code: code, Message: Localize(locale, code, args...), Args: args}
+}
+
+func (e *SyntaxError) Error() string {
+	return e.Message
+}
+
+// Unwrap returns the error that caused the syntax error, if any
+func (e *SyntaxError) Unwrap() error {
+	for _, arg := range e.Args {
+		if err, ok := arg.(error); ok {
+			return err
+		}
+	}
+	return nil
+}
+
+// Is reports whether target is ErrTooDeep for a script nested too deep, or ErrTooLarge for
+// a script larger than the limit
+func (e *SyntaxError) Is(target error) bool {
+	return target == ErrTooDeep && e.Code == CodeTooDeep || target == ErrTooLarge && e.Code == CodeTooLarge
+}
+
+// Localize renders the message of the error for a language tag
+func (e *SyntaxError) Localize(tag string) string {
+	return Localize(tag, e.Code, e.Args...)
+}
+
+var catalogEN = map[Code]string{
+	CodeUnexpectedToken:      "unexpected token %s",
+	CodeOrphanElse:           "`%s` without preceding `if` block at %s",
+	CodeUnknownCommand:       "unknown identifier %s",
+	CodeExpectedEnd:          "expected end `;`",
+	CodeUnexpectedStart:      "unexpected start token %s",
+	CodeExpectedString:       "expected string, got %s",
+	CodeExpectedStringList:   "expected `,` or end of string list `]`, got %s",
+	CodeMalformedString:      "malformed quoted string %s",
+	CodeRequireNotFirst:      "`%s` at %s must come before any other command",
+	CodeInvalidAddress:       "`%s` at %s: %s",
+	CodeNumberOutOfRange:     "number out of range %s",
+	CodeExpectedTest:         "expected test, got %s",
+	CodeNoArguments:          "`%s` at %s does not take arguments",
+	CodeExpectedSingleTest:   "`%s` at %s expects a single test",
+	CodeExpectedTestList:     "`%s` at %s expects a test-list",
+	CodeEmptyTestList:        "empty test-list for `%s` at %s",
+	CodeExpectedTestListOpen: "expected test-list open `(`",
+	CodeExpectedTestListEnd:  "expected `,` or end of test-list `)`, got %s",
+	CodeExpectedBlockOpen:    "expected block open `{`, got %s",
+	CodeExpectedBlockClose:   "expected block close `}`, got EOF",
+	CodeReservedIdentifier:   "`%s` at %s is a reserved command name and can't be used as a test",
+	CodeInvalidArguments:     "`%s` at %s: %s",
+	CodeTooDeep:              "tests and blocks nested deeper than %d levels",
+	CodeTooLarge:             "script of %d bytes exceeds the limit of %d bytes",
+
+	CodeUnexpectedRune:        "syntax error: unexpected rune",
+	CodeUnexpectedCR:          "syntax error: unexpected carriage return",
+	CodeDanglingLF:            "syntax error: dangling line feed",
+	CodeUnexpectedSlash:       "syntax error: unexpected bracket comment",
+	CodeExpectedAlpha:         "syntax error: expected alpha rune as first character",
+	CodeExpectedQuote:         "syntax error: quoted-string opening quote expected",
+	CodeUnsupportedEscape:     "syntax error: quoted-other not supported",
+	CodeDanglingCR:            "syntax error: dangling carriage return",
+	CodeUnexpectedCharacter:   "syntax error: unexpected character",
+	CodeExpectedTextMarker:    "syntax error: missing input marker",
+	CodeExpectedCRLF:          "syntax error: CRLF expected",
+	CodeExpectedBracket:       "syntax error: string list open/close expected",
+	CodeExpectedColon:         "syntax error: colon expected",
+	CodeExpectedDigit:         "syntax error: digit expected",
+	CodeExpectedParen:         "syntax error: test-list open/close expected",
+	CodeExpectedBrace:         "syntax error: block open/close expected",
+	CodeUnterminatedComment:   "syntax error: unterminated bracket comment",
+	CodeUnterminatedString:    "syntax error: unterminated quoted string",
+	CodeUnterminatedMultiline: "syntax error: unterminated multi-line string, expected a line holding only `.`",
+
+	CodeRequirePlacement: "`%s` must come before any other command",
+	CodeRedirectAddress:  "`%s`: %s",
+	CodeUnreachable:      "`%s` is unreachable",
+	CodeAlwaysTrue:       "`%s` condition is always true",
+	CodeAlwaysFalse:      "`%s` condition is always false",
+	CodeShadowed:         "`%s` condition is shadowed by the earlier condition at %s",
+	CodeShadowedByStop:   "`%s` condition is shadowed by the rule at %s that ends with `stop`",
+	CodeContradiction:    "`%s` can never be true: `%s` at %s contradicts `%s` at %s",
+	CodeBlockNeverRuns:   "`%s` block can never run",
+
+	CodeCapabilityRequires:    "capability `%s` requires capability `%s`",
+	CodeCapabilityRequiresAny: "capability `%s` requires one of the capabilities %s",
+	CodeCapabilityConflict:    "capability `%s` conflicts with capability `%s` at %s",
+	CodeNoTranslation:         "capability `%s` isn't supported and has no translation",
+	CodeUntranslatableRegex:   "regular expression %q has no `:matches` equivalent",
+	CodeDuplicateCapability:   "capability `%s` is already required at %s",
+	CodeMissingCapability:     "`%s` needs capability `%s`, which isn't required",
+}
+
+var catalogNL = map[Code]string{
+	CodeUnexpectedToken:      "onverwacht token %s",
+	CodeOrphanElse:           "`%s` zonder voorafgaand `if`-blok op %s",
+	CodeUnknownCommand:       "onbekende identifier %s",
+	CodeExpectedEnd:          "`;` verwacht",
+	CodeUnexpectedStart:      "onverwacht begintoken %s",
+	CodeExpectedString:       "string verwacht, %s gevonden",
+	CodeExpectedStringList:   "`,` of einde van de stringlijst `]` verwacht, %s gevonden",
+	CodeMalformedString:      "ongeldige string %s",
+	CodeRequireNotFirst:      "`%s` op %s moet voor alle andere commando's staan",
+	CodeInvalidAddress:       "`%s` op %s: %s",
+	CodeNumberOutOfRange:     "getal buiten bereik %s",
+	CodeExpectedTest:         "test verwacht, %s gevonden",
+	CodeNoArguments:          "`%s` op %s heeft geen argumenten",
+	CodeExpectedSingleTest:   "`%s` op %s verwacht precies één test",
+	CodeExpectedTestList:     "`%s` op %s verwacht een testlijst",
+	CodeEmptyTestList:        "lege testlijst voor `%s` op %s",
+	CodeExpectedTestListOpen: "begin van de testlijst `(` verwacht",
+	CodeExpectedTestListEnd:  "`,` of einde van de testlijst `)` verwacht, %s gevonden",
+	CodeExpectedBlockOpen:    "begin van het blok `{` verwacht, %s gevonden",
+	CodeExpectedBlockClose:   "einde van het blok `}` verwacht, einde van het script gevonden",
+	CodeReservedIdentifier:   "`%s` op %s is een gereserveerde commandonaam en kan niet als test worden gebruikt",
+	CodeInvalidArguments:     "`%s` op %s: %s",
+	CodeTooDeep:              "tests en blokken dieper genest dan %d niveaus",
+	CodeTooLarge:             "script van %d bytes overschrijdt de limiet van %d bytes",
+
+	CodeUnexpectedRune:        "syntaxfout: onverwacht teken",
+	CodeUnexpectedCR:          "syntaxfout: onverwachte carriage return",
+	CodeDanglingLF:            "syntaxfout: line feed zonder voorafgaande carriage return",
+	CodeUnexpectedSlash:       "syntaxfout: onverwachte `/`",
+	CodeExpectedAlpha:         "syntaxfout: identifier moet met een letter beginnen",
+	CodeExpectedQuote:         "syntaxfout: openingsaanhalingsteken verwacht",
+	CodeUnsupportedEscape:     "syntaxfout: escape-reeks niet ondersteund",
+	CodeDanglingCR:            "syntaxfout: carriage return zonder line feed",
+	CodeUnexpectedCharacter:   "syntaxfout: onverwacht teken in string",
+	CodeExpectedTextMarker:    "syntaxfout: `text:` verwacht",
+	CodeExpectedCRLF:          "syntaxfout: CRLF verwacht",
+	CodeExpectedBracket:       "syntaxfout: `[` of `]` verwacht",
+	CodeExpectedColon:         "syntaxfout: `:` verwacht",
+	CodeExpectedDigit:         "syntaxfout: cijfer verwacht",
+	CodeExpectedParen:         "syntaxfout: `(` of `)` verwacht",
+	CodeExpectedBrace:         "syntaxfout: `{` of `}` verwacht",
+	CodeUnterminatedComment:   "syntaxfout: commentaar zonder `*/`",
+	CodeUnterminatedString:    "syntaxfout: string zonder afsluitend `\"`",
+	CodeUnterminatedMultiline: "syntaxfout: string over meerdere regels zonder regel met alleen `.`",
+
+	CodeRequirePlacement: "`%s` moet voor alle andere commando's staan",
+	CodeRedirectAddress:  "`%s`: %s",
+	CodeUnreachable:      "`%s` is onbereikbaar",
+	CodeAlwaysTrue:       "`%s`-voorwaarde is altijd waar",
+	CodeAlwaysFalse:      "`%s`-voorwaarde is altijd onwaar",
+	CodeShadowed:         "`%s`-voorwaarde wordt overschaduwd door de eerdere voorwaarde op %s",
+	CodeShadowedByStop:   "`%s`-voorwaarde wordt overschaduwd door de regel op %s die eindigt met `stop`",
+	CodeContradiction:    "`%s` kan nooit waar zijn: `%s` op %s spreekt `%s` op %s tegen",
+	CodeBlockNeverRuns:   "`%s`-blok wordt nooit uitgevoerd",
+
+	CodeCapabilityRequires:    "capability `%s` vereist capability `%s`",
+	CodeCapabilityRequiresAny: "capability `%s` vereist een van de capabilities %s",
+	CodeCapabilityConflict:    "capability `%s` is niet te combineren met capability `%s` op %s",
+	CodeNoTranslation:         "capability `%s` wordt niet ondersteund en heeft geen vertaling",
+	CodeUntranslatableRegex:   "reguliere expressie %q heeft geen `:matches`-equivalent",
+	CodeDuplicateCapability:   "capability `%s` is al vereist op %s",
+	CodeMissingCapability:     "`%s` heeft capability `%s` nodig, die niet vereist is",
+}
+
+var catalogDE = map[Code]string{
+	CodeUnexpectedToken:      "unerwartetes Token %s",
+	CodeOrphanElse:           "`%s` ohne vorangehenden `if`-Block bei %s",
+	CodeUnknownCommand:       "unbekannter Bezeichner %s",
+	CodeExpectedEnd:          "`;` erwartet",
+	CodeUnexpectedStart:      "unerwartetes Anfangstoken %s",
+	CodeExpectedString:       "Zeichenkette erwartet, %s gefunden",
+	CodeExpectedStringList:   "`,` oder Ende der Zeichenkettenliste `]` erwartet, %s gefunden",
+	CodeMalformedString:      "ungültige Zeichenkette %s",
+	CodeRequireNotFirst:      "`%s` bei %s muss vor allen anderen Befehlen stehen",
+	CodeInvalidAddress:       "`%s` bei %s: %s",
+	CodeNumberOutOfRange:     "Zahl außerhalb des Wertebereichs %s",
+	CodeExpectedTest:         "Test erwartet, %s gefunden",
+	CodeNoArguments:          "`%s` bei %s erwartet keine Argumente",
+	CodeExpectedSingleTest:   "`%s` bei %s erwartet genau einen Test",
+	CodeExpectedTestList:     "`%s` bei %s erwartet eine Testliste",
+	CodeEmptyTestList:        "leere Testliste für `%s` bei %s",
+	CodeExpectedTestListOpen: "Anfang der Testliste `(` erwartet",
+	CodeExpectedTestListEnd:  "`,` oder Ende der Testliste `)` erwartet, %s gefunden",
+	CodeExpectedBlockOpen:    "Blockanfang `{` erwartet, %s gefunden",
+	CodeExpectedBlockClose:   "Blockende `}` erwartet, Ende des Skripts gefunden",
+	CodeReservedIdentifier:   "`%s` bei %s ist ein reservierter Befehlsname und kann nicht als Test verwendet werden",
+	CodeInvalidArguments:     "`%s` bei %s: %s",
+	CodeTooDeep:              "Tests und Blöcke tiefer als %d Ebenen verschachtelt",
+	CodeTooLarge:             "Skript mit %d Bytes überschreitet die Grenze von %d Bytes",
+
+	CodeUnexpectedRune:        "Syntaxfehler: unerwartetes Zeichen",
+	CodeUnexpectedCR:          "Syntaxfehler: unerwarteter Wagenrücklauf",
+	CodeDanglingLF:            "Syntaxfehler: Zeilenvorschub ohne vorangehenden Wagenrücklauf",
+	CodeUnexpectedSlash:       "Syntaxfehler: unerwartetes `/`",
+	CodeExpectedAlpha:         "Syntaxfehler: Bezeichner muss mit einem Buchstaben beginnen",
+	CodeExpectedQuote:         "Syntaxfehler: öffnendes Anführungszeichen erwartet",
+	CodeUnsupportedEscape:     "Syntaxfehler: Escape-Sequenz nicht unterstützt",
+	CodeDanglingCR:            "Syntaxfehler: Wagenrücklauf ohne Zeilenvorschub",
+	CodeUnexpectedCharacter:   "Syntaxfehler: unerwartetes Zeichen in Zeichenkette",
+	CodeExpectedTextMarker:    "Syntaxfehler: `text:` erwartet",
+	CodeExpectedCRLF:          "Syntaxfehler: CRLF erwartet",
+	CodeExpectedBracket:       "Syntaxfehler: `[` oder `]` erwartet",
+	CodeExpectedColon:         "Syntaxfehler: `:` erwartet",
+	CodeExpectedDigit:         "Syntaxfehler: Ziffer erwartet",
+	CodeExpectedParen:         "Syntaxfehler: `(` oder `)` erwartet",
+	CodeExpectedBrace:         "Syntaxfehler: `{` oder `}` erwartet",
+	CodeUnterminatedComment:   "Syntaxfehler: Kommentar ohne `*/`",
+	CodeUnterminatedString:    "Syntaxfehler: Zeichenkette ohne schließendes `\"`",
+	CodeUnterminatedMultiline: "Syntaxfehler: mehrzeilige Zeichenkette ohne Zeile mit nur `.`",
+
+	CodeRequirePlacement: "`%s` muss vor allen anderen Befehlen stehen",
+	CodeRedirectAddress:  "`%s`: %s",
+	CodeUnreachable:      "`%s` ist unerreichbar",
+	CodeAlwaysTrue:       "`%s`-Bedingung ist immer wahr",
+	CodeAlwaysFalse:      "`%s`-Bedingung ist immer falsch",
+	CodeShadowed:         "`%s`-Bedingung wird von der früheren Bedingung bei %s verdeckt",
+	CodeShadowedByStop:   "`%s`-Bedingung wird von der Regel bei %s verdeckt, die mit `stop` endet",
+	CodeContradiction:    "`%s` kann nie wahr sein: `%s` bei %s widerspricht `%s` bei %s",
+	CodeBlockNeverRuns:   "`%s`-Block wird nie ausgeführt",
+
+	CodeCapabilityRequires:    "Capability `%s` erfordert Capability `%s`",
+	CodeCapabilityRequiresAny: "Capability `%s` erfordert eine der Capabilities %s",
+	CodeCapabilityConflict:    "Capability `%s` steht im Konflikt mit Capability `%s` bei %s",
+	CodeNoTranslation:         "Capability `%s` wird nicht unterstützt und hat keine Übersetzung",
+	CodeUntranslatableRegex:   "regulärer Ausdruck %q hat keine `:matches`-Entsprechung",
+	CodeDuplicateCapability:   "Capability `%s` ist bereits bei %s angefordert",
+	CodeMissingCapability:     "`%s` benötigt Capability `%s`, die nicht angefordert ist",
+}
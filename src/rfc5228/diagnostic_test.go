@@ -0,0 +1,150 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSyntaxErrorLocale(t *testing.T) {
+	_, err := Parse("test", "keep\r\n", 0, WithLocale("nl-NL"))
+	var syntax *SyntaxError
+	if !errors.As(err, &syntax) {
+		t.Fatalf("expected a syntax error, got %v", err)
+	}
+	if syntax.Code != CodeExpectedEnd || syntax.Pos != 4 || syntax.Error() != "`;` verwacht" {
+		t.Errorf("unexpected error %#v", syntax)
+	}
+	if message := syntax.Localize("en"); message != "expected end `;`" {
+		t.Errorf("unexpected message %s", message)
+	}
+
+	_, err = Parse("test", "redirect \"joe\";\r\n", ModeStrict, WithLocale("de"))
+	if !errors.As(err, &syntax) || syntax.Code != CodeInvalidAddress || errors.Unwrap(err) == nil {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestSyntaxErrorTokenLocale(t *testing.T) {
+	for _, test := range []struct {
+		script, locale, expected string
+	}{
+		{"if exists [] {\r\n  keep;\r\n}\r\n", "en", "expected string, got `]`"},
+		{"if exists [] {\r\n  keep;\r\n}\r\n", "nl", "string verwacht, `]` gevonden"},
+		{"if exists [] {\r\n  keep;\r\n}\r\n", "de", "Zeichenkette erwartet, `]` gefunden"},
+		{"if true;\r\n", "de", "Blockanfang `{` erwartet, `;` gefunden"},
+		{"if anyof(true", "nl", "`,` of einde van de testlijst `)` verwacht, EOF gevonden"},
+	} {
+		_, err := Parse("test", test.script, ModeStrict, WithLocale(test.locale))
+		var syntax *SyntaxError
+		if !errors.As(err, &syntax) || syntax.Message != test.expected {
+			t.Errorf("%q (%s): expected %q, got %v", test.script, test.locale, test.expected, err)
+		}
+	}
+}
+
+func TestWarningLocale(t *testing.T) {
+	tree := parse(t, "if false {\r\n  discard;\r\n}\r\n")
+	for tag, expected := range map[string]string{
+		"":      "`if` condition is always false",
+		"en-US": "`if` condition is always false",
+		"nl":    "`if`-voorwaarde is altijd onwaar",
+		"de_AT": "`if`-Bedingung ist immer falsch",
+		"fr":    "`if` condition is always false",
+	} {
+		warnings := Validate(tree, WithLocale(tag))
+		if len(warnings) != 1 || warnings[0].Code != CodeAlwaysFalse || warnings[0].Message != expected {
+			t.Errorf("%s: unexpected warnings %v", tag, warnings)
+		}
+		if message := warnings[0].Localize("nl"); message != "`if`-voorwaarde is altijd onwaar" {
+			t.Errorf("unexpected message %s", message)
+		}
+	}
+}
+
+func TestRegisterCatalog(t *testing.T) {
+	RegisterCatalog("x-test", map[Code]string{
+		CodeContradiction: "%[3]s: `%[2]s` ⟂ `%[4]s` (%[1]s)",
+	})
+	tree := parse(t, "if allof (exists \"a\", not exists \"a\") {\r\n  discard;\r\n}\r\n"+
+		"if false {\r\n  discard;\r\n}\r\n")
+	warnings := Validate(tree, WithLocale("x-test"))
+	if len(warnings) != 2 || warnings[0].Message != "1:11: `exists` ⟂ `not` (allof)" {
+		t.Errorf("unexpected warnings %v", warnings)
+	}
+	// messages missing from the catalog fall back to the default locale
+	if warnings[1].Message != "`if` condition is always false" {
+		t.Errorf("unexpected warning %s", warnings[1])
+	}
+}
+
+func TestCatalogsComplete(t *testing.T) {
+	for tag, catalog := range map[string]map[Code]string{"nl": catalogNL, "de": catalogDE} {
+		for code := range catalogEN {
+			if _, ok := catalog[code]; !ok {
+				t.Errorf("%s: missing message for %s", tag, code)
+			}
+		}
+	}
+}
+
+func TestLexerErrorCodes(t *testing.T) {
+	for input, expected := range map[string]Code{
+		"keep;\n":            CodeDanglingLF,
+		"keep;\r":            CodeUnexpectedCR,
+		"redirect \"a\\b\";": CodeUnsupportedEscape,
+		"keep; @":            CodeUnexpectedRune,
+	} {
+		_, err := Parse("test", input, 0)
+		var syntax *SyntaxError
+		if !errors.As(err, &syntax) || syntax.Code != expected {
+			t.Errorf("%q: expected %s, got %v", input, expected, err)
+		}
+	}
+}
+
+func TestWithSuppressed(t *testing.T) {
+	tree := parse(t, "if true {\r\n  keep;\r\n} elsif exists \"x\" {\r\n  discard;\r\n}\r\n")
+	warnings := Validate(tree, WithSuppressed(CodeAlwaysTrue))
+	if len(warnings) != 1 || warnings[0].Code != CodeUnreachable {
+		t.Errorf("unexpected warnings %v", warnings)
+	}
+}
+
+func TestCodes(t *testing.T) {
+	codes := Codes()
+	seen := map[Code]bool{}
+	for i, code := range codes {
+		if seen[code] || i > 0 && code <= codes[i-1] {
+			t.Errorf("codes not unique and ordered at %s", code)
+		}
+		seen[code] = true
+	}
+	if !IsWarning(CodeContradiction) || IsWarning(CodeExpectedEnd) || IsWarning(CodeExpectedBrace) {
+		t.Error("unexpected severity")
+	}
+}
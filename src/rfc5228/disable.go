@@ -0,0 +1,132 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import (
+	"fmt"
+	"strings"
+)
+
+// disabledMarker starts every line of a rule that is commented out by Disable
+const disabledMarker = "#~"
+
+// DisabledRule is a rule commented out by Disable: a run of hash comments starting with `#~`
+// at the start of consecutive lines
+type DisabledRule struct {
+	Start, End Pos    // the bytes of the comments, up to the CRLF of the last line
+	Text       string // the source of the rule with the markers removed
+}
+
+// Disable returns the edit of the source of the tree that comments out a top-level command,
+// e.g. to toggle a rule in a filter editor. Every line of the command is prefixed with `#~ `,
+// so its formatting is preserved and Enable restores it as it was. The command must be on lines
+// of its own; trailing hash comments on its last line are commented out too.
+func (t *Tree) Disable(command Command) (Edit, error) {
+	if t.Source == nil {
+		return Edit{}, fmt.Errorf("tree %s has no source", t.Name)
+	}
+	commands := t.Commands()
+	index := -1
+	for i, node := range commands {
+		if node == command {
+			index = i
+		}
+	}
+	if index < 0 {
+		return Edit{}, fmt.Errorf("command is not a top-level command of %s", t.Name)
+	}
+
+	content := t.Source.Content
+	start, next := command.Position(), Pos(len(content))
+	if index+1 < len(commands) {
+		next = commands[index+1].Position()
+	}
+	tokens, err := Tokenize(t.Name, content, WithComments(false))
+	if err != nil {
+		return Edit{}, err
+	}
+	end := start
+	for _, token := range tokens {
+		if token.Pos >= start && token.Pos < next && token.End() > end {
+			end = token.End()
+		}
+	}
+
+	lineStart := start - Pos(t.Source.Position(start).Column-1)
+	lineEnd := Pos(len(content))
+	if i := strings.Index(content[end:], "\r\n"); i >= 0 {
+		lineEnd = end + Pos(i)
+	}
+	if rest := strings.TrimLeft(content[end:lineEnd], " \t"); strings.Trim(content[lineStart:start], " \t") != "" ||
+		rest != "" && !strings.HasPrefix(rest, "#") {
+		return Edit{}, fmt.Errorf("command at %s shares a line with other commands", t.Source.Position(start))
+	}
+
+	lines := strings.Split(content[lineStart:lineEnd], "\r\n")
+	for i, line := range lines {
+		lines[i] = disabledMarker + " " + line
+	}
+	return Edit{Start: lineStart, End: lineEnd, Text: strings.Join(lines, "\r\n")}, nil
+}
+
+// DisabledRules returns the rules of the script commented out by Disable in lexical order;
+// adjacent disabled rules are returned as one
+func (t *Tree) DisabledRules() []DisabledRule {
+	var rules []DisabledRule
+	var lines []string
+	line := 0
+	flush := func() {
+		if len(lines) > 0 {
+			rules[len(rules)-1].Text = strings.Join(lines, "\r\n")
+		}
+		lines = nil
+	}
+	for _, comment := range t.Comments {
+		position := t.Source.Position(comment.Pos)
+		if comment.Kind != CommentHash || position.Column != 1 || !strings.HasPrefix(comment.Raw, disabledMarker) {
+			continue
+		}
+		if len(lines) == 0 || position.Line != line+1 {
+			flush()
+			rules = append(rules, DisabledRule{Start: comment.Pos})
+		}
+		rules[len(rules)-1].End = comment.End()
+		text := strings.TrimPrefix(comment.Raw, disabledMarker)
+		lines = append(lines, strings.TrimPrefix(text, " "))
+		line = position.Line
+	}
+	flush()
+	return rules
+}
+
+// Enable returns the edit of the source that restores a disabled rule; the rule must still
+// be a valid script on its own
+func (t *Tree) Enable(rule DisabledRule) (Edit, error) {
+	if _, err := Parse(t.Name, rule.Text+"\r\n", 0); err != nil {
+		return Edit{}, fmt.Errorf("disabled rule at %s: %w", t.Source.Position(rule.Start), err)
+	}
+	return Edit{Start: rule.Start, End: rule.End, Text: rule.Text}, nil
+}
@@ -0,0 +1,67 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import (
+	"testing"
+)
+
+func TestDisableEnable(t *testing.T) {
+	original := "#@ id=spam\r\nif header :contains \"subject\" \"spam\" {\r\n\r\n  discard; /* junk */\r\n} # spam\r\nkeep;\r\n"
+	tree := parse(t, original)
+
+	edit, err := tree.Disable(tree.Commands()[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	input, tree := reparse(t, tree, original, edit)
+	expected := "#@ id=spam\r\n#~ if header :contains \"subject\" \"spam\" {\r\n#~ \r\n#~   discard; /* junk */\r\n#~ } # spam\r\nkeep;\r\n"
+	if input != expected {
+		t.Fatalf("unexpected script %q", input)
+	}
+	if len(tree.Commands()) != 1 {
+		t.Errorf("expected the rule to be disabled, got %v", tree.Commands())
+	}
+
+	rules := tree.DisabledRules()
+	if len(rules) != 1 {
+		t.Fatalf("unexpected disabled rules %v", rules)
+	}
+	if edit, err = tree.Enable(rules[0]); err != nil {
+		t.Fatal(err)
+	}
+	if input, _ = reparse(t, tree, input, edit); input != original {
+		t.Errorf("unexpected script %q", input)
+	}
+
+	tree = parse(t, "keep; discard;\r\n")
+	if _, err := tree.Disable(tree.Commands()[1]); err == nil {
+		t.Errorf("expected an error for a command sharing a line")
+	}
+	if _, err := tree.Disable(&KeepNode{}); err == nil {
+		t.Errorf("expected an error for a command of another tree")
+	}
+}
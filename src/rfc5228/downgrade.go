@@ -0,0 +1,280 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Downgrade returns the script of a tree rewritten for a server that supports only the given
+// capabilities (e.g. when migrating users to a server with fewer extensions). Constructs of
+// unsupported capabilities are replaced by equivalents where possible:
+//
+//   - a :regex match whose patterns are plain text with `.`, `.*`, `.+`, `^` and `$` becomes a
+//     :matches match with the equivalent wildcards
+//
+// Unsupported capabilities that are no longer used are removed from the require commands. The
+// returned warnings report the constructs that couldn't be translated; their capabilities are
+// kept, so the result still requires them. Only the translated arguments and the capability
+// lists are rewritten, the formatting and comments of the rest of the script are preserved.
+func Downgrade(tree *Tree, supported []string, opts ...Option) (string, []Warning, error) {
+	if tree.Source == nil {
+		return "", nil, fmt.Errorf("tree %s has no source", tree.Name)
+	}
+	d := &downgrader{tree: tree, options: newOptions(opts), supported: map[string]bool{}}
+	for _, capability := range supported {
+		d.supported[capability] = true
+	}
+	var err error
+	if d.tokens, err = Tokenize(tree.Name, tree.Source.Content, WithComments(false)); err != nil {
+		return "", nil, err
+	}
+
+	d.commands(tree.Commands())
+	if d.err != nil {
+		return "", nil, d.err
+	}
+
+	// an unsupported capability stays required as long as a construct of it is left
+	analysis := Analyze(tree)
+	left := map[string]bool{}
+	for _, capability := range analysis.CapabilitiesUsed() {
+		if d.supported[capability] {
+			continue
+		}
+		for _, pos := range analysis.Uses(capability) {
+			if !d.translated[pos] {
+				left[capability] = true
+			}
+		}
+		if left[capability] && capability != "regex" {
+			d.warnf(analysis.Uses(capability)[0], CodeNoTranslation, capability)
+		}
+	}
+	for _, require := range tree.Requires() {
+		var kept []string
+		for _, capability := range require.Capabilities {
+			if d.supported[capability] || left[capability] {
+				kept = append(kept, capability)
+			}
+		}
+		if len(kept) == len(require.Capabilities) {
+			continue
+		}
+		edit, err := tree.requireEdit(d.tokens, require, kept)
+		if err != nil {
+			return "", nil, err
+		}
+		d.edits = append(d.edits, edit)
+	}
+
+	content, err := applyEdits(tree.Source.Content, d.edits)
+	if err != nil {
+		return "", nil, err
+	}
+	sort.SliceStable(d.warnings, func(i, j int) bool { return d.warnings[i].Pos < d.warnings[j].Pos })
+	return content, d.warnings, nil
+}
+
+type downgrader struct {
+	tree       *Tree
+	tokens     []Token
+	supported  map[string]bool
+	translated map[Pos]bool // positions of the uses of capabilities that were translated
+	edits      []Edit
+	warnings   []Warning
+	options    options
+	err        error
+}
+
+func (d *downgrader) warnf(pos Pos, code Code, args ...any) {
+	d.warnings = d.options.warn(d.warnings, d.tree.Source, pos, nil, code, args...)
+}
+
+func (d *downgrader) commands(commands []Command) {
+	for _, node := range commands {
+		if n, ok := node.(*IfNode); ok {
+			for _, test := range n.Conditions() {
+				d.test(test)
+			}
+			for _, block := range n.Blocks() {
+				d.commands(block.Commands())
+			}
+		}
+	}
+}
+
+func (d *downgrader) test(test *TestNode) {
+	for _, t := range test.Tests {
+		d.test(t)
+	}
+	if d.supported["regex"] {
+		return
+	}
+	var regex *TagNode
+	for _, tag := range test.Tags() {
+		if isKeyword(tag.Name, ":regex") {
+			regex = tag
+		}
+	}
+	if regex == nil {
+		return
+	}
+
+	// the key-list is the last string or string-list argument
+	var keys Node
+	for _, arg := range test.Arguments {
+		switch arg.(type) {
+		case *StringNode, *StringListNode:
+			keys = arg
+		}
+	}
+	if keys == nil {
+		return
+	}
+	var list []string
+	switch k := keys.(type) {
+	case *StringNode:
+		list = []string{k.Text}
+	case *StringListNode:
+		list = k.Strings
+	}
+	var patterns []string
+	for _, key := range list {
+		pattern, ok := regexToMatches(key)
+		if !ok {
+			d.warnf(regex.Pos, CodeUntranslatableRegex, key)
+			return
+		}
+		patterns = append(patterns, pattern)
+	}
+
+	span, ok := argumentSpan(d.tokens, keys.Position())
+	if !ok {
+		d.err = fmt.Errorf("argument at %s not found in the source", d.tree.Source.Position(keys.Position()))
+		return
+	}
+	var text string
+	var err error
+	if _, ok := keys.(*StringNode); ok {
+		text, err = QuoteString(patterns[0])
+	} else {
+		text, err = QuoteStringList(patterns)
+	}
+	if err != nil {
+		d.err = err
+		return
+	}
+	span.Text = text
+	d.edits = append(d.edits, Edit{Start: regex.Pos, End: regex.Pos + Pos(len(regex.Name)), Text: ":matches"}, span)
+	if d.translated == nil {
+		d.translated = map[Pos]bool{}
+	}
+	d.translated[regex.Pos] = true
+}
+
+// argumentSpan returns the span of the string or string-list argument starting at pos
+func argumentSpan(tokens []Token, pos Pos) (Edit, bool) {
+	i := sort.Search(len(tokens), func(i int) bool { return tokens[i].Pos >= pos })
+	if i >= len(tokens) || tokens[i].Pos != pos {
+		return Edit{}, false
+	}
+	if tokens[i].Type != TokenStringListOpen {
+		return Edit{Start: pos, End: tokens[i].End()}, true
+	}
+	for _, token := range tokens[i:] {
+		if token.Type == TokenStringListClose {
+			return Edit{Start: pos, End: token.End()}, true
+		}
+	}
+	return Edit{}, false
+}
+
+// regexToMatches translates a regular expression to an equivalent :matches pattern, if it consists
+// of literal text (with escaped punctuation), `.`, `.*` and `.+` (optionally lazy) and the
+// anchors `^` and `$`; a :matches pattern is anchored at both ends, a regular expression isn't
+func regexToMatches(regex string) (string, bool) {
+	var b strings.Builder
+	star := false // the pattern ends with a `*` wildcard
+	wildcard := func(w string) {
+		if w == "*" && star {
+			return
+		}
+		b.WriteString(w)
+		star = strings.HasSuffix(w, "*")
+	}
+	if !strings.HasPrefix(regex, "^") {
+		wildcard("*")
+	} else {
+		regex = regex[1:]
+	}
+	anchoredEnd := false
+	for i := 0; i < len(regex); i++ {
+		switch c := regex[i]; c {
+		case '\\':
+			if i+1 == len(regex) || !strings.ContainsRune(`.*+?()[]{}|^$\/-`, rune(regex[i+1])) {
+				return "", false // classes like \d and \w and a trailing backslash
+			}
+			i++
+			if regex[i] == '*' || regex[i] == '?' || regex[i] == '\\' {
+				b.WriteByte('\\')
+			}
+			b.WriteByte(regex[i])
+			star = false
+		case '.':
+			switch {
+			case i+1 < len(regex) && regex[i+1] == '*':
+				wildcard("*")
+				i++
+			case i+1 < len(regex) && regex[i+1] == '+':
+				wildcard("?*")
+				i++
+			default:
+				wildcard("?")
+				continue
+			}
+			if i+1 < len(regex) && regex[i+1] == '?' {
+				i++ // a lazy quantifier matches the same strings
+			}
+		case '$':
+			if i+1 != len(regex) {
+				return "", false
+			}
+			anchoredEnd = true
+		case '*', '+', '?', '(', ')', '[', ']', '{', '}', '|', '^':
+			return "", false
+		default:
+			b.WriteByte(c)
+			star = false
+		}
+	}
+	if !anchoredEnd {
+		wildcard("*")
+	}
+	return b.String(), true
+}
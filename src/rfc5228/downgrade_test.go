@@ -0,0 +1,88 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import "testing"
+
+func TestRegexToMatches(t *testing.T) {
+	for regex, expected := range map[string]string{
+		"":                   "*",
+		"spam":               "*spam*",
+		"^\\[SPAM\\]":        "[SPAM]*",
+		"^a.b$":              "a?b",
+		"^.*@example\\.com$": "*@example.com",
+		"a.+b.*?c":           "*a?*b*c*",
+		"\\*\\?\\\\":         "*\\*\\?\\\\*",
+		".*x.*":              "*x*",
+		"^$":                 "",
+	} {
+		if pattern, ok := regexToMatches(regex); !ok || pattern != expected {
+			t.Errorf("%q: expected %q, got %q (%t)", regex, expected, pattern, ok)
+		}
+	}
+	for _, regex := range []string{"a|b", "[0-9]", "a+", "\\d", "(x)", "a$b", "x{2}", "a\\"} {
+		if pattern, ok := regexToMatches(regex); ok {
+			t.Errorf("%q: unexpected translation %q", regex, pattern)
+		}
+	}
+}
+
+func TestDowngrade(t *testing.T) {
+	tree := parse(t, "require [\"regex\", \"variables\", \"envelope\"];\r\n"+
+		"if header :regex \"subject\" [\"^\\\\[SPAM\\\\]\", \"viagra\"] {\r\n  discard;\r\n}\r\n"+
+		"if address :REGEX :all \"from\" \"^.*@example\\\\.com$\" { # from example.com\r\n  keep;\r\n}\r\n"+
+		"if envelope :is \"from\" \"a@example.com\" {\r\n  stop;\r\n}\r\n")
+	script, warnings, err := Downgrade(tree, []string{"envelope"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "require [\"envelope\"];\r\n" +
+		"if header :matches \"subject\" [\"[SPAM]*\", \"*viagra*\"] {\r\n  discard;\r\n}\r\n" +
+		"if address :matches :all \"from\" \"*@example.com\" { # from example.com\r\n  keep;\r\n}\r\n" +
+		"if envelope :is \"from\" \"a@example.com\" {\r\n  stop;\r\n}\r\n"
+	if script != expected || len(warnings) != 0 {
+		t.Errorf("expected %q, got %q %v", expected, script, warnings)
+	}
+	if _, err := Parse("downgraded", script, ModeStrict); err != nil {
+		t.Errorf("unexpected error %s", err)
+	}
+
+	// untranslatable constructs keep their capabilities
+	tree = parse(t, "require [\"regex\", \"envelope\"];\r\n"+
+		"if header :regex \"subject\" \"^(re|fwd):\" {\r\n  discard;\r\n}\r\n"+
+		"if envelope :is \"from\" \"a@example.com\" {\r\n  stop;\r\n}\r\n")
+	script, warnings, err = Downgrade(tree, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if script != tree.Source.Content {
+		t.Errorf("unexpected rewrite %q", script)
+	}
+	if len(warnings) != 2 || warnings[0].Code != CodeUntranslatableRegex || warnings[1].Code != CodeNoTranslation ||
+		warnings[1].Message != "capability `envelope` isn't supported and has no translation" {
+		t.Errorf("unexpected warnings %v", warnings)
+	}
+}
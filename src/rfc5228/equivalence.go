@@ -0,0 +1,257 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DefaultMaxTests is the number of distinct tests Equivalent considers by default
+const DefaultMaxTests = 16
+
+// EquivalenceOptions controls the equivalence check
+type EquivalenceOptions struct {
+	MaxTests int // maximum number of distinct tests of both scripts combined; 0 means DefaultMaxTests
+}
+
+// Equivalent reports whether two scripts perform the same actions for all messages.
+//
+// The check is conservative: tests other than true, false, not, allof and anyof are treated
+// as independent conditions, except where one test is known to imply or contradict another
+// (e.g. `size :over 2M` implies `size :over 1M`). Scripts are equivalent if they keep, discard
+// and redirect alike for every combination of the outcomes of these conditions, so true means
+// the scripts are equivalent, while false may also mean the equivalence could not be shown.
+// Identical tests are recognized by their fingerprint (see FingerprintTest), and so are unknown
+// commands (see GenericCommandNode), whose blocks aren't run and which may cancel the
+// implicit keep. An error is returned
+// if the scripts have more distinct tests than opts.MaxTests.
+func Equivalent(a, b *Tree, opts EquivalenceOptions) (bool, error) {
+	limit := opts.MaxTests
+	if limit <= 0 {
+		limit = DefaultMaxTests
+	}
+
+	c := &conditions{index: map[*TestNode]int{}, keys: map[string]int{}}
+	c.commands(a.Commands())
+	c.commands(b.Commands())
+	if len(c.tests) > limit || len(c.tests) > 62 {
+		return false, fmt.Errorf("too many distinct tests to decide equivalence: %d, maximum is %d", len(c.tests), limit)
+	}
+	c.relate()
+
+	for values := uint64(0); values < 1<<len(c.tests); values++ {
+		if !c.consistent(values) {
+			continue
+		}
+		if c.outcome(a, values) != c.outcome(b, values) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// conditions numbers the distinct tests of the scripts; a combination of outcomes is a bit set
+type conditions struct {
+	index   map[*TestNode]int
+	keys    map[string]int
+	tests   []*TestNode
+	implied [][2]int // pairs where the first test implies the second
+	exclude [][2]int // pairs of tests that can't both be true
+}
+
+func (c *conditions) commands(commands []Command) {
+	for _, node := range commands {
+		if n, ok := node.(*IfNode); ok {
+			for _, test := range n.Conditions() {
+				c.test(test)
+			}
+			for _, block := range n.Blocks() {
+				c.commands(block.Commands())
+			}
+		}
+	}
+}
+
+func (c *conditions) test(test *TestNode) {
+	switch strings.ToLower(test.Name) {
+	case TRUE, FALSE:
+	case NOT, ALLOF, ANYOF:
+		for _, t := range test.Tests {
+			c.test(t)
+		}
+	default:
+		key := FingerprintTest(test)
+		i, ok := c.keys[key]
+		if !ok {
+			i = len(c.tests)
+			c.keys[key] = i
+			c.tests = append(c.tests, test)
+		}
+		c.index[test] = i
+	}
+}
+
+func (c *conditions) relate() {
+	for i, a := range c.tests {
+		for j, b := range c.tests {
+			if i == j {
+				continue
+			}
+			if implies(a, b) {
+				c.implied = append(c.implied, [2]int{i, j})
+			}
+			if i < j && contradicts(a, b) {
+				c.exclude = append(c.exclude, [2]int{i, j})
+			}
+		}
+	}
+}
+
+// consistent reports whether a combination of outcomes is possible for some message
+func (c *conditions) consistent(values uint64) bool {
+	for _, p := range c.implied {
+		if values&(1<<p[0]) != 0 && values&(1<<p[1]) == 0 {
+			return false
+		}
+	}
+	for _, p := range c.exclude {
+		if values&(1<<p[0]) != 0 && values&(1<<p[1]) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *conditions) eval(test *TestNode, values uint64) bool {
+	switch strings.ToLower(test.Name) {
+	case TRUE:
+		return true
+	case FALSE:
+		return false
+	case NOT:
+		return !c.eval(test.Tests[0], values)
+	case ALLOF:
+		for _, t := range test.Tests {
+			if !c.eval(t, values) {
+				return false
+			}
+		}
+		return true
+	case ANYOF:
+		for _, t := range test.Tests {
+			if c.eval(t, values) {
+				return true
+			}
+		}
+		return false
+	default:
+		return values&(1<<c.index[test]) != 0
+	}
+}
+
+// outcome runs a script for a combination of outcomes and describes the resulting actions
+func (c *conditions) outcome(tree *Tree, values uint64) string {
+	r := &run{conditions: c, values: values, redirects: map[string]bool{}, generic: map[string]bool{}}
+	r.commands(tree.Commands())
+
+	var actions []string
+	switch {
+	case r.keep:
+		actions = append(actions, KEEP)
+	case r.cancelled:
+	case len(r.generic) > 0:
+		// unknown commands (e.g. fileinto) may cancel the implicit keep, so it only
+		// matches an outcome of the same commands without an explicit keep
+		actions = append(actions, "implicit "+KEEP)
+	default:
+		actions = append(actions, KEEP)
+	}
+	for addr := range r.redirects {
+		actions = append(actions, REDIRECT+" "+addr)
+	}
+	for fingerprint := range r.generic {
+		actions = append(actions, fingerprint)
+	}
+	sort.Strings(actions)
+	return strings.Join(actions, "\n")
+}
+
+type run struct {
+	*conditions
+	values    uint64
+	keep      bool // explicit keep
+	cancelled bool // implicit keep cancelled
+	redirects map[string]bool
+	generic   map[string]bool // fingerprints of unknown commands, which are compared as a whole
+	stopped   bool
+}
+
+func (r *run) commands(commands []Command) {
+	for _, node := range commands {
+		if r.stopped {
+			return
+		}
+		switch n := node.(type) {
+		case *StopNode:
+			r.stopped = true
+		case *KeepNode:
+			r.keep = true
+		case *DiscardNode:
+			r.cancelled = true
+		case *RedirectNode:
+			r.cancelled = true
+			r.redirects[normalizeAddress(n.Address)] = true
+		case *GenericCommandNode:
+			r.generic[FingerprintCommand(n)] = true
+		case *IfNode:
+			r.chain(n)
+		}
+	}
+}
+
+func (r *run) chain(n *IfNode) {
+	blocks := n.Blocks()
+	for i, test := range n.Conditions() {
+		if r.eval(test, r.values) {
+			r.commands(blocks[i].Commands())
+			return
+		}
+	}
+	if n.Else != nil {
+		r.commands(n.Else.Body.Commands())
+	}
+}
+
+// normalizeAddress normalizes the (case-insensitive) domain of an address
+func normalizeAddress(addr string) string {
+	local, domain, err := SplitAddress(addr)
+	if err != nil {
+		return addr
+	}
+	return local + "@" + NormalizeDomain(domain)
+}
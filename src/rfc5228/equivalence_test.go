@@ -0,0 +1,77 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import "testing"
+
+func TestEquivalent(t *testing.T) {
+	for _, expected := range []struct {
+		a, b       string
+		equivalent bool
+	}{
+		// spelling, comments and requires don't matter
+		{"if EXISTS \"x\" { Discard; }\r\n", "require \"fileinto\";\r\nif exists \"x\" {\r\n  # drop\r\n  discard;\r\n}\r\n", true},
+		// an explicit keep equals the implicit keep
+		{"keep;\r\n", "if true { }\r\n", true},
+		{"discard;\r\n", "keep;\r\n", false},
+		// swapping independent rules that end with stop
+		{"if exists \"a\" { discard; stop; }\r\nif exists \"b\" { redirect \"x@example.com\"; stop; }\r\n",
+			"if exists \"b\" { redirect \"x@example.com\"; stop; }\r\nif exists \"a\" { discard; stop; }\r\n", false},
+		{"if exists \"a\" { discard; stop; }\r\nif exists \"b\" { discard; stop; }\r\n",
+			"if exists \"b\" { discard; stop; }\r\nif exists \"a\" { discard; stop; }\r\n", true},
+		// nesting vs allof, if/else vs not
+		{"if exists \"a\" { if exists \"b\" { discard; } }\r\n", "if allof (exists \"a\", exists \"b\") { discard; }\r\n", true},
+		{"if exists \"a\" { keep; } else { discard; }\r\n", "if not exists \"a\" { discard; } else { keep; }\r\n", true},
+		{"if anyof (exists \"a\", exists \"b\") { discard; }\r\n", "if exists \"a\" { discard; }\r\n", false},
+		// a condition implied by an earlier one in the chain never decides anything
+		{"if size :over 1M { discard; } elsif size :over 2M { keep; }\r\n", "if size :over 1M { discard; }\r\n", true},
+		{"if size :over 2M { discard; } elsif size :over 1M { keep; }\r\n", "if size :over 2M { discard; }\r\n", true},
+		{"if size :over 1M { discard; }\r\n", "if size :over 2M { discard; }\r\n", false},
+		// redirects are compared with normalized domains and without duplicates
+		{"redirect \"joe@EXAMPLE.com\";\r\nredirect \"joe@example.com\";\r\n", "redirect \"joe@example.com\";\r\n", true},
+		{"redirect \"Joe@example.com\";\r\n", "redirect \"joe@example.com\";\r\n", false},
+		{"redirect \"joe@example.com\";\r\nkeep;\r\n", "redirect \"joe@example.com\";\r\n", false},
+		// unknown commands may cancel the implicit keep
+		{"require \"fileinto\";\r\nfileinto \"Junk\";\r\n", "require \"fileinto\";\r\nfileinto \"Junk\";\r\nkeep;\r\n", false},
+		{"require \"fileinto\";\r\nfileinto \"Junk\";\r\n", "require \"fileinto\";\r\nif true { fileinto \"Junk\"; }\r\n", true},
+		{"require \"fileinto\";\r\nfileinto \"Junk\";\r\nkeep;\r\n", "require \"fileinto\";\r\nkeep;\r\nfileinto \"Junk\";\r\n", true},
+	} {
+		equivalent, err := Equivalent(parse(t, expected.a), parse(t, expected.b), EquivalenceOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if equivalent != expected.equivalent {
+			t.Errorf("expected %t for\n%s\nand\n%s", expected.equivalent, expected.a, expected.b)
+		}
+	}
+}
+
+func TestEquivalentTooManyTests(t *testing.T) {
+	tree := parse(t, "if anyof (exists \"a\", exists \"b\", exists \"c\") { discard; }\r\n")
+	if _, err := Equivalent(tree, tree, EquivalenceOptions{MaxTests: 2}); err == nil {
+		t.Error("expected an error")
+	}
+}
@@ -0,0 +1,110 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"gosieve/src/rfc5228"
+)
+
+// Parse a script and report the warnings of the validator.
+func Example() {
+	script := "require \"fileinto\";\r\n" +
+		"if header :contains \"subject\" \"[SPAM]\" {\r\n" +
+		"  fileinto \"Junk\";\r\n" +
+		"  stop;\r\n" +
+		"}\r\n" +
+		"if header :contains \"subject\" \"[SPAM]\" {\r\n" +
+		"  redirect \"postmaster\";\r\n" +
+		"}\r\n"
+
+	tree, err := rfc5228.Parse("example.sieve", script, 0)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	for _, w := range rfc5228.Validate(tree) {
+		fmt.Printf("%d:%d: %s: %s\n", w.Line, w.Column, w.Code, w.Message)
+	}
+	// Output:
+	// 6:4: SIEVE0107: `if` condition is shadowed by the rule at 2:1 that ends with `stop`
+	// 7:3: SIEVE0102: `redirect`: missing `@` in address "postmaster"
+}
+
+// A script that can't be parsed is a *SyntaxError, which holds the line and column of the
+// error and a stable code.
+func ExampleParse_syntaxError() {
+	_, err := rfc5228.Parse("example.sieve", "keep;\r\nstop\r\n", 0)
+
+	var syntax *rfc5228.SyntaxError
+	if errors.As(err, &syntax) {
+		fmt.Printf("%d:%d: %s\n", syntax.Line, syntax.Column, syntax.Code)
+	}
+	// Output:
+	// 2:5: SIEVE0004
+}
+
+// Format a script in the default style.
+func ExampleFormat() {
+	tree, err := rfc5228.Parse("example.sieve", "if size :over 1M { discard; stop; } keep;", 0)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	formatted, err := rfc5228.Format(tree, rfc5228.DefaultFormatOptions())
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	// scripts end their lines with CRLF
+	fmt.Print(strings.ReplaceAll(formatted, "\r\n", "\n"))
+	// Output:
+	// if size :over 1M {
+	//   discard;
+	//   stop;
+	// }
+	// keep;
+}
+
+// Tokenize a script, e.g. for syntax highlighting.
+func ExampleTokenize() {
+	tokens, err := rfc5228.Tokenize("example.sieve", "redirect \"a@example.com\"; # forward\r\n", rfc5228.WithComments(true))
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	for _, token := range tokens {
+		fmt.Printf("%d %q\n", token.Pos, token.Value)
+	}
+	// Output:
+	// 0 "redirect"
+	// 9 "\"a@example.com\""
+	// 24 ";"
+	// 26 "# forward"
+}
@@ -0,0 +1,156 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"hash"
+	"strings"
+)
+
+// Fingerprint returns a stable hash (hex encoded SHA-256) of the semantic content of a script.
+//
+// Comments, whitespace, the spelling of identifiers and tags (which are case-insensitive)
+// and the notation of numbers (e.g. 1K vs 1024) do not affect the fingerprint.
+func Fingerprint(tree *Tree) string {
+	f := &fingerprinter{hash: sha256.New()}
+	f.commands(tree.Commands())
+	return hex.EncodeToString(f.hash.Sum(nil))
+}
+
+// FingerprintCommand returns a stable hash of the semantic content of a single command
+// (see Fingerprint), e.g. to identify a rule across revisions of a script
+func FingerprintCommand(node Command) string {
+	f := &fingerprinter{hash: sha256.New()}
+	f.command(node)
+	return hex.EncodeToString(f.hash.Sum(nil))
+}
+
+// FingerprintTest returns a stable hash of the semantic content of a test (see Fingerprint)
+func FingerprintTest(test *TestNode) string {
+	f := &fingerprinter{hash: sha256.New()}
+	f.test(test)
+	return hex.EncodeToString(f.hash.Sum(nil))
+}
+
+type fingerprinter struct {
+	hash hash.Hash
+	buf  [binary.MaxVarintLen64]byte
+}
+
+// uint writes an unsigned integer
+func (f *fingerprinter) uint(n uint64) {
+	f.hash.Write(f.buf[:binary.PutUvarint(f.buf[:], n)])
+}
+
+// string writes a length prefixed string so that adjacent values can't be confused
+func (f *fingerprinter) string(s string) {
+	f.uint(uint64(len(s)))
+	f.hash.Write([]byte(s))
+}
+
+// identifier writes a case-insensitive identifier or tag
+func (f *fingerprinter) identifier(s string) {
+	f.string(strings.ToLower(s))
+}
+
+func (f *fingerprinter) commands(commands []Command) {
+	f.uint(uint64(len(commands)))
+	for _, node := range commands {
+		f.command(node)
+	}
+}
+
+func (f *fingerprinter) command(node Command) {
+	f.uint(uint64(node.Type()))
+	switch n := node.(type) {
+	case *RequireNode:
+		f.uint(uint64(len(n.Capabilities)))
+		for _, c := range n.Capabilities {
+			f.string(c)
+		}
+	case *RedirectNode:
+		f.string(n.Address)
+	case *IfNode:
+		f.test(n.Test)
+		f.commands(n.Body.Commands())
+		f.uint(uint64(len(n.ElseIfs)))
+		for _, elsif := range n.ElseIfs {
+			f.test(elsif.Test)
+			f.commands(elsif.Body.Commands())
+		}
+		if n.Else != nil {
+			f.uint(1)
+			f.commands(n.Else.Body.Commands())
+		} else {
+			f.uint(0)
+		}
+	case *GenericCommandNode:
+		f.identifier(n.Name)
+		f.arguments(n.Arguments)
+		f.uint(uint64(len(n.Tests)))
+		for _, t := range n.Tests {
+			f.test(t)
+		}
+		if n.Block != nil {
+			f.uint(1)
+			f.commands(n.Block.Commands())
+		} else {
+			f.uint(0)
+		}
+	}
+}
+
+func (f *fingerprinter) test(test *TestNode) {
+	f.identifier(test.Name)
+	f.arguments(test.Arguments)
+	f.uint(uint64(len(test.Tests)))
+	for _, t := range test.Tests {
+		f.test(t)
+	}
+}
+
+func (f *fingerprinter) arguments(args []Argument) {
+	f.uint(uint64(len(args)))
+	for _, arg := range args {
+		f.uint(uint64(arg.Type()))
+		switch a := arg.(type) {
+		case *TagNode:
+			f.identifier(a.Name)
+		case *NumberNode:
+			f.uint(a.Value)
+		case *StringNode:
+			f.string(a.Text)
+		case *StringListNode:
+			f.uint(uint64(len(a.Strings)))
+			for _, s := range a.Strings {
+				f.string(s)
+			}
+		}
+	}
+}
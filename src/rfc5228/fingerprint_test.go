@@ -0,0 +1,62 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import "testing"
+
+func TestFingerprint(t *testing.T) {
+	a := parse(t, "require [\"fileinto\"];\r\nif size :over 1K {\r\n  discard;\r\n}\r\n")
+	b := parse(t, "# comment\r\nREQUIRE   \"fileinto\" ;\r\nIf SIZE :Over 1024 { /* drop */ Discard; }\r\n")
+	c := parse(t, "require [\"fileinto\"];\r\nif size :under 1K {\r\n  discard;\r\n}\r\n")
+
+	if Fingerprint(a) != Fingerprint(b) {
+		t.Errorf("expected equal fingerprints for semantically equal scripts")
+	}
+	if Fingerprint(a) == Fingerprint(c) {
+		t.Errorf("expected different fingerprints for different scripts")
+	}
+}
+
+func TestFingerprintStringBoundaries(t *testing.T) {
+	a := parse(t, "if header :is [\"ab\", \"c\"] \"d\" {\r\n  keep;\r\n}\r\n")
+	b := parse(t, "if header :is [\"a\", \"bc\"] \"d\" {\r\n  keep;\r\n}\r\n")
+
+	if Fingerprint(a) == Fingerprint(b) {
+		t.Errorf("expected different fingerprints for different string boundaries")
+	}
+}
+
+func TestFingerprintCommand(t *testing.T) {
+	a := parse(t, "keep;\r\nif exists \"X\" {\r\n  discard;\r\n}\r\n")
+	b := parse(t, "IF Exists \"X\" { discard; }\r\n")
+
+	if FingerprintCommand(a.Commands()[1]) != FingerprintCommand(b.Commands()[0]) {
+		t.Errorf("expected equal fingerprints for semantically equal commands")
+	}
+	if FingerprintCommand(a.Commands()[0]) == FingerprintCommand(a.Commands()[1]) {
+		t.Errorf("expected different fingerprints for different commands")
+	}
+}
@@ -0,0 +1,154 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// FixRequires returns the script of a tree with its require commands fixed up by the capability
+// usage report (see Analyze): capabilities the script uses but doesn't require are added to the
+// first top-level require command, or to a new one before the first command, and capabilities
+// it requires but doesn't use are removed. Require commands left without capabilities are
+// removed with their line. Only the capability lists are rewritten, so the order of the kept
+// capabilities and the formatting and comments of the rest of the script are preserved.
+func FixRequires(tree *Tree) (string, error) {
+	if tree.Source == nil {
+		return "", fmt.Errorf("tree %s has no source", tree.Name)
+	}
+	content := tree.Source.Content
+	analysis := Analyze(tree)
+	missing, unused := analysis.Missing(), map[string]bool{}
+	for _, capability := range analysis.Unused() {
+		unused[capability] = true
+	}
+
+	tokens, err := Tokenize(tree.Name, content, WithComments(false))
+	if err != nil {
+		return "", err
+	}
+
+	var edits []Edit
+	for i, require := range tree.Requires() {
+		var kept []string
+		for _, capability := range require.Capabilities {
+			if !unused[capability] {
+				kept = append(kept, capability)
+			}
+		}
+		changed := len(kept) != len(require.Capabilities)
+		if i == 0 && len(tree.Commands()) > 0 && tree.Commands()[0] == Command(require) && len(missing) > 0 {
+			kept = append(kept, missing...)
+			missing, changed = nil, true
+		}
+		if !changed {
+			continue
+		}
+		edit, err := tree.requireEdit(tokens, require, kept)
+		if err != nil {
+			return "", err
+		}
+		edits = append(edits, edit)
+	}
+
+	if len(missing) > 0 {
+		text, err := QuoteStringList(missing)
+		if err != nil {
+			return "", err
+		}
+		pos := Pos(len(content))
+		if commands := tree.Commands(); len(commands) > 0 {
+			pos = commands[0].Position()
+		}
+		edits = append(edits, Edit{Start: pos, End: pos, Text: REQUIRE + " " + text + ";\r\n"})
+	}
+
+	return applyEdits(content, edits)
+}
+
+// applyEdits applies non-overlapping edits back to front so that earlier positions stay valid
+func applyEdits(content string, edits []Edit) (string, error) {
+	sort.SliceStable(edits, func(i, j int) bool { return edits[i].Start > edits[j].Start })
+	for _, edit := range edits {
+		var err error
+		if content, err = edit.Apply(content); err != nil {
+			return "", err
+		}
+	}
+	return content, nil
+}
+
+// requireEdit returns the edit replacing the capabilities of a require command by kept, or
+// removing the command if kept is empty
+func (t *Tree) requireEdit(tokens []Token, require *RequireNode, kept []string) (Edit, error) {
+	// require <capabilities: string-list> ;
+	arg, end, ok := requireSpan(tokens, require.Pos)
+	if !ok {
+		return Edit{}, fmt.Errorf("require at %s not found in the source", t.Source.Position(require.Pos))
+	}
+	if len(kept) == 0 {
+		return t.removal(require.Pos, end), nil
+	}
+	text, err := QuoteStringList(kept)
+	if err != nil {
+		return Edit{}, err
+	}
+	return Edit{Start: arg.Start, End: arg.End, Text: text}, nil
+}
+
+// requireSpan returns the span of the capability argument of the require command at pos and
+// the end of its `;`
+func requireSpan(tokens []Token, pos Pos) (arg Edit, end Pos, ok bool) {
+	i := sort.Search(len(tokens), func(i int) bool { return tokens[i].Pos >= pos })
+	if i+1 >= len(tokens) || tokens[i].Pos != pos {
+		return Edit{}, 0, false
+	}
+	arg.Start = tokens[i+1].Pos
+	for _, token := range tokens[i+1:] {
+		if token.Type == TokenEnd {
+			return arg, token.End(), true
+		}
+		arg.End = token.End()
+	}
+	return Edit{}, 0, false
+}
+
+// removal returns the edit removing the source [start, end) of a command, with its line if
+// the command is on a line of its own
+func (t *Tree) removal(start, end Pos) Edit {
+	content := t.Source.Content
+	lineStart := start - Pos(t.Source.Position(start).Column-1)
+	lineEnd := Pos(len(content))
+	if i := strings.Index(content[end:], "\r\n"); i >= 0 {
+		lineEnd = end + Pos(i) + 2
+	}
+	if strings.Trim(content[lineStart:start], " \t") == "" && strings.Trim(content[end:lineEnd], " \t\r\n") == "" {
+		return Edit{Start: lineStart, End: lineEnd}
+	}
+	return Edit{Start: start, End: end}
+}
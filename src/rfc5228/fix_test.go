@@ -0,0 +1,55 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import (
+	"testing"
+)
+
+func TestFixRequires(t *testing.T) {
+	for _, test := range []struct {
+		input, expected string
+	}{
+		// add to the first require and prune the others
+		{"# filters\r\nrequire [\"fileinto\", \"envelope\"]; # first\r\nrequire \"vacation\";\r\nif envelope :user \"to\" \"me\" {\r\n  keep;\r\n}\r\n",
+			"# filters\r\nrequire [\"envelope\", \"subaddress\"]; # first\r\nif envelope :user \"to\" \"me\" {\r\n  keep;\r\n}\r\n"},
+		// insert a require before the first command
+		{"# filters\r\nif envelope \"to\" \"me\" {\r\n  keep;\r\n}\r\n",
+			"# filters\r\nrequire [\"envelope\"];\r\nif envelope \"to\" \"me\" {\r\n  keep;\r\n}\r\n"},
+		// nothing to fix
+		{"require \"envelope\";\r\nif envelope \"to\" \"me\" {\r\n  keep;\r\n}\r\n",
+			"require \"envelope\";\r\nif envelope \"to\" \"me\" {\r\n  keep;\r\n}\r\n"},
+		{"keep;\r\n", "keep;\r\n"},
+	} {
+		fixed, err := FixRequires(parse(t, test.input))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fixed != test.expected {
+			t.Errorf("%q: expected %q, got %q", test.input, test.expected, fixed)
+		}
+	}
+}
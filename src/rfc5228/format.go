@@ -0,0 +1,431 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ListWrap controls when Format breaks string-lists over lines
+type ListWrap int
+
+const (
+	ListWrapAuto   ListWrap = iota // one element per line when the line would be too long
+	ListWrapAlways                 // one element per line for lists of more than one element
+	ListWrapNever                  // always on one line
+)
+
+// BraceStyle controls where Format places the opening brace of a block
+type BraceStyle int
+
+const (
+	BraceSameLine BraceStyle = iota // `if true {` and `} else {`
+	BraceNextLine                   // `{` and `else` on lines of their own
+)
+
+// FormatOptions is the style of Format; the zero value doesn't indent and never wraps
+type FormatOptions struct {
+	IndentWidth   int  // spaces per level of indentation, and the width of a tab for MaxLineLength
+	UseTabs       bool // indent with tabs instead of spaces
+	MaxLineLength int  // the length at which tests and string-lists are wrapped; 0 for no limit
+	ListWrap      ListWrap
+	BraceStyle    BraceStyle
+}
+
+// DefaultFormatOptions returns the default style: two spaces, lines of at most 80 characters
+func DefaultFormatOptions() FormatOptions {
+	return FormatOptions{IndentWidth: 2, MaxLineLength: 80}
+}
+
+// ParseFormatOptions reads a `.sievefmt` file, which holds editorconfig-style `key = value`
+// lines; keys that are left out keep their default (see DefaultFormatOptions). Lines
+// starting with `#` or `;` are comments.
+//
+//	indent_style = space | tab
+//	indent_size = <number>
+//	max_line_length = <number> | off
+//	list_wrap = auto | always | never
+//	brace_style = same_line | next_line
+func ParseFormatOptions(content string) (FormatOptions, error) {
+	opts := DefaultFormatOptions()
+	for i, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return opts, fmt.Errorf("line %d: expected key = value", i+1)
+		}
+		key, value = strings.ToLower(strings.TrimSpace(key)), strings.ToLower(strings.TrimSpace(value))
+
+		var err error
+		switch key {
+		case "indent_style":
+			switch value {
+			case "space":
+				opts.UseTabs = false
+			case "tab":
+				opts.UseTabs = true
+			default:
+				err = fmt.Errorf("unknown indent_style %q", value)
+			}
+		case "indent_size":
+			opts.IndentWidth, err = strconv.Atoi(value)
+			if err == nil && opts.IndentWidth < 0 {
+				err = fmt.Errorf("negative indent_size %d", opts.IndentWidth)
+			}
+		case "max_line_length":
+			if value == "off" {
+				opts.MaxLineLength = 0
+				break
+			}
+			opts.MaxLineLength, err = strconv.Atoi(value)
+			if err == nil && opts.MaxLineLength < 0 {
+				err = fmt.Errorf("negative max_line_length %d", opts.MaxLineLength)
+			}
+		case "list_wrap":
+			switch value {
+			case "auto":
+				opts.ListWrap = ListWrapAuto
+			case "always":
+				opts.ListWrap = ListWrapAlways
+			case "never":
+				opts.ListWrap = ListWrapNever
+			default:
+				err = fmt.Errorf("unknown list_wrap %q", value)
+			}
+		case "brace_style":
+			switch value {
+			case "same_line":
+				opts.BraceStyle = BraceSameLine
+			case "next_line":
+				opts.BraceStyle = BraceNextLine
+			default:
+				err = fmt.Errorf("unknown brace_style %q", value)
+			}
+		default:
+			err = fmt.Errorf("unknown key %q", key)
+		}
+		if err != nil {
+			return opts, fmt.Errorf("line %d: %w", i+1, err)
+		}
+	}
+	return opts, nil
+}
+
+// Format returns the script of a tree in the given style. Comments are kept with the
+// command they are attached to (see CommentNode), and a blank line between two commands is
+// kept as a single blank line. Identifiers keep the case in which they are written.
+func Format(tree *Tree, opts FormatOptions) (string, error) {
+	f := &formatter{opts: opts, tree: tree, comments: map[Command][]*CommentNode{}}
+	for _, comment := range tree.Comments {
+		f.comments[comment.Command] = append(f.comments[comment.Command], comment)
+	}
+	var b strings.Builder
+	for _, comment := range f.comments[nil] {
+		b.WriteString(comment.Raw + "\r\n")
+	}
+	b.WriteString(f.commands(tree.Commands(), 0))
+	return b.String(), f.err
+}
+
+type formatter struct {
+	opts     FormatOptions
+	tree     *Tree
+	comments map[Command][]*CommentNode
+	err      error
+}
+
+func (f *formatter) indent(depth int) string {
+	if f.opts.UseTabs {
+		return strings.Repeat("\t", depth)
+	}
+	return strings.Repeat(" ", depth*f.opts.IndentWidth)
+}
+
+// fits reports whether text starting at column, followed by suffix bytes, fits on the
+// line; only the first line of text that spans lines (a multi-line string) is measured
+func (f *formatter) fits(column int, text string, suffix int) bool {
+	if f.opts.MaxLineLength <= 0 {
+		return true
+	}
+	if i := strings.Index(text, "\r\n"); i >= 0 {
+		text, suffix = text[:i], 0
+	}
+	return column+utf8.RuneCountInString(text)+suffix <= f.opts.MaxLineLength
+}
+
+// blankBefore reports whether the script has a blank line just before a position; a tree
+// without its source text, e.g. built in code or decoded from JSON, has none
+func (f *formatter) blankBefore(pos Pos) bool {
+	if f.tree.Source == nil || int(pos) > len(f.tree.Source.Content) {
+		return false
+	}
+	content := f.tree.Source.Content
+	breaks := 0
+	for i := int(pos) - 1; i >= 0 && strings.IndexByte(" \t\r\n", content[i]) >= 0; i-- {
+		if content[i] == '\n' {
+			breaks++
+		}
+	}
+	return breaks >= 2
+}
+
+// commands formats the commands of a block, each line ending with CRLF
+func (f *formatter) commands(commands []Command, depth int) string {
+	var b strings.Builder
+	indent := f.indent(depth)
+	first := true
+	line := func(pos Pos, text string) {
+		if !first && f.blankBefore(pos) {
+			b.WriteString("\r\n")
+		}
+		first = false
+		b.WriteString(indent + text + "\r\n")
+	}
+	for _, node := range commands {
+		var trailing, after []*CommentNode
+		for _, comment := range f.comments[node] {
+			switch {
+			case comment.Trailing:
+				trailing = append(trailing, comment)
+			case comment.Pos < node.Position():
+				line(comment.Pos, comment.Raw)
+			default:
+				after = append(after, comment)
+			}
+		}
+
+		text := f.command(node, depth)
+		for _, comment := range trailing {
+			if i := strings.Index(text, "\r\n"); i >= 0 {
+				text = text[:i] + " " + comment.Raw + text[i:]
+			} else {
+				text += " " + comment.Raw
+			}
+		}
+		line(node.Position(), text)
+
+		for _, comment := range after {
+			line(comment.Pos, comment.Raw)
+		}
+	}
+	return b.String()
+}
+
+// command formats a command without the indentation of its first line and the line break
+// of its last line
+func (f *formatter) command(node Command, depth int) string {
+	column := depth * f.opts.IndentWidth
+	switch n := node.(type) {
+	case *RequireNode:
+		if len(n.Capabilities) == 1 {
+			return n.Name + " " + f.quote(n.Capabilities[0]) + ";"
+		}
+		list := f.list(n.Capabilities, depth, false)
+		if !f.fits(column+len(n.Name)+1, list, 1) {
+			list = f.list(n.Capabilities, depth, true)
+		}
+		return n.Name + " " + list + ";"
+	case *StopNode:
+		return n.Name + ";"
+	case *KeepNode:
+		return n.Name + ";"
+	case *DiscardNode:
+		return n.Name + ";"
+	case *RedirectNode:
+		return n.Name + " " + f.quote(n.Address) + ";"
+	case *GenericCommandNode:
+		suffix := ";"
+		if n.Block != nil {
+			suffix = ""
+		}
+		text := n.Name + f.arguments(n.Arguments, depth, false) + f.genericTests(n, depth, false)
+		if !f.fits(column, text, len(suffix)+f.braceWidth(n.Block != nil)) {
+			text = n.Name + f.arguments(n.Arguments, depth, true) + f.genericTests(n, depth, true)
+		}
+		if n.Block != nil {
+			return text + f.block(n.Block, depth)
+		}
+		return text + suffix
+	case *IfNode:
+		text := f.condition(n.Name, n.Test, depth) + f.block(n.Body, depth)
+		for _, elsif := range n.ElseIfs {
+			text += f.continuation(depth) + f.condition(elsif.Name, elsif.Test, depth) + f.block(elsif.Body, depth)
+		}
+		if n.Else != nil {
+			text += f.continuation(depth) + n.Else.Name + f.block(n.Else.Body, depth)
+		}
+		return text
+	default:
+		f.err = fmt.Errorf("can't format %T", node)
+		return ""
+	}
+}
+
+// braceWidth returns the width the opening brace of a block adds to the line before it
+func (f *formatter) braceWidth(block bool) int {
+	if !block || f.opts.BraceStyle == BraceNextLine {
+		return 0
+	}
+	return 2
+}
+
+// block formats a block, starting with the opening brace on the line before it or on a
+// line of its own
+func (f *formatter) block(block *CommandsNode, depth int) string {
+	open := " {\r\n"
+	if f.opts.BraceStyle == BraceNextLine {
+		open = "\r\n" + f.indent(depth) + "{\r\n"
+	}
+	return open + f.commands(block.Commands(), depth+1) + f.indent(depth) + "}"
+}
+
+// continuation returns the text between the closing brace of a block and elsif or else
+func (f *formatter) continuation(depth int) string {
+	if f.opts.BraceStyle == BraceNextLine {
+		return "\r\n" + f.indent(depth)
+	}
+	return " "
+}
+
+func (f *formatter) condition(name string, test *TestNode, depth int) string {
+	column := depth*f.opts.IndentWidth + len(name) + 1
+	return name + " " + f.fitTest(test, depth, column, f.braceWidth(true))
+}
+
+// fitTest formats a test on one line if it fits, else wrapped over lines
+func (f *formatter) fitTest(test *TestNode, depth, column, suffix int) string {
+	if text := f.test(test, depth, false); f.fits(column, text, suffix) {
+		return text
+	}
+	return f.test(test, depth, true)
+}
+
+func (f *formatter) test(test *TestNode, depth int, wrap bool) string {
+	text := test.Name + f.arguments(test.Arguments, depth, wrap)
+	switch {
+	case isKeyword(test.Name, NOT) && len(test.Tests) == 1:
+		column := depth*f.opts.IndentWidth + len(text) + 1
+		return text + " " + f.fitTest(test.Tests[0], depth, column, 0)
+	case len(test.Tests) > 0 || isKeyword(test.Name, ALLOF) || isKeyword(test.Name, ANYOF):
+		return text + " " + f.testList(test.Tests, depth, wrap)
+	}
+	return text
+}
+
+func (f *formatter) testList(tests []*TestNode, depth int, wrap bool) string {
+	if !wrap {
+		texts := make([]string, len(tests))
+		for i, test := range tests {
+			texts[i] = f.test(test, depth, false)
+		}
+		return "(" + strings.Join(texts, ", ") + ")"
+	}
+	indent := f.indent(depth + 1)
+	text := "(\r\n"
+	for i, test := range tests {
+		text += indent + f.fitTest(test, depth+1, (depth+1)*f.opts.IndentWidth, 1)
+		if i < len(tests)-1 {
+			text += ","
+		}
+		text += "\r\n"
+	}
+	return text + f.indent(depth) + ")"
+}
+
+func (f *formatter) genericTests(n *GenericCommandNode, depth int, wrap bool) string {
+	switch {
+	case n.TestList:
+		return " " + f.testList(n.Tests, depth, wrap)
+	case len(n.Tests) == 1:
+		return " " + f.test(n.Tests[0], depth, wrap)
+	}
+	return ""
+}
+
+// arguments formats arguments, each preceded by a space
+func (f *formatter) arguments(args []Argument, depth int, wrap bool) string {
+	var b strings.Builder
+	for _, arg := range args {
+		b.WriteByte(' ')
+		switch a := arg.(type) {
+		case *TagNode:
+			b.WriteString(a.Name)
+		case *NumberNode:
+			b.WriteString(a.Text)
+		case *StringNode:
+			b.WriteString(f.quote(a.Text))
+		case *StringListNode:
+			b.WriteString(f.list(a.Strings, depth, wrap))
+		}
+	}
+	return b.String()
+}
+
+// list formats a string-list, with one element per line if wrap is set or ListWrap says so
+func (f *formatter) list(list []string, depth int, wrap bool) string {
+	switch {
+	case len(list) < 2 || f.opts.ListWrap == ListWrapNever:
+		wrap = false
+	case f.opts.ListWrap == ListWrapAlways:
+		wrap = true
+	}
+	quoted := make([]string, len(list))
+	for i, s := range list {
+		quoted[i] = f.quote(s)
+	}
+	if !wrap {
+		return "[" + strings.Join(quoted, ", ") + "]"
+	}
+	indent := f.indent(depth + 1)
+	return "[\r\n" + indent + strings.Join(quoted, ",\r\n"+indent) + "\r\n" + f.indent(depth) + "]"
+}
+
+// quote returns a string as a quoted string, or as a multi-line string if it holds lines
+func (f *formatter) quote(s string) string {
+	if strings.HasSuffix(s, "\r\n") && strings.Count(s, "\r\n") > 1 && !strings.ContainsAny(strings.ReplaceAll(s, "\r\n", ""), "\r\n\x00") {
+		var b strings.Builder
+		b.WriteString("text:\r\n")
+		for _, line := range strings.SplitAfter(s[:len(s)-2], "\r\n") {
+			if strings.HasPrefix(line, ".") {
+				b.WriteByte('.')
+			}
+			b.WriteString(line)
+		}
+		b.WriteString("\r\n.\r\n")
+		return b.String()
+	}
+	quoted, err := QuoteString(s)
+	if err != nil {
+		f.err = err
+	}
+	return quoted
+}
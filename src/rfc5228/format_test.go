@@ -0,0 +1,380 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/quick"
+)
+
+// format formats a script and checks that formatting the result again doesn't change it
+func format(t *testing.T, input string, opts FormatOptions) string {
+	t.Helper()
+	output := formatOnce(t, input, opts)
+	if again := formatOnce(t, output, opts); again != output {
+		t.Errorf("formatting %q again gives %q", output, again)
+	}
+	return output
+}
+
+func formatOnce(t *testing.T, input string, opts FormatOptions) string {
+	t.Helper()
+	tree, err := Parse("format", input, 0)
+	if err != nil {
+		t.Fatalf("%q: %v", input, err)
+	}
+	output, err := Format(tree, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return output
+}
+
+func lines(lines ...string) string {
+	return strings.Join(lines, "\r\n") + "\r\n"
+}
+
+const messy = "require [\"fileinto\",\"envelope\"];\r\n# spam\r\nif anyof(header :contains \"subject\" [\"viagra\",\"lottery\"], size :over 1M) { fileinto \"Junk\"; stop; } # rule\r\n" +
+	"elsif not exists \"x\" {keep;}\r\nelse {\r\ndiscard;\r\n}\r\n\r\n\r\nvacation :days 1 text:\r\nHi\r\n..there\r\n.\r\n;\r\n"
+
+func TestFormat(t *testing.T) {
+	expected := lines(
+		`require ["fileinto", "envelope"];`,
+		`# spam`,
+		`if anyof (header :contains "subject" ["viagra", "lottery"], size :over 1M) {`,
+		`  fileinto "Junk";`,
+		`  stop; # rule`,
+		`} elsif not exists "x" {`,
+		`  keep;`,
+		`} else {`,
+		`  discard;`,
+		`}`,
+		``,
+		`vacation :days 1 text:`,
+		`Hi`,
+		`..there`,
+		`.`,
+		`;`,
+	)
+	if output := format(t, messy, DefaultFormatOptions()); output != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, output)
+	}
+}
+
+func TestFormatWithoutSource(t *testing.T) {
+	tree := parse(t, "keep;\r\n\r\nif true {\r\n  stop;\r\n}\r\n")
+	tree.Source = nil
+	output, err := Format(tree, DefaultFormatOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// blank lines can't be kept without the source
+	if expected := lines(`keep;`, `if true {`, `  stop;`, `}`); output != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, output)
+	}
+
+	// positions beyond the source, e.g. of commands added to a tree
+	tree.Source = NewSourceFile("test", "")
+	if _, err := Format(tree, DefaultFormatOptions()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFormatOptions(t *testing.T) {
+	opts := FormatOptions{IndentWidth: 4, UseTabs: true, MaxLineLength: 40, BraceStyle: BraceNextLine}
+	expected := lines(
+		`require ["fileinto", "envelope"];`,
+		`# spam`,
+		`if anyof (`,
+		"\theader :contains \"subject\" [",
+		"\t\t\"viagra\",",
+		"\t\t\"lottery\"",
+		"\t],",
+		"\tsize :over 1M",
+		`)`,
+		`{`,
+		"\tfileinto \"Junk\";",
+		"\tstop; # rule",
+		`}`,
+		`elsif not exists "x"`,
+		`{`,
+		"\tkeep;",
+		`}`,
+		`else`,
+		`{`,
+		"\tdiscard;",
+		`}`,
+		``,
+		`vacation :days 1 text:`,
+		`Hi`,
+		`..there`,
+		`.`,
+		`;`,
+	)
+	if output := format(t, messy, opts); output != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, output)
+	}
+}
+
+func TestFormatListWrap(t *testing.T) {
+	input := "if header :is \"from\" [\"a@example.org\", \"b@example.org\"] {\r\n  redirect \"c@example.org\";\r\n}\r\n"
+	for _, test := range []struct {
+		wrap     ListWrap
+		max      int
+		expected string
+	}{
+		{ListWrapAuto, 80, input},
+		{ListWrapAuto, 40, lines(`if header :is "from" [`, `  "a@example.org",`, `  "b@example.org"`, `] {`, `  redirect "c@example.org";`, `}`)},
+		{ListWrapAlways, 0, lines(`if header :is "from" [`, `  "a@example.org",`, `  "b@example.org"`, `] {`, `  redirect "c@example.org";`, `}`)},
+		{ListWrapNever, 40, input},
+	} {
+		opts := FormatOptions{IndentWidth: 2, MaxLineLength: test.max, ListWrap: test.wrap}
+		if output := format(t, input, opts); output != test.expected {
+			t.Errorf("%d/%d: expected\n%s\ngot\n%s", test.wrap, test.max, test.expected, output)
+		}
+	}
+}
+
+func TestParseFormatOptions(t *testing.T) {
+	opts, err := ParseFormatOptions("# style\r\nindent_style = tab\r\nindent_size=8\r\n; wrapping\r\nmax_line_length = off\r\nlist_wrap = always\r\nbrace_style = next_line\r\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := (FormatOptions{IndentWidth: 8, UseTabs: true, ListWrap: ListWrapAlways, BraceStyle: BraceNextLine}); opts != expected {
+		t.Errorf("expected %+v, got %+v", expected, opts)
+	}
+	if opts, err := ParseFormatOptions(""); err != nil || opts != DefaultFormatOptions() {
+		t.Errorf("expected the default options, got %+v, %v", opts, err)
+	}
+
+	for _, content := range []string{"indent_size = two", "indent_style = spaces", "max_line_length = -1", "tab_width = 4", "indent_size"} {
+		if _, err := ParseFormatOptions("list_wrap = auto\n" + content); err == nil || !strings.HasPrefix(err.Error(), "line 2: ") {
+			t.Errorf("%q: expected an error on line 2, got %v", content, err)
+		}
+	}
+}
+
+// randomTree is a random script for property-based tests; Generate builds its tree with the
+// constructors of the parser, so that it holds every kind of node Format handles
+type randomTree struct {
+	*Tree
+}
+
+func (randomTree) Generate(r *rand.Rand, size int) reflect.Value {
+	g := &treeGenerator{r: r, tree: newTree("random")}
+	g.tree.Source = NewSourceFile("random", "")
+	if r.Intn(2) == 0 {
+		require := g.tree.newRequire(0, g.pick("require", "REQUIRE"))
+		require.Capabilities = g.strings(1 + r.Intn(3))
+		g.tree.Root.append(require)
+	}
+	for _, node := range g.commands(size%8+1, 3) {
+		g.tree.Root.append(node)
+	}
+	return reflect.ValueOf(randomTree{g.tree})
+}
+
+type treeGenerator struct {
+	r    *rand.Rand
+	tree *Tree
+}
+
+func (g *treeGenerator) pick(choices ...string) string {
+	return choices[g.r.Intn(len(choices))]
+}
+
+// string returns a string that may need escapes or a multi-line string to be written
+func (g *treeGenerator) string() string {
+	var b strings.Builder
+	for n := g.r.Intn(8); n > 0; n-- {
+		b.WriteString(g.pick("a", "Z", "0", " ", "\t", "\"", "\\", ".", "#", "*", "/*", "\r\n", "\u00e9", "\U0001F600"))
+	}
+	return b.String()
+}
+
+func (g *treeGenerator) strings(n int) []string {
+	list := make([]string, n)
+	for i := range list {
+		list[i] = g.string()
+	}
+	return list
+}
+
+func (g *treeGenerator) arguments() []Argument {
+	var args []Argument
+	for n := g.r.Intn(4); n > 0; n-- {
+		switch g.r.Intn(4) {
+		case 0:
+			args = append(args, g.tree.newTag(0, g.pick(":is", ":CONTAINS", ":comparator", ":over")))
+		case 1:
+			text, value := g.pick("0", "100K", "7M", "1G"), uint64(0)
+			if n, err := parseNumber(text); err == nil {
+				value = n
+			}
+			args = append(args, g.tree.newNumber(0, text, value))
+		case 2:
+			args = append(args, g.tree.newString(0, g.string()))
+		default:
+			args = append(args, g.tree.newStringList(0, g.strings(1+g.r.Intn(3))))
+		}
+	}
+	return args
+}
+
+func (g *treeGenerator) test(depth int) *TestNode {
+	kind := g.r.Intn(5)
+	if depth == 0 {
+		kind = 0
+	}
+	switch kind {
+	case 0:
+		test := g.tree.newTest(0, g.pick("true", "False", "header", "exists", "size", "body"))
+		if !isKeyword(test.Name, TRUE) && !isKeyword(test.Name, FALSE) {
+			test.Arguments = g.arguments()
+		}
+		return test
+	case 1:
+		test := g.tree.newTest(0, g.pick("not", "NOT"))
+		test.Tests = []*TestNode{g.test(depth - 1)}
+		return test
+	case 2, 3:
+		test := g.tree.newTest(0, g.pick("allof", "anyof", "AnyOf"))
+		test.Tests = []*TestNode{}
+		for n := g.r.Intn(4); n > 0; n-- {
+			test.Tests = append(test.Tests, g.test(depth-1))
+		}
+		return test
+	default:
+		// a test of an extension with a test-list
+		test := g.tree.newTest(0, g.pick("ensure", "mime"))
+		test.Arguments = g.arguments()
+		for n := 1 + g.r.Intn(2); n > 0; n-- {
+			test.Tests = append(test.Tests, g.test(depth-1))
+		}
+		return test
+	}
+}
+
+func (g *treeGenerator) block(depth int) *CommandsNode {
+	block := g.tree.newCommands(0)
+	for _, node := range g.commands(g.r.Intn(3), depth-1) {
+		block.append(node)
+	}
+	return block
+}
+
+func (g *treeGenerator) commands(n, depth int) []Command {
+	var commands []Command
+	for ; n > 0; n-- {
+		kind := g.r.Intn(7)
+		if depth == 0 {
+			kind %= 4
+		}
+		switch kind {
+		case 0:
+			commands = append(commands, g.tree.newKeep(0, g.pick("keep", "Keep")))
+		case 1:
+			commands = append(commands, g.tree.newDiscard(0, "discard"))
+		case 2:
+			redirect := g.tree.newRedirect(0, "redirect")
+			redirect.Address = g.string()
+			commands = append(commands, redirect)
+		case 3:
+			command := g.tree.newGenericCommand(0, g.pick("fileinto", "addflag", "vacation"))
+			command.Arguments = g.arguments()
+			commands = append(commands, command)
+		case 4:
+			commands = append(commands, g.tree.newStop(0, "stop"))
+		case 5:
+			// a command of an extension with tests and a block
+			command := g.tree.newGenericCommand(0, g.pick("foreverypart", "ensure"))
+			command.Arguments = g.arguments()
+			switch g.r.Intn(3) {
+			case 0:
+				command.Tests = []*TestNode{g.test(depth)}
+			case 1:
+				command.Tests, command.TestList = []*TestNode{g.test(depth), g.test(depth)}, true
+			}
+			command.Block = g.block(depth)
+			commands = append(commands, command)
+		default:
+			n := g.tree.newIf(0, g.pick("if", "IF"))
+			n.Test, n.Body = g.test(depth), g.block(depth)
+			for i := g.r.Intn(3); i > 0; i-- {
+				elsif := g.tree.newElseIf(0, "elsif")
+				elsif.Test, elsif.Body = g.test(depth), g.block(depth)
+				n.ElseIfs = append(n.ElseIfs, elsif)
+			}
+			if g.r.Intn(2) == 0 {
+				n.Else = g.tree.newElse(0, "else")
+				n.Else.Body = g.block(depth)
+			}
+			commands = append(commands, n)
+		}
+	}
+	return commands
+}
+
+func TestFormatProperties(t *testing.T) {
+	styles := []FormatOptions{
+		DefaultFormatOptions(),
+		{UseTabs: true, MaxLineLength: 40, BraceStyle: BraceNextLine},
+		{IndentWidth: 4, MaxLineLength: 20, ListWrap: ListWrapAlways},
+		{IndentWidth: 1, ListWrap: ListWrapNever},
+	}
+	for _, opts := range styles {
+		opts := opts
+		// the formatted script parses to the same tree, and formatting that tree again gives
+		// the same script
+		property := func(script randomTree) bool {
+			output, err := Format(script.Tree, opts)
+			if err != nil {
+				t.Errorf("can't format: %v", err)
+				return false
+			}
+			tree, err := Parse("random", output, 0)
+			if err != nil {
+				t.Errorf("can't parse %q: %v", output, err)
+				return false
+			}
+			if Fingerprint(tree) != Fingerprint(script.Tree) {
+				t.Errorf("%q doesn't parse to the formatted tree", output)
+				return false
+			}
+			if again, err := Format(tree, opts); err != nil || again != output {
+				t.Errorf("formatting %q again gives %q (%v)", output, again, err)
+				return false
+			}
+			return true
+		}
+		if err := quick.Check(property, &quick.Config{MaxCount: 300, Rand: rand.New(rand.NewSource(1))}); err != nil {
+			t.Errorf("%+v: %v", opts, err)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import "strings"
+
+// DestinationClass classifies the destination of a redirect or notify action
+type DestinationClass int
+
+const (
+	DestinationInternal DestinationClass = iota // an address in one of the internal domains
+	DestinationExternal                         // an address in another domain, or a method without one (e.g. sms:)
+	DestinationUnknown                          // a target that can't be resolved statically, e.g. one holding variables
+)
+
+func (c DestinationClass) String() string {
+	switch c {
+	case DestinationInternal:
+		return "internal"
+	case DestinationExternal:
+		return "external"
+	default:
+		return "unknown"
+	}
+}
+
+// Destination is an address or URI a script sends mail or notifications to
+type Destination struct {
+	Pos     Pos    // the position of the action
+	Action  string // redirect or notify, lowercased
+	Target  string // the address of redirect or the method of notify, as written
+	Address string // the address of a redirect or a mailto recipient; empty for other methods
+	Domain  string // the normalized domain of Address (see NormalizeDomain)
+	Class   DestinationClass
+}
+
+// Destinations returns the destinations of the redirect and notify (RFC 5435) actions of a
+// script in lexical order, e.g. for compliance reports on the automatic forwarding of mail.
+// A destination is internal if its domain is one of the given domains or a subdomain of one.
+// A mailto method with several recipients (in its path or its to, cc or bcc fields) has a
+// destination per recipient. Commands of extensions, such as notify, are only parsed outside
+// strict mode (see GenericCommandNode).
+func Destinations(tree *Tree, internal []string) []Destination {
+	domains := make([]string, len(internal))
+	for i, domain := range internal {
+		domains[i] = NormalizeDomain(strings.TrimSuffix(domain, "."))
+	}
+	classify := func(d Destination) Destination {
+		_, domain, err := SplitAddress(d.Address)
+		if err != nil || strings.Contains(d.Address, "${") {
+			d.Class = DestinationUnknown
+		} else {
+			d.Domain = NormalizeDomain(domain)
+			d.Class = DestinationExternal
+			for _, internal := range domains {
+				if d.Domain == internal || strings.HasSuffix(d.Domain, "."+internal) {
+					d.Class = DestinationInternal
+					break
+				}
+			}
+		}
+		return d
+	}
+
+	var destinations []Destination
+	var walk func(commands []Command)
+	walk = func(commands []Command) {
+		for _, node := range commands {
+			switch n := node.(type) {
+			case *RedirectNode:
+				destinations = append(destinations, classify(Destination{Pos: n.Pos, Action: REDIRECT, Target: n.Address, Address: n.Address}))
+			case *IfNode:
+				for _, block := range n.Blocks() {
+					walk(block.Commands())
+				}
+			case *GenericCommandNode:
+				if n.Block != nil {
+					walk(n.Block.Commands())
+				}
+				// notify [:from string] [:importance <1|2|3>] [:options string-list] [:message string] <method: string>
+				if !isKeyword(n.Name, "notify") || len(n.Arguments) == 0 {
+					continue
+				}
+				method, ok := n.Arguments[len(n.Arguments)-1].(*StringNode)
+				if !ok {
+					continue
+				}
+				destinations = append(destinations, notifyDestinations(n.Pos, method.Text, classify)...)
+			}
+		}
+	}
+	walk(tree.Commands())
+	return destinations
+}
+
+// notifyDestinations returns the destinations of the method of a notify action
+func notifyDestinations(pos Pos, method string, classify func(Destination) Destination) []Destination {
+	d := Destination{Pos: pos, Action: "notify", Target: method}
+	if strings.Contains(method, "${") {
+		d.Class = DestinationUnknown
+		return []Destination{d}
+	}
+	uri, err := ParseMailto(method)
+	if err != nil {
+		// other methods (xmpp:, sms:, ...) deliver outside the mail system
+		d.Class = DestinationExternal
+		if !strings.Contains(method, ":") {
+			d.Class = DestinationUnknown
+		}
+		return []Destination{d}
+	}
+	recipients := append([]string{}, uri.To...)
+	for _, field := range uri.Headers {
+		if equalFoldASCII(field.Name, "cc") || equalFoldASCII(field.Name, "bcc") {
+			for _, addr := range strings.Split(field.Value, ",") {
+				recipients = append(recipients, strings.TrimSpace(addr))
+			}
+		}
+	}
+	if len(recipients) == 0 {
+		d.Class = DestinationUnknown
+		return []Destination{d}
+	}
+	var destinations []Destination
+	for _, addr := range recipients {
+		d.Address = addr
+		destinations = append(destinations, classify(d))
+	}
+	return destinations
+}
@@ -0,0 +1,71 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import "testing"
+
+func TestDestinations(t *testing.T) {
+	tree := parse(t, lines(
+		`require ["enotify", "variables"];`,
+		`redirect "Alice@Example.COM";`,
+		`if header :is "x-forward" "yes" {`,
+		`  redirect "bob@mail.example.com";`,
+		`  redirect "carol@gmail.com";`,
+		`} else {`,
+		`  notify :message "new mail" "mailto:dave@example.org?cc=erin@example.com";`,
+		`  notify "xmpp:frank@jabber.example";`,
+		`  redirect "${forward}";`,
+		`}`,
+	))
+	expected := []struct {
+		action, address string
+		class           DestinationClass
+	}{
+		{"redirect", "Alice@Example.COM", DestinationInternal},
+		{"redirect", "bob@mail.example.com", DestinationInternal},
+		{"redirect", "carol@gmail.com", DestinationExternal},
+		{"notify", "dave@example.org", DestinationExternal},
+		{"notify", "erin@example.com", DestinationInternal},
+		{"notify", "", DestinationExternal},
+		{"redirect", "${forward}", DestinationUnknown},
+	}
+
+	destinations := Destinations(tree, []string{"Example.com."})
+	if len(destinations) != len(expected) {
+		t.Fatalf("expected %d destinations, got %+v", len(expected), destinations)
+	}
+	for i, d := range destinations {
+		if e := expected[i]; d.Action != e.action || d.Address != e.address || d.Class != e.class {
+			t.Errorf("%d: expected %s %q %s, got %s %q %s", i, e.action, e.address, e.class, d.Action, d.Address, d.Class)
+		}
+	}
+	if d := destinations[0]; d.Domain != "example.com" || d.Pos != 35 {
+		t.Errorf("expected the normalized domain and the position of the redirect, got %+v", d)
+	}
+	if d := destinations[5]; d.Target != "xmpp:frank@jabber.example" {
+		t.Errorf("expected the method as target, got %+v", d)
+	}
+}
@@ -0,0 +1,402 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import (
+	"math"
+	"strings"
+)
+
+// maxTestMessageSize is the size of the largest message GenerateTestMessages pads a body to
+const maxTestMessageSize = 64 << 20
+
+// HeaderField is a header field of a message
+type HeaderField struct {
+	Name  string
+	Value string
+}
+
+// TestMessage is a synthetic message that runs a block of a script
+type TestMessage struct {
+	Pos    Pos    // position of the if, elsif or else command whose block the message runs
+	Name   string // identifier of that command as written in the script
+	Header []HeaderField
+	Body   string
+}
+
+// String returns the message in RFC 5322 format
+func (m TestMessage) String() string {
+	var b strings.Builder
+	for _, field := range m.Header {
+		b.WriteString(field.Name + ": " + field.Value + "\r\n")
+	}
+	b.WriteString("\r\n")
+	b.WriteString(m.Body)
+	return b.String()
+}
+
+// GenerateTestMessages generates a message for every block of a script, in lexical order, that
+// runs the block: the message satisfies the conditions leading to the block, fails the
+// conditions of the preceding blocks of the chain and doesn't run any earlier block that
+// ends with `stop`.
+//
+// Header values are chosen to satisfy the :is, :contains and :matches match types of header and
+// address tests with the default comparator, header presence to satisfy exists tests and the
+// body is padded to satisfy size tests. Blocks that depend on other tests, or on conditions the
+// generator can't satisfy at once, are skipped, so the result is a best-effort regression corpus.
+func GenerateTestMessages(tree *Tree) []TestMessage {
+	g := &generator{cache: NewMatchCache()}
+	g.commands(tree.Commands(), nil)
+	return g.messages
+}
+
+// constraint requires a test to evaluate to the given value
+type constraint struct {
+	test *TestNode
+	want bool
+}
+
+type generator struct {
+	messages []TestMessage
+	cache    *MatchCache // compiled :matches keys, shared by all blocks
+}
+
+func with(path []constraint, constraints ...constraint) []constraint {
+	return append(append([]constraint{}, path...), constraints...)
+}
+
+func (g *generator) commands(commands []Command, path []constraint) {
+	for _, node := range commands {
+		switch n := node.(type) {
+		case *StopNode:
+			return
+		case *IfNode:
+			g.chain(n, path)
+			path = with(path, passed(n)...)
+		case *GenericCommandNode:
+			// the rules in the block of an extension command, e.g. foreverypart, run
+			// under the same conditions as the command
+			if n.Block != nil {
+				g.commands(n.Block.Commands(), path)
+			}
+		}
+	}
+}
+
+func (g *generator) chain(n *IfNode, path []constraint) {
+	blocks := n.Blocks()
+	var earlier []constraint
+	for i, test := range n.Conditions() {
+		name, pos := n.Name, n.Pos
+		if i > 0 {
+			name, pos = n.ElseIfs[i-1].Name, n.ElseIfs[i-1].Pos
+		}
+		g.block(pos, name, blocks[i], with(with(path, earlier...), constraint{test, true}))
+		earlier = append(earlier, constraint{test, false})
+	}
+	if n.Else != nil {
+		g.block(n.Else.Pos, n.Else.Name, n.Else.Body, with(path, earlier...))
+	}
+}
+
+// passed returns the constraints for passing an if/elsif/else chain without running a block
+// that ends with `stop`
+func passed(n *IfNode) []constraint {
+	var constraints []constraint
+	conditions := n.Conditions()
+	for i, block := range n.Blocks() {
+		if !endsWithStop(block) {
+			continue
+		}
+		if i == len(conditions) {
+			// the else block doesn't run if any condition is true
+			constraints = append(constraints, constraint{anyOf(conditions), true})
+			continue
+		}
+		// an earlier condition is true or this one is false
+		alternatives := append([]*TestNode{}, conditions[:i]...)
+		alternatives = append(alternatives, &TestNode{NodeType: nodeTest, Pos: conditions[i].Pos, Name: NOT, Tests: conditions[i : i+1]})
+		constraints = append(constraints, constraint{anyOf(alternatives), true})
+	}
+	return constraints
+}
+
+func anyOf(tests []*TestNode) *TestNode {
+	return &TestNode{NodeType: nodeTest, Pos: tests[0].Pos, Name: ANYOF, Tests: tests}
+}
+
+func (g *generator) block(pos Pos, name string, block *CommandsNode, path []constraint) {
+	s := &synthesis{absent: map[string]bool{}, max: math.MaxUint64, cache: g.cache}
+	for _, c := range path {
+		if !s.satisfy(c.test, c.want) {
+			return
+		}
+	}
+	message, ok := s.message(pos, name)
+	if !ok {
+		return
+	}
+	g.messages = append(g.messages, message)
+	g.commands(block.Commands(), path)
+}
+
+// synthesis is a message under construction
+type synthesis struct {
+	header   []HeaderField
+	absent   map[string]bool // names, folded with asciiLower, of header fields that must not be added
+	min, max uint64          // bounds of the size of the message
+	cache    *MatchCache
+}
+
+func (s *synthesis) clone() *synthesis {
+	c := &synthesis{header: append([]HeaderField{}, s.header...), absent: map[string]bool{}, min: s.min, max: s.max, cache: s.cache}
+	for k, v := range s.absent {
+		c.absent[k] = v
+	}
+	return c
+}
+
+func (s *synthesis) message(pos Pos, name string) (TestMessage, bool) {
+	m := TestMessage{Pos: pos, Name: name, Header: s.header}
+	size := uint64(len(m.String()))
+	if size > s.max || s.min > maxTestMessageSize {
+		return m, false
+	}
+	if size < s.min {
+		m.Body = strings.Repeat("x", int(s.min-size))
+	}
+	return m, true
+}
+
+func (s *synthesis) field(name string) (HeaderField, bool) {
+	for _, field := range s.header {
+		if equalFoldASCII(field.Name, name) {
+			return field, true
+		}
+	}
+	return HeaderField{}, false
+}
+
+// try applies f to a copy of the message and keeps the copy if f succeeds
+func (s *synthesis) try(f func(c *synthesis) bool) bool {
+	c := s.clone()
+	if !f(c) {
+		return false
+	}
+	*s = *c
+	return true
+}
+
+// satisfy changes the message so that the test evaluates to want; the message is
+// left in an undefined state if that is not possible
+func (s *synthesis) satisfy(test *TestNode, want bool) bool {
+	switch strings.ToLower(test.Name) {
+	case TRUE:
+		return want
+	case FALSE:
+		return !want
+	case NOT:
+		return s.satisfy(test.Tests[0], !want)
+	case ALLOF, ANYOF:
+		// every member must evaluate to want for allof (true) and anyof (false), any member otherwise
+		if isKeyword(test.Name, ALLOF) == want {
+			for _, t := range test.Tests {
+				if !s.satisfy(t, want) {
+					return false
+				}
+			}
+			return true
+		}
+		for _, t := range test.Tests {
+			if s.try(func(c *synthesis) bool { return c.satisfy(t, want) }) {
+				return true
+			}
+		}
+		return false
+	case EXISTS:
+		return s.exists(test, want)
+	case SIZE:
+		return s.size(test, want)
+	case HEADER, ADDRESS:
+		return s.match(test, want)
+	default:
+		return false
+	}
+}
+
+// exists satisfies `exists <header-names: string-list>`, which is true if all headers are present
+func (s *synthesis) exists(test *TestNode, want bool) bool {
+	args, err := test.Bind()
+	if err != nil || len(test.Tags()) > 0 {
+		return false
+	}
+	for _, name := range args.StringList(0) {
+		_, present := s.field(name)
+		switch {
+		case want && !present:
+			if s.absent[asciiLower(name)] {
+				return false
+			}
+			s.header = append(s.header, HeaderField{name, "test"})
+		case !want && !present:
+			s.absent[asciiLower(name)] = true
+			return true
+		}
+	}
+	return want
+}
+
+func (s *synthesis) size(test *TestNode, want bool) bool {
+	over, limit, ok := sizeLimit(test)
+	if !ok {
+		return false
+	}
+	switch {
+	case over && want && limit == math.MaxUint64, !over && want && limit == 0:
+		return false
+	case over && want:
+		s.min = maxUint(s.min, limit+1)
+	case over:
+		s.max = minUint(s.max, limit)
+	case want:
+		s.max = minUint(s.max, limit-1)
+	default:
+		s.min = maxUint(s.min, limit)
+	}
+	return s.min <= s.max
+}
+
+func (s *synthesis) match(test *TestNode, want bool) bool {
+	m, ok := matchOf(test)
+	if !ok {
+		return false
+	}
+	part := test.AddressPart()
+	matches := func(value string) bool {
+		if m.part != "" {
+			extracted, err := ExtractAddressPart(value, part, false)
+			if err != nil {
+				return false
+			}
+			value = extracted
+		}
+		for _, key := range m.keys {
+			if s.cache.matchValue(m.typ, value, key) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if !want {
+		for _, name := range m.names {
+			if field, present := s.field(name); present {
+				if matches(field.Value) {
+					return false
+				}
+				continue
+			}
+			s.absent[asciiLower(name)] = true
+		}
+		return true
+	}
+
+	for _, name := range m.names {
+		if field, present := s.field(name); present && matches(field.Value) {
+			return true
+		}
+	}
+	for _, name := range m.names {
+		if _, present := s.field(name); present || s.absent[asciiLower(name)] {
+			continue
+		}
+		for _, key := range m.keys {
+			value := literal(m.typ, key)
+			switch {
+			case m.part == ":localpart":
+				value += "@example.com"
+			case m.part == ":domain":
+				value = "test@" + value
+			case m.part == ":all" && !strings.Contains(value, "@"):
+				value += "@example.com"
+			}
+			if matches(value) {
+				s.header = append(s.header, HeaderField{name, value})
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// literal returns a value that matches a key with the given match type
+func literal(typ, key string) string {
+	if typ != ":matches" {
+		return key
+	}
+	var b strings.Builder
+	escaped := false
+	for _, r := range key {
+		switch {
+		case escaped:
+			b.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '?':
+			b.WriteRune('x')
+		case r != '*':
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// matchValue compares a value to a key with the i;ascii-casemap comparator
+func (c *MatchCache) matchValue(typ, value, key string) bool {
+	if typ == ":matches" {
+		m, _ := c.Compile(key, ComparatorASCIICasemap)
+		return m.Match(value)
+	}
+	if typ == ":contains" {
+		return containsFoldASCII(value, key)
+	}
+	return equalFoldASCII(value, key)
+}
+
+func minUint(a, b uint64) uint64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxUint(a, b uint64) uint64 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,82 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGenerateTestMessages(t *testing.T) {
+	tree := parse(t, "if header :contains \"subject\" \"offer\" {\r\n  discard;\r\n  stop;\r\n"+
+		"} elsif address :domain :is \"from\" \"example.org\" {\r\n  keep;\r\n"+
+		"} else {\r\n  keep;\r\n}\r\n"+
+		"if allof (exists \"X-Spam\", not header :matches \"x-spam\" \"y*\") {\r\n  redirect \"spam@example.com\";\r\n}\r\n")
+
+	messages := GenerateTestMessages(tree)
+	var headers [][]HeaderField
+	for _, m := range messages {
+		headers = append(headers, m.Header)
+	}
+	expected := [][]HeaderField{
+		{{"subject", "offer"}},
+		{{"from", "test@example.org"}},
+		nil,
+		{{"X-Spam", "test"}},
+	}
+	if !reflect.DeepEqual(headers, expected) {
+		t.Fatalf("unexpected headers %v", headers)
+	}
+	if messages[1].Name != "elsif" || messages[2].Name != "else" {
+		t.Errorf("unexpected blocks %v", messages)
+	}
+	if messages[3].String() != "X-Spam: test\r\n\r\n" {
+		t.Errorf("unexpected message %q", messages[3].String())
+	}
+}
+
+func TestGenerateTestMessagesGenericBlock(t *testing.T) {
+	tree := parse(t, "require [\"foreverypart\", \"mime\"];\r\nforeverypart {\r\n  if exists \"X-Part\" {\r\n    discard;\r\n  }\r\n}\r\n")
+	messages := GenerateTestMessages(tree)
+	if len(messages) != 1 || !reflect.DeepEqual(messages[0].Header, []HeaderField{{"X-Part", "test"}}) {
+		t.Errorf("expected a message for the rule in the loop, got %v", messages)
+	}
+}
+
+func TestGenerateTestMessagesSize(t *testing.T) {
+	tree := parse(t, "if size :over 100 {\r\n  if size :under 200 {\r\n    discard;\r\n  }\r\n}\r\n"+
+		"if allof (size :over 100, size :under 50) {\r\n  discard;\r\n}\r\n")
+
+	messages := GenerateTestMessages(tree)
+	if len(messages) != 2 {
+		t.Fatalf("expected 2 messages, got %v", messages)
+	}
+	for _, m := range messages {
+		if size := len(m.String()); size != 101 {
+			t.Errorf("unexpected size %d", size)
+		}
+	}
+}
@@ -0,0 +1,265 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// WriteDOT renders the control flow of a script as a Graphviz digraph: conditions are
+// diamonds with true and false edges, actions are boxes and every path ends in a
+// single end node, either directly or through a stop node.
+func WriteDOT(w io.Writer, tree *Tree) error {
+	g := buildFlow(tree)
+	b := bufio.NewWriter(w)
+	fmt.Fprintf(b, "digraph \"%s\" {\n", dotEscape(tree.Name))
+	for _, n := range g.nodes {
+		fmt.Fprintf(b, "  n%d [label=\"%s\", shape=%s];\n", n.id, dotEscape(n.label), dotShapes[n.kind])
+	}
+	for _, e := range g.edges {
+		if e.label == "" {
+			fmt.Fprintf(b, "  n%d -> n%d;\n", e.from, e.to)
+		} else {
+			fmt.Fprintf(b, "  n%d -> n%d [label=\"%s\"];\n", e.from, e.to, e.label)
+		}
+	}
+	b.WriteString("}\n")
+	return b.Flush()
+}
+
+// WriteMermaid renders the control flow of a script as a Mermaid flowchart (see WriteDOT)
+func WriteMermaid(w io.Writer, tree *Tree) error {
+	g := buildFlow(tree)
+	b := bufio.NewWriter(w)
+	b.WriteString("flowchart TD\n")
+	for _, n := range g.nodes {
+		shape := mermaidShapes[n.kind]
+		fmt.Fprintf(b, "  n%d%s\"%s\"%s\n", n.id, shape[0], mermaidEscape(n.label), shape[1])
+	}
+	for _, e := range g.edges {
+		if e.label == "" {
+			fmt.Fprintf(b, "  n%d --> n%d\n", e.from, e.to)
+		} else {
+			fmt.Fprintf(b, "  n%d -->|%s| n%d\n", e.from, e.label, e.to)
+		}
+	}
+	return b.Flush()
+}
+
+type flowKind int
+
+const (
+	flowStart flowKind = iota
+	flowEnd
+	flowCondition
+	flowAction
+	flowStop
+)
+
+var dotShapes = map[flowKind]string{
+	flowStart:     "circle",
+	flowEnd:       "doublecircle",
+	flowCondition: "diamond",
+	flowAction:    "box",
+	flowStop:      "octagon",
+}
+
+var mermaidShapes = map[flowKind][2]string{
+	flowStart:     {"((", "))"},
+	flowEnd:       {"(((", ")))"},
+	flowCondition: {"{", "}"},
+	flowAction:    {"[", "]"},
+	flowStop:      {"{{", "}}"},
+}
+
+type flowNode struct {
+	id    int
+	kind  flowKind
+	label string
+}
+
+type flowEdge struct {
+	from, to int
+	label    string // true or false for the edges of a condition
+}
+
+// flowGraph is the control-flow graph of a script
+type flowGraph struct {
+	nodes []flowNode
+	edges []flowEdge
+}
+
+// exit is an edge leaving a node whose target is not known yet
+type exit struct {
+	from  int
+	label string
+}
+
+func buildFlow(tree *Tree) *flowGraph {
+	g := &flowGraph{}
+	start := g.node(flowStart, "start")
+	exits := g.commands(tree.Commands(), []exit{{from: start}})
+	end := g.node(flowEnd, "end")
+	g.connect(exits, end)
+	for _, n := range g.nodes {
+		if n.kind == flowStop {
+			g.connect([]exit{{from: n.id}}, end)
+		}
+	}
+	return g
+}
+
+func (g *flowGraph) node(kind flowKind, label string) int {
+	id := len(g.nodes)
+	g.nodes = append(g.nodes, flowNode{id, kind, label})
+	return id
+}
+
+func (g *flowGraph) connect(exits []exit, to int) {
+	for _, e := range exits {
+		g.edges = append(g.edges, flowEdge{e.from, to, e.label})
+	}
+}
+
+// commands adds a sequence of commands entered through exits and returns the exits of the
+// sequence; commands that can't be reached (after stop) are left out
+func (g *flowGraph) commands(commands []Command, exits []exit) []exit {
+	for _, node := range commands {
+		if len(exits) == 0 {
+			break
+		}
+		switch n := node.(type) {
+		case *StopNode:
+			g.connect(exits, g.node(flowStop, STOP))
+			exits = nil
+		case *KeepNode:
+			exits = g.action(exits, KEEP)
+		case *DiscardNode:
+			exits = g.action(exits, DISCARD)
+		case *RedirectNode:
+			exits = g.action(exits, REDIRECT+" "+quote(n.Address))
+		case *GenericCommandNode:
+			// the semantics of unknown commands are unknown: the block is drawn as run once
+			exits = g.action(exits, formatGenericCommand(n))
+			if n.Block != nil {
+				exits = g.commands(n.Block.Commands(), exits)
+			}
+		case *IfNode:
+			var out []exit
+			blocks := n.Blocks()
+			for i, test := range n.Conditions() {
+				condition := g.node(flowCondition, formatTest(test))
+				g.connect(exits, condition)
+				out = append(out, g.commands(blocks[i].Commands(), []exit{{condition, "true"}})...)
+				exits = []exit{{condition, "false"}}
+			}
+			if n.Else != nil {
+				exits = g.commands(n.Else.Body.Commands(), exits)
+			}
+			exits = append(out, exits...)
+		}
+	}
+	return exits
+}
+
+func (g *flowGraph) action(exits []exit, label string) []exit {
+	action := g.node(flowAction, label)
+	g.connect(exits, action)
+	return []exit{{from: action}}
+}
+
+// formatTest returns the source text of a test in a canonical notation: identifiers as
+// written in the script, numbers as written and strings quoted.
+func formatTest(test *TestNode) string {
+	var b strings.Builder
+	b.WriteString(test.Name)
+	formatArguments(&b, test.Arguments)
+	switch {
+	case isKeyword(test.Name, NOT):
+		b.WriteString(" " + formatTest(test.Tests[0]))
+	case len(test.Tests) > 0 || isKeyword(test.Name, ALLOF) || isKeyword(test.Name, ANYOF):
+		formatTests(&b, test.Tests)
+	}
+	return b.String()
+}
+
+// formatGenericCommand returns the source text of an unknown command without its block
+func formatGenericCommand(n *GenericCommandNode) string {
+	var b strings.Builder
+	b.WriteString(n.Name)
+	formatArguments(&b, n.Arguments)
+	switch {
+	case n.TestList:
+		formatTests(&b, n.Tests)
+	case len(n.Tests) > 0:
+		b.WriteString(" " + formatTest(n.Tests[0]))
+	}
+	return b.String()
+}
+
+func formatTests(b *strings.Builder, tests []*TestNode) {
+	formatted := make([]string, len(tests))
+	for i, t := range tests {
+		formatted[i] = formatTest(t)
+	}
+	b.WriteString(" (" + strings.Join(formatted, ", ") + ")")
+}
+
+func formatArguments(b *strings.Builder, args []Argument) {
+	for _, arg := range args {
+		b.WriteByte(' ')
+		switch a := arg.(type) {
+		case *TagNode:
+			b.WriteString(a.Name)
+		case *NumberNode:
+			b.WriteString(a.Text)
+		case *StringNode:
+			b.WriteString(quote(a.Text))
+		case *StringListNode:
+			quoted := make([]string, len(a.Strings))
+			for i, s := range a.Strings {
+				quoted[i] = quote(s)
+			}
+			b.WriteString("[" + strings.Join(quoted, ", ") + "]")
+		}
+	}
+}
+
+// quote quotes a string for display; unlike QuoteString it never fails
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+func dotEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`).Replace(s)
+}
+
+func mermaidEscape(s string) string {
+	return strings.NewReplacer(`"`, "#quot;", "\r\n", "<br>", "\n", "<br>", "\r", "<br>").Replace(s)
+}
@@ -0,0 +1,100 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import (
+	"strings"
+	"testing"
+)
+
+const graphScript = "if header :contains \"Subject\" [\"offer\", \"\\\"win\\\"\"] {\r\n  discard;\r\n  stop;\r\n" +
+	"} elsif not size :over 1M {\r\n  redirect \"joe@example.com\";\r\n}\r\nkeep;\r\n"
+
+func TestWriteDOT(t *testing.T) {
+	var b strings.Builder
+	if err := WriteDOT(&b, parse(t, graphScript)); err != nil {
+		t.Fatal(err)
+	}
+	expected := `digraph "test" {
+  n0 [label="start", shape=circle];
+  n1 [label="header :contains \"Subject\" [\"offer\", \"\\\"win\\\"\"]", shape=diamond];
+  n2 [label="discard", shape=box];
+  n3 [label="stop", shape=octagon];
+  n4 [label="not size :over 1M", shape=diamond];
+  n5 [label="redirect \"joe@example.com\"", shape=box];
+  n6 [label="keep", shape=box];
+  n7 [label="end", shape=doublecircle];
+  n0 -> n1;
+  n1 -> n2 [label="true"];
+  n2 -> n3;
+  n1 -> n4 [label="false"];
+  n4 -> n5 [label="true"];
+  n5 -> n6;
+  n4 -> n6 [label="false"];
+  n6 -> n7;
+  n3 -> n7;
+}
+`
+	if b.String() != expected {
+		t.Errorf("unexpected graph\n%s", b.String())
+	}
+}
+
+func TestWriteMermaid(t *testing.T) {
+	var b strings.Builder
+	if err := WriteMermaid(&b, parse(t, graphScript)); err != nil {
+		t.Fatal(err)
+	}
+	expected := `flowchart TD
+  n0(("start"))
+  n1{"header :contains #quot;Subject#quot; [#quot;offer#quot;, #quot;\#quot;win\#quot;#quot;]"}
+  n2["discard"]
+  n3{{"stop"}}
+  n4{"not size :over 1M"}
+  n5["redirect #quot;joe@example.com#quot;"]
+  n6["keep"]
+  n7((("end")))
+  n0 --> n1
+  n1 -->|true| n2
+  n2 --> n3
+  n1 -->|false| n4
+  n4 -->|true| n5
+  n5 --> n6
+  n4 -->|false| n6
+  n6 --> n7
+  n3 --> n7
+`
+	if b.String() != expected {
+		t.Errorf("unexpected graph\n%s", b.String())
+	}
+}
+
+func TestFormatTest(t *testing.T) {
+	tree := parse(t, "if AllOf (exists \"a\", anyof ()) {\r\n}\r\n")
+	if s := formatTest(tree.Commands()[0].(*IfNode).Test); s != "AllOf (exists \"a\", anyof ())" {
+		t.Errorf("unexpected test %s", s)
+	}
+}
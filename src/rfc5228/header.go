@@ -0,0 +1,68 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+// HeaderIndex is a case-insensitive multimap of the header fields of a message, built once per
+// message so that the tests of one or more scripts evaluated for the same delivery look fields
+// up directly rather than scanning the header block. An index is read-only after it is built
+// and can be shared between goroutines.
+type HeaderIndex struct {
+	values map[string][]string // values by name folded with asciiLower, in the order of the header
+	names  []string            // names as first written, in the order of first occurrence
+}
+
+// NewHeaderIndex indexes header fields
+func NewHeaderIndex(header []HeaderField) *HeaderIndex {
+	x := &HeaderIndex{values: make(map[string][]string, len(header))}
+	for _, field := range header {
+		key := asciiLower(field.Name)
+		if _, ok := x.values[key]; !ok {
+			x.names = append(x.names, field.Name)
+		}
+		x.values[key] = append(x.values[key], field.Value)
+	}
+	return x
+}
+
+// Index returns the header index of the message
+func (m TestMessage) Index() *HeaderIndex {
+	return NewHeaderIndex(m.Header)
+}
+
+// Values returns the values of all fields with a (case-insensitive) name in the order of the header
+func (x *HeaderIndex) Values(name string) []string {
+	return x.values[asciiLower(name)]
+}
+
+// Has reports whether the header has a field with a (case-insensitive) name
+func (x *HeaderIndex) Has(name string) bool {
+	return len(x.values[asciiLower(name)]) > 0
+}
+
+// Names returns the names of the fields as first written, in the order of first occurrence
+func (x *HeaderIndex) Names() []string {
+	return append([]string{}, x.names...)
+}
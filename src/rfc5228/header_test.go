@@ -0,0 +1,49 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHeaderIndex(t *testing.T) {
+	x := TestMessage{Header: []HeaderField{
+		{"Received", "from a"},
+		{"Subject", "hello"},
+		{"received", "from b"},
+	}}.Index()
+
+	if values := x.Values("RECEIVED"); !reflect.DeepEqual(values, []string{"from a", "from b"}) {
+		t.Errorf("unexpected values %v", values)
+	}
+	if !x.Has("subject") || x.Has("to") || x.Values("to") != nil {
+		t.Errorf("unexpected presence")
+	}
+	if names := x.Names(); !reflect.DeepEqual(names, []string{"Received", "Subject"}) {
+		t.Errorf("unexpected names %v", names)
+	}
+}
@@ -0,0 +1,260 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"unicode/utf8"
+)
+
+// Punycode parameters (RFC 3492, section 5)
+const (
+	punyBase        = 36
+	punyTMin        = 1
+	punyTMax        = 26
+	punySkew        = 38
+	punyDamp        = 700
+	punyInitialBias = 72
+	punyInitialN    = 128
+)
+
+const acePrefix = "xn--"
+
+// DomainToASCII converts a domain to its ASCII form: labels holding non-ASCII
+// characters (U-labels) are converted to A-labels (`xn--`), all labels are lower-cased.
+//
+// This is the IDNA conversion only; the IDNA2008 mapping and validity rules,
+// other than lower-casing, are not applied.
+func DomainToASCII(domain string) (string, error) {
+	labels := strings.Split(domain, ".")
+	for i, label := range labels {
+		label = strings.ToLower(label)
+		if isASCII(label) {
+			labels[i] = label
+			continue
+		}
+		encoded, err := punyEncode(label)
+		if err != nil {
+			return "", fmt.Errorf("invalid label %q: %w", label, err)
+		}
+		labels[i] = acePrefix + encoded
+	}
+	return strings.Join(labels, "."), nil
+}
+
+// DomainToUnicode converts a domain to its Unicode form: A-labels (`xn--`) are
+// converted to U-labels, all labels are lower-cased.
+func DomainToUnicode(domain string) (string, error) {
+	labels := strings.Split(domain, ".")
+	for i, label := range labels {
+		label = strings.ToLower(label)
+		if strings.HasPrefix(label, acePrefix) {
+			decoded, err := punyDecode(label[len(acePrefix):])
+			if err != nil {
+				return "", fmt.Errorf("invalid label %q: %w", label, err)
+			}
+			label = decoded
+		}
+		labels[i] = label
+	}
+	return strings.Join(labels, "."), nil
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+func punyAdapt(delta, points int, first bool) int {
+	if first {
+		delta /= punyDamp
+	} else {
+		delta /= 2
+	}
+	delta += delta / points
+
+	k := 0
+	for delta > ((punyBase-punyTMin)*punyTMax)/2 {
+		delta /= punyBase - punyTMin
+		k += punyBase
+	}
+	return k + (punyBase-punyTMin+1)*delta/(delta+punySkew)
+}
+
+func punyThreshold(k, bias int) int {
+	switch {
+	case k <= bias:
+		return punyTMin
+	case k >= bias+punyTMax:
+		return punyTMax
+	default:
+		return k - bias
+	}
+}
+
+func punyEncodeDigit(d int) byte {
+	if d < 26 {
+		return byte('a' + d)
+	}
+	return byte('0' + d - 26)
+}
+
+func punyDecodeDigit(c byte) (int, bool) {
+	switch {
+	case c >= 'a' && c <= 'z':
+		return int(c - 'a'), true
+	case c >= 'A' && c <= 'Z':
+		return int(c - 'A'), true
+	case c >= '0' && c <= '9':
+		return int(c-'0') + 26, true
+	}
+	return 0, false
+}
+
+// punyEncode encodes a label with Punycode (RFC 3492, section 6.3)
+func punyEncode(label string) (string, error) {
+	if !utf8.ValidString(label) {
+		return "", fmt.Errorf("invalid UTF-8")
+	}
+	input := []rune(label)
+
+	var b strings.Builder
+	for _, r := range input {
+		if r < utf8.RuneSelf {
+			b.WriteByte(byte(r))
+		}
+	}
+	basic := b.Len()
+	if basic > 0 {
+		b.WriteByte('-')
+	}
+
+	n, delta, bias := punyInitialN, 0, punyInitialBias
+	for h := basic; h < len(input); {
+		m := math.MaxInt32
+		for _, r := range input {
+			if int(r) >= n && int(r) < m {
+				m = int(r)
+			}
+		}
+		if (m - n) > (math.MaxInt32-delta)/(h+1) {
+			return "", fmt.Errorf("overflow")
+		}
+		delta += (m - n) * (h + 1)
+		n = m
+
+		for _, r := range input {
+			if int(r) < n {
+				delta++
+			}
+			if int(r) != n {
+				continue
+			}
+			q := delta
+			for k := punyBase; ; k += punyBase {
+				t := punyThreshold(k, bias)
+				if q < t {
+					break
+				}
+				b.WriteByte(punyEncodeDigit(t + (q-t)%(punyBase-t)))
+				q = (q - t) / (punyBase - t)
+			}
+			b.WriteByte(punyEncodeDigit(q))
+			bias = punyAdapt(delta, h+1, h == basic)
+			delta = 0
+			h++
+		}
+		delta++
+		n++
+	}
+	return b.String(), nil
+}
+
+// punyDecode decodes a Punycode label (RFC 3492, section 6.2)
+func punyDecode(encoded string) (string, error) {
+	var output []rune
+	pos := 0
+	if b := strings.LastIndexByte(encoded, '-'); b > 0 {
+		for i := 0; i < b; i++ {
+			if encoded[i] >= utf8.RuneSelf {
+				return "", fmt.Errorf("non-basic code point")
+			}
+			output = append(output, rune(encoded[i]))
+		}
+		pos = b + 1
+	}
+
+	n, i, bias := punyInitialN, 0, punyInitialBias
+	for pos < len(encoded) {
+		oldi, w := i, 1
+		for k := punyBase; ; k += punyBase {
+			if pos >= len(encoded) {
+				return "", fmt.Errorf("truncated input")
+			}
+			digit, ok := punyDecodeDigit(encoded[pos])
+			pos++
+			if !ok {
+				return "", fmt.Errorf("invalid digit")
+			}
+			if digit > (math.MaxInt32-i)/w {
+				return "", fmt.Errorf("overflow")
+			}
+			i += digit * w
+
+			t := punyThreshold(k, bias)
+			if digit < t {
+				break
+			}
+			if w > math.MaxInt32/(punyBase-t) {
+				return "", fmt.Errorf("overflow")
+			}
+			w *= punyBase - t
+		}
+
+		points := len(output) + 1
+		bias = punyAdapt(i-oldi, points, oldi == 0)
+		if i/points > math.MaxInt32-n {
+			return "", fmt.Errorf("overflow")
+		}
+		n += i / points
+		i %= points
+		if n > utf8.MaxRune || (n >= 0xD800 && n <= 0xDFFF) {
+			return "", fmt.Errorf("invalid code point")
+		}
+
+		output = append(output, 0)
+		copy(output[i+1:], output[i:])
+		output[i] = rune(n)
+		i++
+	}
+	return string(output), nil
+}
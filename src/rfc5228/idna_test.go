@@ -0,0 +1,70 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import "testing"
+
+func TestPunycode(t *testing.T) {
+	// samples from RFC 3492, section 7.1
+	for decoded, encoded := range map[string]string{
+		"bücher":                   "bcher-kva",
+		"münchen":                  "mnchen-3ya",
+		"3年B組金八先生":                 "3B-ww4c5e180e575a65lsy2b",
+		"安室奈美恵-with-SUPER-MONKEYS": "-with-SUPER-MONKEYS-pc58ag80a8qai00g7n9n",
+		"ليهمابتكلموشعربي؟":        "egbpdaj6bu4bxfgehfvwxn",
+	} {
+		e, err := punyEncode(decoded)
+		if err != nil || e != encoded {
+			t.Errorf("%s: expected %s, got %s (%v)", decoded, encoded, e, err)
+		}
+		d, err := punyDecode(encoded)
+		if err != nil || d != decoded {
+			t.Errorf("%s: expected %s, got %s (%v)", encoded, decoded, d, err)
+		}
+	}
+
+	for _, invalid := range []string{"bcher-kv!", "99999999999", "b!"} {
+		if _, err := punyDecode(invalid); err == nil {
+			t.Errorf("%s: expected error", invalid)
+		}
+	}
+}
+
+func TestDomainConversion(t *testing.T) {
+	ascii, err := DomainToASCII("Bücher.Example")
+	if err != nil || ascii != "xn--bcher-kva.example" {
+		t.Errorf("unexpected ASCII domain %s (%v)", ascii, err)
+	}
+
+	unicode, err := DomainToUnicode("XN--BCHER-KVA.example")
+	if err != nil || unicode != "bücher.example" {
+		t.Errorf("unexpected Unicode domain %s (%v)", unicode, err)
+	}
+
+	if _, err := DomainToUnicode("xn--bcher-kv!.example"); err == nil {
+		t.Errorf("expected error for invalid A-label")
+	}
+}
@@ -0,0 +1,258 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Edit replaces the bytes [Start, End) of a script with Text
+type Edit struct {
+	Start, End Pos
+	Text       string
+}
+
+// Apply returns the input with the edit applied
+func (e Edit) Apply(input string) (string, error) {
+	if e.Start < 0 || e.Start > e.End || int(e.End) > len(input) {
+		return "", fmt.Errorf("edit [%d, %d) out of range for input of %d bytes", e.Start, e.End, len(input))
+	}
+	return input[:e.Start] + e.Text + input[e.End:], nil
+}
+
+// Reparse returns the tree of a script after an edit, given the tree of the script before the
+// edit. Only the top-level commands touched by the edit, including the command preceding it
+// (an edit may extend an if with elsif or else) are lexed and parsed again; the other
+// commands are reused, with their positions moved if the edit changed the length of the
+// script. If the affected region can't be parsed on its own the whole script is parsed, so
+// the result, including errors, is always that of Parse on the edited script.
+func Reparse(tree *Tree, input string, edit Edit, mode Mode, opts ...Option) (*Tree, error) {
+	edited, err := edit.Apply(input)
+	if err != nil {
+		return nil, err
+	}
+
+	commands := tree.Commands()
+	if len(commands) == 0 {
+		return Parse(tree.Name, edited, mode, opts...)
+	}
+
+	// the region of the old script that is parsed again: from the start of the last command
+	// starting before the edit up to the start of the first command starting after its end
+	first, last := 0, -1
+	for i, node := range commands {
+		if node.Position() < edit.Start {
+			first = i
+		}
+		if node.Position() <= edit.End {
+			last = i
+		}
+	}
+	start := Pos(0)
+	if first > 0 || commands[0].Position() < edit.Start {
+		start = commands[first].Position()
+	} else {
+		first = 0
+	}
+	end := Pos(len(input))
+	if last+1 < len(commands) {
+		end = commands[last+1].Position()
+	}
+	delta := Pos(len(edit.Text)) - (edit.End - edit.Start)
+
+	// lex the region in place, so that positions are those of the edited script
+	l := lex(tree.Name, edited[:end+delta])
+	l.seek(start)
+	parser, err := newParser(l, opts...)
+	if err != nil {
+		return Parse(tree.Name, edited, mode, opts...)
+	}
+	parser.Mode = mode
+	for _, node := range commands[:first] {
+		if _, ok := node.(*RequireNode); !ok {
+			parser.commands = true
+		}
+	}
+	region, err := parser.Parse()
+	if err != nil || !parser.consumed(edited[:end+delta], start) {
+		return Parse(tree.Name, edited, mode, opts...)
+	}
+
+	result := newTree(tree.Name)
+	result.Source = NewSourceFile(tree.Name, edited)
+	for _, node := range commands[:first] {
+		result.Root.append(node)
+	}
+	for _, node := range region.Commands() {
+		result.Root.append(node)
+	}
+	for _, node := range commands[last+1:] {
+		result.Root.append(result.moveCommand(node, delta))
+	}
+
+	// comments before the region are kept, those after it are moved
+	for _, comment := range tree.Comments {
+		if comment.Pos < start {
+			result.Comments = append(result.Comments, result.newComment(comment.Pos, comment.Raw))
+		}
+	}
+	result.Comments = append(result.Comments, region.Comments...)
+	for _, comment := range tree.Comments {
+		if comment.Pos >= end {
+			result.Comments = append(result.Comments, result.newComment(comment.Pos+delta, comment.Raw))
+		}
+	}
+	result.attachComments()
+
+	// in strict mode require must still come before any other command
+	if mode&ModeStrict != 0 && parser.commands {
+		for _, node := range commands[last+1:] {
+			if _, ok := node.(*RequireNode); ok {
+				return Parse(tree.Name, edited, mode, opts...)
+			}
+		}
+	}
+	return result, nil
+}
+
+// moveCommand returns a copy of a command with all positions moved by delta;
+// the command itself is returned if delta is zero
+func (t *Tree) moveCommand(node Command, delta Pos) Command {
+	if delta == 0 {
+		return node
+	}
+	switch n := node.(type) {
+	case *RequireNode:
+		c := *n
+		c.Pos += delta
+		return &c
+	case *StopNode:
+		c := *n
+		c.Pos += delta
+		return &c
+	case *KeepNode:
+		c := *n
+		c.Pos += delta
+		return &c
+	case *DiscardNode:
+		c := *n
+		c.Pos += delta
+		return &c
+	case *RedirectNode:
+		c := *n
+		c.Pos += delta
+		return &c
+	case *GenericCommandNode:
+		c := *n
+		c.Pos += delta
+		c.Arguments = t.moveArguments(n.Arguments, delta)
+		c.Tests = make([]*TestNode, len(n.Tests))
+		for i, test := range n.Tests {
+			c.Tests[i] = t.moveTest(test, delta)
+		}
+		if n.Block != nil {
+			c.Block = t.moveCommands(n.Block, delta)
+		}
+		return &c
+	case *IfNode:
+		c := *n
+		c.Pos += delta
+		c.Test = t.moveTest(n.Test, delta)
+		c.Body = t.moveCommands(n.Body, delta)
+		c.ElseIfs = make([]*ElseIfNode, len(n.ElseIfs))
+		for i, elsif := range n.ElseIfs {
+			e := *elsif
+			e.Pos += delta
+			e.Test = t.moveTest(elsif.Test, delta)
+			e.Body = t.moveCommands(elsif.Body, delta)
+			c.ElseIfs[i] = &e
+		}
+		if n.Else != nil {
+			e := *n.Else
+			e.Pos += delta
+			e.Body = t.moveCommands(n.Else.Body, delta)
+			c.Else = &e
+		}
+		return &c
+	default:
+		return node
+	}
+}
+
+func (t *Tree) moveCommands(block *CommandsNode, delta Pos) *CommandsNode {
+	c := t.newCommands(block.Pos + delta)
+	for _, node := range block.Commands() {
+		c.append(t.moveCommand(node, delta))
+	}
+	return c
+}
+
+func (t *Tree) moveTest(test *TestNode, delta Pos) *TestNode {
+	c := t.newTest(test.Pos+delta, test.Name)
+	c.Arguments = t.moveArguments(test.Arguments, delta)
+	for _, nested := range test.Tests {
+		c.Tests = append(c.Tests, t.moveTest(nested, delta))
+	}
+	return c
+}
+
+func (t *Tree) moveArguments(args []Argument, delta Pos) []Argument {
+	var moved []Argument
+	for _, arg := range args {
+		switch a := arg.(type) {
+		case *TagNode:
+			moved = append(moved, t.newTag(a.Pos+delta, a.Name))
+		case *NumberNode:
+			moved = append(moved, t.newNumber(a.Pos+delta, a.Text, a.Value))
+		case *StringNode:
+			moved = append(moved, t.newString(a.Pos+delta, a.Text))
+		case *StringListNode:
+			moved = append(moved, t.newStringList(a.Pos+delta, a.Strings))
+		}
+	}
+	return moved
+}
+
+// consumed reports whether the tokens of the parser, scanned from position start, cover
+// all of the input but trailing whitespace and whether the scan ended in between tokens:
+// a hash comment ended by the end of the input would continue into the text after the region
+func (p *Parser) consumed(input string, start Pos) bool {
+	for _, token := range p.comments {
+		if input[token.pos] == '#' && int(token.end) == len(input) {
+			return false
+		}
+	}
+	end := start
+	for _, tokens := range [][]item{p.tokens, p.comments} {
+		for _, token := range tokens {
+			if token.end > end {
+				end = token.end
+			}
+		}
+	}
+	return strings.Trim(input[end:], " \t\r\n") == ""
+}
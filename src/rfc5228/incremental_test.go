@@ -0,0 +1,90 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const incrementalScript = "require \"fileinto\";\r\n# rules\r\nif header :is \"subject\" \"a\" {\r\n  discard;\r\n} elsif size :over 1M {\r\n  keep;\r\n}\r\n" +
+	"redirect \"joe@example.com\";\r\nif true { stop; }\r\nkeep;\r\n"
+
+func TestReparse(t *testing.T) {
+	for _, mode := range []Mode{0, ModeStrict} {
+		tree, err := Parse("test", incrementalScript, mode)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for start := 0; start <= len(incrementalScript); start++ {
+			for _, text := range []string{"", " ", "keep;\r\n", "else { }\r\n", "elsif false {}", "}", "\"", "/*", "# x\r\n", "#", "require \"x\";"} {
+				for length := 0; length <= 3 && start+length <= len(incrementalScript); length++ {
+					edit := Edit{Start: Pos(start), End: Pos(start + length), Text: text}
+					compareReparse(t, tree, edit, mode)
+				}
+			}
+		}
+	}
+}
+
+func compareReparse(t *testing.T, tree *Tree, edit Edit, mode Mode) {
+	t.Helper()
+	edited, _ := edit.Apply(incrementalScript)
+	expected, expectedErr := Parse("test", edited, mode)
+	actual, err := Reparse(tree, incrementalScript, edit, mode)
+	if (err == nil) != (expectedErr == nil) || err != nil && err.Error() != expectedErr.Error() {
+		t.Fatalf("%+v: expected error %v, got %v", edit, expectedErr, err)
+	}
+	if err != nil {
+		return
+	}
+	a, _ := json.Marshal(actual)
+	e, _ := json.Marshal(expected)
+	if string(a) != string(e) {
+		t.Fatalf("%+v: expected\n%s\ngot\n%s", edit, e, a)
+	}
+}
+
+func TestReparseReusesCommands(t *testing.T) {
+	tree := parse(t, incrementalScript)
+	pos := Pos(strings.Index(incrementalScript, "} elsif"))
+	edit := Edit{Start: pos, End: pos, Text: "  stop;\r\n"} // inside the first if block
+	result, err := Reparse(tree, incrementalScript, edit, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Commands()[0] != tree.Commands()[0] {
+		t.Error("expected the command before the edit to be reused")
+	}
+	if result.Commands()[2].Position() != tree.Commands()[2].Position()+9 {
+		t.Error("expected the command after the edit to be moved")
+	}
+
+	if _, err := Reparse(tree, incrementalScript, Edit{Start: 5, End: 2}, 0); err == nil {
+		t.Error("expected an error for an invalid edit")
+	}
+}
@@ -0,0 +1,77 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import "strings"
+
+// interaction is a rule for a capability that only works with, or not at all with, other capabilities
+type interaction struct {
+	requires    []string // capabilities that must all be required as well
+	requiresAny []string // capabilities of which at least one must be required as well
+	conflicts   []string // capabilities that can't be required together with it
+}
+
+// interactions are the rules of capabilities that interact with others, decided by the require
+// commands; the capabilities needed by the commands and tags a script uses (e.g. imap4flags for
+// :flags of fileinto, variables for global) are reported by Analyze
+var interactions = map[string]interaction{
+	"extracttext":      {requires: []string{"foreverypart", "variables"}}, // RFC 5703, section 7
+	"fcc":              {requiresAny: []string{"vacation", "enotify"}},    // RFC 8580, section 3
+	"vacation-seconds": {requires: []string{"vacation"}},                  // RFC 6131, section 2
+	"imap4flags":       {conflicts: []string{"imapflags"}},                // imapflags is the draft of RFC 5232
+	"enotify":          {conflicts: []string{"notify"}},                   // notify is the draft of RFC 5435
+}
+
+// interactions warns about the capabilities of a require command whose rule isn't met by the
+// capabilities required by the script; a conflict is reported at the capability with the rule
+func (v *validator) interactions(require *RequireNode) {
+	for _, capability := range require.Capabilities {
+		rule, ok := interactions[capability]
+		if !ok {
+			continue
+		}
+		for _, dependency := range rule.requires {
+			if _, ok := v.required[dependency]; !ok {
+				v.warnf(require.Pos, CodeCapabilityRequires, capability, dependency)
+			}
+		}
+		if len(rule.requiresAny) > 0 {
+			found := false
+			for _, dependency := range rule.requiresAny {
+				_, ok := v.required[dependency]
+				found = found || ok
+			}
+			if !found {
+				v.warnf(require.Pos, CodeCapabilityRequiresAny, capability, "`"+strings.Join(rule.requiresAny, "`, `")+"`")
+			}
+		}
+		for _, conflict := range rule.conflicts {
+			if pos, ok := v.required[conflict]; ok {
+				v.relatedf(require.Pos, []Pos{pos}, CodeCapabilityConflict, capability, conflict, v.source.Position(pos))
+			}
+		}
+	}
+}
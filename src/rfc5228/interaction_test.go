@@ -0,0 +1,59 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import "testing"
+
+func TestValidateInteractions(t *testing.T) {
+	tree := parse(t, "require [\"fcc\", \"extracttext\", \"variables\", \"imapflags\"];\r\n"+
+		"require [\"vacation-seconds\", \"imap4flags\"];\r\n")
+	warnings := Validate(tree)
+	expected := []struct {
+		code    Code
+		message string
+	}{
+		{CodeCapabilityRequiresAny, "capability `fcc` requires one of the capabilities `vacation`, `enotify`"},
+		{CodeCapabilityRequires, "capability `extracttext` requires capability `foreverypart`"},
+		{CodeCapabilityRequires, "capability `vacation-seconds` requires capability `vacation`"},
+		{CodeCapabilityConflict, "capability `imap4flags` conflicts with capability `imapflags` at 1:1"},
+	}
+	if len(warnings) != len(expected) {
+		t.Fatalf("unexpected warnings %v", warnings)
+	}
+	for i, e := range expected {
+		if warnings[i].Code != e.code || warnings[i].Message != e.message {
+			t.Errorf("expected %s %q, got %v", e.code, e.message, warnings[i])
+		}
+	}
+	if related := warnings[3].Related; len(related) != 1 || related[0] != 0 || warnings[3].Line != 2 {
+		t.Errorf("unexpected conflict %+v", warnings[3])
+	}
+
+	// a dependency may be required by a later require
+	if warnings := Validate(parse(t, "require \"fcc\";\r\nrequire \"enotify\";\r\n")); len(warnings) != 0 {
+		t.Errorf("unexpected warnings %v", warnings)
+	}
+}
@@ -0,0 +1,439 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// JSONSchema is the version of the JSON encoding of trees written by MarshalJSON, in its
+// `schema` field. It is raised when the encoding changes; UnmarshalJSON migrates the trees of
+// earlier versions, so stored trees stay readable.
+//
+// Version 1 added the `schema` field and the `testList` field of commands of extensions.
+const JSONSchema = 1
+
+// jsonMigrations upgrade a decoded tree from a schema version to the next: jsonMigrations[v]
+// turns version v into version v+1
+var jsonMigrations = []func(tree map[string]any){
+	// 0: trees without a schema field; commands of extensions didn't record whether their
+	// tests were a test-list, which is assumed for more than one test
+	func(tree map[string]any) {
+		var walk func(commands any)
+		walk = func(commands any) {
+			list, _ := commands.([]any)
+			for _, c := range list {
+				command, _ := c.(map[string]any)
+				if command == nil {
+					continue
+				}
+				if tests, _ := command["tests"].([]any); command["type"] == "command" && len(tests) > 1 {
+					command["testList"] = true
+				}
+				walk(command["body"])
+				if elsifs, _ := command["elsif"].([]any); elsifs != nil {
+					for _, e := range elsifs {
+						if elsif, _ := e.(map[string]any); elsif != nil {
+							walk(elsif["body"])
+						}
+					}
+				}
+				if els, _ := command["else"].(map[string]any); els != nil {
+					walk(els["body"])
+				}
+			}
+		}
+		walk(tree["commands"])
+	},
+}
+
+// MarshalJSON encodes the tree as a JSON object holding the schema version (see JSONSchema),
+// the name of the script and its top-level commands. Every node is encoded as an object with
+// a `type` and a `pos` field; identifiers are encoded as written in the script. Comments, if
+// any, are encoded in a separate list with the position of the command they are attached to.
+func (t *Tree) MarshalJSON() ([]byte, error) {
+	m := map[string]any{
+		"schema":   JSONSchema,
+		"name":     t.Name,
+		"commands": encodeCommands(t.Commands()),
+	}
+	if len(t.Comments) > 0 {
+		comments := make([]any, 0, len(t.Comments))
+		for _, comment := range t.Comments {
+			comments = append(comments, encodeComment(comment))
+		}
+		m["comments"] = comments
+	}
+	return json.Marshal(m)
+}
+
+func encodeComment(n *CommentNode) map[string]any {
+	m := map[string]any{"type": "comment", "pos": n.Pos, "kind": "hash", "text": n.Text}
+	if n.Kind == CommentBracket {
+		m["kind"] = "bracket"
+	}
+	if n.Command != nil {
+		m["command"] = n.Command.Position()
+		m["trailing"] = n.Trailing
+	}
+	return m
+}
+
+func encodeCommands(commands []Command) []any {
+	nodes := make([]any, 0, len(commands))
+	for _, node := range commands {
+		nodes = append(nodes, encodeCommand(node))
+	}
+	return nodes
+}
+
+func encodeCommand(node Command) map[string]any {
+	switch n := node.(type) {
+	case *RequireNode:
+		return map[string]any{"type": "require", "pos": n.Pos, "name": n.Name, "capabilities": n.Capabilities}
+	case *StopNode:
+		return map[string]any{"type": "stop", "pos": n.Pos, "name": n.Name}
+	case *KeepNode:
+		return map[string]any{"type": "keep", "pos": n.Pos, "name": n.Name}
+	case *DiscardNode:
+		return map[string]any{"type": "discard", "pos": n.Pos, "name": n.Name}
+	case *RedirectNode:
+		return map[string]any{"type": "redirect", "pos": n.Pos, "name": n.Name, "address": n.Address}
+	case *IfNode:
+		elsifs := make([]any, 0, len(n.ElseIfs))
+		for _, elsif := range n.ElseIfs {
+			elsifs = append(elsifs, map[string]any{
+				"type": "elsif",
+				"pos":  elsif.Pos,
+				"name": elsif.Name,
+				"test": encodeTest(elsif.Test),
+				"body": encodeCommands(elsif.Body.Commands()),
+			})
+		}
+		m := map[string]any{
+			"type":  "if",
+			"pos":   n.Pos,
+			"name":  n.Name,
+			"test":  encodeTest(n.Test),
+			"body":  encodeCommands(n.Body.Commands()),
+			"elsif": elsifs,
+		}
+		if n.Else != nil {
+			m["else"] = map[string]any{
+				"type": "else",
+				"pos":  n.Else.Pos,
+				"name": n.Else.Name,
+				"body": encodeCommands(n.Else.Body.Commands()),
+			}
+		}
+		return m
+	case *GenericCommandNode:
+		m := map[string]any{
+			"type":      "command",
+			"pos":       n.Pos,
+			"name":      n.Name,
+			"arguments": encodeArguments(n.Arguments),
+			"tests":     encodeTests(n.Tests),
+		}
+		if n.TestList {
+			m["testList"] = true
+		}
+		if n.Block != nil {
+			m["body"] = encodeCommands(n.Block.Commands())
+		}
+		return m
+	default:
+		return map[string]any{"type": "unknown", "pos": node.Position()}
+	}
+}
+
+func encodeTest(test *TestNode) map[string]any {
+	return map[string]any{
+		"type":      "test",
+		"pos":       test.Pos,
+		"name":      test.Name,
+		"arguments": encodeArguments(test.Arguments),
+		"tests":     encodeTests(test.Tests),
+	}
+}
+
+func encodeTests(tests []*TestNode) []any {
+	encoded := make([]any, 0, len(tests))
+	for _, t := range tests {
+		encoded = append(encoded, encodeTest(t))
+	}
+	return encoded
+}
+
+func encodeArguments(arguments []Argument) []any {
+	args := make([]any, 0, len(arguments))
+	for _, arg := range arguments {
+		switch a := arg.(type) {
+		case *TagNode:
+			args = append(args, map[string]any{"type": "tag", "pos": a.Pos, "name": a.Name})
+		case *NumberNode:
+			args = append(args, map[string]any{"type": "number", "pos": a.Pos, "text": a.Text, "value": a.Value})
+		case *StringNode:
+			args = append(args, map[string]any{"type": "string", "pos": a.Pos, "value": a.Text})
+		case *StringListNode:
+			args = append(args, map[string]any{"type": "string-list", "pos": a.Pos, "value": a.Strings})
+		}
+	}
+
+	return args
+}
+
+// UnmarshalJSON decodes a tree encoded by MarshalJSON, of the current or an earlier schema
+// version. A tree of a later version is decoded as far as it is understood: unknown fields
+// are ignored and commands of unknown types are decoded as commands of extensions (see
+// GenericCommandNode), so trees holding nodes added later stay readable.
+//
+// The text of the script isn't part of the encoding, so the Source of the tree is nil: its
+// positions can't be mapped to lines, and the APIs that edit the source of a tree (e.g.
+// FixRequires, SortRules and Disable) return an error; Format works without it.
+func (t *Tree) UnmarshalJSON(data []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var m map[string]any
+	if err := decoder.Decode(&m); err != nil {
+		return err
+	}
+	version := 0
+	if schema, ok := m["schema"].(json.Number); ok {
+		v, err := strconv.Atoi(schema.String())
+		if err != nil || v < 0 {
+			return fmt.Errorf("invalid schema version %s", schema)
+		}
+		version = v
+	}
+	for ; version < JSONSchema; version++ {
+		jsonMigrations[version](m)
+	}
+
+	d := &jsonDecoder{tree: newTree(jsonString(m["name"]))}
+	for _, node := range d.commands(m["commands"]) {
+		d.tree.Root.append(node)
+	}
+	d.comments(m["comments"])
+	if d.err != nil {
+		return d.err
+	}
+	*t = *d.tree
+	return nil
+}
+
+// jsonString returns a JSON string, or "" for any other value
+func jsonString(v any) string {
+	s, _ := v.(string)
+	return s
+}
+
+// jsonDecoder builds a tree from decoded JSON; the first error is kept in err
+type jsonDecoder struct {
+	tree    *Tree
+	decoded map[Pos]Command // decoded commands by position, to attach comments
+	err     error
+}
+
+func (d *jsonDecoder) fail(format string, args ...any) {
+	if d.err == nil {
+		d.err = fmt.Errorf(format, args...)
+	}
+}
+
+func (d *jsonDecoder) object(v any) map[string]any {
+	m, ok := v.(map[string]any)
+	if !ok {
+		d.fail("expected a JSON object, got %v", v)
+		return map[string]any{}
+	}
+	return m
+}
+
+func (d *jsonDecoder) list(v any) []any {
+	if v == nil {
+		return nil
+	}
+	list, ok := v.([]any)
+	if !ok {
+		d.fail("expected a JSON array, got %v", v)
+	}
+	return list
+}
+
+func (d *jsonDecoder) number(v any) uint64 {
+	n, ok := v.(json.Number)
+	if !ok {
+		d.fail("expected a number, got %v", v)
+		return 0
+	}
+	u, err := strconv.ParseUint(n.String(), 10, 64)
+	if err != nil {
+		d.fail("invalid number %s", n)
+	}
+	return u
+}
+
+func (d *jsonDecoder) pos(m map[string]any) Pos {
+	return Pos(d.number(m["pos"]))
+}
+
+func (d *jsonDecoder) strings(v any) []string {
+	var strings []string
+	for _, s := range d.list(v) {
+		text, ok := s.(string)
+		if !ok {
+			d.fail("expected a string, got %v", s)
+		}
+		strings = append(strings, text)
+	}
+	return strings
+}
+
+func (d *jsonDecoder) block(pos Pos, v any) *CommandsNode {
+	block := d.tree.newCommands(pos)
+	for _, node := range d.commands(v) {
+		block.append(node)
+	}
+	return block
+}
+
+func (d *jsonDecoder) commands(v any) []Command {
+	var commands []Command
+	for _, c := range d.list(v) {
+		if node := d.command(d.object(c)); node != nil {
+			if d.decoded == nil {
+				d.decoded = map[Pos]Command{}
+			}
+			d.decoded[node.Position()] = node
+			commands = append(commands, node)
+		}
+	}
+	return commands
+}
+
+func (d *jsonDecoder) command(m map[string]any) Command {
+	pos, name := d.pos(m), jsonString(m["name"])
+	switch m["type"] {
+	case "require":
+		n := d.tree.newRequire(pos, name)
+		n.Capabilities = d.strings(m["capabilities"])
+		return n
+	case "stop":
+		return d.tree.newStop(pos, name)
+	case "keep":
+		return d.tree.newKeep(pos, name)
+	case "discard":
+		return d.tree.newDiscard(pos, name)
+	case "redirect":
+		n := d.tree.newRedirect(pos, name)
+		n.Address = jsonString(m["address"])
+		return n
+	case "if":
+		n := d.tree.newIf(pos, name)
+		n.Test = d.test(d.object(m["test"]))
+		n.Body = d.block(pos, m["body"])
+		for _, e := range d.list(m["elsif"]) {
+			elsif := d.object(e)
+			node := d.tree.newElseIf(d.pos(elsif), jsonString(elsif["name"]))
+			node.Test = d.test(d.object(elsif["test"]))
+			node.Body = d.block(node.Pos, elsif["body"])
+			n.ElseIfs = append(n.ElseIfs, node)
+		}
+		if m["else"] != nil {
+			els := d.object(m["else"])
+			n.Else = d.tree.newElse(d.pos(els), jsonString(els["name"]))
+			n.Else.Body = d.block(n.Else.Pos, els["body"])
+		}
+		return n
+	default:
+		// commands of extensions, and commands of node types added in later versions
+		n := d.tree.newGenericCommand(pos, name)
+		n.Arguments = d.arguments(m["arguments"])
+		for _, test := range d.list(m["tests"]) {
+			n.Tests = append(n.Tests, d.test(d.object(test)))
+		}
+		n.TestList, _ = m["testList"].(bool)
+		if m["body"] != nil {
+			n.Block = d.block(pos, m["body"])
+		}
+		return n
+	}
+}
+
+func (d *jsonDecoder) test(m map[string]any) *TestNode {
+	n := d.tree.newTest(d.pos(m), jsonString(m["name"]))
+	n.Arguments = d.arguments(m["arguments"])
+	for _, test := range d.list(m["tests"]) {
+		n.Tests = append(n.Tests, d.test(d.object(test)))
+	}
+	// the parser guarantees the single test of not, which the rest of the package relies on;
+	// allof and anyof may have an empty test-list, as in the parser
+	if isKeyword(n.Name, NOT) && len(n.Tests) != 1 {
+		d.fail("`%s` at %d expects a single test, got %d", n.Name, n.Pos, len(n.Tests))
+	}
+	return n
+}
+
+func (d *jsonDecoder) arguments(v any) []Argument {
+	var args []Argument
+	for _, a := range d.list(v) {
+		m := d.object(a)
+		switch pos := d.pos(m); m["type"] {
+		case "tag":
+			args = append(args, d.tree.newTag(pos, jsonString(m["name"])))
+		case "number":
+			args = append(args, d.tree.newNumber(pos, jsonString(m["text"]), d.number(m["value"])))
+		case "string":
+			args = append(args, d.tree.newString(pos, jsonString(m["value"])))
+		case "string-list":
+			args = append(args, d.tree.newStringList(pos, d.strings(m["value"])))
+		default:
+			d.fail("unknown argument type %v at %d", m["type"], pos)
+		}
+	}
+	return args
+}
+
+func (d *jsonDecoder) comments(v any) {
+	for _, c := range d.list(v) {
+		m := d.object(c)
+		text := jsonString(m["text"])
+		raw := "#" + text
+		if m["kind"] == "bracket" {
+			raw = "/*" + text + "*/"
+		}
+		comment := d.tree.newComment(d.pos(m), raw)
+		if m["command"] != nil {
+			comment.Command = d.decoded[Pos(d.number(m["command"]))]
+			comment.Trailing, _ = m["trailing"].(bool)
+		}
+		d.tree.Comments = append(d.tree.Comments, comment)
+	}
+}
@@ -0,0 +1,183 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestTreeMarshalJSON(t *testing.T) {
+	tree := parse(t, "require \"fileinto\";\r\nif size :over 1K {\r\n  redirect \"a@example.com\";\r\n} else {\r\n  keep;\r\n}\r\n")
+
+	data, err := json.Marshal(tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"commands":[` +
+		`{"capabilities":["fileinto"],"name":"require","pos":0,"type":"require"},` +
+		`{"body":[{"address":"a@example.com","name":"redirect","pos":43,"type":"redirect"}],` +
+		`"else":{"body":[{"name":"keep","pos":82,"type":"keep"}],"name":"else","pos":72,"type":"else"},` +
+		`"elsif":[],"name":"if","pos":21,` +
+		`"test":{"arguments":[{"name":":over","pos":29,"type":"tag"},{"pos":35,"text":"1K","type":"number","value":1024}],` +
+		`"name":"size","pos":24,"tests":[],"type":"test"},"type":"if"}],"name":"test","schema":1}`
+	if string(data) != expected {
+		t.Errorf("unexpected JSON %s", data)
+	}
+}
+
+// a decoded tree has no source text, so the APIs that edit it fail instead of slicing it
+func TestTreeUnmarshalJSONWithoutSource(t *testing.T) {
+	data, err := json.Marshal(parse(t, "require \"fileinto\";\r\nif exists \"x\" {\r\n  keep;\r\n}\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tree Tree
+	if err := json.Unmarshal(data, &tree); err != nil {
+		t.Fatal(err)
+	}
+	if tree.Source != nil {
+		t.Fatalf("unexpected source %q", tree.Source.Content)
+	}
+	rule := tree.Commands()[1]
+
+	if _, err := Format(&tree, DefaultFormatOptions()); err != nil {
+		t.Errorf("Format: %v", err)
+	}
+	for name, call := range map[string]func() error{
+		"SortRules":   func() error { _, err := SortRules(&tree); return err },
+		"Disable":     func() error { _, err := tree.Disable(rule); return err },
+		"Annotate":    func() error { _, err := tree.Annotate(rule, tree.AnnotationOf(rule)); return err },
+		"FixRequires": func() error { _, err := FixRequires(&tree); return err },
+		"Downgrade":   func() error { _, _, err := Downgrade(&tree, nil); return err },
+	} {
+		if err := call(); err == nil {
+			t.Errorf("%s: expected an error without source", name)
+		}
+	}
+	Validate(&tree)
+}
+
+func TestTreeUnmarshalJSON(t *testing.T) {
+	input := "require [\"fileinto\", \"vacation\"];\r\n" +
+		"# spam\r\n" +
+		"if anyof (header :contains \"subject\" \"[SPAM]\", size :over 1M) {\r\n" +
+		"  fileinto \"Junk\"; /* junk */\r\n" +
+		"  stop;\r\n" +
+		"} elsif not exists \"x-list\" {\r\n" +
+		"  vacation :days 3 \"away\";\r\n" +
+		"} else {\r\n" +
+		"  redirect \"a@example.com\";\r\n" +
+		"}\r\n" +
+		"discard;\r\n"
+	tree, err := Parse("test", input, 0, WithComments(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded Tree
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if Fingerprint(&decoded) != Fingerprint(tree) {
+		t.Errorf("unexpected tree %v", decoded.Commands())
+	}
+	if len(decoded.Comments) != 2 || decoded.Comments[1].Command == nil || !decoded.Comments[1].Trailing {
+		t.Errorf("unexpected comments %v", decoded.Comments)
+	}
+	again, err := json.Marshal(&decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(again) != string(data) {
+		t.Errorf("unexpected JSON %s", again)
+	}
+}
+
+func TestTreeUnmarshalJSONSchema(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		testList bool
+	}{
+		{
+			// unversioned trees don't record test-lists of commands of extensions
+			name: "version 0",
+			data: `{"name":"test","commands":[{"type":"command","pos":0,"name":"x","arguments":[],` +
+				`"tests":[{"type":"test","pos":3,"name":"true"},{"type":"test","pos":9,"name":"false"}]}]}`,
+			testList: true,
+		},
+		{
+			// a node type of a later version is decoded as a command of an extension
+			name: "unknown type",
+			data: `{"schema":2,"name":"test","commands":[{"type":"notify","pos":0,"name":"notify","method":"mailto:a@example.com",` +
+				`"arguments":[{"type":"string","pos":7,"value":"mailto:a@example.com"}]}]}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var tree Tree
+			if err := json.Unmarshal([]byte(test.data), &tree); err != nil {
+				t.Fatal(err)
+			}
+			commands := tree.Commands()
+			if len(commands) != 1 {
+				t.Fatalf("unexpected commands %v", commands)
+			}
+			command, ok := commands[0].(*GenericCommandNode)
+			if !ok {
+				t.Fatalf("unexpected command %T", commands[0])
+			}
+			if command.TestList != test.testList {
+				t.Errorf("unexpected test-list %v", command.TestList)
+			}
+		})
+	}
+
+	var tree Tree
+	if err := json.Unmarshal([]byte(`{"schema":1,"commands":[{"type":"command","pos":0,"name":"x","arguments":[{"type":"regex","pos":5}]}]}`), &tree); err == nil {
+		t.Error("expected an error for an unknown argument type")
+	}
+	for _, input := range []string{
+		`{"schema":1,"commands":[{"type":"if","pos":0,"name":"if","test":{"pos":3,"name":"not"},"body":[]}]}`,
+		`{"schema":1,"commands":[{"type":"if","pos":0,"name":"if","test":{"pos":3,"name":"not","tests":[{"pos":7,"name":"true"},{"pos":12,"name":"false"}]},"body":[]}]}`,
+	} {
+		if err := json.Unmarshal([]byte(input), &tree); err == nil {
+			t.Errorf("expected an error for the test arity of %s", input)
+		}
+	}
+	// an empty test-list parses, so it decodes too
+	if err := json.Unmarshal([]byte(`{"schema":1,"commands":[{"type":"if","pos":0,"name":"if","test":{"pos":3,"name":"anyof"},"body":[]}]}`), &tree); err != nil {
+		t.Errorf("unexpected error %v", err)
+	} else if warnings := Validate(&tree); len(warnings) != 1 || warnings[0].Code != CodeAlwaysFalse {
+		t.Errorf("unexpected warnings %v", warnings)
+	}
+}
@@ -0,0 +1,119 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import (
+	"strings"
+)
+
+// Identifiers defined by RFC 5228
+const (
+	// control commands (section 3)
+	IF      = "if"
+	ELSIF   = "elsif"
+	ELSE    = "else"
+	REQUIRE = "require"
+	STOP    = "stop"
+
+	// actions (section 4)
+	KEEP     = "keep"
+	DISCARD  = "discard"
+	REDIRECT = "redirect"
+	FILEINTO = "fileinto" // requires the "fileinto" capability
+
+	// tests (section 5)
+	ADDRESS  = "address"
+	ALLOF    = "allof"
+	ANYOF    = "anyof"
+	ENVELOPE = "envelope" // requires the "envelope" capability
+	EXISTS   = "exists"
+	FALSE    = "false"
+	HEADER   = "header"
+	NOT      = "not"
+	SIZE     = "size"
+	TRUE     = "true"
+)
+
+// KeywordKind is the role of an identifier defined by RFC 5228
+type KeywordKind int
+
+const (
+	KeywordControl KeywordKind = iota + 1 // control command, e.g. if
+	KeywordAction                         // action, e.g. keep
+	KeywordTest                           // test, e.g. header
+)
+
+func (k KeywordKind) String() string {
+	switch k {
+	case KeywordControl:
+		return "control"
+	case KeywordAction:
+		return "action"
+	case KeywordTest:
+		return "test"
+	}
+	return "unknown"
+}
+
+// keywords is the table of the identifiers defined by RFC 5228, by lower-cased spelling.
+// They are reserved: an identifier in the table can only be used in its role, also when
+// the parser or an extension doesn't implement it (e.g. fileinto without the capability).
+var keywords = map[string]KeywordKind{
+	IF:       KeywordControl,
+	ELSIF:    KeywordControl,
+	ELSE:     KeywordControl,
+	REQUIRE:  KeywordControl,
+	STOP:     KeywordControl,
+	KEEP:     KeywordAction,
+	DISCARD:  KeywordAction,
+	REDIRECT: KeywordAction,
+	FILEINTO: KeywordAction,
+	ADDRESS:  KeywordTest,
+	ALLOF:    KeywordTest,
+	ANYOF:    KeywordTest,
+	ENVELOPE: KeywordTest,
+	EXISTS:   KeywordTest,
+	FALSE:    KeywordTest,
+	HEADER:   KeywordTest,
+	NOT:      KeywordTest,
+	SIZE:     KeywordTest,
+	TRUE:     KeywordTest,
+}
+
+// LookupKeyword returns the role of an identifier if it is defined by RFC 5228;
+// identifiers are case-insensitive
+func LookupKeyword(identifier string) (KeywordKind, bool) {
+	kind, ok := keywords[strings.ToLower(identifier)]
+	return kind, ok
+}
+
+// isKeyword reports whether an identifier or tag equals the given keyword.
+//
+// Identifiers are case-insensitive (RFC 5228, section 2.9); the original
+// spelling of the identifier is retained in the resulting node.
+func isKeyword(val, keyword string) bool {
+	return equalFoldASCII(val, keyword)
+}
@@ -26,25 +26,150 @@
 package rfc5228
 
 import (
-	"fmt"
+	"errors"
 	"os"
+	"strings"
 	"testing"
 )
 
 func TestLexer(t *testing.T) {
-	dat, _ := os.ReadFile("../../input/comment.sieve")
+	dat, err := os.ReadFile("../../input/comment.sieve")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []struct {
+		typ   itemType
+		value string
+	}{
+		{itemComment, "#"},
+		{itemComment, "# Example Sieve Filter"},
+		{itemComment, "# Declare any optional features or extension used by the script"},
+		{itemComment, "#"},
+		{itemIdentifier, "require"},
+		{itemStringListOpen, "["},
+		{itemString, "\"fileinto\""},
+		{itemStringListClose, "]"},
+		{itemEnd, ";"},
+		{itemComment, "#"},
+		{itemComment, "# Handle messages from known mailing lists"},
+		{itemComment, "# Move messages from IETF filter discussion list to filter mailbox"},
+		{itemComment, "#"},
+		{itemIdentifier, "if"},
+		{itemIdentifier, "header"},
+		{itemTag, ":is"},
+		{itemString, "\"Sender\""},
+		{itemString, "\"owner-ietf-mta-filters@imc.org\""},
+		{itemBlockOpen, "{"},
+		{itemIdentifier, "fileinto"},
+		{itemString, "\"filter\""},
+	}
+
 	lexer := lex("test", string(dat))
+	for n := 1; ; n++ {
+		i := lexer.nextItem()
+		if n <= len(expected) && (i.typ != expected[n-1].typ || i.value(lexer.input) != expected[n-1].value) {
+			t.Errorf("item %d: expected %d %q, got %d %q", n, expected[n-1].typ, expected[n-1].value, i.typ, i.value(lexer.input))
+		}
+		switch i.typ {
+		case itemError:
+			t.Fatalf("item %d: unexpected error %s", n, lexer.code)
+		case itemEOF:
+			if n != 249 {
+				t.Errorf("expected 249 items, got %d", n)
+			}
+			return
+		}
+	}
+}
 
-	for {
-		switch i := lexer.nextItem(); {
+func TestLexerMultiline(t *testing.T) {
+	for _, test := range []struct {
+		input    string
+		expected string // the content of the string, or the code of the error
+	}{
+		{"text:\r\n.\r\n", ""},
+		{"TEXT: \t\r\nline\r\n.\r\n", "line\r\n"},
+		{"text: # a comment\r\nline\r\n.\r\n", "line\r\n"},
+		// comments and dots on the lines of the literal are content
+		{"text:\r\n# not a comment\r\n/* nor this */\r\n..dot\r\n.\r\n", "# not a comment\r\n/* nor this */\r\n.dot\r\n"},
+		{"text: /* no bracket comment */\r\n.\r\n", string(CodeExpectedCRLF)},
+		{"text: x\r\n.\r\n", string(CodeExpectedCRLF)},
+		{"text: # comment", string(CodeExpectedCRLF)},
+		{"text:\r\nlone\rcr\r\n.\r\n", string(CodeUnexpectedCR)},
+	} {
+		l := lex("test", test.input+" ")
+		i := l.nextItem()
+		switch {
 		case i.typ == itemError:
-			fmt.Printf("error = [%s]\n", i.val)
-			return
-		case i.typ == itemEOF:
-			fmt.Printf("EOF = [%s]\n", i.val)
-			return
+			if string(l.code) != test.expected {
+				t.Errorf("%q: unexpected error %s", test.input, l.code)
+			}
+		case i.typ != itemString:
+			t.Errorf("%q: unexpected item %s", test.input, i.describe(l.input))
 		default:
-			fmt.Printf("%s\n", i)
+			if s, err := unquote(i.value(l.input)); err != nil || s != test.expected {
+				t.Errorf("%q: expected %q, got %q (%v)", test.input, test.expected, s, err)
+			}
 		}
 	}
+
+	tree := parse(t, "if header :contains \"subject\" text: # keys\r\n#1\r\n.\r\n {\r\n  keep;\r\n}\r\n")
+	if keys := tree.Commands()[0].(*IfNode).Test.StringLists()[1]; len(keys) != 1 || keys[0] != "#1\r\n" {
+		t.Errorf("unexpected keys %q", keys)
+	}
+}
+
+func TestLexerEndOfInput(t *testing.T) {
+	for _, test := range []struct {
+		input    string
+		expected string // the values of the tokens, or the code of the error
+	}{
+		// the last token of a script without a final CRLF
+		{"keep", "keep"},
+		{"keep;", "keep ;"},
+		{":copy", ":copy"},
+		{"100K", "100K"},
+		{"# comment", ""},
+		{"text:\r\nline\r\n.", "text:\r\nline\r\n."},
+		{"\"unterminated", string(CodeUnterminatedString)},
+		{"\"escape\\", string(CodeUnterminatedString)},
+		{"text:\r\nline\r\n", string(CodeUnterminatedMultiline)},
+		{"text:\r\nline", string(CodeUnterminatedMultiline)},
+		{"/* comment", string(CodeUnterminatedComment)},
+		// trailing NULs end the input, a NUL elsewhere doesn't
+		{"keep;\x00\x00\x00", "keep ;"},
+		{"keep\x00", "keep"},
+		{"keep\x00;", string(CodeUnexpectedRune)},
+		// byte order marks between tokens are whitespace
+		{"\uFEFFkeep;", "keep ;"},
+		{"keep;\r\n\uFEFFstop;", "keep ; stop ;"},
+		{"\"\uFEFF\";", "\"\uFEFF\" ;"},
+	} {
+		var values []string
+		for l := lex("test", test.input); ; {
+			i := l.nextItem()
+			if i.typ == itemError {
+				values = []string{string(l.code)}
+				break
+			}
+			if i.typ == itemEOF {
+				break
+			}
+			if i.typ != itemComment {
+				values = append(values, i.value(l.input))
+			}
+		}
+		if got := strings.Join(values, " "); got != test.expected {
+			t.Errorf("%q: expected %q, got %q", test.input, test.expected, got)
+		}
+	}
+
+	tree := parse(t, "\uFEFFrequire \"fileinto\";\r\nfileinto \"INBOX\";\x00\x00")
+	if len(tree.Commands()) != 2 {
+		t.Errorf("unexpected commands %v", tree.Commands())
+	}
+	var syntax *SyntaxError
+	if _, err := Parse("test", "keep", 0); !errors.As(err, &syntax) || syntax.Pos != 4 {
+		t.Errorf("expected a syntax error at the end of the input, got %v", err)
+	}
 }
@@ -0,0 +1,71 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultMaxReceived is the number of Received fields above which a message is considered
+// to loop; RFC 5321, section 6.3, suggests a threshold of 100
+const DefaultMaxReceived = 100
+
+// LoopPolicy configures DetectLoop for a host
+type LoopPolicy struct {
+	MaxReceived int      // maximum number of Received fields; DefaultMaxReceived if 0, unlimited if negative
+	Fields      []string // fields naming the recipients a message was delivered to; Delivered-To if empty
+}
+
+// DetectLoop inspects the header of a message before it is redirected to address and returns
+// an error if the redirect would most likely bring the message back (RFC 5228, section 4.2):
+// a delivery field (e.g. Delivered-To) already names the address, or the message has passed
+// more hops than the policy allows. Addresses are compared with a case-insensitive local part
+// and a normalized domain.
+func DetectLoop(header *HeaderIndex, address string, policy LoopPolicy) error {
+	limit := policy.MaxReceived
+	if limit == 0 {
+		limit = DefaultMaxReceived
+	}
+	if received := len(header.Values("Received")); limit > 0 && received > limit {
+		return fmt.Errorf("message has %d Received fields, more than %d", received, limit)
+	}
+
+	fields := policy.Fields
+	if len(fields) == 0 {
+		fields = []string{"Delivered-To"}
+	}
+	target := normalizeAddress(strings.TrimSpace(address))
+	for _, name := range fields {
+		for _, value := range header.Values(name) {
+			value = strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(value), "<"), ">")
+			if equalFoldASCII(normalizeAddress(value), target) {
+				return fmt.Errorf("message was already delivered to %s (%s)", address, name)
+			}
+		}
+	}
+	return nil
+}
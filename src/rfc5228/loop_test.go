@@ -0,0 +1,56 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDetectLoop(t *testing.T) {
+	header := NewHeaderIndex([]HeaderField{
+		{"Received", "from a by b"},
+		{"Delivered-To", "<Alias@Example.COM>"},
+		{"Received", "from c by d"},
+		{"X-Original-To", "list@example.com"},
+	})
+
+	if err := DetectLoop(header, "alias@example.com", LoopPolicy{}); err == nil || !strings.Contains(err.Error(), "Delivered-To") {
+		t.Errorf("expected a loop through Delivered-To, got %v", err)
+	}
+	if err := DetectLoop(header, "other@example.com", LoopPolicy{}); err != nil {
+		t.Errorf("unexpected error %s", err)
+	}
+	if err := DetectLoop(header, "list@example.com", LoopPolicy{Fields: []string{"X-Original-To"}}); err == nil {
+		t.Errorf("expected a loop through X-Original-To")
+	}
+	if err := DetectLoop(header, "other@example.com", LoopPolicy{MaxReceived: 1}); err == nil {
+		t.Errorf("expected a loop for too many hops")
+	}
+	if err := DetectLoop(header, "other@example.com", LoopPolicy{MaxReceived: -1}); err != nil {
+		t.Errorf("unexpected error %s", err)
+	}
+}
@@ -0,0 +1,221 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import (
+	"fmt"
+	"mime"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const mailtoScheme = "mailto:"
+
+// mailtoReserved are the header fields a mailto notification must not take from the URI
+// (RFC 5436, section 2.2): they describe the notification itself and are set by the host
+var mailtoReserved = []string{"Auto-Submitted", "Received", "Return-Path", "Sender", "From", "Date", "Message-ID", "Resent-From", "Resent-Sender", "Resent-To", "Resent-Cc", "Resent-Bcc", "Resent-Date", "Resent-Message-ID"}
+
+// MailtoURI is a mailto URI (RFC 6068) naming the recipients, header fields and body of a message,
+// e.g. the method of a notify action (RFC 5436)
+type MailtoURI struct {
+	To      []string      // addresses of the path and the `to` header fields
+	Headers []HeaderField // the other header fields, in the order of the URI
+	Body    string        // content of the `body` header field
+}
+
+// ParseMailto parses a mailto URI; the addresses, field names and values are percent-decoded
+// (a `+` isn't a space in a mailto URI), but not validated, see Validate
+func ParseMailto(uri string) (*MailtoURI, error) {
+	if len(uri) < len(mailtoScheme) || !equalFoldASCII(uri[:len(mailtoScheme)], mailtoScheme) {
+		return nil, fmt.Errorf("invalid mailto URI %q: missing scheme %s", uri, mailtoScheme)
+	}
+	path, query, _ := strings.Cut(uri[len(mailtoScheme):], "?")
+
+	u := &MailtoURI{}
+	if path != "" {
+		for _, addr := range strings.Split(path, ",") {
+			addr, err := url.PathUnescape(addr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid mailto URI %q: %w", uri, err)
+			}
+			u.To = append(u.To, addr)
+		}
+	}
+	if query == "" {
+		return u, nil
+	}
+	for _, hfield := range strings.Split(query, "&") {
+		name, value, ok := strings.Cut(hfield, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid mailto URI %q: header field %q without `=`", uri, hfield)
+		}
+		name, err := url.PathUnescape(name)
+		if err == nil {
+			value, err = url.PathUnescape(value)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid mailto URI %q: %w", uri, err)
+		}
+		switch {
+		case equalFoldASCII(name, "to"):
+			for _, addr := range strings.Split(value, ",") {
+				u.To = append(u.To, strings.TrimSpace(addr))
+			}
+		case equalFoldASCII(name, "body"):
+			u.Body = value
+		default:
+			u.Headers = append(u.Headers, HeaderField{name, value})
+		}
+	}
+	return u, nil
+}
+
+// String returns the URI with the addresses in its path and the header fields and body in its query
+func (u *MailtoURI) String() string {
+	var b strings.Builder
+	b.WriteString(mailtoScheme)
+	for i, addr := range u.To {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(mailtoEscape(addr, "@!$'()*+;:"))
+	}
+	sep := byte('?')
+	hfield := func(name, value string) {
+		b.WriteByte(sep)
+		b.WriteString(mailtoEscape(name, ""))
+		b.WriteByte('=')
+		b.WriteString(mailtoEscape(value, "@!$'()*+,;:/?"))
+		sep = '&'
+	}
+	for _, field := range u.Headers {
+		hfield(field.Name, field.Value)
+	}
+	if u.Body != "" {
+		hfield("body", u.Body)
+	}
+	return b.String()
+}
+
+// mailtoEscape percent-encodes all bytes of s but unreserved characters and those in allowed
+func mailtoEscape(s, allowed string) string {
+	const hex = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || strings.IndexByte("-._~", c) >= 0 || strings.IndexByte(allowed, c) >= 0 {
+			b.WriteByte(c)
+		} else {
+			b.WriteByte('%')
+			b.WriteByte(hex[c>>4])
+			b.WriteByte(hex[c&15])
+		}
+	}
+	return b.String()
+}
+
+// Validate returns an error if the URI has no recipients, an invalid address (see SplitAddress),
+// a header field with an invalid name or a line break in its value, or a header field a
+// notification must not take from the URI, such as From or Auto-Submitted
+func (u *MailtoURI) Validate() error {
+	if len(u.To) == 0 {
+		return fmt.Errorf("mailto URI without recipients")
+	}
+	for _, addr := range u.To {
+		if _, _, err := SplitAddress(addr); err != nil {
+			return err
+		}
+	}
+	for _, field := range u.Headers {
+		if !isFieldName(field.Name) {
+			return fmt.Errorf("invalid header field name %q in mailto URI", field.Name)
+		}
+		if strings.ContainsAny(field.Value, "\r\n") {
+			return fmt.Errorf("line break in header field %s of mailto URI", field.Name)
+		}
+		for _, name := range mailtoReserved {
+			if equalFoldASCII(field.Name, name) {
+				return fmt.Errorf("header field %s not allowed in mailto URI", field.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// isFieldName reports whether s is a header field name: printable US-ASCII except `:` (RFC 5322, section 3.6.8)
+func isFieldName(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] <= ' ' || s[i] > '~' || s[i] == ':' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// Notification returns the notification message (RFC 5436, section 2.7) to send for the URI:
+// from the address from to the recipients of the URI, with the header fields of the URI, an
+// Auto-Submitted field naming owner, the address of the user whose script notifies, and
+// message as the subject, unless the URI has a Subject, and as the body, unless the URI has
+// a body. Header values that aren't ASCII are encoded (RFC 2047), lines end with CRLF.
+func (u *MailtoURI) Notification(from, owner, message string, date time.Time) (string, error) {
+	if err := u.Validate(); err != nil {
+		return "", err
+	}
+	if _, _, err := SplitAddress(from); err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	field := func(name, value string) {
+		b.WriteString(name)
+		b.WriteString(": ")
+		b.WriteString(mime.QEncoding.Encode("utf-8", value))
+		b.WriteString("\r\n")
+	}
+	field("From", from)
+	field("To", strings.Join(u.To, ", "))
+	field("Date", date.Format(time.RFC1123Z))
+	field("Auto-Submitted", fmt.Sprintf("auto-notified; owner-email=%q", owner))
+	subject := false
+	for _, h := range u.Headers {
+		subject = subject || equalFoldASCII(h.Name, "Subject")
+		field(h.Name, h.Value)
+	}
+	if !subject {
+		field("Subject", message)
+	}
+	body := u.Body
+	if body == "" {
+		body = message
+	}
+	b.WriteString("MIME-Version: 1.0\r\nContent-Type: text/plain; charset=utf-8\r\nContent-Transfer-Encoding: 8bit\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(strings.ReplaceAll(body, "\r\n", "\n"), "\n", "\r\n"))
+	if !strings.HasSuffix(body, "\n") {
+		b.WriteString("\r\n")
+	}
+	return b.String(), nil
+}
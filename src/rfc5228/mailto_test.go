@@ -0,0 +1,108 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseMailto(t *testing.T) {
+	u, err := ParseMailto("MAILTO:a@example.com,%22b%20c%22@example.org?to=d@example.net&Subject=Hi%20there+you&body=line%201%0D%0Aline%202&In-Reply-To=%3C1@example.com%3E")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &MailtoURI{
+		To:      []string{"a@example.com", `"b c"@example.org`, "d@example.net"},
+		Headers: []HeaderField{{"Subject", "Hi there+you"}, {"In-Reply-To", "<1@example.com>"}},
+		Body:    "line 1\r\nline 2",
+	}
+	if !reflect.DeepEqual(u, expected) {
+		t.Errorf("expected %+v, got %+v", expected, u)
+	}
+	if err := u.Validate(); err != nil {
+		t.Errorf("unexpected error %s", err)
+	}
+
+	// composing and parsing again is lossless
+	again, err := ParseMailto(u.String())
+	if err != nil || !reflect.DeepEqual(again, expected) {
+		t.Errorf("%s: expected %+v, got %+v (%v)", u, expected, again, err)
+	}
+
+	for _, uri := range []string{"http://example.com", "mailto:a%zz@example.com", "mailto:a@example.com?subject"} {
+		if _, err := ParseMailto(uri); err == nil {
+			t.Errorf("%s: expected an error", uri)
+		}
+	}
+}
+
+func TestMailtoValidate(t *testing.T) {
+	for uri, reason := range map[string]string{
+		"mailto:":       "without recipients",
+		"mailto:nobody": "missing `@`",
+		"mailto:a@example.com?from=b@example.com":            "not allowed",
+		"mailto:a@example.com?auto-submitted=no":             "not allowed",
+		"mailto:a@example.com?subject=a%0D%0ABcc:b@evil.com": "line break",
+		"mailto:a@example.com?x%20y=z":                       "invalid header field name",
+	} {
+		u, err := ParseMailto(uri)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := u.Validate(); err == nil || !strings.Contains(err.Error(), reason) {
+			t.Errorf("%s: expected an error with %q, got %v", uri, reason, err)
+		}
+	}
+}
+
+func TestMailtoNotification(t *testing.T) {
+	u, err := ParseMailto("mailto:alert@example.com?importance=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	message, err := u.Notification("sieve@example.com", "user@example.com", "Nieuw bericht van Zoë", time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "From: sieve@example.com\r\n" +
+		"To: alert@example.com\r\n" +
+		"Date: Mon, 01 May 2023 12:00:00 +0000\r\n" +
+		"Auto-Submitted: auto-notified; owner-email=\"user@example.com\"\r\n" +
+		"importance: 1\r\n" +
+		"Subject: =?utf-8?q?Nieuw_bericht_van_Zo=C3=AB?=\r\n" +
+		"MIME-Version: 1.0\r\nContent-Type: text/plain; charset=utf-8\r\nContent-Transfer-Encoding: 8bit\r\n\r\n" +
+		"Nieuw bericht van Zoë\r\n"
+	if message != expected {
+		t.Errorf("expected %q, got %q", expected, message)
+	}
+
+	if _, err := (&MailtoURI{To: []string{"a@example.com"}}).Notification("invalid", "user@example.com", "", time.Now()); err == nil {
+		t.Errorf("expected an error for an invalid from address")
+	}
+}
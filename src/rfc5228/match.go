@@ -0,0 +1,249 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Matcher is a compiled :matches pattern (RFC 5228, section 2.7.1)
+type Matcher struct {
+	comparator string
+	pattern    []wildcard
+}
+
+// wildcard is an element of a compiled pattern: a literal character, `?` or `*`
+type wildcard struct {
+	r    rune
+	kind wildcardKind
+}
+
+type wildcardKind int
+
+const (
+	wildcardLiteral wildcardKind = iota
+	wildcardOne                  // `?`, a single character
+	wildcardAny                  // `*`, any sequence of characters
+)
+
+// CompileMatch compiles a :matches pattern for a comparator, e.g. to reuse a pattern over
+// many messages. `*` matches any sequence of characters, `?` a single one and `\` makes the
+// next character literal, so `\*` and `\?` match `*` and `?`; a trailing `\` matches itself.
+//
+// The pattern is the value of a string, after the quoted-string escapes are resolved: the
+// script `"a\\*"` holds the pattern `a\*`, a literal star.
+// For i;octet characters are octets; for i;ascii-casemap (or an empty comparator) they are
+// UTF-8 characters and ASCII letters match regardless of case. i;ascii-numeric doesn't
+// support :matches (RFC 4790, section 9.1).
+func CompileMatch(pattern, comparator string) (*Matcher, error) {
+	comparator = strings.ToLower(comparator)
+	switch comparator {
+	case "":
+		comparator = ComparatorASCIICasemap
+	case ComparatorOctet, ComparatorASCIICasemap:
+	default:
+		return nil, fmt.Errorf("comparator %q does not support :matches", comparator)
+	}
+
+	m := &Matcher{comparator: comparator}
+	units := m.units(pattern)
+	for i := 0; i < len(units); i++ {
+		switch r := units[i]; {
+		case r == '\\' && i+1 < len(units):
+			i++
+			m.pattern = append(m.pattern, wildcard{r: units[i]})
+		case r == '?':
+			m.pattern = append(m.pattern, wildcard{kind: wildcardOne})
+		case r == '*':
+			// consecutive stars match the same sequences as a single one
+			if n := len(m.pattern); n == 0 || m.pattern[n-1].kind != wildcardAny {
+				m.pattern = append(m.pattern, wildcard{kind: wildcardAny})
+			}
+		default:
+			m.pattern = append(m.pattern, wildcard{r: r})
+		}
+	}
+	return m, nil
+}
+
+// units splits a string into the characters of the comparator
+func (m *Matcher) units(s string) []rune {
+	if m.comparator == ComparatorOctet {
+		units := make([]rune, len(s))
+		for i := 0; i < len(s); i++ {
+			units[i] = rune(s[i])
+		}
+		return units
+	}
+	return []rune(asciiLower(s))
+}
+
+// Match reports whether a value matches the pattern; it doesn't allocate
+func (m *Matcher) Match(value string) bool {
+	// on a mismatch, let the last `*` absorb one more character and retry from there
+	p, i := 0, 0
+	star, starAt := -1, 0
+	for i < len(value) {
+		r, size := m.unit(value, i)
+		switch {
+		case p < len(m.pattern) && m.pattern[p].kind == wildcardAny:
+			star, starAt = p, i
+			p++
+		case p < len(m.pattern) && (m.pattern[p].kind == wildcardOne || m.pattern[p].r == r):
+			p++
+			i += size
+		case star >= 0:
+			_, skipped := m.unit(value, starAt)
+			starAt += skipped
+			p, i = star+1, starAt
+		default:
+			return false
+		}
+	}
+	for p < len(m.pattern) && m.pattern[p].kind == wildcardAny {
+		p++
+	}
+	return p == len(m.pattern)
+}
+
+// unit returns the character of the comparator at byte offset i of s and its width
+func (m *Matcher) unit(s string, i int) (rune, int) {
+	if m.comparator == ComparatorOctet {
+		return rune(s[i]), 1
+	}
+	r, size := utf8.DecodeRuneInString(s[i:])
+	if 'A' <= r && r <= 'Z' {
+		r += 'a' - 'A'
+	}
+	return r, size
+}
+
+// MatchCache holds compiled :matches patterns so that every pattern of a script is compiled
+// once rather than for every message; it is safe for concurrent use
+type MatchCache struct {
+	mu       sync.RWMutex
+	matchers map[matchKey]*Matcher
+}
+
+type matchKey struct {
+	pattern, comparator string
+}
+
+// NewMatchCache returns an empty cache
+func NewMatchCache() *MatchCache {
+	return &MatchCache{matchers: map[matchKey]*Matcher{}}
+}
+
+// CompileMatches returns a cache holding the compiled keys of all :matches tests of a script;
+// an error is returned for a comparator that doesn't support :matches
+func CompileMatches(tree *Tree) (*MatchCache, error) {
+	c := NewMatchCache()
+	var err error
+	var test func(t *TestNode)
+	test = func(t *TestNode) {
+		if t.HasTag(":matches") {
+			// the keys are the last string-list of the test, following the comparator name
+			if lists := t.StringLists(); len(lists) > 0 {
+				for _, key := range lists[len(lists)-1] {
+					if _, e := c.Compile(key, matchComparator(t)); e != nil && err == nil {
+						err = fmt.Errorf("%s at %s: %w", t.Name, tree.Source.Position(t.Pos), e)
+					}
+				}
+			}
+		}
+		for _, nested := range t.Tests {
+			test(nested)
+		}
+	}
+	var walk func(commands []Command)
+	walk = func(commands []Command) {
+		for _, node := range commands {
+			switch n := node.(type) {
+			case *IfNode:
+				for _, t := range n.Conditions() {
+					test(t)
+				}
+				for _, block := range n.Blocks() {
+					walk(block.Commands())
+				}
+			case *GenericCommandNode:
+				for _, t := range n.Tests {
+					test(t)
+				}
+				if n.Block != nil {
+					walk(n.Block.Commands())
+				}
+			}
+		}
+	}
+	walk(tree.Commands())
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// matchComparator returns the name of the :comparator argument of a test, if any
+func matchComparator(test *TestNode) string {
+	for i, arg := range test.Arguments {
+		if tag, ok := arg.(*TagNode); ok && isKeyword(tag.Name, ":comparator") && i+1 < len(test.Arguments) {
+			if s, ok := test.Arguments[i+1].(*StringNode); ok {
+				return s.Text
+			}
+		}
+	}
+	return ""
+}
+
+// Compile returns the compiled pattern from the cache, compiling and adding it if it is missing
+func (c *MatchCache) Compile(pattern, comparator string) (*Matcher, error) {
+	key := matchKey{pattern, strings.ToLower(comparator)}
+	c.mu.RLock()
+	m, ok := c.matchers[key]
+	c.mu.RUnlock()
+	if ok {
+		return m, nil
+	}
+
+	m, err := CompileMatch(pattern, comparator)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.matchers[key] = m
+	c.mu.Unlock()
+	return m, nil
+}
+
+// Len returns the number of compiled patterns in the cache
+func (c *MatchCache) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.matchers)
+}
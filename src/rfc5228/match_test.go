@@ -0,0 +1,135 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import (
+	"testing"
+)
+
+func TestCompileMatch(t *testing.T) {
+	for _, expected := range []struct {
+		value, pattern string
+		comparator     string
+		matches        bool
+	}{
+		{"hello", "h*o", "", true},
+		{"hello", "h?llo", "", true},
+		{"hello", "h?lo", "", false},
+		{"a*b", "a\\*b", "", true},
+		{"axb", "a\\*b", "", false},
+		{"a?b", "a\\?b", "", true},
+		{"axb", "a\\?b", "", false},
+		{"a\\", "a\\", "", true},
+		{"a\\b", "a\\\\b", "", true},
+		{"", "*", "", true},
+		{"", "?", "", false},
+		{"HeLLo", "h*L?O", ComparatorASCIICasemap, true},
+		{"HeLLo", "h*L?O", ComparatorOctet, false},
+		{"Hello", "H*l?o", ComparatorOctet, true},
+		{"abcbcd", "a*bcd", "", true},
+		{"abcbce", "a**bc?d", "", false},
+		// a character is a UTF-8 sequence for i;ascii-casemap and an octet for i;octet
+		{"é", "?", ComparatorASCIICasemap, true},
+		{"é", "??", ComparatorOctet, true},
+	} {
+		m, err := CompileMatch(expected.pattern, expected.comparator)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m.Match(expected.value) != expected.matches {
+			t.Errorf("%q %q (%s): expected %t", expected.value, expected.pattern, expected.comparator, expected.matches)
+		}
+	}
+
+	if _, err := CompileMatch("1*", ComparatorASCIINumeric); err == nil {
+		t.Errorf("expected an error for i;ascii-numeric")
+	}
+
+	// the quoted-string escape is resolved before the pattern is compiled
+	tree := parse(t, "if header :matches \"subject\" [\"a\\\\*\", \"b*\"] {\r\n  keep;\r\n}\r\n")
+	keys := tree.Commands()[0].(*IfNode).Test.StringLists()[1]
+	literal, _ := CompileMatch(keys[0], "")
+	wildcard, _ := CompileMatch(keys[1], "")
+	if keys[0] != "a\\*" || !literal.Match("a*") || literal.Match("ab") || !wildcard.Match("bc") {
+		t.Errorf("unexpected matches for %q", keys)
+	}
+}
+
+func TestCompileMatches(t *testing.T) {
+	tree := parse(t, "if header :matches \"subject\" [\"*spam*\", \"*junk?\"] {\r\n  discard;\r\n}\r\n"+
+		"if anyof (address :matches :comparator \"i;octet\" \"from\" \"*@Example.com\", header :is \"x\" \"*\") {\r\n  keep;\r\n}\r\n")
+	c, err := CompileMatches(tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Len() != 3 {
+		t.Errorf("expected 3 compiled patterns, got %d", c.Len())
+	}
+	m, _ := c.Compile("*@Example.com", ComparatorOctet)
+	if again, _ := c.Compile("*@Example.com", "I;OCTET"); again != m || c.Len() != 3 {
+		t.Errorf("expected the cached pattern")
+	}
+
+	tree = parse(t, "if header :matches :comparator \"i;ascii-numeric\" \"x\" \"1*\" {\r\n  keep;\r\n}\r\n")
+	if _, err := CompileMatches(tree); err == nil {
+		t.Errorf("expected an error for i;ascii-numeric")
+	}
+}
+
+// benchmarkHeaders are the header values of a typical message
+var benchmarkHeaders = []string{
+	"Re: [announce] Release 1.2 of the project is out",
+	"\"Example Sender\" <sender@lists.example.com>",
+	"from mx.example.org (mx.example.org [192.0.2.1]) by mail.example.com with ESMTPS id 4F3A2",
+	"list-announce.lists.example.com",
+	"<20231014120000.12345@mail.example.com>",
+}
+
+// benchmarkPatterns are the :matches keys of a header-heavy script
+var benchmarkPatterns = []string{"*[announce]*", "*@lists.example.com>", "from *.example.org *", "*-announce.*", "<*@*.example.com>", "*spam*", "re: *release ?.?*"}
+
+func BenchmarkMatchCompiled(b *testing.B) {
+	c := NewMatchCache()
+	for i := 0; i < b.N; i++ {
+		for _, pattern := range benchmarkPatterns {
+			m, _ := c.Compile(pattern, "")
+			for _, header := range benchmarkHeaders {
+				m.Match(header)
+			}
+		}
+	}
+}
+
+func BenchmarkMatchUncached(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		for _, pattern := range benchmarkPatterns {
+			for _, header := range benchmarkHeaders {
+				m, _ := CompileMatch(pattern, "")
+				m.Match(header)
+			}
+		}
+	}
+}
@@ -61,8 +61,8 @@ type ControlCommandNode interface {
 	Node
 }
 
-// CommandNode represents a node that may exist by itself
-type CommandNode interface {
+// Command represents a node that may exist by itself
+type Command interface {
 	ControlCommandNode
 	ActionCommandNode
 }
@@ -89,6 +89,10 @@ const (
 	nodeRedirect
 	NodeString
 	NodeStringList
+	NodeTag
+	NodeNumber
+	NodeComment
+	NodeGenericCommand
 )
 
 // Pos represents a byte position in the original input input
@@ -102,25 +106,32 @@ func (p Pos) Position() Pos {
 type CommandsNode struct {
 	NodeType
 	Pos
-	Nodes []CommandNode // The element nodes in lexical order.
+	Nodes []Command // The element nodes in lexical order.
 }
 
 func (t *Tree) newCommands(pos Pos) *CommandsNode {
-	return &CommandsNode{NodeType: NodeList, Pos: pos}
+	n := t.arena.commandsNode()
+	*n = CommandsNode{NodeType: NodeList, Pos: pos}
+	return n
 }
 
-func (l *CommandsNode) append(n CommandNode) {
+func (l *CommandsNode) append(n Command) {
 	l.Nodes = append(l.Nodes, n)
 }
 
+// Commands returns the commands of the block in lexical order
+func (l *CommandsNode) Commands() []Command {
+	return l.Nodes
+}
+
 type StopNode struct {
-	ActionCommandNode
 	NodeType
 	Pos
+	Name string // identifier as written in the script
 }
 
-func (t *Tree) newStop(pos Pos) *StopNode {
-	return &StopNode{NodeType: nodeControlStop, Pos: pos}
+func (t *Tree) newStop(pos Pos, name string) *StopNode {
+	return &StopNode{NodeType: nodeControlStop, Pos: pos, Name: name}
 }
 
 func (n *StopNode) Type() NodeType {
@@ -132,14 +143,14 @@ func (n *StopNode) Position() Pos {
 }
 
 type RequireNode struct {
-	ActionCommandNode
 	NodeType
 	Pos
+	Name         string // identifier as written in the script
 	Capabilities []string
 }
 
-func (t *Tree) newRequire(pos Pos) *RequireNode {
-	return &RequireNode{NodeType: NodeControlRequire, Pos: pos}
+func (t *Tree) newRequire(pos Pos, name string) *RequireNode {
+	return &RequireNode{NodeType: NodeControlRequire, Pos: pos, Name: name}
 }
 
 func (n *RequireNode) Type() NodeType {
@@ -151,13 +162,13 @@ func (n *RequireNode) Position() Pos {
 }
 
 type KeepNode struct {
-	ActionCommandNode
 	NodeType
 	Pos
+	Name string // identifier as written in the script
 }
 
-func (t *Tree) newKeep(pos Pos) *KeepNode {
-	return &KeepNode{NodeType: nodeKeep, Pos: pos}
+func (t *Tree) newKeep(pos Pos, name string) *KeepNode {
+	return &KeepNode{NodeType: nodeKeep, Pos: pos, Name: name}
 }
 
 func (n *KeepNode) Type() NodeType {
@@ -169,13 +180,13 @@ func (n *KeepNode) Position() Pos {
 }
 
 type DiscardNode struct {
-	ActionCommandNode
 	NodeType
 	Pos
+	Name string // identifier as written in the script
 }
 
-func (t *Tree) newDiscard(pos Pos) *DiscardNode {
-	return &DiscardNode{NodeType: nodeDiscard, Pos: pos}
+func (t *Tree) newDiscard(pos Pos, name string) *DiscardNode {
+	return &DiscardNode{NodeType: nodeDiscard, Pos: pos, Name: name}
 }
 
 func (n *DiscardNode) Type() NodeType {
@@ -187,14 +198,14 @@ func (n *DiscardNode) Position() Pos {
 }
 
 type RedirectNode struct {
-	ActionCommandNode
 	NodeType
 	Pos
+	Name    string // identifier as written in the script
 	Address string
 }
 
-func (t *Tree) newRedirect(pos Pos) *RedirectNode {
-	return &RedirectNode{NodeType: nodeRedirect, Pos: pos}
+func (t *Tree) newRedirect(pos Pos, name string) *RedirectNode {
+	return &RedirectNode{NodeType: nodeRedirect, Pos: pos, Name: name}
 }
 
 func (n *RedirectNode) Type() NodeType {
@@ -206,13 +217,17 @@ func (n *RedirectNode) Position() Pos {
 }
 
 type TestNode struct {
-	TestCommandNode
 	NodeType
 	Pos
+	Name      string      // identifier as written in the script
+	Arguments []Argument  // tag, number, string and string-list arguments in lexical order
+	Tests     []*TestNode // nested test or test-list (e.g. not, allof, anyof)
 }
 
-func (t *Tree) newTest(pos Pos) *TestNode {
-	return &TestNode{NodeType: nodeTest, Pos: pos}
+func (t *Tree) newTest(pos Pos, name string) *TestNode {
+	n := t.arena.test()
+	*n = TestNode{NodeType: nodeTest, Pos: pos, Name: name}
+	return n
 }
 
 func (n *TestNode) Type() NodeType {
@@ -223,19 +238,65 @@ func (n *TestNode) Position() Pos {
 	return n.Pos
 }
 
+// Tags returns the tagged arguments of the test in lexical order
+func (n *TestNode) Tags() []*TagNode {
+	var tags []*TagNode
+	for _, arg := range n.Arguments {
+		if tag, ok := arg.(*TagNode); ok {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// HasTag reports whether the test has the given tagged argument (e.g. ":is"); tags are case-insensitive
+func (n *TestNode) HasTag(name string) bool {
+	for _, tag := range n.Tags() {
+		if isKeyword(tag.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// StringLists returns the string and string-list arguments of the test in lexical order;
+// a single string is returned as a string-list with one element
+func (n *TestNode) StringLists() [][]string {
+	var lists [][]string
+	for _, arg := range n.Arguments {
+		switch a := arg.(type) {
+		case *StringNode:
+			lists = append(lists, []string{a.Text})
+		case *StringListNode:
+			lists = append(lists, a.Strings)
+		}
+	}
+	return lists
+}
+
+// Numbers returns the number arguments of the test in lexical order
+func (n *TestNode) Numbers() []*NumberNode {
+	var numbers []*NumberNode
+	for _, arg := range n.Arguments {
+		if number, ok := arg.(*NumberNode); ok {
+			numbers = append(numbers, number)
+		}
+	}
+	return numbers
+}
+
 type IfNode struct {
-	CommandNode
-	// fields
 	NodeType
 	Pos
-	Tests   []*TestNode
+	Name    string // identifier as written in the script
+	Test    *TestNode
 	Body    *CommandsNode
 	ElseIfs []*ElseIfNode
 	Else    *ElseNode
 }
 
-func (t *Tree) newIf(pos Pos) *IfNode {
-	return &IfNode{NodeType: NodeControlIf, Pos: pos}
+func (t *Tree) newIf(pos Pos, name string) *IfNode {
+	return &IfNode{NodeType: NodeControlIf, Pos: pos, Name: name}
 }
 
 func (n *IfNode) Type() NodeType {
@@ -246,18 +307,37 @@ func (n *IfNode) Position() Pos {
 	return n.Pos
 }
 
-type ElseIfNode struct {
-	CommandNode
+// Blocks returns the blocks of the if, elsif and else commands of the chain in lexical order
+func (n *IfNode) Blocks() []*CommandsNode {
+	blocks := []*CommandsNode{n.Body}
+	for _, elsif := range n.ElseIfs {
+		blocks = append(blocks, elsif.Body)
+	}
+	if n.Else != nil {
+		blocks = append(blocks, n.Else.Body)
+	}
+	return blocks
+}
+
+// Conditions returns the tests of the if and elsif commands of the chain in lexical order
+func (n *IfNode) Conditions() []*TestNode {
+	tests := []*TestNode{n.Test}
+	for _, elsif := range n.ElseIfs {
+		tests = append(tests, elsif.Test)
+	}
+	return tests
+}
 
-	// fields
+type ElseIfNode struct {
 	NodeType
 	Pos
-	Test []*TestNode
+	Name string // identifier as written in the script
+	Test *TestNode
 	Body *CommandsNode
 }
 
-func (t *Tree) newElseIf(pos Pos) *ElseIfNode {
-	return &ElseIfNode{NodeType: NodeControlIfElse, Pos: pos}
+func (t *Tree) newElseIf(pos Pos, name string) *ElseIfNode {
+	return &ElseIfNode{NodeType: NodeControlIfElse, Pos: pos, Name: name}
 }
 
 func (n *ElseIfNode) Type() NodeType {
@@ -269,16 +349,14 @@ func (n *ElseIfNode) Position() Pos {
 }
 
 type ElseNode struct {
-	CommandNode
-
-	// fields
 	NodeType
 	Pos
-	Body []*CommandsNode
+	Name string // identifier as written in the script
+	Body *CommandsNode
 }
 
-func (t *Tree) newElse(pos Pos) *ElseNode {
-	return &ElseNode{NodeType: NodeControlElse, Pos: pos}
+func (t *Tree) newElse(pos Pos, name string) *ElseNode {
+	return &ElseNode{NodeType: NodeControlElse, Pos: pos, Name: name}
 }
 
 func (n *ElseNode) Type() NodeType {
@@ -288,3 +366,83 @@ func (n *ElseNode) Type() NodeType {
 func (n *ElseNode) Position() Pos {
 	return n.Pos
 }
+
+// StringNode holds a single (decoded) string argument
+type StringNode struct {
+	NodeType
+	Pos
+	Text string
+}
+
+func (t *Tree) newString(pos Pos, text string) *StringNode {
+	n := t.arena.string()
+	*n = StringNode{NodeType: NodeString, Pos: pos, Text: text}
+	return n
+}
+
+// StringListNode holds a bracketed string-list argument
+type StringListNode struct {
+	NodeType
+	Pos
+	Strings []string
+}
+
+func (t *Tree) newStringList(pos Pos, strings []string) *StringListNode {
+	n := t.arena.stringList()
+	*n = StringListNode{NodeType: NodeStringList, Pos: pos, Strings: strings}
+	return n
+}
+
+// TagNode holds a tagged argument; Name includes the leading colon as written in the script
+type TagNode struct {
+	NodeType
+	Pos
+	Name string
+}
+
+func (t *Tree) newTag(pos Pos, name string) *TagNode {
+	n := t.arena.tag()
+	*n = TagNode{NodeType: NodeTag, Pos: pos, Name: name}
+	return n
+}
+
+// NumberNode holds a number argument; Value has the quantifier (K, M, G) applied
+type NumberNode struct {
+	NodeType
+	Pos
+	Text  string
+	Value uint64
+}
+
+func (t *Tree) newNumber(pos Pos, text string, value uint64) *NumberNode {
+	n := t.arena.number()
+	*n = NumberNode{NodeType: NodeNumber, Pos: pos, Text: text, Value: value}
+	return n
+}
+
+// GenericCommandNode holds a command the parser doesn't know, e.g. of an extension, parsed by the
+// grammar of RFC 5228 (section 8.2) only, so that tools can still process the script; the parser
+// only accepts these commands outside strict mode
+type GenericCommandNode struct {
+	NodeType
+	Pos
+	Name      string        // identifier as written in the script
+	Arguments []Argument    // tag, number, string and string-list arguments in lexical order
+	Tests     []*TestNode   // test or test-list following the arguments
+	TestList  bool          // the tests are a parenthesized test-list
+	Block     *CommandsNode // block of the command; nil if the command ends with `;`
+}
+
+func (t *Tree) newGenericCommand(pos Pos, name string) *GenericCommandNode {
+	n := t.arena.genericCommand()
+	*n = GenericCommandNode{NodeType: NodeGenericCommand, Pos: pos, Name: name}
+	return n
+}
+
+func (n *GenericCommandNode) Type() NodeType {
+	return n.NodeType
+}
+
+func (n *GenericCommandNode) Position() Pos {
+	return n.Pos
+}
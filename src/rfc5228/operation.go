@@ -0,0 +1,139 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import "fmt"
+
+// Operation is a kind of work an evaluator counts with an OperationCounter
+type Operation int
+
+const (
+	OperationTest       Operation = iota // a test evaluated
+	OperationComparison                  // a value compared with a key (:is, :contains, :matches, relational)
+	OperationRegex                       // a :regex pattern executed
+	operationCount
+)
+
+func (o Operation) String() string {
+	switch o {
+	case OperationTest:
+		return "test"
+	case OperationComparison:
+		return "comparison"
+	case OperationRegex:
+		return "regex"
+	default:
+		return fmt.Sprintf("operation %d", int(o))
+	}
+}
+
+// OperationLimits are the maximum numbers of operations of an evaluation; 0 is unlimited
+type OperationLimits struct {
+	Tests       uint64
+	Comparisons uint64
+	Regex       uint64
+	Total       uint64 // all operations together
+}
+
+func (l OperationLimits) limit(op Operation) uint64 {
+	switch op {
+	case OperationTest:
+		return l.Tests
+	case OperationComparison:
+		return l.Comparisons
+	case OperationRegex:
+		return l.Regex
+	default:
+		return 0
+	}
+}
+
+// OperationLimitError is returned by OperationCounter.Count when an evaluation exceeds a limit
+type OperationLimitError struct {
+	Operation Operation // the operation whose limit was exceeded; for the total, the operation exceeding it
+	Limit     uint64
+	Total     bool // the limit of all operations together was exceeded
+}
+
+func (e *OperationLimitError) Error() string {
+	if e.Total {
+		return fmt.Sprintf("script exceeded its limit of %d operations", e.Limit)
+	}
+	return fmt.Sprintf("script exceeded its limit of %d %s operations", e.Limit, e.Operation)
+}
+
+// OperationCounter counts the operations of an evaluation and cuts it off deterministically,
+// independent of wall-clock time, once a limit is reached: unlike a timeout, the same script
+// and message always stop at the same operation. A counter is used by a single evaluation.
+type OperationCounter struct {
+	limits OperationLimits
+	counts [operationCount]uint64
+	total  uint64
+}
+
+// NewOperationCounter returns a counter enforcing limits
+func NewOperationCounter(limits OperationLimits) *OperationCounter {
+	return &OperationCounter{limits: limits}
+}
+
+// Count counts an operation; it returns an *OperationLimitError if the operation would
+// exceed a limit, in which case it isn't counted and the evaluation must stop
+func (c *OperationCounter) Count(op Operation) error {
+	if op < 0 || op >= operationCount {
+		return fmt.Errorf("unknown %s", op)
+	}
+	if limit := c.limits.limit(op); limit > 0 && c.counts[op] >= limit {
+		return &OperationLimitError{Operation: op, Limit: limit}
+	}
+	if c.limits.Total > 0 && c.total >= c.limits.Total {
+		return &OperationLimitError{Operation: op, Limit: c.limits.Total, Total: true}
+	}
+	c.counts[op]++
+	c.total++
+	return nil
+}
+
+// Used returns the number of operations of a kind counted so far
+func (c *OperationCounter) Used(op Operation) uint64 {
+	if op < 0 || op >= operationCount {
+		return 0
+	}
+	return c.counts[op]
+}
+
+// Total returns the number of operations counted so far
+func (c *OperationCounter) Total() uint64 {
+	return c.total
+}
+
+// MatchCounted is Match counting a comparison; the value isn't matched if the counter's
+// limit is reached
+func (m *Matcher) MatchCounted(value string, counter *OperationCounter) (bool, error) {
+	if err := counter.Count(OperationComparison); err != nil {
+		return false, err
+	}
+	return m.Match(value), nil
+}
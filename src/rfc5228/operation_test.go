@@ -0,0 +1,72 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestOperationCounter(t *testing.T) {
+	counter := NewOperationCounter(OperationLimits{Comparisons: 2, Total: 4})
+	m, err := CompileMatch("*spam*", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, value := range []string{"SPAM offer", "hello"} {
+		if _, err := m.MatchCounted(value, counter); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var limit *OperationLimitError
+	if ok, err := m.MatchCounted("more spam", counter); ok || !errors.As(err, &limit) || limit.Operation != OperationComparison || limit.Total {
+		t.Errorf("expected the comparison limit, got %v, %v", ok, err)
+	}
+	if counter.Used(OperationComparison) != 2 {
+		t.Errorf("expected 2 comparisons, got %d", counter.Used(OperationComparison))
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := counter.Count(OperationTest); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := counter.Count(OperationRegex); !errors.As(err, &limit) || !limit.Total || limit.Limit != 4 {
+		t.Errorf("expected the total limit, got %v", err)
+	} else if err.Error() != "script exceeded its limit of 4 operations" {
+		t.Errorf("unexpected message %q", err)
+	}
+	if counter.Total() != 4 || counter.Used(OperationRegex) != 0 {
+		t.Errorf("expected 4 operations and no regex, got %d and %d", counter.Total(), counter.Used(OperationRegex))
+	}
+
+	unlimited := NewOperationCounter(OperationLimits{})
+	for i := 0; i < 1000; i++ {
+		if err := unlimited.Count(OperationRegex); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
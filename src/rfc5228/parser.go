@@ -25,35 +25,124 @@
 
 package rfc5228
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
 
 // Tree is the representation of a sieve script
 type Tree struct {
-	Start
+	Name     string         // name of the script; used for error reporting
+	Root     *CommandsNode  // top-level commands of the script
+	Source   *SourceFile    `json:"-"` // text of the script; maps positions to lines and columns
+	Comments []*CommentNode // comments of the script in lexical order
+	arena    *Arena         // allocates the nodes; nil to allocate them as usual
 }
 
-func newTree() *Tree {
-	return &Tree{}
+func newTree(name string) *Tree {
+	t := &Tree{Name: name}
+	t.Root = t.newCommands(0)
+	return t
 }
 
-type Start []*CommandNode
+// Commands returns the top-level commands of the script in lexical order
+func (t *Tree) Commands() []Command {
+	return t.Root.Nodes
+}
 
-func (s *Start) append(node *CommandNode) {
-	*s = append(*s, node)
+// Requires returns all require commands of the script in lexical order,
+// including those (misplaced) inside blocks, also of extension commands such as foreverypart
+func (t *Tree) Requires() []*RequireNode {
+	var requires []*RequireNode
+	var walk func(commands []Command)
+	walk = func(commands []Command) {
+		for _, node := range commands {
+			switch n := node.(type) {
+			case *RequireNode:
+				requires = append(requires, n)
+			case *IfNode:
+				for _, block := range n.Blocks() {
+					walk(block.Commands())
+				}
+			case *GenericCommandNode:
+				if n.Block != nil {
+					walk(n.Block.Commands())
+				}
+			}
+		}
+	}
+	walk(t.Commands())
+	return requires
 }
 
+// A Mode value is a set of flags (or 0). They control the parser behavior.
+type Mode uint
+
+const (
+	ModeStrict Mode = 1 << iota // reject constructs the RFC forbids but that can be parsed unambiguously
+)
+
 // Parser is an eager token stream
 type Parser struct {
 	Pos
-	tokens []item
+	Mode     Mode
+	tokens   []item
+	comments []item // comments are equivalent to whitespace and kept out of the token stream
+	name     string // name of the script; used for error reporting
+	atEOF    bool   // the last call to next returned EOF and did not advance
+	commands bool   // a command other than require has been parsed
+	locale   string // language tag of error messages
+	eof      Pos    // position of the end of the input
+	source   *SourceFile
+	depth    int // number of tests and blocks being parsed
+	maxDepth int // limit of depth; no limit if 0
+	arena    *Arena
+}
+
+// DefaultMaxDepth is the limit of nested tests and blocks unless WithMaxDepth is given
+const DefaultMaxDepth = 100
+
+// ErrTooDeep matches, with errors.Is, the syntax error of a script nesting tests and blocks
+// deeper than the limit
+var ErrTooDeep = errors.New("tests and blocks nested too deep")
+
+// WithMaxDepth limits the nesting of tests (e.g. `not anyof(...)`) and blocks, which the parser
+// descends into recursively, so that untrusted input can't exhaust the stack; 0 disables
+// the limit
+func WithMaxDepth(n int) Option {
+	return func(o *options) {
+		o.maxDepth = n
+	}
+}
+
+// ErrTooLarge matches, with errors.Is, the syntax error of a script larger than the limit
+var ErrTooLarge = errors.New("script too large")
+
+// WithMaxSize limits the size of a script in bytes, e.g. to the largest upload a ManageSieve
+// server accepts; 0, the default, disables the limit
+func WithMaxSize(n int) Option {
+	return func(o *options) {
+		o.maxSize = n
+	}
+}
+
+// MaxSize returns the limit of the size of a script that opts set with WithMaxSize; 0 if
+// there's none
+func MaxSize(opts ...Option) int {
+	return newOptions(opts).maxSize
 }
 
 // next advances the position in the token stream
 func (p *Parser) next() item {
 	// if we read past the end of the input we've reached the end of the file
 	if p.isAtEOF() {
-		return item{typ: itemEOF, pos: p.Pos, val: "EOF"}
+		p.atEOF = true
+		return item{typ: itemEOF, pos: p.eof, end: p.eof}
 	}
+	p.atEOF = false
 
 	// advance the pointer after we returned the token @ pos
 	defer func() {
@@ -80,7 +169,7 @@ func (p *Parser) advance() {
 
 // backup steps back one token
 func (p *Parser) backup() {
-	if !p.isAtEOF() && p.Pos > 0 {
+	if !p.atEOF && p.Pos > 0 {
 		p.Pos -= Pos(1)
 	}
 }
@@ -94,106 +183,561 @@ func (p *Parser) accept(typ itemType) bool {
 }
 
 // newTokenStream creates a token stream
-func newParser(l *lexer) (*Parser, error) {
-	var tokens []item
+func newParser(l *scanner, opts ...Option) (*Parser, error) {
+	o := newOptions(opts)
+	source := NewSourceFile(l.name, l.input)
+	if o.maxSize > 0 && len(l.input) > o.maxSize {
+		return nil, newSyntaxError(o.locale, source, Pos(o.maxSize), CodeTooLarge, len(l.input), o.maxSize)
+	}
+	var tokens, comments []item
+	var eof Pos
 
 iter:
 	for {
 		switch token := l.nextItem(); {
 		case token.typ == itemError:
-			return nil, fmt.Errorf("syntax error: `%s`", token.val)
+			return nil, newSyntaxError(o.locale, source, token.pos, l.code)
 		case token.typ == itemEOF:
+			eof = token.pos
 			break iter
+		case token.typ == itemComment:
+			comments = append(comments, token)
 		default:
 			tokens = append(tokens, token)
 		}
 	}
 
-	return &Parser{tokens: tokens, Pos: Pos(0)}, nil
+	return &Parser{name: l.name, tokens: tokens, comments: comments, Pos: Pos(0), locale: o.locale, eof: eof, source: source, maxDepth: o.maxDepth, arena: o.arena}, nil
+}
+
+// Parse lexes and parses a sieve script; name is used for error reporting.
+// Errors are returned as *SyntaxError.
+//
+// The script needn't end with a CRLF: a hash comment or the `.` line of a multi-line string
+// may end at the end of the input, and a string or bracket comment left open there is a syntax
+// error. Trailing NULs are ignored and byte order marks between tokens are whitespace.
+func Parse(name, input string, mode Mode, opts ...Option) (*Tree, error) {
+	parser, err := newParser(lex(name, input), opts...)
+	if err != nil {
+		return nil, err
+	}
+	parser.Mode = mode
+	return parser.Parse()
+}
+
+// enter descends into a test or block at pos; leave must be called once it is parsed
+func (p *Parser) enter(pos Pos) error {
+	p.depth++
+	if p.maxDepth > 0 && p.depth > p.maxDepth {
+		return p.errorf(pos, CodeTooDeep, p.maxDepth)
+	}
+	return nil
+}
+
+func (p *Parser) leave() {
+	p.depth--
+}
+
+// value returns the text of a token of the parser
+func (p *Parser) value(token item) string {
+	return token.value(p.source.Content)
+}
+
+// describe formats a token of the parser for error messages
+func (p *Parser) describe(token item) string {
+	return token.describe(p.source.Content)
+}
+
+// errorf returns a syntax error in the locale of the parser
+func (p *Parser) errorf(pos Pos, code Code, args ...any) error {
+	return newSyntaxError(p.locale, p.source, pos, code, args...)
 }
 
 func (p *Parser) Parse() (*Tree, error) {
-	tree := newTree()
+	tree := newTree(p.name)
+	tree.Source = p.source
+	tree.arena = p.arena
 	for {
 		switch token := p.peek(); token.typ {
 		case itemEOF:
+			for _, comment := range p.comments {
+				tree.Comments = append(tree.Comments, tree.newComment(comment.pos, p.value(comment)))
+			}
+			tree.attachComments()
 			return tree, nil
-		case itemComment:
-			p.advance() // absorb the peeked token
 		case itemIdentifier:
 			node, err := p.parseCommand(tree)
 			if err != nil {
 				return nil, err
 			}
-			tree.Start.append(&node)
+			tree.Root.append(node)
 		default:
-			return nil, fmt.Errorf("unexpected token")
+			return nil, p.errorf(token.pos, CodeUnexpectedToken, p.describe(token))
 		}
 	}
 }
 
-type keyword int
-
-const (
-	require keyword = iota
-	stop
-	keep
-	discard
-	redirect
-)
-
-const (
-	IF       = "if"
-	REQUIRE  = "require"
-	STOP     = "stop"
-	KEEP     = "keep"
-	DISCARD  = "discard"
-	REDIRECT = "redirect"
-)
-
-func (p *Parser) parseCommand(tree *Tree) (CommandNode, error) {
+func (p *Parser) parseCommand(tree *Tree) (Command, error) {
 	switch token := p.next(); token.typ {
 	case itemEOF:
 		return nil, nil
 	case itemIdentifier:
-		var node CommandNode
+		var node Command
+
+		if !isKeyword(p.value(token), REQUIRE) {
+			p.commands = true
+		}
 
-		switch token.val {
+		switch strings.ToLower(p.value(token)) {
 		case IF:
-			return p.parseIf(tree)
+			return p.parseIf(tree, token)
+		case ELSIF, ELSE:
+			// elsif and else are only valid directly after the block of an if or elsif
+			return nil, p.errorf(token.pos, CodeOrphanElse, p.value(token), p.source.Position(token.pos))
 		case REQUIRE: // require <capabilities: string-list>
-			return p.parseRequire(tree)
+			return p.parseRequire(tree, token)
 		case STOP: // stop
-			node = tree.newStop(p.Pos)
+			node = tree.newStop(token.pos, p.value(token))
 		case KEEP: // keep
-			node = tree.newKeep(p.Pos)
+			node = tree.newKeep(token.pos, p.value(token))
 		case DISCARD: // discard
-			node = tree.newDiscard(p.Pos)
+			node = tree.newDiscard(token.pos, p.value(token))
 		case REDIRECT: //  redirect <address: string>
-			return p.parseRequire(tree)
+			return p.parseRedirect(tree, token)
 		default:
-			return nil, fmt.Errorf("uknown identifier %s", token)
+			if p.Mode&ModeStrict != 0 {
+				return nil, p.errorf(token.pos, CodeUnknownCommand, p.describe(token))
+			}
+			return p.parseGenericCommand(tree, token)
 		}
 
 		// expect inline handled commands (stop/keep/discard) to end with a ;
 		if !p.accept(itemEnd) {
-			return nil, fmt.Errorf("expected end `;`")
+			return nil, p.errorf(p.peek().pos, CodeExpectedEnd)
+		}
+
+		return node, nil
+	default:
+		return nil, p.errorf(token.pos, CodeUnexpectedStart, p.describe(token))
+	}
+}
+
+// parseString parses a single quoted string argument
+func (p *Parser) parseString() (string, error) {
+	token := p.next()
+	if token.typ != itemString {
+		return "", p.errorf(token.pos, CodeExpectedString, p.describe(token))
+	}
+	s, err := unquote(p.value(token))
+	if err != nil {
+		return "", p.errorf(token.pos, CodeMalformedString, p.value(token))
+	}
+	return s, nil
+}
+
+// parseStringList parses a string-list argument; a single string is
+// treated as a string-list with one element
+func (p *Parser) parseStringList() ([]string, error) {
+	if !p.accept(itemStringListOpen) {
+		s, err := p.parseString()
+		if err != nil {
+			return nil, err
+		}
+		return []string{s}, nil
+	}
+
+	var list []string
+	for {
+		s, err := p.parseString()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, s)
+
+		switch token := p.next(); token.typ {
+		case itemComma:
+			// absorb.
+		case itemStringListClose:
+			return list, nil
+		default:
+			return nil, p.errorf(token.pos, CodeExpectedStringList, p.describe(token))
+		}
+	}
+}
+
+// unquote removes the surrounding quotes of a quoted string and resolves the
+// quoted-special escape sequences (`\"` and `\\`); multi-line strings are
+// passed on to unquoteMultiline
+func unquote(s string) (string, error) {
+	if len(s) >= len(textMarker) && strings.EqualFold(s[:len(textMarker)], textMarker) {
+		return unquoteMultiline(s)
+	}
+	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
+		return "", fmt.Errorf("malformed quoted string %s", s)
+	}
+	s = s[1 : len(s)-1]
+	if !strings.ContainsRune(s, '\\') {
+		return s, nil
+	}
+
+	var b strings.Builder
+	b.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String(), nil
+}
+
+// unquoteMultiline returns the content of a multi-line string: the lines following the
+// `text:` line (and its hash comment) up to the terminating `.` line, which includes the CRLF
+// of the last line. The leading dot of a dot-stuffed line is removed (RFC 5228, section 2.4.2).
+func unquoteMultiline(s string) (string, error) {
+	start := strings.Index(s, "\r\n")
+	switch {
+	case start < 0:
+		return "", fmt.Errorf("malformed multi-line string %s", s)
+	case strings.HasSuffix(s[start:], "\r\n.\r\n"):
+		s = s[start+2 : len(s)-3]
+	case strings.HasSuffix(s[start:], "\r\n."):
+		// the `.` line without CRLF at the end of the script
+		s = s[start+2 : len(s)-1]
+	default:
+		return "", fmt.Errorf("malformed multi-line string %s", s)
+	}
+
+	var b strings.Builder
+	b.Grow(len(s))
+	for _, line := range strings.SplitAfter(s, "\r\n") {
+		b.WriteString(strings.TrimPrefix(line, "."))
+	}
+	return b.String(), nil
+}
+
+func (p *Parser) parseRequire(tree *Tree, token item) (Command, error) {
+	// require must come before any other command (RFC 5228, section 3.2)
+	if p.Mode&ModeStrict != 0 && p.commands {
+		return nil, p.errorf(token.pos, CodeRequireNotFirst, p.value(token), p.source.Position(token.pos))
+	}
+
+	node := tree.newRequire(token.pos, p.value(token))
+
+	capabilities, err := p.parseStringList()
+	if err != nil {
+		return nil, err
+	}
+	node.Capabilities = capabilities
+
+	if !p.accept(itemEnd) {
+		return nil, p.errorf(p.peek().pos, CodeExpectedEnd)
+	}
+	return node, nil
+}
+
+func (p *Parser) parseRedirect(tree *Tree, token item) (Command, error) {
+	node := tree.newRedirect(token.pos, p.value(token))
+
+	address, err := p.parseString()
+	if err != nil {
+		return nil, err
+	}
+	node.Address = address
+
+	// the address must be syntactically valid (RFC 5228, section 4.2)
+	if p.Mode&ModeStrict != 0 {
+		if _, _, err := SplitAddress(address); err != nil {
+			return nil, p.errorf(token.pos, CodeInvalidAddress, p.value(token), p.source.Position(token.pos), err)
+		}
+	}
+
+	if !p.accept(itemEnd) {
+		return nil, p.errorf(p.peek().pos, CodeExpectedEnd)
+	}
+	return node, nil
+}
+
+// parseArguments parses the arguments of a command or test
+//
+//	argument = string-list / number / tag
+func (p *Parser) parseArguments(tree *Tree) ([]Argument, error) {
+	var args []Argument
+	for {
+		switch token := p.peek(); {
+		case token.typ == itemTag:
+			p.advance() // absorb the peeked token
+			args = append(args, tree.newTag(token.pos, p.value(token)))
+		case token.typ == itemNumeric:
+			p.advance() // absorb the peeked token
+			n, err := parseNumber(p.value(token))
+			if err != nil {
+				return nil, p.errorf(token.pos, CodeNumberOutOfRange, p.value(token))
+			}
+			args = append(args, tree.newNumber(token.pos, p.value(token), n))
+		case token.typ == itemString:
+			s, err := p.parseString()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, tree.newString(token.pos, s))
+		case token.typ == itemStringListOpen:
+			list, err := p.parseStringList()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, tree.newStringList(token.pos, list))
+		default:
+			return args, nil
+		}
+	}
+}
+
+// parseGenericCommand parses a command that isn't known by the grammar of RFC 5228
+//
+//	command = identifier arguments (";" / block)
+//	arguments = *argument [ test / test-list ]
+func (p *Parser) parseGenericCommand(tree *Tree, token item) (Command, error) {
+	node := tree.newGenericCommand(token.pos, p.value(token))
+	args, err := p.parseArguments(tree)
+	if err != nil {
+		return nil, err
+	}
+	node.Arguments = args
+
+	switch next := p.peek(); next.typ {
+	case itemTestListOpen:
+		if node.Tests, err = p.parseTestList(tree); err != nil {
+			return nil, err
+		}
+		node.TestList = true
+	case itemIdentifier:
+		test, err := p.parseTest(tree)
+		if err != nil {
+			return nil, err
 		}
+		node.Tests = []*TestNode{test}
+	}
 
+	if p.accept(itemEnd) {
 		return node, nil
+	}
+	if p.peek().typ != itemBlockOpen {
+		return nil, p.errorf(p.peek().pos, CodeExpectedEnd)
+	}
+	if node.Block, err = p.parseBlock(tree); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+// parseNumber converts a number with an optional quantifier (K, M or G) to its value
+func parseNumber(val string) (uint64, error) {
+	var shift uint
+	switch val[len(val)-1] {
+	case 'K':
+		shift = 10
+	case 'M':
+		shift = 20
+	case 'G':
+		shift = 30
+	}
+	if shift > 0 {
+		val = val[:len(val)-1]
+	}
+
+	n, err := strconv.ParseUint(val, 10, 64)
+	if err != nil || n > math.MaxUint64>>shift {
+		return 0, fmt.Errorf("number out of range %s", val)
+	}
+	return n << shift, nil
+}
+
+// parseTest parses a test with its arguments and nested test(s)
+//
+//	test = identifier arguments
+//	arguments = *argument [ test / test-list ]
+func (p *Parser) parseTest(tree *Tree) (*TestNode, error) {
+	token := p.next()
+	if token.typ != itemIdentifier {
+		return nil, p.errorf(token.pos, CodeExpectedTest, p.describe(token))
+	}
+	if err := p.enter(token.pos); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+	// control commands and actions can't be used as tests, even if an extension defines the test
+	if kind, ok := LookupKeyword(p.value(token)); p.Mode&ModeStrict != 0 && ok && kind != KeywordTest {
+		return nil, p.errorf(token.pos, CodeReservedIdentifier, p.value(token), p.source.Position(token.pos))
+	}
+	node := tree.newTest(token.pos, p.value(token))
+
+	args, err := p.parseArguments(tree)
+	if err != nil {
+		return nil, err
+	}
+	node.Arguments = args
+
+	list := false
+	switch next := p.peek(); {
+	case next.typ == itemTestListOpen:
+		tests, err := p.parseTestList(tree)
+		if err != nil {
+			return nil, err
+		}
+		node.Tests = tests
+		list = true
+	case next.typ == itemIdentifier:
+		test, err := p.parseTest(tree)
+		if err != nil {
+			return nil, err
+		}
+		node.Tests = []*TestNode{test}
+	}
+
+	switch strings.ToLower(node.Name) {
+	case TRUE, FALSE: // true / false
+		if len(node.Arguments) > 0 || len(node.Tests) > 0 || list {
+			return nil, p.errorf(node.Pos, CodeNoArguments, node.Name, p.source.Position(node.Pos))
+		}
+	case NOT: // not <test1: test>
+		if len(node.Arguments) > 0 || len(node.Tests) != 1 || list {
+			return nil, p.errorf(node.Pos, CodeExpectedSingleTest, node.Name, p.source.Position(node.Pos))
+		}
+	case ALLOF, ANYOF: // allof/anyof <tests: test-list>
+		if len(node.Arguments) > 0 || !list {
+			return nil, p.errorf(node.Pos, CodeExpectedTestList, node.Name, p.source.Position(node.Pos))
+		}
 	default:
-		return nil, fmt.Errorf("unexpected start token %s", token)
+		if list && len(node.Tests) == 0 {
+			return nil, p.errorf(node.Pos, CodeEmptyTestList, node.Name, p.source.Position(node.Pos))
+		}
+		// the arguments of tests with a schema are only validated in strict mode
+		if schema, ok := LookupTestSchema(node.Name); ok && p.Mode&ModeStrict != 0 {
+			if _, err := node.BindSchema(schema); err != nil {
+				return nil, p.errorf(node.Pos, CodeInvalidArguments, node.Name, p.source.Position(node.Pos), err)
+			}
+		}
 	}
+	return node, nil
 }
 
-func (p *Parser) parseRequire(tree *Tree) (CommandNode, error) {
-	return nil, fmt.Errorf("not implemented")
+// parseTestList parses a parenthesized, comma separated list of tests
+//
+//	test-list = "(" test *("," test) ")"
+//
+// The grammar requires at least one test, but an empty test-list is accepted
+// so that allof() and anyof() have a defined meaning: allof() is true, anyof() is false.
+func (p *Parser) parseTestList(tree *Tree) ([]*TestNode, error) {
+	if !p.accept(itemTestListOpen) {
+		return nil, p.errorf(p.peek().pos, CodeExpectedTestListOpen)
+	}
+
+	tests := []*TestNode{}
+	if p.accept(itemTestListClose) {
+		return tests, nil
+	}
+
+	for {
+		test, err := p.parseTest(tree)
+		if err != nil {
+			return nil, err
+		}
+		tests = append(tests, test)
+
+		switch token := p.next(); token.typ {
+		case itemComma:
+			// absorb.
+		case itemTestListClose:
+			return tests, nil
+		default:
+			return nil, p.errorf(token.pos, CodeExpectedTestListEnd, p.describe(token))
+		}
+	}
 }
 
-func (p *Parser) parseRedirect(tree *Tree) (CommandNode, error) {
-	return nil, fmt.Errorf("not implemented")
+// parseBlock parses a block of commands
+//
+//	block = "{" commands "}"
+func (p *Parser) parseBlock(tree *Tree) (*CommandsNode, error) {
+	token := p.next()
+	if token.typ != itemBlockOpen {
+		return nil, p.errorf(token.pos, CodeExpectedBlockOpen, p.describe(token))
+	}
+	if err := p.enter(token.pos); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+	block := tree.newCommands(token.pos)
+
+	for {
+		switch token := p.peek(); token.typ {
+		case itemEOF:
+			return nil, p.errorf(token.pos, CodeExpectedBlockClose)
+		case itemBlockClose:
+			p.advance() // absorb the peeked token
+			return block, nil
+		case itemIdentifier:
+			node, err := p.parseCommand(tree)
+			if err != nil {
+				return nil, err
+			}
+			block.append(node)
+		default:
+			return nil, p.errorf(token.pos, CodeUnexpectedToken, p.describe(token))
+		}
+	}
 }
 
-func (p *Parser) parseIf(tree *Tree) (CommandNode, error) {
-	return nil, fmt.Errorf("not implemented")
+// parseIf parses an if control command and the elsif/else commands chained to it
+//
+//	if <test1: test> <block1: block>
+//	elsif <test2: test> <block2: block>
+//	else <block3: block>
+//
+// elsif and else must immediately follow the block of the preceding if or elsif.
+func (p *Parser) parseIf(tree *Tree, token item) (Command, error) {
+	node := tree.newIf(token.pos, p.value(token))
+
+	test, err := p.parseTest(tree)
+	if err != nil {
+		return nil, err
+	}
+	node.Test = test
+
+	body, err := p.parseBlock(tree)
+	if err != nil {
+		return nil, err
+	}
+	node.Body = body
+
+	for {
+		next := p.peek()
+		if next.typ != itemIdentifier {
+			return node, nil
+		}
+
+		switch strings.ToLower(p.value(next)) {
+		case ELSIF:
+			p.advance() // absorb the peeked token
+			elsif := tree.newElseIf(next.pos, p.value(next))
+			if elsif.Test, err = p.parseTest(tree); err != nil {
+				return nil, err
+			}
+			if elsif.Body, err = p.parseBlock(tree); err != nil {
+				return nil, err
+			}
+			node.ElseIfs = append(node.ElseIfs, elsif)
+		case ELSE:
+			p.advance() // absorb the peeked token
+			els := tree.newElse(next.pos, p.value(next))
+			if els.Body, err = p.parseBlock(tree); err != nil {
+				return nil, err
+			}
+			node.Else = els
+			return node, nil
+		default:
+			return node, nil
+		}
+	}
 }
@@ -26,7 +26,9 @@
 package rfc5228
 
 import (
+	"errors"
 	"os"
+	"strings"
 	"testing"
 )
 
@@ -45,3 +47,298 @@ func TestParser(t *testing.T) {
 	}
 	println(tree)
 }
+
+func parse(t *testing.T, input string) *Tree {
+	t.Helper()
+	parser, err := newParser(lex("test", input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree, err := parser.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tree
+}
+
+func TestParserCaseInsensitiveIdentifiers(t *testing.T) {
+	tree := parse(t, "Require [\"fileinto\", \"envelope\"];\r\nREDIRECT \"a@example.com\";\r\nKeep;\r\nSTOP;\r\n")
+
+	if len(tree.Commands()) != 4 {
+		t.Fatalf("expected 4 commands, got %d", len(tree.Commands()))
+	}
+
+	require, ok := tree.Commands()[0].(*RequireNode)
+	if !ok {
+		t.Fatalf("expected require node, got %T", tree.Commands()[0])
+	}
+	if require.Name != "Require" {
+		t.Errorf("expected original spelling `Require`, got `%s`", require.Name)
+	}
+	if len(require.Capabilities) != 2 || require.Capabilities[0] != "fileinto" || require.Capabilities[1] != "envelope" {
+		t.Errorf("unexpected capabilities %v", require.Capabilities)
+	}
+
+	redirect, ok := tree.Commands()[1].(*RedirectNode)
+	if !ok {
+		t.Fatalf("expected redirect node, got %T", tree.Commands()[1])
+	}
+	if redirect.Address != "a@example.com" {
+		t.Errorf("unexpected address %s", redirect.Address)
+	}
+	if _, ok := tree.Commands()[2].(*KeepNode); !ok {
+		t.Errorf("expected keep node, got %T", tree.Commands()[2])
+	}
+	if _, ok := tree.Commands()[3].(*StopNode); !ok {
+		t.Errorf("expected stop node, got %T", tree.Commands()[3])
+	}
+}
+
+func TestUnquote(t *testing.T) {
+	s, err := unquote(`"a \"quoted\" \\ string"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != `a "quoted" \ string` {
+		t.Errorf("unexpected unquoted string %s", s)
+	}
+}
+
+func TestParserIfElsifElse(t *testing.T) {
+	tree := parse(t, "if header :is \"Sender\" \"owner@example.com\" {\r\n"+
+		"  keep; # keep it\r\n"+
+		"} ELSIF anyof (not address :all :contains [\"To\", \"Cc\"] \"me@example.com\",\r\n"+
+		"               size :over 100K) {\r\n"+
+		"  discard;\r\n"+
+		"  stop;\r\n"+
+		"} elsif exists \"X-Spam\" {\r\n"+
+		"  discard;\r\n"+
+		"}\r\n"+
+		"else { keep; }\r\n"+
+		"stop;\r\n")
+
+	if len(tree.Commands()) != 2 {
+		t.Fatalf("expected 2 commands, got %d", len(tree.Commands()))
+	}
+
+	node, ok := tree.Commands()[0].(*IfNode)
+	if !ok {
+		t.Fatalf("expected if node, got %T", tree.Commands()[0])
+	}
+	if node.Test.Name != "header" || len(node.Test.Arguments) != 3 {
+		t.Errorf("unexpected if test %+v", node.Test)
+	}
+	if len(node.Body.Nodes) != 1 {
+		t.Errorf("expected 1 command in if block, got %d", len(node.Body.Nodes))
+	}
+	if len(node.ElseIfs) != 2 {
+		t.Fatalf("expected 2 elsif blocks, got %d", len(node.ElseIfs))
+	}
+
+	anyof := node.ElseIfs[0].Test
+	if anyof.Name != "anyof" || len(anyof.Tests) != 2 {
+		t.Fatalf("unexpected elsif test %+v", anyof)
+	}
+	if not := anyof.Tests[0]; not.Name != "not" || len(not.Tests) != 1 || len(not.Tests[0].Arguments) != 4 {
+		t.Errorf("unexpected not test %+v", not)
+	}
+	if size := anyof.Tests[1]; len(size.Arguments) != 2 || size.Arguments[1].(*NumberNode).Value != 100*1024 {
+		t.Errorf("unexpected size test %+v", size)
+	}
+	if len(node.ElseIfs[0].Body.Nodes) != 2 {
+		t.Errorf("expected 2 commands in elsif block, got %d", len(node.ElseIfs[0].Body.Nodes))
+	}
+	if node.Else == nil || len(node.Else.Body.Nodes) != 1 {
+		t.Errorf("unexpected else block %+v", node.Else)
+	}
+}
+
+func TestParserDanglingElse(t *testing.T) {
+	for _, input := range []string{
+		"elsif true { stop; }\r\n",
+		"else { stop; }\r\n",
+		"if true { stop; }\r\nkeep;\r\nelse { stop; }\r\n",
+	} {
+		parser, err := newParser(lex("test", input))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := parser.Parse(); err == nil {
+			t.Errorf("expected error for dangling else in %q", input)
+		}
+	}
+}
+
+func TestParserTestArity(t *testing.T) {
+	for _, input := range []string{
+		"if true \"x\" { stop; }\r\n",
+		"if false (true) { stop; }\r\n",
+		"if not (true, false) { stop; }\r\n",
+		"if allof true { stop; }\r\n",
+		"if exists () { stop; }\r\n",
+	} {
+		parser, err := newParser(lex("test", input))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := parser.Parse(); err == nil {
+			t.Errorf("expected error for %q", input)
+		}
+	}
+}
+
+func TestParserRequirePlacement(t *testing.T) {
+	input := "require \"fileinto\";\r\nkeep;\r\nrequire \"envelope\";\r\nif true {\r\n  require \"reject\";\r\n}\r\n" +
+		"foreverypart {\r\n  require \"mime\";\r\n}\r\n"
+
+	if _, err := Parse("test", input, ModeStrict); err == nil {
+		t.Errorf("expected error for misplaced require in strict mode")
+	}
+
+	tree, err := Parse("test", input, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	requires := tree.Requires()
+	if len(requires) != 4 {
+		t.Fatalf("expected 4 require commands, got %d", len(requires))
+	}
+	for i, expected := range []string{"fileinto", "envelope", "reject", "mime"} {
+		if requires[i].Capabilities[0] != expected {
+			t.Errorf("expected capability %s, got %s", expected, requires[i].Capabilities[0])
+		}
+	}
+
+	if _, err := Parse("test", "require \"fileinto\";\r\nrequire \"envelope\";\r\nkeep;\r\n", ModeStrict); err != nil {
+		t.Errorf("unexpected error %s", err)
+	}
+}
+
+func TestParserReservedIdentifiers(t *testing.T) {
+	input := "if allof (Keep, header \"a\" \"b\") {\r\n  stop;\r\n}\r\n"
+
+	_, err := Parse("test", input, ModeStrict)
+	var syntax *SyntaxError
+	if !errors.As(err, &syntax) || syntax.Code != CodeReservedIdentifier || syntax.Pos != 10 {
+		t.Errorf("unexpected error %v", err)
+	}
+	if _, err := Parse("test", input, 0); err != nil {
+		t.Errorf("unexpected error %s", err)
+	}
+
+	if kind, ok := LookupKeyword("FileInto"); !ok || kind != KeywordAction {
+		t.Errorf("unexpected kind %s", kind)
+	}
+	if _, ok := LookupKeyword("vacation"); ok {
+		t.Errorf("vacation is not defined by RFC 5228")
+	}
+}
+
+func TestTreeAccessors(t *testing.T) {
+	tree := parse(t, "if header :Contains \"Subject\" [\"a\", \"b\"] {\r\n  keep;\r\n} elsif size :over 1M {\r\n  discard;\r\n} else {\r\n  stop;\r\n}\r\n")
+
+	node := tree.Commands()[0].(*IfNode)
+	if blocks := node.Blocks(); len(blocks) != 3 {
+		t.Errorf("expected 3 blocks, got %d", len(blocks))
+	}
+	conditions := node.Conditions()
+	if len(conditions) != 2 {
+		t.Fatalf("expected 2 conditions, got %d", len(conditions))
+	}
+
+	header := conditions[0]
+	if !header.HasTag(":contains") || header.HasTag(":is") {
+		t.Errorf("unexpected tags %v", header.Tags())
+	}
+	lists := header.StringLists()
+	if len(lists) != 2 || len(lists[0]) != 1 || len(lists[1]) != 2 {
+		t.Errorf("unexpected string lists %v", lists)
+	}
+	if numbers := conditions[1].Numbers(); len(numbers) != 1 || numbers[0].Value != 1<<20 {
+		t.Errorf("unexpected numbers %v", numbers)
+	}
+}
+
+func TestParserGenericCommands(t *testing.T) {
+	input := "require [\"fileinto\", \"imap4flags\", \"foreverypart\"];\r\n" +
+		"if header :contains \"subject\" \"x\" {\r\n  fileinto :flags [\"\\\\Seen\"] \"Junk\";\r\n}\r\n" +
+		"foreverypart :name \"part\" {\r\n  Break;\r\n}\r\n"
+
+	if _, err := Parse("test", input, ModeStrict); err == nil {
+		t.Errorf("expected an error for unknown commands in strict mode")
+	}
+	tree := parse(t, input)
+	fileinto := tree.Commands()[1].(*IfNode).Body.Commands()[0].(*GenericCommandNode)
+	if fileinto.Name != "fileinto" || len(fileinto.Arguments) != 3 || fileinto.Block != nil ||
+		fileinto.Arguments[1].(*StringListNode).Strings[0] != "\\Seen" {
+		t.Errorf("unexpected command %+v", fileinto)
+	}
+	loop := tree.Commands()[2].(*GenericCommandNode)
+	if loop.Block == nil || len(loop.Block.Commands()) != 1 || loop.Block.Commands()[0].(*GenericCommandNode).Name != "Break" {
+		t.Errorf("unexpected command %+v", loop)
+	}
+	if unused := Analyze(tree).Unused(); len(unused) != 0 {
+		t.Errorf("unexpected unused capabilities %v", unused)
+	}
+
+	// a test or test-list may follow the arguments
+	tree = parse(t, "ensure :all (true, exists \"x\");\r\n")
+	if n := tree.Commands()[0].(*GenericCommandNode); !n.TestList || len(n.Tests) != 2 {
+		t.Errorf("unexpected command %+v", n)
+	}
+	if _, err := Parse("test", "fileinto \"a\" )\r\n", 0); err == nil {
+		t.Errorf("expected an error for a missing `;`")
+	}
+
+	// commands after an edit are moved
+	_, tree = reparse(t, tree, "ensure :all (true, exists \"x\");\r\n", Edit{Start: 0, End: 0, Text: "keep;\r\n"})
+	if n := tree.Commands()[1].(*GenericCommandNode); n.Pos != 7 || n.Arguments[0].Position() != 14 || n.Tests[1].Pos != 26 {
+		t.Errorf("unexpected positions %+v", n)
+	}
+}
+
+func TestParserMaxDepth(t *testing.T) {
+	nested := func(n int) string {
+		return "if " + strings.Repeat("anyof(", n) + "true" + strings.Repeat(")", n) + " { keep; }\r\n"
+	}
+	parse(t, nested(DefaultMaxDepth-1))
+
+	// deeply nested tests and blocks don't exhaust the stack
+	for _, input := range []string{nested(100000), strings.Repeat("foreverypart {", 100000)} {
+		_, err := Parse("test", input, 0)
+		var syntax *SyntaxError
+		if !errors.Is(err, ErrTooDeep) || !errors.As(err, &syntax) || syntax.Code != CodeTooDeep {
+			t.Errorf("expected ErrTooDeep, got %v", err)
+		}
+	}
+
+	// blocks and tests add up to the depth
+	input := "if true {\r\n  if not true {\r\n    keep;\r\n  }\r\n}\r\n"
+	if _, err := Parse("test", input, 0, WithMaxDepth(3)); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	if _, err := Parse("test", input, 0, WithMaxDepth(2)); !errors.Is(err, ErrTooDeep) {
+		t.Errorf("expected ErrTooDeep, got %v", err)
+	}
+	if _, err := Parse("test", nested(1000), 0, WithMaxDepth(0)); err != nil {
+		t.Errorf("unexpected error without a limit %v", err)
+	}
+	if _, err := Parse("test", "keep;", 0); errors.Is(err, ErrTooDeep) {
+		t.Errorf("unexpected ErrTooDeep")
+	}
+}
+
+func TestParserMaxSize(t *testing.T) {
+	input := "keep;\r\nstop;\r\n"
+	if _, err := Parse("test", input, 0, WithMaxSize(len(input))); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	_, err := Parse("test", input, 0, WithMaxSize(10))
+	var syntax *SyntaxError
+	if !errors.Is(err, ErrTooLarge) || !errors.As(err, &syntax) || syntax.Line != 2 || syntax.Message != "script of 14 bytes exceeds the limit of 10 bytes" {
+		t.Errorf("expected ErrTooLarge, got %v", err)
+	}
+	if n := MaxSize(WithMaxDepth(3), WithMaxSize(10)); n != 10 || MaxSize() != 0 {
+		t.Errorf("unexpected limit %d", n)
+	}
+}
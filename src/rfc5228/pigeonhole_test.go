@@ -0,0 +1,134 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestPigeonhole compares the blocks GenerateTestMessages expects a message to run with the
+// actions Dovecot's sieve-test performs for it, for the scripts in input/. sieve-test is
+// taken from $SIEVE_TEST or the PATH; the test is skipped without it.
+//
+// gosieve has no evaluator, so the comparison is limited to the redirect and fileinto
+// actions directly in the expected block: their address and folder must be in the output.
+func TestPigeonhole(t *testing.T) {
+	binary := os.Getenv("SIEVE_TEST")
+	if binary == "" {
+		var err error
+		if binary, err = exec.LookPath("sieve-test"); err != nil {
+			t.Skip("sieve-test not found; set SIEVE_TEST to run the differential test")
+		}
+	}
+
+	scripts, _ := filepath.Glob("../../input/*.sieve")
+	for _, script := range scripts {
+		content, err := os.ReadFile(script)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// some scripts of the corpus end their lines with LF, which sieve-test accepts as well
+		input := strings.ReplaceAll(strings.ReplaceAll(string(content), "\r\n", "\n"), "\n", "\r\n")
+		tree, err := Parse(script, input, 0)
+		if err != nil {
+			t.Logf("%s: skipped: %v", script, err)
+			continue
+		}
+		for _, message := range GenerateTestMessages(tree) {
+			file := filepath.Join(t.TempDir(), "message.eml")
+			if err := os.WriteFile(file, []byte(message.String()), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			output, err := exec.Command(binary, script, file).CombinedOutput()
+			if err != nil {
+				t.Logf("%s: sieve-test failed: %v\n%s", script, err, output)
+				break
+			}
+			position := tree.Source.Position(message.Pos)
+			for _, target := range blockTargets(tree, message.Pos) {
+				if !strings.Contains(string(output), target) {
+					t.Errorf("%s: the %s at %s runs for\n%s\nbut sieve-test doesn't perform its action for %q:\n%s",
+						script, message.Name, position, message, target, output)
+				}
+			}
+		}
+	}
+}
+
+// blockTargets returns the addresses of redirects and the folders of fileintos directly in
+// the block of the if, elsif or else at pos
+func blockTargets(tree *Tree, pos Pos) []string {
+	var block *CommandsNode
+	var find func(commands []Command)
+	find = func(commands []Command) {
+		for _, node := range commands {
+			switch n := node.(type) {
+			case *IfNode:
+				if n.Pos == pos {
+					block = n.Body
+				}
+				for _, elsif := range n.ElseIfs {
+					if elsif.Pos == pos {
+						block = elsif.Body
+					}
+				}
+				if n.Else != nil && n.Else.Pos == pos {
+					block = n.Else.Body
+				}
+				for _, b := range n.Blocks() {
+					find(b.Commands())
+				}
+			case *GenericCommandNode:
+				if n.Block != nil {
+					find(n.Block.Commands())
+				}
+			}
+		}
+	}
+	find(tree.Commands())
+	if block == nil {
+		return nil
+	}
+
+	var targets []string
+	for _, node := range block.Commands() {
+		switch n := node.(type) {
+		case *RedirectNode:
+			targets = append(targets, n.Address)
+		case *GenericCommandNode:
+			if isKeyword(n.Name, "fileinto") && len(n.Arguments) > 0 {
+				if s, ok := n.Arguments[len(n.Arguments)-1].(*StringNode); ok {
+					targets = append(targets, s.Text)
+				}
+			}
+		}
+	}
+	return targets
+}
@@ -0,0 +1,173 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import (
+	"fmt"
+	"strings"
+)
+
+// directiveMarker starts the lines of preprocessing directives; to the parser they are hash comments
+const directiveMarker = "#%"
+
+// section is an #%if block being preprocessed
+type section struct {
+	line   int  // line of the #%if
+	parent bool // the lines around the block are included
+	taken  bool // a branch of the block has been included
+	active bool // the lines of the current branch are included
+	isElse bool // the current branch is the #%else
+}
+
+// Preprocess resolves the conditional sections of a master script for a set of flags, e.g. the
+// features of a plan level at provisioning time, and returns the plain script, which must pass
+// the parser. The directives are lines of their own:
+//
+//	#%if pro || business
+//	require "envelope";
+//	#%elif !basic
+//	...
+//	#%else
+//	...
+//	#%endif
+//
+// A condition combines flags with `!`, `&&` and `||` (in order of precedence); every flag must
+// be defined. Sections can be nested. Directive lines and the lines of sections that aren't
+// taken are removed with their line breaks. Unlike ihave (RFC 5463) the conditions are resolved
+// before the script is installed, so the server never sees the other sections. Directives are
+// recognized on any line, so a multiline string must not have lines starting with `#%`.
+func Preprocess(name, source string, flags map[string]bool) (string, error) {
+	script, err := preprocess(name, source, flags)
+	if err != nil {
+		return "", err
+	}
+	if _, err := Parse(name, script, 0); err != nil {
+		return "", fmt.Errorf("invalid script from %s: %w", name, err)
+	}
+	return script, nil
+}
+
+// PreprocessTemplate resolves the conditional sections of a master template (see Preprocess)
+// and parses the result as a template (see NewTemplate)
+func PreprocessTemplate(name, source string, flags map[string]bool, params map[string]ParamType) (*Template, error) {
+	script, err := preprocess(name, source, flags)
+	if err != nil {
+		return nil, err
+	}
+	return NewTemplate(name, script, params)
+}
+
+func preprocess(name, source string, flags map[string]bool) (string, error) {
+	var b strings.Builder
+	var stack []*section
+	active := true
+	for n, line := 1, ""; source != ""; n++ {
+		if i := strings.Index(source, "\r\n"); i >= 0 {
+			line, source = source[:i+2], source[i+2:]
+		} else {
+			line, source = source, ""
+		}
+
+		directive, ok := strings.CutPrefix(strings.TrimLeft(line, " \t"), directiveMarker)
+		if !ok {
+			if active {
+				b.WriteString(line)
+			}
+			continue
+		}
+		keyword, condition, _ := strings.Cut(strings.TrimSpace(directive), " ")
+		condition = strings.TrimSpace(condition)
+
+		var top *section
+		if len(stack) > 0 {
+			top = stack[len(stack)-1]
+		}
+		switch keyword {
+		case "if":
+			value, err := evalCondition(condition, flags)
+			if err != nil {
+				return "", fmt.Errorf("%s:%d: %w", name, n, err)
+			}
+			stack = append(stack, &section{line: n, parent: active, taken: value, active: active && value})
+		case "elif":
+			if top == nil || top.isElse {
+				return "", fmt.Errorf("%s:%d: `%selif` without `%sif`", name, n, directiveMarker, directiveMarker)
+			}
+			value, err := evalCondition(condition, flags)
+			if err != nil {
+				return "", fmt.Errorf("%s:%d: %w", name, n, err)
+			}
+			top.active = top.parent && !top.taken && value
+			top.taken = top.taken || value
+		case "else":
+			if top == nil || top.isElse || condition != "" {
+				return "", fmt.Errorf("%s:%d: unexpected `%selse`", name, n, directiveMarker)
+			}
+			top.active = top.parent && !top.taken
+			top.isElse, top.taken = true, true
+		case "endif":
+			if top == nil || condition != "" {
+				return "", fmt.Errorf("%s:%d: unexpected `%sendif`", name, n, directiveMarker)
+			}
+			stack = stack[:len(stack)-1]
+		default:
+			return "", fmt.Errorf("%s:%d: unknown directive `%s%s`", name, n, directiveMarker, keyword)
+		}
+		active = true
+		if len(stack) > 0 {
+			active = stack[len(stack)-1].active
+		}
+	}
+	if len(stack) > 0 {
+		return "", fmt.Errorf("%s:%d: `%sif` without `%sendif`", name, stack[len(stack)-1].line, directiveMarker, directiveMarker)
+	}
+	return b.String(), nil
+}
+
+// evalCondition evaluates a condition of flags combined with `!`, `&&` and `||`
+func evalCondition(condition string, flags map[string]bool) (bool, error) {
+	if condition == "" {
+		return false, fmt.Errorf("missing condition")
+	}
+	result := false
+	for _, alternative := range strings.Split(condition, "||") {
+		all := true
+		for _, term := range strings.Split(alternative, "&&") {
+			term = strings.TrimSpace(term)
+			negate := false
+			for strings.HasPrefix(term, "!") {
+				negate, term = !negate, strings.TrimSpace(term[1:])
+			}
+			value, ok := flags[term]
+			if !ok {
+				return false, fmt.Errorf("undefined flag `%s` in condition %q", term, condition)
+			}
+			all = all && value != negate
+		}
+		result = result || all
+	}
+	return result, nil
+}
@@ -0,0 +1,90 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import (
+	"strings"
+	"testing"
+)
+
+const masterScript = "#%if pro || business\r\n" +
+	"require \"envelope\";\r\n" +
+	"#%endif\r\n" +
+	"if header :contains \"subject\" \"[SPAM]\" {\r\n" +
+	"  #%if business && !trial\r\n" +
+	"  redirect \"quarantine@example.com\";\r\n" +
+	"  #%elif pro\r\n" +
+	"  discard;\r\n" +
+	"  #%else\r\n" +
+	"  keep;\r\n" +
+	"  #%endif\r\n" +
+	"}\r\n"
+
+func TestPreprocess(t *testing.T) {
+	for flags, expected := range map[[3]bool]string{
+		{false, false, false}: "if header :contains \"subject\" \"[SPAM]\" {\r\n  keep;\r\n}\r\n",
+		{true, false, false}:  "require \"envelope\";\r\nif header :contains \"subject\" \"[SPAM]\" {\r\n  discard;\r\n}\r\n",
+		{false, true, false}:  "require \"envelope\";\r\nif header :contains \"subject\" \"[SPAM]\" {\r\n  redirect \"quarantine@example.com\";\r\n}\r\n",
+		{false, true, true}:   "require \"envelope\";\r\nif header :contains \"subject\" \"[SPAM]\" {\r\n  keep;\r\n}\r\n",
+	} {
+		script, err := Preprocess("master", masterScript, map[string]bool{"pro": flags[0], "business": flags[1], "trial": flags[2]})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if script != expected {
+			t.Errorf("%v: expected %q, got %q", flags, expected, script)
+		}
+	}
+}
+
+func TestPreprocessErrors(t *testing.T) {
+	flags := map[string]bool{"pro": true}
+	for source, expected := range map[string]string{
+		"#%if pro\r\nkeep;\r\n":                           "master:1: `#%if` without `#%endif`",
+		"keep;\r\n#%endif\r\n":                            "master:2: unexpected `#%endif`",
+		"#%if pro\r\n#%else\r\n#%elif pro\r\n#%endif\r\n": "master:3: `#%elif` without `#%if`",
+		"#%if plus\r\n#%endif\r\n":                        "master:1: undefined flag `plus`",
+		"#%if\r\n#%endif\r\n":                             "master:1: missing condition",
+		"#%ifdef pro\r\n#%endif\r\n":                      "master:1: unknown directive `#%ifdef`",
+		"#%if pro\r\nkeep\r\n#%endif\r\n":                 "invalid script from master",
+	} {
+		if _, err := Preprocess("master", source, flags); err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("%q: expected %q, got %v", source, expected, err)
+		}
+	}
+}
+
+func TestPreprocessTemplate(t *testing.T) {
+	source := "#%if forward\r\nredirect {{address}};\r\n#%else\r\nkeep;\r\n#%endif\r\n"
+	params := map[string]ParamType{"address": ParamAddress}
+	tmpl, err := PreprocessTemplate("master", source, map[string]bool{"forward": true}, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if script, err := tmpl.Execute(map[string]any{"address": "me@example.org"}); err != nil || script != "redirect \"me@example.org\";\r\n" {
+		t.Errorf("unexpected script %q (%v)", script, err)
+	}
+}
@@ -0,0 +1,98 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestQuoteString(t *testing.T) {
+	for s, expected := range map[string]string{
+		"":             `""`,
+		`say "hi"`:     `"say \"hi\""`,
+		`back\slash`:   `"back\\slash"`,
+		"a\r\nb\nc\rd": "\"a\r\nb\r\nc\r\nd\"",
+		"bücher 日本語":   "\"bücher 日本語\"",
+	} {
+		quoted, err := QuoteString(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if quoted != expected {
+			t.Errorf("%q: expected %q, got %q", s, expected, quoted)
+		}
+	}
+
+	if _, err := QuoteString("nul\x00"); err == nil {
+		t.Errorf("expected error for NUL")
+	}
+}
+
+func TestQuoteStringList(t *testing.T) {
+	quoted, err := QuoteStringList([]string{"a", `"b"`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if quoted != `["a", "\"b\""]` {
+		t.Errorf("unexpected string list %s", quoted)
+	}
+
+	if _, err := QuoteStringList(nil); err == nil {
+		t.Errorf("expected error for empty string list")
+	}
+}
+
+// FuzzQuoteString checks that any quoted string parses back to the original value,
+// with line breaks normalized to CRLF, and never escapes its argument
+func FuzzQuoteString(f *testing.F) {
+	for _, seed := range []string{"", "a", `"`, `\`, "\r\n", "\n", "\r", `"; discard; "`, "\r\n.\r\n", "ü", "\xff"} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, s string) {
+		quoted, err := QuoteString(s)
+		if err != nil {
+			if !strings.ContainsRune(s, 0) {
+				t.Fatalf("%q: unexpected error %s", s, err)
+			}
+			return
+		}
+
+		tree, err := Parse("fuzz", "redirect "+quoted+";\r\nkeep;\r\n", 0)
+		if err != nil {
+			t.Fatalf("%q: %s", s, err)
+		}
+		if len(tree.Commands()) != 2 {
+			t.Fatalf("%q: escaped its argument", s)
+		}
+
+		expected := strings.NewReplacer("\r\n", "\r\n", "\r", "\r\n", "\n", "\r\n").Replace(s)
+		if address := tree.Commands()[0].(*RedirectNode).Address; address != expected {
+			t.Fatalf("%q: expected %q, got %q", s, expected, address)
+		}
+	})
+}
@@ -0,0 +1,105 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import (
+	"sync"
+	"time"
+)
+
+// RateKey identifies the sender side and recipient of an outgoing action
+type RateKey struct {
+	User      string // owner of the script
+	Recipient string // address the action sends to, e.g. the redirect address or vacation recipient
+	Action    string // identifier of the action, e.g. "redirect", "vacation" or "notify"
+}
+
+// RateLimiter is consulted before an outgoing action (redirect, vacation, notify) is
+// executed, so that a broken or compromised script can't send mail without bounds
+type RateLimiter interface {
+	// Allow reports whether the action may be executed now and, if so, accounts for it
+	Allow(key RateKey) bool
+}
+
+// TokenBucket is a RateLimiter with a token bucket per key: a bucket holds up to Burst tokens,
+// is refilled at Rate tokens per second and every allowed action takes a token
+type TokenBucket struct {
+	Rate  float64 // tokens added per second
+	Burst int     // capacity of a bucket; a new bucket is full
+
+	mu      sync.Mutex
+	buckets map[RateKey]*bucket
+	now     func() time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewTokenBucket returns a TokenBucket allowing burst actions per key at once and rate per second after that
+func NewTokenBucket(rate float64, burst int) *TokenBucket {
+	return &TokenBucket{Rate: rate, Burst: burst, buckets: map[RateKey]*bucket{}, now: time.Now}
+}
+
+// refill adds the tokens accrued since the last update of a bucket
+func (l *TokenBucket) refill(b *bucket, now time.Time) {
+	b.tokens += now.Sub(b.last).Seconds() * l.Rate
+	if b.tokens > float64(l.Burst) {
+		b.tokens = float64(l.Burst)
+	}
+	b.last = now
+}
+
+func (l *TokenBucket) Allow(key RateKey) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(l.Burst), last: now}
+		l.buckets[key] = b
+	}
+	l.refill(b, now)
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// Prune removes the buckets that are full again, which behave like new buckets, so that
+// the memory used is bounded by the keys that were active recently
+func (l *TokenBucket) Prune() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	for key, b := range l.buckets {
+		if l.refill(b, now); b.tokens >= float64(l.Burst) {
+			delete(l.buckets, key)
+		}
+	}
+}
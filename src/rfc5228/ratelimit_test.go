@@ -0,0 +1,61 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTokenBucket(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := NewTokenBucket(0.5, 2)
+	l.now = func() time.Time { return now }
+	key := RateKey{User: "user", Recipient: "a@example.com", Action: REDIRECT}
+
+	var limiter RateLimiter = l
+	if !limiter.Allow(key) || !limiter.Allow(key) || limiter.Allow(key) {
+		t.Errorf("expected a burst of 2")
+	}
+	if !limiter.Allow(RateKey{User: "user", Recipient: "b@example.com", Action: REDIRECT}) {
+		t.Errorf("expected a separate bucket for another recipient")
+	}
+
+	now = now.Add(time.Second)
+	if limiter.Allow(key) {
+		t.Errorf("expected half a token after a second")
+	}
+	now = now.Add(time.Second)
+	if !limiter.Allow(key) || limiter.Allow(key) {
+		t.Errorf("expected one token after two seconds")
+	}
+
+	now = now.Add(time.Minute)
+	l.Prune()
+	if len(l.buckets) != 0 {
+		t.Errorf("expected full buckets to be pruned, got %d", len(l.buckets))
+	}
+}
@@ -0,0 +1,259 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+// endsWithStop reports whether the last command of a block is stop
+func endsWithStop(block *CommandsNode) bool {
+	commands := block.Commands()
+	if len(commands) == 0 {
+		return false
+	}
+	_, ok := commands[len(commands)-1].(*StopNode)
+	return ok
+}
+
+// shadowed warns about an elsif condition that implies an earlier condition of the chain:
+// when the elsif is reached the earlier condition was false, so it is false as well
+func (v *validator) shadowed(elsif *ElseIfNode, earlier []*TestNode) {
+	if _, ok := constant(elsif.Test); ok {
+		return // reported as constant condition
+	}
+	for _, test := range earlier {
+		if implies(elsif.Test, test) {
+			v.relatedf(elsif.Test.Pos, []Pos{test.Pos}, CodeShadowed, elsif.Name, v.source.Position(test.Pos))
+			return
+		}
+	}
+}
+
+// shadowedByStop warns about an if condition that implies the condition of a preceding
+// rule that ends with stop: whenever it would be true, the script has already stopped
+func (v *validator) shadowedByStop(n *IfNode, stops []*IfNode) {
+	if _, ok := constant(n.Test); ok {
+		return // reported as constant condition
+	}
+	for _, stop := range stops {
+		if implies(n.Test, stop.Test) {
+			v.relatedf(n.Test.Pos, []Pos{stop.Test.Pos}, CodeShadowedByStop, n.Name, v.source.Position(stop.Pos))
+			return
+		}
+	}
+}
+
+// contradictions warns about allof tests holding tests that can't be true at the same time
+func (v *validator) contradictions(test *TestNode) {
+	if isKeyword(test.Name, ALLOF) {
+	search:
+		for i, a := range test.Tests {
+			for _, b := range test.Tests[i+1:] {
+				if contradicts(a, b) {
+					v.relatedf(a.Pos, []Pos{b.Pos}, CodeContradiction, test.Name, a.Name, v.source.Position(a.Pos), b.Name, v.source.Position(b.Pos))
+					break search
+				}
+			}
+		}
+	}
+	for _, t := range test.Tests {
+		v.contradictions(t)
+	}
+}
+
+// sizeLimit returns the bound of a size test: size :over/:under <limit: number>
+func sizeLimit(test *TestNode) (over bool, limit uint64, ok bool) {
+	if !isKeyword(test.Name, SIZE) {
+		return false, 0, false
+	}
+	args, err := test.Bind()
+	if err != nil || args.Tag("size") == "" {
+		return false, 0, false
+	}
+	number, _ := args.Number(0)
+	return args.Tag("size") == ":over", number.Value, true
+}
+
+// contradicts conservatively reports whether two tests can't both be true
+func contradicts(a, b *TestNode) bool {
+	if isKeyword(a.Name, NOT) && FingerprintTest(a.Tests[0]) == FingerprintTest(b) ||
+		isKeyword(b.Name, NOT) && FingerprintTest(b.Tests[0]) == FingerprintTest(a) {
+		return true
+	}
+
+	overA, limitA, okA := sizeLimit(a)
+	overB, limitB, okB := sizeLimit(b)
+	if !okA || !okB || overA == overB {
+		return false
+	}
+	if !overA {
+		limitA, limitB = limitB, limitA
+	}
+	// size > limitA and size < limitB
+	return limitB <= limitA+1
+}
+
+// implies conservatively reports whether a being true means that b is true
+func implies(a, b *TestNode) bool {
+	if FingerprintTest(a) == FingerprintTest(b) {
+		return true
+	}
+	if value, ok := constant(b); ok && value {
+		return true
+	}
+	if value, ok := constant(a); ok && !value {
+		return true
+	}
+
+	switch {
+	case isKeyword(a.Name, ALLOF):
+		for _, t := range a.Tests {
+			if implies(t, b) {
+				return true
+			}
+		}
+	case isKeyword(a.Name, ANYOF) && len(a.Tests) > 0:
+		all := true
+		for _, t := range a.Tests {
+			all = all && implies(t, b)
+		}
+		if all {
+			return true
+		}
+	}
+
+	switch {
+	case isKeyword(b.Name, ANYOF):
+		for _, t := range b.Tests {
+			if implies(a, t) {
+				return true
+			}
+		}
+		return false
+	case isKeyword(b.Name, ALLOF) && len(b.Tests) > 0:
+		for _, t := range b.Tests {
+			if !implies(a, t) {
+				return false
+			}
+		}
+		return true
+	}
+
+	if overA, limitA, ok := sizeLimit(a); ok {
+		overB, limitB, ok := sizeLimit(b)
+		return ok && overA == overB && (overA && limitA >= limitB || !overA && limitA <= limitB)
+	}
+	return impliesMatch(a, b)
+}
+
+// match is a header or address test with the default comparator
+type match struct {
+	part  string // address-part tag for address tests, e.g. ":domain"
+	typ   string // match-type tag
+	names []string
+	keys  []string
+}
+
+func matchOf(test *TestNode) (m match, ok bool) {
+	if !isKeyword(test.Name, HEADER) && !isKeyword(test.Name, ADDRESS) {
+		return m, false
+	}
+	args, err := test.Bind()
+	if err != nil {
+		return m, false
+	}
+
+	m = match{typ: ":is", names: args.StringList(0), keys: args.StringList(1), part: args.Tag("address-part")}
+	switch typ := args.Tag("match-type"); typ {
+	case "":
+	case ":is", ":contains", ":matches":
+		m.typ = typ
+	default:
+		// relational and other match types are not analyzed
+		return m, false
+	}
+	switch m.part {
+	case "":
+		if isKeyword(test.Name, ADDRESS) {
+			m.part = ":all"
+		}
+	case ":all", ":localpart", ":domain":
+	default:
+		return m, false
+	}
+	// other comparators and the tags of extensions (e.g. :index) are not analyzed
+	for group := range args.groups {
+		if group != "match-type" && group != "address-part" && group != "comparator" {
+			return m, false
+		}
+	}
+	return m, equalFoldASCII(args.Comparator(), ComparatorASCIICasemap)
+}
+
+// impliesMatch reports whether header or address test a implies test b: every header
+// a looks at is looked at by b, and every value matching a key of a matches a key of b
+func impliesMatch(a, b *TestNode) bool {
+	ma, ok := matchOf(a)
+	if !ok {
+		return false
+	}
+	mb, ok := matchOf(b)
+	if !ok || !isKeyword(a.Name, b.Name) || ma.part != mb.part || ma.typ == ":matches" || mb.typ == ":matches" {
+		return false
+	}
+
+	for _, name := range ma.names {
+		if !containsFold(mb.names, name) {
+			return false
+		}
+	}
+
+	for _, ka := range ma.keys {
+		covered := false
+		for _, kb := range mb.keys {
+			switch {
+			case ma.typ == ":is" && mb.typ == ":is":
+				covered = equalFoldASCII(ka, kb)
+			case mb.typ == ":contains":
+				// ka is (or is contained in) the value, so kb is contained in the value as well
+				covered = containsFoldASCII(ka, kb)
+			}
+			if covered {
+				break
+			}
+		}
+		if !covered {
+			return false
+		}
+	}
+	return true
+}
+
+func containsFold(list []string, s string) bool {
+	for _, l := range list {
+		if equalFoldASCII(l, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,116 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValidateContradictions(t *testing.T) {
+	tree := parse(t, "if allof (size :over 1M, size :under 100K) {\r\n  discard;\r\n}\r\n"+
+		"if allof (exists \"X\", not exists \"X\") {\r\n  discard;\r\n}\r\n"+
+		"if allof (size :over 10, size :under 12) {\r\n  keep;\r\n}\r\n")
+
+	warnings := Validate(tree)
+	if len(warnings) != 2 {
+		t.Fatalf("expected 2 warnings, got %v", warnings)
+	}
+	if warnings[0].Pos != 10 || !reflect.DeepEqual(warnings[0].Related, []Pos{25}) {
+		t.Errorf("unexpected positions %s", warnings[0])
+	}
+	if warnings[0].Message != "`allof` can never be true: `size` at 1:11 contradicts `size` at 1:26" {
+		t.Errorf("unexpected warning %s", warnings[0])
+	}
+}
+
+func TestValidateShadowedConditions(t *testing.T) {
+	tree := parse(t, "if header :contains \"subject\" \"money\" {\r\n  discard;\r\n"+
+		"} elsif header :is \"Subject\" \"Make money fast\" {\r\n  keep;\r\n"+
+		"} elsif size :over 1M {\r\n  keep;\r\n"+
+		"} elsif allof (size :over 2M, exists \"X\") {\r\n  keep;\r\n"+
+		"} elsif header :is \"subject\" \"hello\" {\r\n  keep;\r\n}\r\n")
+
+	warnings := Validate(tree)
+	if len(warnings) != 2 {
+		t.Fatalf("expected 2 warnings, got %v", warnings)
+	}
+	if warnings[0].Message != "`elsif` condition is shadowed by the earlier condition at 1:4" {
+		t.Errorf("unexpected warning %s", warnings[0])
+	}
+	if warnings[1].Related[0] != tree.Commands()[0].(*IfNode).ElseIfs[1].Test.Pos {
+		t.Errorf("unexpected related position %s", warnings[1])
+	}
+}
+
+func TestValidateShadowedByStop(t *testing.T) {
+	tree := parse(t, "if address :domain :is \"from\" \"example.com\" {\r\n  discard;\r\n  stop;\r\n}\r\n"+
+		"if allof (address :domain :is \"from\" \"example.com\", size :over 1K) {\r\n  discard;\r\n}\r\n"+
+		"if address :localpart :is \"from\" \"example.com\" {\r\n  discard;\r\n}\r\n")
+
+	warnings := Validate(tree)
+	if len(warnings) != 1 || warnings[0].Message != "`if` condition is shadowed by the rule at 1:1 that ends with `stop`" {
+		t.Errorf("unexpected warnings %v", warnings)
+	}
+}
+
+func TestImplies(t *testing.T) {
+	for _, expected := range []struct {
+		a, b    string
+		implies bool
+	}{
+		{"size :over 2M", "size :over 1M", true},
+		{"size :over 1M", "size :over 2M", false},
+		{"size :under 1K", "size :under 2K", true},
+		{"size :under 1K", "size :over 2K", false},
+		{"header :is \"subject\" \"abc\"", "header :contains [\"subject\", \"to\"] \"B\"", true},
+		{"header :is [\"subject\", \"to\"] \"abc\"", "header :contains \"subject\" \"b\"", false},
+		{"header :contains \"subject\" \"abc\"", "header :is \"subject\" \"abc\"", false},
+		{"header :comparator \"i;octet\" :is \"subject\" \"abc\"", "header :is \"subject\" \"abc\"", false},
+		{"anyof (size :over 2M, size :over 3M)", "size :over 1M", true},
+		{"exists \"X\"", "anyof (exists \"Y\", exists \"X\")", true},
+		{"exists \"X\"", "allof (exists \"Y\", exists \"X\")", false},
+	} {
+		a := parseTestString(t, expected.a)
+		b := parseTestString(t, expected.b)
+		if implies(a, b) != expected.implies {
+			t.Errorf("%s => %s: expected %t", expected.a, expected.b, expected.implies)
+		}
+	}
+}
+
+func parseTestString(t *testing.T, input string) *TestNode {
+	t.Helper()
+	parser, err := newParser(lex("test", input+" "))
+	if err != nil {
+		t.Fatal(err)
+	}
+	test, err := parser.parseTest(newTree("test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return test
+}
@@ -0,0 +1,136 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Severity ranks diagnostics: syntax errors are errors, the findings of the validator are
+// warnings unless their code has another severity (see SeverityOf), e.g. for a linter or
+// language server that shows hints differently from problems. Severities are ordered, so
+// `w.Severity >= SeverityWarning` selects warnings and errors.
+type Severity int
+
+const (
+	SeverityHint    Severity = iota + 1 // a suggestion, e.g. a simpler way to write a construct
+	SeverityInfo                        // a fact about the script that is worth knowing
+	SeverityWarning                     // a likely mistake; the script still runs
+	SeverityError                       // the script can't run as intended
+)
+
+var severityNames = map[Severity]string{
+	SeverityHint:    "hint",
+	SeverityInfo:    "info",
+	SeverityWarning: "warning",
+	SeverityError:   "error",
+}
+
+func (s Severity) String() string {
+	if name, ok := severityNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("Severity(%d)", int(s))
+}
+
+// MarshalText encodes the severity as its name, e.g. in the JSON of a Warning
+func (s Severity) MarshalText() ([]byte, error) {
+	if _, ok := severityNames[s]; !ok {
+		return nil, fmt.Errorf("invalid severity %d", int(s))
+	}
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText decodes the name of a severity
+func (s *Severity) UnmarshalText(text []byte) error {
+	for severity, name := range severityNames {
+		if name == string(text) {
+			*s = severity
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown severity %q", text)
+}
+
+var (
+	severitiesMu sync.RWMutex
+	severities   = map[Code]Severity{
+		CodeBlockNeverRuns: SeverityInfo,
+	}
+)
+
+// SeverityOf returns the severity of a code: SeverityError for syntax errors, the registered
+// severity of a warning (see RegisterSeverity) and SeverityWarning otherwise
+func SeverityOf(code Code) Severity {
+	if !IsWarning(code) {
+		return SeverityError
+	}
+	severitiesMu.RLock()
+	defer severitiesMu.RUnlock()
+	if severity, ok := severities[code]; ok {
+		return severity
+	}
+	return SeverityWarning
+}
+
+// RegisterSeverity sets the severity of the code of a warning, e.g. of a custom validation
+// pass (see RegisterValidation); the severity of syntax errors can't be changed
+func RegisterSeverity(code Code, severity Severity) {
+	if !IsWarning(code) {
+		return
+	}
+	severitiesMu.Lock()
+	defer severitiesMu.Unlock()
+	severities[code] = severity
+}
+
+// WithWarningsAsErrors reports findings of the validator as errors, e.g. to fail a deployment
+// on warnings: those of the given codes whatever their severity, or, without codes, all
+// findings of SeverityWarning
+func WithWarningsAsErrors(codes ...Code) Option {
+	return func(o *options) {
+		if len(codes) == 0 {
+			o.allErrors = true
+			return
+		}
+		if o.asErrors == nil {
+			o.asErrors = map[Code]bool{}
+		}
+		for _, code := range codes {
+			o.asErrors[code] = true
+		}
+	}
+}
+
+// severity returns the severity of a finding with code
+func (o options) severity(code Code) Severity {
+	severity := SeverityOf(code)
+	if o.asErrors[code] || o.allErrors && severity == SeverityWarning {
+		return SeverityError
+	}
+	return severity
+}
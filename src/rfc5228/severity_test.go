@@ -0,0 +1,78 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSeverity(t *testing.T) {
+	if SeverityOf(CodeExpectedEnd) != SeverityError || SeverityOf(CodeShadowed) != SeverityWarning || SeverityOf(CodeBlockNeverRuns) != SeverityInfo {
+		t.Error("unexpected severities")
+	}
+	RegisterSeverity(CodeExpectedEnd, SeverityHint)
+	if SeverityOf(CodeExpectedEnd) != SeverityError {
+		t.Error("unexpected severity of a syntax error")
+	}
+
+	tree := parse(t, "require [\"fileinto\", \"fileinto\"];\r\nrequire \"fileinto\";\r\nredirect \"postmaster\";\r\n")
+	warnings := Validate(tree)
+	if len(warnings) != 3 {
+		t.Fatalf("unexpected warnings %v", warnings)
+	}
+	for _, w := range warnings {
+		if w.Severity != SeverityWarning {
+			t.Errorf("unexpected severity %s of %s", w.Severity, w.Code)
+		}
+	}
+	if warnings[0].Code != CodeDuplicateCapability || warnings[0].Related[0] != 0 || warnings[1].Code != CodeDuplicateCapability {
+		t.Errorf("unexpected warnings %v", warnings)
+	}
+
+	for _, test := range []struct {
+		opts     []Option
+		expected []Severity
+	}{
+		{[]Option{WithWarningsAsErrors()}, []Severity{SeverityError, SeverityError, SeverityError}},
+		{[]Option{WithWarningsAsErrors(CodeRedirectAddress)}, []Severity{SeverityWarning, SeverityWarning, SeverityError}},
+	} {
+		for i, w := range Validate(tree, test.opts...) {
+			if w.Severity != test.expected[i] {
+				t.Errorf("%s: expected %s, got %s", w.Code, test.expected[i], w.Severity)
+			}
+		}
+	}
+
+	data, err := json.Marshal(warnings[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Warning
+	if err := json.Unmarshal(data, &decoded); err != nil || decoded.Severity != SeverityWarning {
+		t.Errorf("unexpected warning %s (%v)", data, err)
+	}
+}
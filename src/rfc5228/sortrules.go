@@ -0,0 +1,174 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SortRules returns the script of a tree in a canonical order, so that scripts generated by
+// editors diff well: the top-level require commands are merged into a single one at the
+// start of the script, with the capabilities sorted, and top-level if rules are ordered by
+// their key. The key of a rule is the id of its annotation (see Annotation); rules without one
+// follow the annotated rules, ordered by the subject of their first test (the strings of the
+// first test without test arguments, e.g. `subject viagra` for
+// `header :contains "subject" "viagra"`). Rules with the same key keep their order.
+//
+// Only runs of consecutive if rules are reordered; any other top-level command, whose
+// position matters (e.g. keep or stop), stays in place between them. Reordering assumes the
+// rules of a run are independent, as they are in scripts generated by editors: if rules
+// match the same message, their actions run in a different order. The text of each rule,
+// including the comments preceding it, is copied unchanged.
+func SortRules(tree *Tree) (string, error) {
+	if tree.Source == nil {
+		return "", fmt.Errorf("tree %s has no source", tree.Name)
+	}
+	content := tree.Source.Content
+	commands := tree.Commands()
+	if len(commands) == 0 {
+		return content, nil
+	}
+	tokens, err := Tokenize(tree.Name, content, WithComments(false))
+	if err != nil {
+		return "", err
+	}
+
+	// the text of a command runs from its first leading comment up to the text of the next;
+	// leading comments inside the previous command (at the end of its block) are not its own
+	starts := make([]Pos, len(commands)+1)
+	starts[len(commands)] = Pos(len(content))
+	end, token := Pos(0), 0
+	for i, node := range commands {
+		starts[i] = node.Position()
+		for _, comment := range tree.CommentsOf(node) {
+			if !comment.Trailing && comment.Pos < starts[i] && comment.Pos >= end {
+				starts[i] = comment.Pos
+			}
+		}
+		next := Pos(len(content))
+		if i+1 < len(commands) {
+			next = commands[i+1].Position()
+		}
+		for ; token < len(tokens) && tokens[token].Pos < next; token++ {
+			end = tokens[token].End()
+		}
+	}
+	starts[0] = 0
+
+	var b, header strings.Builder
+	var capabilities []string
+	seen := map[string]bool{}
+	var rules []sortedRule
+	flush := func() {
+		sort.SliceStable(rules, func(i, j int) bool { return rules[i].less(rules[j]) })
+		for _, rule := range rules {
+			b.WriteString(rule.text)
+		}
+		rules = nil
+	}
+	for i, node := range commands {
+		text := segment(content[starts[i]:starts[i+1]])
+		switch n := node.(type) {
+		case *RequireNode:
+			for _, capability := range n.Capabilities {
+				if !seen[capability] {
+					seen[capability] = true
+					capabilities = append(capabilities, capability)
+				}
+			}
+			// the comments of require commands are kept before the merged one
+			for _, comment := range tree.Comments {
+				if comment.Pos >= starts[i] && comment.Pos < starts[i+1] {
+					header.WriteString(comment.Raw + "\r\n")
+				}
+			}
+		case *IfNode:
+			rule := sortedRule{text: text}
+			if id, ok := tree.AnnotationOf(n).Get("id"); ok {
+				rule.annotated, rule.key = true, id
+			} else {
+				rule.key = subjectOf(n.Test)
+			}
+			rules = append(rules, rule)
+		default:
+			flush()
+			b.WriteString(text)
+		}
+	}
+	flush()
+
+	if len(capabilities) > 0 {
+		sort.Strings(capabilities)
+		list, err := QuoteStringList(capabilities)
+		if err != nil {
+			return "", err
+		}
+		header.WriteString(REQUIRE + " " + list + ";\r\n")
+		if b.Len() > 0 {
+			header.WriteString("\r\n")
+		}
+	}
+	return header.String() + strings.TrimRight(b.String(), " \t\r\n") + "\r\n", nil
+}
+
+// sortedRule is the text of a top-level if rule with its sort key
+type sortedRule struct {
+	text      string
+	annotated bool // the key is the id of the annotation of the rule
+	key       string
+}
+
+func (r sortedRule) less(o sortedRule) bool {
+	if r.annotated != o.annotated {
+		return r.annotated
+	}
+	return r.key < o.key
+}
+
+// segment returns the text of a command with the whitespace after it reduced to a line
+// break, or to a blank line if it holds one
+func segment(text string) string {
+	trimmed := strings.TrimRight(text, " \t\r\n")
+	if strings.Count(text[len(trimmed):], "\n") > 1 {
+		return trimmed + "\r\n\r\n"
+	}
+	return trimmed + "\r\n"
+}
+
+// subjectOf returns the sort key of a test: the lowercased strings of the first test, in
+// depth-first order, without test arguments
+func subjectOf(test *TestNode) string {
+	for len(test.Tests) > 0 {
+		test = test.Tests[0]
+	}
+	var strs []string
+	for _, list := range test.StringLists() {
+		strs = append(strs, list...)
+	}
+	return strings.ToLower(strings.Join(strs, " "))
+}
@@ -0,0 +1,82 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import "testing"
+
+func TestSortRules(t *testing.T) {
+	input := lines(
+		`# banner`,
+		`require "fileinto";`,
+		``,
+		`#@ id=zeta`,
+		`if header :is "subject" "z" {`,
+		`  fileinto "Z";`,
+		`  # end of zeta`,
+		`}`,
+		``,
+		`if address :is "from" "b@example.org" { discard; } # b`,
+		`require ["envelope", "fileinto"]; # late`,
+		`#@ id=alpha`,
+		`if envelope :is "to" "a" { keep; }`,
+		``,
+		`keep;`,
+		``,
+		`if exists "x" { stop; }`,
+		`if anyof (exists "a", true) { stop; }`,
+	)
+	expected := lines(
+		`# banner`,
+		`# late`,
+		`require ["envelope", "fileinto"];`,
+		``,
+		`#@ id=alpha`,
+		`if envelope :is "to" "a" { keep; }`,
+		``,
+		`#@ id=zeta`,
+		`if header :is "subject" "z" {`,
+		`  fileinto "Z";`,
+		`  # end of zeta`,
+		`}`,
+		``,
+		`if address :is "from" "b@example.org" { discard; } # b`,
+		`keep;`,
+		``,
+		`if anyof (exists "a", true) { stop; }`,
+		`if exists "x" { stop; }`,
+	)
+
+	output, err := SortRules(parse(t, input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output != expected {
+		t.Fatalf("expected\n%s\ngot\n%s", expected, output)
+	}
+	if again, err := SortRules(parse(t, output)); err != nil || again != output {
+		t.Errorf("sorting again gives\n%s\n%v", again, err)
+	}
+}
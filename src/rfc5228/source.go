@@ -0,0 +1,174 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import (
+	"fmt"
+	"sort"
+	"unicode/utf8"
+)
+
+// Position is a location in a source file. Lines and columns are 1-based; columns count
+// bytes (Column) or UTF-16 code units (UTF16Column, as used by the Language Server
+// Protocol, whose lines and characters are 0-based).
+type Position struct {
+	Offset      Pos // byte offset in the input string
+	Line        int // line number, 0 if unknown
+	Column      int // column in bytes
+	UTF16Column int // column in UTF-16 code units
+}
+
+// IsValid reports whether the line and column of the position are known
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+// String returns `line:column`, or the byte offset if the line is not known
+func (p Position) String() string {
+	if !p.IsValid() {
+		return fmt.Sprintf("%d", p.Offset)
+	}
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// SourceFile maps byte offsets of a script to lines and columns. Lines end with CRLF, LF or
+// a CR that is not followed by LF, so positions are also correct for input the lexer
+// rejects for a bare line break.
+type SourceFile struct {
+	Name    string
+	Content string
+	lines   []Pos // offsets of the first byte of each line
+}
+
+// NewSourceFile indexes the lines of content
+func NewSourceFile(name, content string) *SourceFile {
+	f := &SourceFile{Name: name, Content: content, lines: []Pos{0}}
+	for i := 0; i < len(content); i++ {
+		switch content[i] {
+		case '\r':
+			if i+1 < len(content) && content[i+1] == '\n' {
+				i++
+			}
+			f.lines = append(f.lines, Pos(i+1))
+		case '\n':
+			f.lines = append(f.lines, Pos(i+1))
+		}
+	}
+	return f
+}
+
+// LineCount returns the number of lines; a line break at the end of the content
+// starts an (empty) last line
+func (f *SourceFile) LineCount() int {
+	return len(f.lines)
+}
+
+// Position returns the line and columns of a byte offset; offsets outside the content are
+// clamped to it. The position of a nil SourceFile only has the offset.
+func (f *SourceFile) Position(pos Pos) Position {
+	if f == nil {
+		return Position{Offset: pos}
+	}
+	offset := pos
+	if offset < 0 {
+		offset = 0
+	} else if int(offset) > len(f.Content) {
+		offset = Pos(len(f.Content))
+	}
+
+	line := sort.Search(len(f.lines), func(i int) bool { return f.lines[i] > offset }) - 1
+	start := f.lines[line]
+	return Position{
+		Offset:      pos,
+		Line:        line + 1,
+		Column:      int(offset-start) + 1,
+		UTF16Column: utf16Length(f.Content[start:offset]) + 1,
+	}
+}
+
+// Offset returns the byte offset of a line and byte column
+func (f *SourceFile) Offset(line, column int) (Pos, error) {
+	start, end, err := f.line(line)
+	if err != nil {
+		return 0, err
+	}
+	if column < 1 || Pos(column-1) > end-start {
+		return 0, fmt.Errorf("column %d out of range for line %d", column, line)
+	}
+	return start + Pos(column-1), nil
+}
+
+// OffsetUTF16 returns the byte offset of a line and UTF-16 column; a column in the
+// middle of a character (a surrogate pair) is an error
+func (f *SourceFile) OffsetUTF16(line, column int) (Pos, error) {
+	start, end, err := f.line(line)
+	if err != nil {
+		return 0, err
+	}
+	units := 1
+	for offset := start; offset <= end; {
+		if units == column {
+			return offset, nil
+		}
+		if offset == end || units > column {
+			break
+		}
+		r, size := utf8.DecodeRuneInString(f.Content[offset:end])
+		units += utf16Units(r)
+		offset += Pos(size)
+	}
+	return 0, fmt.Errorf("UTF-16 column %d out of range for line %d", column, line)
+}
+
+// line returns the offsets of the first byte and of the line break of a line
+func (f *SourceFile) line(line int) (start, end Pos, err error) {
+	if line < 1 || line > len(f.lines) {
+		return 0, 0, fmt.Errorf("line %d out of range", line)
+	}
+	start, end = f.lines[line-1], Pos(len(f.Content))
+	if line < len(f.lines) {
+		end = f.lines[line] - 1
+		if f.Content[end] == '\n' && end > start && f.Content[end-1] == '\r' {
+			end--
+		}
+	}
+	return start, end, nil
+}
+
+func utf16Units(r rune) int {
+	if r >= 0x10000 && r <= utf8.MaxRune {
+		return 2
+	}
+	return 1
+}
+
+func utf16Length(s string) int {
+	n := 0
+	for _, r := range s {
+		n += utf16Units(r)
+	}
+	return n
+}
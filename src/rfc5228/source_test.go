@@ -0,0 +1,109 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSourceFilePosition(t *testing.T) {
+	// CRLF, LF and a lone CR each end a line; 😀 is 4 bytes and 2 UTF-16 code units
+	source := NewSourceFile("test", "keep;\r\n😀 stop;\nx\ry")
+	if source.LineCount() != 4 {
+		t.Errorf("unexpected line count %d", source.LineCount())
+	}
+	for _, test := range []struct {
+		pos      Pos
+		expected Position
+	}{
+		{0, Position{0, 1, 1, 1}},
+		{5, Position{5, 1, 6, 6}},
+		{7, Position{7, 2, 1, 1}},
+		{11, Position{11, 2, 5, 3}},
+		{12, Position{12, 2, 6, 4}},
+		{18, Position{18, 3, 1, 1}},
+		{20, Position{20, 4, 1, 1}},
+		{21, Position{21, 4, 2, 2}},
+	} {
+		if position := source.Position(test.pos); position != test.expected {
+			t.Errorf("%d: expected %+v, got %+v", test.pos, test.expected, position)
+		}
+	}
+
+	var none *SourceFile
+	if position := none.Position(3); position.IsValid() || position.String() != "3" {
+		t.Errorf("unexpected position %+v", position)
+	}
+}
+
+func TestSourceFileOffset(t *testing.T) {
+	source := NewSourceFile("test", "keep;\r\n😀 stop;\r\n")
+	for _, test := range []struct {
+		line, column, utf16 int
+		expected            Pos
+	}{
+		{1, 1, 1, 0},
+		{1, 6, 6, 5},
+		{2, 5, 3, 11},
+		{2, 11, 9, 17},
+		{3, 1, 1, 19},
+	} {
+		if offset, err := source.Offset(test.line, test.column); err != nil || offset != test.expected {
+			t.Errorf("%d:%d: expected %d, got %d (%v)", test.line, test.column, test.expected, offset, err)
+		}
+		if offset, err := source.OffsetUTF16(test.line, test.utf16); err != nil || offset != test.expected {
+			t.Errorf("%d:%d (UTF-16): expected %d, got %d (%v)", test.line, test.utf16, test.expected, offset, err)
+		}
+	}
+
+	for _, test := range []struct{ line, column int }{{0, 1}, {4, 1}, {1, 7}, {1, 0}} {
+		if _, err := source.Offset(test.line, test.column); err == nil {
+			t.Errorf("%d:%d: expected an error", test.line, test.column)
+		}
+	}
+	// the middle of a surrogate pair
+	if _, err := source.OffsetUTF16(2, 2); err == nil {
+		t.Errorf("expected an error")
+	}
+}
+
+func TestDiagnosticLines(t *testing.T) {
+	_, err := Parse("test", "keep;\r\nif true {\r\n  keep\r\n}\r\n", 0)
+	var syntax *SyntaxError
+	if !errors.As(err, &syntax) || syntax.Line != 4 || syntax.Column != 1 {
+		t.Errorf("unexpected error %#v", err)
+	}
+
+	tree := parse(t, "keep;\r\nif true {\r\n  keep;\r\n}\r\n")
+	if source := tree.Source.Position(tree.Commands()[1].Position()); source.String() != "2:1" {
+		t.Errorf("unexpected position %s", source)
+	}
+	warnings := Validate(tree)
+	if len(warnings) != 1 || warnings[0].Line != 2 || warnings[0].Column != 4 {
+		t.Errorf("unexpected warnings %+v", warnings)
+	}
+}
@@ -0,0 +1,275 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import (
+	"sort"
+	"strings"
+)
+
+// Facts are properties of a deployment that are known before any message is evaluated
+type Facts struct {
+	Headers     map[string]bool   // header presence by (case-insensitive) name: always (true) or never (false) present
+	Environment map[string]string // values of environment items (RFC 5183), e.g. "domain"
+}
+
+// Specialize partially evaluates a script given facts: tests decided by the facts are
+// replaced by true or false, and if/elsif/else chains are reduced to the blocks that can
+// still run. The returned warnings report the blocks that were removed because they can
+// never run. The input tree is not modified, but unchanged nodes are shared with the result.
+func Specialize(tree *Tree, facts Facts, opts ...Option) (*Tree, []Warning) {
+	s := &specializer{tree: newTree(tree.Name), facts: facts, options: newOptions(opts)}
+	s.tree.Source = tree.Source
+	for _, node := range s.commands(tree.Commands()) {
+		s.tree.Root.append(node)
+	}
+	return s.tree, s.warnings
+}
+
+type specializer struct {
+	tree     *Tree
+	facts    Facts
+	warnings []Warning
+	options  options
+}
+
+// header looks up the presence of a header field; of names differing in case only, the exact
+// name or else the first in sorted order is used, so the result doesn't depend on the order of
+// the map
+func (s *specializer) header(name string) (present bool, known bool) {
+	if v, ok := s.facts.Headers[name]; ok {
+		return v, true
+	}
+	names := make([]string, 0, len(s.facts.Headers))
+	for k := range s.facts.Headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		if isKeyword(k, name) {
+			return s.facts.Headers[k], true
+		}
+	}
+	return false, false
+}
+
+func (s *specializer) constant(test *TestNode, value bool) *TestNode {
+	if value {
+		return s.tree.newTest(test.Pos, TRUE)
+	}
+	return s.tree.newTest(test.Pos, FALSE)
+}
+
+// test folds a test; the test itself is returned if nothing could be folded
+func (s *specializer) test(test *TestNode) *TestNode {
+	switch strings.ToLower(test.Name) {
+	case NOT:
+		inner := s.test(test.Tests[0])
+		if value, ok := constant(inner); ok {
+			return s.constant(test, !value)
+		}
+		if inner == test.Tests[0] {
+			return test
+		}
+		node := s.tree.newTest(test.Pos, test.Name)
+		node.Tests = []*TestNode{inner}
+		return node
+	case ALLOF, ANYOF:
+		// a test equal to the neutral value is dropped, one equal to the decisive value decides
+		decisive := isKeyword(test.Name, ANYOF)
+		changed := false
+		tests := []*TestNode{}
+		for _, t := range test.Tests {
+			folded := s.test(t)
+			if value, ok := constant(folded); ok {
+				if value == decisive {
+					return s.constant(test, decisive)
+				}
+				changed = true
+				continue
+			}
+			changed = changed || folded != t
+			tests = append(tests, folded)
+		}
+		switch {
+		case len(tests) == 0:
+			return s.constant(test, !decisive)
+		case len(tests) == 1 && changed:
+			return tests[0]
+		case !changed:
+			return test
+		}
+		node := s.tree.newTest(test.Pos, test.Name)
+		node.Tests = tests
+		return node
+	case EXISTS:
+		// exists <header-names: string-list>; true if all headers are present
+		args, err := test.Bind()
+		if err != nil || len(test.Tags()) > 0 {
+			return test
+		}
+		all := true
+		for _, name := range args.StringList(0) {
+			present, known := s.header(name)
+			if known && !present {
+				return s.constant(test, false)
+			}
+			all = all && known
+		}
+		if all {
+			return s.constant(test, true)
+		}
+		return test
+	case HEADER:
+		// a header test never matches headers that are absent (:count is an exception)
+		args, err := test.Bind()
+		if err != nil || args.Tag("match-type") == ":count" {
+			return test
+		}
+		for _, name := range args.StringList(0) {
+			if present, known := s.header(name); !known || present {
+				return test
+			}
+		}
+		return s.constant(test, false)
+	case "environment":
+		return s.environment(test)
+	default:
+		return test
+	}
+}
+
+// environment folds `environment [COMPARATOR] [MATCH-TYPE] <name: string> <key-list: string-list>`
+// for the :is and :contains match types with the default comparator
+func (s *specializer) environment(test *TestNode) *TestNode {
+	args, err := test.Bind()
+	if err != nil || args.Tag("comparator") != "" {
+		return test
+	}
+	typ := args.Tag("match-type")
+	if typ != "" && typ != ":is" && typ != ":contains" {
+		return test
+	}
+	name, _ := args.String(0)
+	value, ok := s.facts.Environment[name]
+	if !ok {
+		return test
+	}
+
+	// i;ascii-casemap, the default comparator
+	for _, key := range args.StringList(1) {
+		if typ == ":contains" && containsFoldASCII(value, key) || equalFoldASCII(value, key) {
+			return s.constant(test, true)
+		}
+	}
+	return s.constant(test, false)
+}
+
+func (s *specializer) commands(commands []Command) []Command {
+	result := make([]Command, 0, len(commands))
+	for _, node := range commands {
+		if n, ok := node.(*IfNode); ok {
+			result = append(result, s.chain(n)...)
+		} else {
+			result = append(result, node)
+		}
+	}
+	return result
+}
+
+func (s *specializer) block(block *CommandsNode) *CommandsNode {
+	node := s.tree.newCommands(block.Pos)
+	for _, c := range s.commands(block.Commands()) {
+		node.append(c)
+	}
+	return node
+}
+
+// chain reduces an if/elsif/else chain; the result is empty, the commands of a single
+// block that always runs, or a new if node
+func (s *specializer) chain(n *IfNode) []Command {
+	type branch struct {
+		pos  Pos
+		name string
+		test *TestNode
+		body *CommandsNode
+	}
+
+	branches := []branch{{n.Pos, n.Name, n.Test, n.Body}}
+	for _, elsif := range n.ElseIfs {
+		branches = append(branches, branch{elsif.Pos, elsif.Name, elsif.Test, elsif.Body})
+	}
+
+	var kept []branch
+	var otherwise *branch
+	for i, b := range branches {
+		test := s.test(b.test)
+		value, ok := constant(test)
+		if ok && !value {
+			s.warnf(b.pos, CodeBlockNeverRuns, b.name)
+			continue
+		}
+		if ok && value {
+			// all remaining blocks, including else, can never run
+			for _, r := range branches[i+1:] {
+				s.warnf(r.pos, CodeBlockNeverRuns, r.name)
+			}
+			if n.Else != nil {
+				s.warnf(n.Else.Pos, CodeBlockNeverRuns, n.Else.Name)
+			}
+			otherwise = &branch{pos: b.pos, name: ELSE, body: s.block(b.body)}
+			break
+		}
+		kept = append(kept, branch{b.pos, b.name, test, s.block(b.body)})
+	}
+	if otherwise == nil && n.Else != nil {
+		otherwise = &branch{pos: n.Else.Pos, name: n.Else.Name, body: s.block(n.Else.Body)}
+	}
+
+	if len(kept) == 0 {
+		if otherwise == nil {
+			return nil
+		}
+		return otherwise.body.Commands()
+	}
+
+	node := s.tree.newIf(kept[0].pos, n.Name)
+	node.Test, node.Body = kept[0].test, kept[0].body
+	for _, b := range kept[1:] {
+		elsif := s.tree.newElseIf(b.pos, b.name)
+		elsif.Test, elsif.Body = b.test, b.body
+		node.ElseIfs = append(node.ElseIfs, elsif)
+	}
+	if otherwise != nil {
+		node.Else = s.tree.newElse(otherwise.pos, otherwise.name)
+		node.Else.Body = otherwise.body
+	}
+	return []Command{node}
+}
+
+func (s *specializer) warnf(pos Pos, code Code, args ...any) {
+	s.warnings = s.options.warn(s.warnings, s.tree.Source, pos, nil, code, args...)
+}
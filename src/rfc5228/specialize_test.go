@@ -0,0 +1,88 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSpecialize(t *testing.T) {
+	tree := parse(t, "if environment :is \"domain\" \"example.org\" {\r\n"+
+		"  redirect \"a@example.org\";\r\n"+
+		"} elsif allof (exists \"X-Spam-Score\", header :contains \"subject\" \"offer\") {\r\n"+
+		"  discard;\r\n"+
+		"} elsif header :is \"X-Legacy\" \"1\" {\r\n"+
+		"  stop;\r\n"+
+		"} else {\r\n"+
+		"  keep;\r\n"+
+		"}\r\n")
+
+	specialized, warnings := Specialize(tree, Facts{
+		Headers:     map[string]bool{"x-spam-score": true, "x-legacy": false},
+		Environment: map[string]string{"domain": "Example.com"},
+	})
+
+	if len(warnings) != 2 {
+		t.Fatalf("expected 2 warnings, got %v", warnings)
+	}
+	for _, w := range warnings {
+		if w.Message != "`if` block can never run" && w.Message != "`elsif` block can never run" {
+			t.Errorf("unexpected warning %s", w)
+		}
+	}
+
+	if len(specialized.Commands()) != 1 {
+		t.Fatalf("expected 1 command, got %d", len(specialized.Commands()))
+	}
+	node := specialized.Commands()[0].(*IfNode)
+	if node.Name != "if" || node.Test.Name != "header" || len(node.ElseIfs) != 0 || node.Else == nil {
+		t.Errorf("unexpected specialized chain %+v", node)
+	}
+
+	// the input tree is not modified
+	if len(tree.Commands()[0].(*IfNode).ElseIfs) != 2 {
+		t.Errorf("input tree was modified")
+	}
+}
+
+func TestSpecializeAlwaysTrue(t *testing.T) {
+	tree := parse(t, "if not exists \"X-Internal\" {\r\n  if true {\r\n    keep;\r\n  }\r\n} else {\r\n  discard;\r\n}\r\nstop;\r\n")
+
+	specialized, warnings := Specialize(tree, Facts{Headers: map[string]bool{"X-Internal": false}})
+	if len(warnings) != 1 || warnings[0].Message != "`else` block can never run" {
+		t.Errorf("unexpected warnings %v", warnings)
+	}
+
+	data, err := json.Marshal(specialized)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"commands":[{"name":"keep","pos":47,"type":"keep"},{"name":"stop","pos":84,"type":"stop"}],"name":"test","schema":1}`
+	if string(data) != expected {
+		t.Errorf("unexpected specialized tree %s", data)
+	}
+}
@@ -0,0 +1,112 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import (
+	"sort"
+	"strings"
+)
+
+// ScriptStats is a summary of what a script does, e.g. to build dashboards over the stored
+// scripts of all users ("how many users forward externally"). Command and test names are
+// lowercased; commands of extensions are only parsed outside strict mode (see
+// GenericCommandNode).
+type ScriptStats struct {
+	Actions         map[string]int `json:"actions"`         // the number of each action (keep, fileinto, vacation, ...)
+	Tests           map[string]int `json:"tests"`           // the number of each test, including nested tests
+	Folders         []string       `json:"folders"`         // the mailboxes of fileinto, sorted and without duplicates
+	RedirectTargets []string       `json:"redirectTargets"` // the addresses of redirect, sorted and without duplicates
+	Capabilities    []string       `json:"capabilities"`    // the required capabilities, sorted and without duplicates
+}
+
+// Stats walks a tree and counts its actions and tests. Control commands (require, if,
+// stop and commands with a block, e.g. foreverypart) are not counted as actions.
+func Stats(tree *Tree) *ScriptStats {
+	s := &ScriptStats{Actions: map[string]int{}, Tests: map[string]int{}}
+	folders, targets, capabilities := map[string]bool{}, map[string]bool{}, map[string]bool{}
+
+	var test func(t *TestNode)
+	test = func(t *TestNode) {
+		s.Tests[strings.ToLower(t.Name)]++
+		for _, nested := range t.Tests {
+			test(nested)
+		}
+	}
+	var walk func(commands []Command)
+	walk = func(commands []Command) {
+		for _, node := range commands {
+			switch n := node.(type) {
+			case *RequireNode:
+				for _, capability := range n.Capabilities {
+					capabilities[capability] = true
+				}
+			case *KeepNode:
+				s.Actions[KEEP]++
+			case *DiscardNode:
+				s.Actions[DISCARD]++
+			case *RedirectNode:
+				s.Actions[REDIRECT]++
+				targets[n.Address] = true
+			case *IfNode:
+				for _, t := range n.Conditions() {
+					test(t)
+				}
+				for _, block := range n.Blocks() {
+					walk(block.Commands())
+				}
+			case *GenericCommandNode:
+				for _, t := range n.Tests {
+					test(t)
+				}
+				if n.Block != nil {
+					walk(n.Block.Commands())
+					continue
+				}
+				name := strings.ToLower(n.Name)
+				s.Actions[name]++
+				// fileinto [:copy] [:flags <list-of-flags>] [:create] ... <mailbox: string>
+				if name == "fileinto" && len(n.Arguments) > 0 {
+					if mailbox, ok := n.Arguments[len(n.Arguments)-1].(*StringNode); ok {
+						folders[mailbox.Text] = true
+					}
+				}
+			}
+		}
+	}
+	walk(tree.Commands())
+
+	s.Folders, s.RedirectTargets, s.Capabilities = sortedSet(folders), sortedSet(targets), sortedSet(capabilities)
+	return s
+}
+
+func sortedSet(set map[string]bool) []string {
+	values := []string{}
+	for value := range set {
+		values = append(values, value)
+	}
+	sort.Strings(values)
+	return values
+}
@@ -0,0 +1,62 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	tree := parse(t, lines(
+		`require ["fileinto", "imap4flags"];`,
+		`if anyof (header :contains "subject" "spam", not exists "from") {`,
+		`  fileinto :flags "\\Seen" "Junk";`,
+		`  stop;`,
+		`} elsif address :domain :is "from" "example.org" {`,
+		`  redirect "boss@example.com";`,
+		`  FileInto "Work";`,
+		`} else {`,
+		`  redirect "me@example.net";`,
+		`  redirect "boss@example.com";`,
+		`}`,
+		`foreverypart {`,
+		`  discard;`,
+		`}`,
+		`keep;`,
+	))
+	stats := Stats(tree)
+	expected := &ScriptStats{
+		Actions:         map[string]int{"fileinto": 2, "redirect": 3, "discard": 1, "keep": 1},
+		Tests:           map[string]int{"anyof": 1, "header": 1, "not": 1, "exists": 1, "address": 1},
+		Folders:         []string{"Junk", "Work"},
+		RedirectTargets: []string{"boss@example.com", "me@example.net"},
+		Capabilities:    []string{"fileinto", "imap4flags"},
+	}
+	if !reflect.DeepEqual(stats, expected) {
+		t.Errorf("expected %+v, got %+v", expected, stats)
+	}
+}
@@ -0,0 +1,128 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Comparators every implementation supports (RFC 5228, section 2.7.3) and the
+// i;ascii-numeric comparator (RFC 4790, section 9.1)
+const (
+	ComparatorOctet        = "i;octet"
+	ComparatorASCIICasemap = "i;ascii-casemap"
+	ComparatorASCIINumeric = "i;ascii-numeric"
+)
+
+// compareStrings orders two strings with a comparator; strings that compare equal are
+// the same for the match types of the comparator (e.g. "A" and "a" for i;ascii-casemap)
+func compareStrings(comparator string, a, b string) (int, error) {
+	switch strings.ToLower(comparator) {
+	case ComparatorOctet:
+		return strings.Compare(a, b), nil
+	case "", ComparatorASCIICasemap:
+		return compareFoldASCII(a, b), nil
+	case ComparatorASCIINumeric:
+		// strings that don't start with a digit are equal to each other and greater than any number
+		na, nb := numericPrefix(a), numericPrefix(b)
+		switch {
+		case na == nil && nb == nil:
+			return 0, nil
+		case na == nil:
+			return 1, nil
+		case nb == nil:
+			return -1, nil
+		case len(na) != len(nb):
+			if len(na) < len(nb) {
+				return -1, nil
+			}
+			return 1, nil
+		}
+		return strings.Compare(string(na), string(nb)), nil
+	}
+	return 0, fmt.Errorf("unsupported comparator %q", comparator)
+}
+
+// numericPrefix returns the leading digits of s without leading zeros, "0" for zero, or nil
+// if s doesn't start with a digit
+func numericPrefix(s string) []byte {
+	end := 0
+	for end < len(s) && isDigit(rune(s[end])) {
+		end++
+	}
+	if end == 0 {
+		return nil
+	}
+	digits := strings.TrimLeft(s[:end], "0")
+	if digits == "" {
+		digits = "0"
+	}
+	return []byte(digits)
+}
+
+// DedupeStrings returns a string-list without the strings that are equal, with the given
+// comparator, to an earlier string; the first spelling of every string is kept. An empty
+// comparator is the default, i;ascii-casemap.
+func DedupeStrings(list []string, comparator string) ([]string, error) {
+	return MergeStrings(comparator, list)
+}
+
+// MergeStrings returns the union of string-lists in order of first occurrence, e.g. to combine
+// the address lists of generated rules, removing duplicates as DedupeStrings does
+func MergeStrings(comparator string, lists ...[]string) ([]string, error) {
+	if _, err := compareStrings(comparator, "", ""); err != nil {
+		return nil, err
+	}
+	result := []string{}
+	for _, list := range lists {
+	next:
+		for _, s := range list {
+			for _, r := range result {
+				if c, _ := compareStrings(comparator, s, r); c == 0 {
+					continue next
+				}
+			}
+			result = append(result, s)
+		}
+	}
+	return result, nil
+}
+
+// SortStrings returns a sorted copy of a string-list in the order of a comparator; strings
+// that compare equal keep their relative order
+func SortStrings(list []string, comparator string) ([]string, error) {
+	if _, err := compareStrings(comparator, "", ""); err != nil {
+		return nil, err
+	}
+	sorted := append([]string{}, list...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		c, _ := compareStrings(comparator, sorted[i], sorted[j])
+		return c < 0
+	})
+	return sorted, nil
+}
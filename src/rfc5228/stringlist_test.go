@@ -0,0 +1,67 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMergeStrings(t *testing.T) {
+	merged, err := MergeStrings("", []string{"a@example.com", "B@example.com"}, []string{"A@EXAMPLE.COM", "c@example.com", "b@example.com"})
+	if err != nil || !reflect.DeepEqual(merged, []string{"a@example.com", "B@example.com", "c@example.com"}) {
+		t.Errorf("unexpected result %v (%v)", merged, err)
+	}
+	merged, _ = MergeStrings(ComparatorOctet, []string{"a", "A", "a"})
+	if !reflect.DeepEqual(merged, []string{"a", "A"}) {
+		t.Errorf("unexpected result %v", merged)
+	}
+	// only ASCII letters are folded
+	deduped, _ := DedupeStrings([]string{"É", "é", "E", "e"}, ComparatorASCIICasemap)
+	if !reflect.DeepEqual(deduped, []string{"É", "é", "E"}) {
+		t.Errorf("unexpected result %v", deduped)
+	}
+	if _, err := MergeStrings("i;unknown", []string{"a"}); err == nil {
+		t.Errorf("expected an error for an unknown comparator")
+	}
+}
+
+func TestSortStrings(t *testing.T) {
+	for _, test := range []struct {
+		comparator string
+		list       []string
+		expected   []string
+	}{
+		{ComparatorOctet, []string{"b", "B", "a"}, []string{"B", "a", "b"}},
+		{ComparatorASCIICasemap, []string{"b", "B", "a"}, []string{"a", "b", "B"}},
+		{ComparatorASCIINumeric, []string{"x", "10", "9", "009b", "0"}, []string{"0", "9", "009b", "10", "x"}},
+	} {
+		sorted, err := SortStrings(test.list, test.comparator)
+		if err != nil || !reflect.DeepEqual(sorted, test.expected) {
+			t.Errorf("%s: expected %v, got %v (%v)", test.comparator, test.expected, sorted, err)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import (
+	"fmt"
+	"net/mail"
+	"strings"
+)
+
+// ParamType identifies the kind of value a template placeholder accepts
+type ParamType int
+
+const (
+	ParamString     ParamType = iota // any string
+	ParamStringList                  // a list of arbitrary strings
+	ParamAddress                     // an email address (addr-spec, e.g. `user@example.com`)
+	ParamMailbox                     // a mailbox (folder) name
+	ParamKeyword                     // an IMAP keyword or system flag (e.g. `$Junk`, `\Seen`)
+)
+
+const (
+	placeholderOpen  = "{{"
+	placeholderClose = "}}"
+)
+
+// Template is a sieve script with typed placeholders
+//
+// A placeholder `{{name}}` stands for a complete string (or string-list) argument and
+// is replaced by a quoted string (or string-list) holding the escaped value, e.g.
+//
+//	redirect {{forward}};
+//
+// This way values can never break out of their argument, whatever they contain.
+type Template struct {
+	name   string
+	chunks []chunk
+	params map[string]ParamType
+}
+
+// chunk is either literal script text or a placeholder
+type chunk struct {
+	text  string
+	param string // name of the placeholder; empty for literal text
+}
+
+// NewTemplate parses a template; every placeholder must be declared in params. The template
+// is rejected if it isn't a valid script with its placeholders substituted.
+func NewTemplate(name, source string, params map[string]ParamType) (*Template, error) {
+	t := &Template{name: name, params: params}
+
+	for len(source) > 0 {
+		i := strings.Index(source, placeholderOpen)
+		if i < 0 {
+			t.chunks = append(t.chunks, chunk{text: source})
+			break
+		}
+		if i > 0 {
+			t.chunks = append(t.chunks, chunk{text: source[:i]})
+		}
+		source = source[i+len(placeholderOpen):]
+
+		j := strings.Index(source, placeholderClose)
+		if j < 0 {
+			return nil, fmt.Errorf("unclosed placeholder in template %s", name)
+		}
+		param := strings.TrimSpace(source[:j])
+		if _, ok := params[param]; !ok {
+			return nil, fmt.Errorf("undeclared placeholder `%s` in template %s", param, name)
+		}
+		t.chunks = append(t.chunks, chunk{param: param})
+		source = source[j+len(placeholderClose):]
+	}
+
+	// substitute a sample value for each placeholder to verify they
+	// only occur in argument positions
+	var b strings.Builder
+	type sample struct {
+		param string
+		pos   Pos
+	}
+	var samples []sample
+	for _, c := range t.chunks {
+		if c.param == "" {
+			b.WriteString(c.text)
+			continue
+		}
+		samples = append(samples, sample{c.param, Pos(b.Len())})
+		if t.params[c.param] == ParamStringList {
+			b.WriteString(`["x"]`)
+		} else {
+			b.WriteString(`"x"`)
+		}
+	}
+	if _, err := Parse(name, b.String(), 0); err != nil {
+		return nil, fmt.Errorf("invalid template %s: %w", name, err)
+	}
+
+	// a sample must be tokens of its own: in a comment or a multi-line string, a value
+	// could end the comment or the string and inject commands
+	tokens, err := Tokenize(name, b.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid template %s: %w", name, err)
+	}
+	at := make(map[Pos]Token, len(tokens))
+	for _, token := range tokens {
+		at[token.Pos] = token
+	}
+	for _, sample := range samples {
+		pos := sample.pos
+		expected := []Token{{TokenString, pos, `"x"`}}
+		if t.params[sample.param] == ParamStringList {
+			expected = []Token{{TokenStringListOpen, pos, "["}, {TokenString, pos + 1, `"x"`}, {TokenStringListClose, pos + 4, "]"}}
+		}
+		for _, token := range expected {
+			if at[token.Pos] != token {
+				return nil, fmt.Errorf("placeholder `%s` outside of an argument in template %s", sample.param, name)
+			}
+		}
+	}
+	return t, nil
+}
+
+// Execute substitutes the placeholders of the template; values holds a string for
+// each placeholder, or a []string for placeholders of type ParamStringList
+func (t *Template) Execute(values map[string]any) (string, error) {
+	var b strings.Builder
+	for _, c := range t.chunks {
+		if c.param == "" {
+			b.WriteString(c.text)
+			continue
+		}
+
+		value, ok := values[c.param]
+		if !ok {
+			return "", fmt.Errorf("missing value for placeholder `%s`", c.param)
+		}
+		s, err := t.substitute(c.param, value)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(s)
+	}
+
+	script := b.String()
+	if _, err := Parse(t.name, script, 0); err != nil {
+		return "", fmt.Errorf("invalid script from template %s: %w", t.name, err)
+	}
+	return script, nil
+}
+
+// substitute validates a value against the type of its placeholder and returns it quoted
+func (t *Template) substitute(param string, value any) (string, error) {
+	typ := t.params[param]
+
+	if typ == ParamStringList {
+		list, ok := value.([]string)
+		if !ok {
+			return "", fmt.Errorf("expected []string for placeholder `%s`, got %T", param, value)
+		}
+		if len(list) == 0 {
+			return "", fmt.Errorf("empty string list for placeholder `%s`", param)
+		}
+		return QuoteStringList(list)
+	}
+
+	s, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("expected string for placeholder `%s`, got %T", param, value)
+	}
+
+	switch typ {
+	case ParamAddress:
+		if addr, err := mail.ParseAddress(s); err != nil || addr.Address != s {
+			return "", fmt.Errorf("invalid address %q for placeholder `%s`", s, param)
+		}
+	case ParamMailbox:
+		if s == "" || strings.IndexFunc(s, isControl) >= 0 {
+			return "", fmt.Errorf("invalid mailbox %q for placeholder `%s`", s, param)
+		}
+	case ParamKeyword:
+		if !isKeywordFlag(s) {
+			return "", fmt.Errorf("invalid keyword %q for placeholder `%s`", s, param)
+		}
+	}
+	return QuoteString(s)
+}
+
+func isControl(r rune) bool {
+	return r < 0x20 || r == 0x7F
+}
+
+// isKeywordFlag reports whether s is an IMAP flag: an atom, optionally preceded by a
+// backslash for system flags (RFC 3501, section 9)
+func isKeywordFlag(s string) bool {
+	s = strings.TrimPrefix(s, "\\")
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if isControl(r) || r > 0x7E || strings.ContainsRune(`(){ %*"\]`, r) {
+			return false
+		}
+	}
+	return true
+}
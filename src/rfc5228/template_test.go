@@ -0,0 +1,114 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import "testing"
+
+func TestTemplate(t *testing.T) {
+	tmpl, err := NewTemplate("forward", "if address :is \"from\" {{senders}} {\r\n  redirect {{forward}};\r\n}\r\n",
+		map[string]ParamType{"senders": ParamStringList, "forward": ParamAddress})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	script, err := tmpl.Execute(map[string]any{
+		"senders": []string{"boss@example.com", "evil\"; discard; \"@example.com"},
+		"forward": "me@example.org",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "if address :is \"from\" [\"boss@example.com\", \"evil\\\"; discard; \\\"@example.com\"] {\r\n  redirect \"me@example.org\";\r\n}\r\n"
+	if script != expected {
+		t.Errorf("unexpected script %q", script)
+	}
+
+	tree := parse(t, script)
+	node := tree.Commands()[0].(*IfNode)
+	if len(node.Body.Commands()) != 1 || node.Test.StringLists()[1][1] != "evil\"; discard; \"@example.com" {
+		t.Errorf("value escaped its argument")
+	}
+}
+
+func TestTemplateInvalidValues(t *testing.T) {
+	tmpl, err := NewTemplate("fileinto", "if header :contains \"subject\" {{subject}} {\r\n  keep;\r\n}\r\nredirect {{address}};\r\n",
+		map[string]ParamType{"subject": ParamString, "address": ParamAddress})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, values := range []map[string]any{
+		{"subject": "x"},
+		{"subject": "x", "address": "not an address"},
+		{"subject": "x", "address": "Me <me@example.com>"},
+		{"subject": []string{"x"}, "address": "me@example.com"},
+	} {
+		if _, err := tmpl.Execute(values); err == nil {
+			t.Errorf("expected error for values %v", values)
+		}
+	}
+}
+
+func TestTemplateInvalidPlaceholders(t *testing.T) {
+	for _, source := range []string{
+		"redirect {{address}};\r\n",
+		"redirect {{forward};\r\n",
+		"redirect \"{{forward}}\";\r\n",
+		"{{forward}};\r\n",
+		"# forward to {{forward}}\r\nkeep;\r\n",
+		"/* {{forward}} */ keep;\r\n",
+		"vacation text:\r\n{{forward}}\r\n.\r\n;\r\n",
+	} {
+		if _, err := NewTemplate("test", source, map[string]ParamType{"forward": ParamAddress}); err == nil {
+			t.Errorf("expected error for template %q", source)
+		}
+	}
+}
+
+// a placeholder in a comment would let a value end the comment and inject commands
+func TestTemplateCommentInjection(t *testing.T) {
+	tmpl, err := NewTemplate("owner", "# owner {{name}}\r\nkeep;\r\n", map[string]ParamType{"name": ParamString})
+	if err == nil {
+		script, _ := tmpl.Execute(map[string]any{"name": "x\r\ndiscard;\r\n#"})
+		t.Fatalf("expected the template to be rejected, got script %q", script)
+	}
+}
+
+func TestIsKeywordFlag(t *testing.T) {
+	for s, expected := range map[string]bool{
+		"$Junk":  true,
+		"\\Seen": true,
+		"":       false,
+		"\\":     false,
+		"a b":    false,
+		"a(b":    false,
+	} {
+		if isKeywordFlag(s) != expected {
+			t.Errorf("%q: expected %t", s, expected)
+		}
+	}
+}
@@ -0,0 +1,226 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Default bounds of TerminationLimits
+const (
+	DefaultMaxMIMEParts    = 100
+	DefaultMaxIncludeDepth = 10
+)
+
+// TerminationLimits are the bounds the environment of an evaluator guarantees, from which
+// StepBound derives the bound of a script
+type TerminationLimits struct {
+	MaxMIMEParts    int // the parts a foreverypart loop iterates at most; DefaultMaxMIMEParts if 0
+	MaxIncludeDepth int // the depth of nested includes; DefaultMaxIncludeDepth if 0
+
+	// Resolve returns the tree of an included script (RFC 6609); a script that includes
+	// others can only be bounded with a resolver
+	Resolve func(name string, global bool) (*Tree, error)
+}
+
+// StepBound verifies that a script terminates and returns the number of steps it executes at
+// most, counting a step for every command and every test, including nested tests. The tests
+// of an if chain all count, the block that executes most counts; a foreverypart loop (RFC 5703)
+// counts MaxMIMEParts times and an included script counts as if it were inlined, up to
+// MaxIncludeDepth levels, which also rejects include cycles. A script with a block of an
+// unknown extension can't be bounded.
+//
+// An evaluator enforces the bound with a StepBudget of the returned number of steps.
+func StepBound(tree *Tree, limits TerminationLimits) (uint64, error) {
+	if limits.MaxMIMEParts == 0 {
+		limits.MaxMIMEParts = DefaultMaxMIMEParts
+	}
+	if limits.MaxIncludeDepth == 0 {
+		limits.MaxIncludeDepth = DefaultMaxIncludeDepth
+	}
+	b := &bounder{limits: limits}
+	return b.commands(tree, tree.Commands(), 0)
+}
+
+type bounder struct {
+	limits TerminationLimits
+}
+
+func (b *bounder) commands(tree *Tree, commands []Command, depth int) (uint64, error) {
+	var steps uint64
+	for _, node := range commands {
+		n, err := b.command(tree, node, depth)
+		if err != nil {
+			return 0, err
+		}
+		if steps, err = addSteps(steps, n); err != nil {
+			return 0, err
+		}
+	}
+	return steps, nil
+}
+
+func (b *bounder) command(tree *Tree, node Command, depth int) (uint64, error) {
+	switch n := node.(type) {
+	case *IfNode:
+		var tests, block uint64
+		var err error
+		for _, test := range n.Conditions() {
+			if tests, err = addSteps(tests, testSteps(test)); err != nil {
+				return 0, err
+			}
+		}
+		for _, body := range n.Blocks() {
+			steps, err := b.commands(tree, body.Commands(), depth)
+			if err != nil {
+				return 0, err
+			}
+			if steps > block {
+				block = steps
+			}
+		}
+		return addSteps(tests, block)
+	case *GenericCommandNode:
+		steps := uint64(1)
+		for _, test := range n.Tests {
+			steps += testSteps(test)
+		}
+		switch name := strings.ToLower(n.Name); {
+		case name == "foreverypart" && n.Block == nil:
+			return steps, nil
+		case name == "foreverypart":
+			body, err := b.commands(tree, n.Block.Commands(), depth)
+			if err != nil {
+				return 0, err
+			}
+			loop, err := mulSteps(uint64(b.limits.MaxMIMEParts), body)
+			if err != nil {
+				return 0, err
+			}
+			return addSteps(steps, loop)
+		case n.Block != nil:
+			return 0, fmt.Errorf("%s at %s: `%s` blocks can't be bounded", tree.Name, tree.Source.Position(n.Pos), n.Name)
+		case name == "include":
+			included, err := b.include(tree, n, depth)
+			if err != nil {
+				return 0, err
+			}
+			return addSteps(steps, included)
+		}
+		return steps, nil
+	default:
+		return 1, nil
+	}
+}
+
+// include returns the bound of the script included by an include command:
+//
+//	include [:personal / :global] [:once] [:optional] <value: string>
+func (b *bounder) include(tree *Tree, n *GenericCommandNode, depth int) (uint64, error) {
+	pos := tree.Source.Position(n.Pos)
+	if depth >= b.limits.MaxIncludeDepth {
+		return 0, fmt.Errorf("%s at %s: includes nest deeper than %d levels", tree.Name, pos, b.limits.MaxIncludeDepth)
+	}
+	if b.limits.Resolve == nil {
+		return 0, fmt.Errorf("%s at %s: `%s` can't be bounded without a resolver", tree.Name, pos, n.Name)
+	}
+	var name *StringNode
+	global := false
+	for _, arg := range n.Arguments {
+		switch a := arg.(type) {
+		case *TagNode:
+			global = global || isKeyword(a.Name, ":global")
+		case *StringNode:
+			name = a
+		}
+	}
+	if name == nil {
+		return 0, fmt.Errorf("%s at %s: `%s` without a script name", tree.Name, pos, n.Name)
+	}
+	included, err := b.limits.Resolve(name.Text, global)
+	if err != nil {
+		return 0, fmt.Errorf("%s at %s: %w", tree.Name, pos, err)
+	}
+	return b.commands(included, included.Commands(), depth+1)
+}
+
+func testSteps(test *TestNode) uint64 {
+	steps := uint64(1)
+	for _, t := range test.Tests {
+		steps += testSteps(t)
+	}
+	return steps
+}
+
+func addSteps(a, b uint64) (uint64, error) {
+	if a > math.MaxUint64-b {
+		return 0, fmt.Errorf("step bound overflows")
+	}
+	return a + b, nil
+}
+
+func mulSteps(a, b uint64) (uint64, error) {
+	if a != 0 && b > math.MaxUint64/a {
+		return 0, fmt.Errorf("step bound overflows")
+	}
+	return a * b, nil
+}
+
+// StepLimitError is returned by StepBudget.Step when a script executes more steps than its budget
+type StepLimitError struct {
+	Limit uint64 // the budget that was exceeded
+}
+
+func (e *StepLimitError) Error() string {
+	return fmt.Sprintf("script exceeded its budget of %d steps", e.Limit)
+}
+
+// StepBudget enforces a bound on the steps of an evaluation at runtime; an evaluator calls
+// Step before every command and test it executes, the steps counted by StepBound
+type StepBudget struct {
+	limit, used uint64
+}
+
+// NewStepBudget returns a budget of limit steps, e.g. the bound returned by StepBound
+func NewStepBudget(limit uint64) *StepBudget {
+	return &StepBudget{limit: limit}
+}
+
+// Step takes a step of the budget; it returns a *StepLimitError once the budget is exhausted
+func (b *StepBudget) Step() error {
+	if b.used >= b.limit {
+		return &StepLimitError{Limit: b.limit}
+	}
+	b.used++
+	return nil
+}
+
+// Used returns the number of steps taken
+func (b *StepBudget) Used() uint64 {
+	return b.used
+}
@@ -0,0 +1,97 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestStepBound(t *testing.T) {
+	scripts := map[string]string{
+		"spam":  lines(`if header :contains "subject" "spam" {`, `  discard;`, `  stop;`, `}`),
+		"loop":  lines(`include "loop";`),
+		"outer": lines(`include :global "spam";`, `keep;`),
+	}
+	resolve := func(name string, global bool) (*Tree, error) {
+		script, ok := scripts[name]
+		if !ok {
+			return nil, fmt.Errorf("script %q not found", name)
+		}
+		return Parse(name, script, 0)
+	}
+
+	for _, test := range []struct {
+		script string
+		limits TerminationLimits
+		steps  uint64
+		err    string
+	}{
+		{lines(`keep;`, `stop;`), TerminationLimits{}, 2, ""},
+		// a test and the longest block: 1 + 2
+		{scripts["spam"], TerminationLimits{}, 3, ""},
+		// anyof with two tests, the elsif test and the longest block
+		{lines(`if anyof (true, false) {`, `  keep;`, `} elsif true {`, `  keep;`, `  keep;`, `}`), TerminationLimits{}, 6, ""},
+		// the loop, 10 times its body of two commands
+		{lines(`foreverypart {`, `  keep;`, `  break;`, `}`), TerminationLimits{MaxMIMEParts: 10}, 21, ""},
+		{lines(`foreverypart {`, `  foreverypart {`, `    keep;`, `  }`, `}`), TerminationLimits{MaxMIMEParts: 3}, 13, ""},
+		// a loop without a body, which parses outside of strict mode
+		{lines(`require "foreverypart";`, `foreverypart;`), TerminationLimits{MaxMIMEParts: 10}, 2, ""},
+		// the include, the included script and keep
+		{lines(`include "outer";`), TerminationLimits{Resolve: resolve}, 6, ""},
+		{lines(`include "loop";`), TerminationLimits{Resolve: resolve, MaxIncludeDepth: 3}, 0, "includes nest deeper than 3 levels"},
+		{lines(`include "missing";`), TerminationLimits{Resolve: resolve}, 0, `script "missing" not found`},
+		{lines(`include "spam";`), TerminationLimits{}, 0, "can't be bounded without a resolver"},
+		{lines(`while true {`, `  keep;`, `}`), TerminationLimits{}, 0, "`while` blocks can't be bounded"},
+	} {
+		tree, err := Parse("test", test.script, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		steps, err := StepBound(tree, test.limits)
+		switch {
+		case test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)):
+			t.Errorf("%q: expected error %q, got %v", test.script, test.err, err)
+		case test.err == "" && (err != nil || steps != test.steps):
+			t.Errorf("%q: expected %d steps, got %d, %v", test.script, test.steps, steps, err)
+		}
+	}
+}
+
+func TestStepBudget(t *testing.T) {
+	budget := NewStepBudget(2)
+	for i := 0; i < 2; i++ {
+		if err := budget.Step(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var limit *StepLimitError
+	if err := budget.Step(); !errors.As(err, &limit) || limit.Limit != 2 || budget.Used() != 2 {
+		t.Errorf("expected a *StepLimitError after 2 steps, got %v after %d", err, budget.Used())
+	}
+}
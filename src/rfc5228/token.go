@@ -0,0 +1,110 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+// TokenType identifies the type of a token
+type TokenType int
+
+const (
+	TokenComment         TokenType = iota // hash or bracket comment
+	TokenIdentifier                       // identifier
+	TokenString                           // quoted string
+	TokenNumber                           // number with an optional quantifier
+	TokenEnd                              // `;`
+	TokenComma                            // `,`
+	TokenStringListOpen                   // `[`
+	TokenStringListClose                  // `]`
+	TokenTestListOpen                     // `(`
+	TokenTestListClose                    // `)`
+	TokenBlockOpen                        // `{`
+	TokenBlockClose                       // `}`
+	TokenTag                              // tag (`:` identifier); the value includes the colon
+)
+
+var tokenTypes = map[itemType]TokenType{
+	itemComment:         TokenComment,
+	itemIdentifier:      TokenIdentifier,
+	itemString:          TokenString,
+	itemNumeric:         TokenNumber,
+	itemEnd:             TokenEnd,
+	itemComma:           TokenComma,
+	itemStringListOpen:  TokenStringListOpen,
+	itemStringListClose: TokenStringListClose,
+	itemTestListOpen:    TokenTestListOpen,
+	itemTestListClose:   TokenTestListClose,
+	itemBlockOpen:       TokenBlockOpen,
+	itemBlockClose:      TokenBlockClose,
+	itemTag:             TokenTag,
+}
+
+// Token is a lexical token of a script
+type Token struct {
+	Type  TokenType
+	Pos   Pos    // The starting position, in bytes, of the token in the input string.
+	Value string // The source text of the token, including quotes and comment delimiters.
+}
+
+// End returns the position just after the token
+func (t Token) End() Pos {
+	return t.Pos + Pos(len(t.Value))
+}
+
+// Comment returns the text of a comment token without its delimiters: the `#` of a
+// hash comment (the terminating CRLF is never part of the token) or the `/*` and `*/`
+// of a bracket comment, which may span lines
+func (t Token) Comment() string {
+	return commentText(t.Value)
+}
+
+// WithComments controls whether Tokenize includes comment tokens, which it does by default
+func WithComments(include bool) Option {
+	return func(o *options) {
+		o.comments = include
+	}
+}
+
+// Tokenize returns the tokens of a script in lexical order, e.g. for syntax highlighting;
+// whitespace is left out. A script that can't be tokenized is a *SyntaxError.
+func Tokenize(name, input string, opts ...Option) ([]Token, error) {
+	o := newOptions(opts)
+	l := lex(name, input)
+
+	var tokens []Token
+	for {
+		switch item := l.nextItem(); item.typ {
+		case itemEOF:
+			return tokens, nil
+		case itemError:
+			return nil, newSyntaxError(o.locale, NewSourceFile(name, input), item.pos, l.code)
+		case itemComment:
+			if o.comments {
+				tokens = append(tokens, Token{TokenComment, item.pos, item.value(l.input)})
+			}
+		default:
+			tokens = append(tokens, Token{tokenTypes[item.typ], item.pos, item.value(l.input)})
+		}
+	}
+}
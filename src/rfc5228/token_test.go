@@ -0,0 +1,91 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	input := "# hello\r\nif /* multi\r\n   line */ true { keep; } # bye"
+	tokens, err := Tokenize("test", input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var comments []string
+	for _, token := range tokens {
+		if input[token.Pos:token.End()] != token.Value {
+			t.Errorf("token %v does not match the input at its position", token)
+		}
+		if token.Type == TokenComment {
+			comments = append(comments, token.Comment())
+		}
+	}
+	if expected := []string{" hello", " multi\r\n   line ", " bye"}; !reflect.DeepEqual(comments, expected) {
+		t.Errorf("unexpected comments %q", comments)
+	}
+	if tokens[2] != (Token{TokenComment, 12, "/* multi\r\n   line */"}) {
+		t.Errorf("unexpected bracket comment %v", tokens[2])
+	}
+
+	tokens, err = Tokenize("test", input, WithComments(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var types []TokenType
+	for _, token := range tokens {
+		types = append(types, token.Type)
+	}
+	expected := []TokenType{TokenIdentifier, TokenIdentifier, TokenBlockOpen, TokenIdentifier, TokenEnd, TokenBlockClose}
+	if !reflect.DeepEqual(types, expected) {
+		t.Errorf("unexpected tokens %v", tokens)
+	}
+}
+
+func TestTokenizeUnterminatedComment(t *testing.T) {
+	_, err := Tokenize("test", "keep; /* open\r\n")
+	var syntax *SyntaxError
+	if !errors.As(err, &syntax) || syntax.Code != CodeUnterminatedComment || syntax.Pos != 6 {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestTokenizeTags(t *testing.T) {
+	tokens, err := Tokenize("test", "if header :Contains \"to\" \"me\" {\r\n  keep;\r\n}\r\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tokens[1].Type != TokenIdentifier || tokens[2].Type != TokenTag || tokens[2].Value != ":Contains" {
+		t.Errorf("unexpected tokens %v", tokens[:3])
+	}
+
+	if _, err := Parse("test", ":is;\r\n", 0); err == nil {
+		t.Errorf("expected an error for a tag in command position")
+	}
+}
@@ -0,0 +1,59 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import (
+	"fmt"
+)
+
+// Names of the traceability header fields returned by TraceFields
+const (
+	TraceField          = "X-Sieve"
+	TraceRedirectedFrom = "X-Sieve-Redirected-From"
+)
+
+// traceFingerprintLength is the number of hex digits of the fingerprints in a trace field
+const traceFingerprintLength = 16
+
+// TraceFields returns the header fields an executor can add to a message it redirects or keeps
+// to identify the script and rule that caused the action, e.g. for abuse investigations:
+//
+//	X-Sieve: script=user; fingerprint=9f86d081884c7d65; rule=2c26b46b68ffc68f; pos=12:3
+//	X-Sieve-Redirected-From: user@example.com
+//
+// The fingerprints are the first 16 hex digits of Fingerprint and FingerprintCommand, and pos
+// is the line and column of the command; X-Sieve-Redirected-From is only returned for a
+// redirect command and a non-empty recipient, the address the message was delivered to.
+func TraceFields(tree *Tree, command Command, recipient string) []HeaderField {
+	value := fmt.Sprintf("script=%s; fingerprint=%s; rule=%s; pos=%s", tree.Name,
+		Fingerprint(tree)[:traceFingerprintLength], FingerprintCommand(command)[:traceFingerprintLength],
+		tree.Source.Position(command.Position()))
+	fields := []HeaderField{{TraceField, value}}
+	if _, ok := command.(*RedirectNode); ok && recipient != "" {
+		fields = append(fields, HeaderField{TraceRedirectedFrom, recipient})
+	}
+	return fields
+}
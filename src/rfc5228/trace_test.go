@@ -0,0 +1,45 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import (
+	"testing"
+)
+
+func TestTraceFields(t *testing.T) {
+	tree := parse(t, "if true {\r\n  redirect \"other@example.com\";\r\n}\r\nkeep;\r\n")
+	redirect := tree.Commands()[0].(*IfNode).Body.Commands()[0]
+
+	fields := TraceFields(tree, redirect, "user@example.com")
+	expected := "script=test; fingerprint=" + Fingerprint(tree)[:16] + "; rule=" + FingerprintCommand(redirect)[:16] + "; pos=2:3"
+	if len(fields) != 2 || fields[0].Name != TraceField || fields[0].Value != expected ||
+		fields[1] != (HeaderField{TraceRedirectedFrom, "user@example.com"}) {
+		t.Errorf("unexpected fields %v", fields)
+	}
+	if fields := TraceFields(tree, tree.Commands()[1], "user@example.com"); len(fields) != 1 {
+		t.Errorf("unexpected fields %v", fields)
+	}
+}
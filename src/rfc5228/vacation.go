@@ -0,0 +1,246 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/mail"
+	"strconv"
+	"strings"
+)
+
+// recipientFields are the fields in which the address of the user must appear for a vacation
+// response to be sent (RFC 5230, section 4.5)
+var recipientFields = []string{"To", "Cc", "Bcc", "Resent-To", "Resent-Cc", "Resent-Bcc"}
+
+// listFields are the fields identifying a message sent through a mailing list (RFC 2369, RFC 2919)
+var listFields = []string{"List-Id", "List-Help", "List-Subscribe", "List-Unsubscribe", "List-Post", "List-Owner", "List-Archive"}
+
+// CheckVacation returns an error if a vacation response to a message must not be sent (RFC 5230,
+// sections 4.5 and 4.6, and RFC 3834): the return path (sender) is empty, one of the user's
+// addresses or that of a list or mailer daemon; the message is auto-submitted, has a bulk, list
+// or junk precedence or comes from a mailing list; or none of the addresses (the user's own and
+// those of :addresses) is a recipient in To, Cc, Bcc or their Resent- variants.
+func CheckVacation(header *HeaderIndex, sender string, addresses []string) error {
+	sender = returnPath(sender)
+	if sender == "" {
+		return fmt.Errorf("message has an empty return path")
+	}
+	if local, _, err := SplitAddress(sender); err == nil && isAutomatedSender(local) {
+		return fmt.Errorf("return path %s is an automated sender", sender)
+	}
+	if containsAddress(addresses, sender) {
+		return fmt.Errorf("return path %s is an address of the user", sender)
+	}
+
+	for _, value := range header.Values("Auto-Submitted") {
+		if keyword := fieldKeyword(value); keyword != "" && !equalFoldASCII(keyword, "no") {
+			return fmt.Errorf("message is auto-submitted (%s)", keyword)
+		}
+	}
+	for _, value := range header.Values("X-Auto-Response-Suppress") {
+		for _, keyword := range strings.Split(value, ",") {
+			if keyword = strings.TrimSpace(keyword); equalFoldASCII(keyword, "All") || equalFoldASCII(keyword, "OOF") {
+				return fmt.Errorf("message suppresses automatic responses (%s)", keyword)
+			}
+		}
+	}
+	for _, value := range header.Values("Precedence") {
+		switch keyword := asciiLower(fieldKeyword(value)); keyword {
+		case "bulk", "list", "junk":
+			return fmt.Errorf("message has precedence %s", keyword)
+		}
+	}
+	for _, name := range listFields {
+		if header.Has(name) {
+			return fmt.Errorf("message comes from a mailing list (%s)", name)
+		}
+	}
+
+	for _, name := range recipientFields {
+		for _, value := range header.Values(name) {
+			for _, recipient := range headerAddresses(value) {
+				if containsAddress(addresses, recipient) {
+					return nil
+				}
+			}
+		}
+	}
+	return fmt.Errorf("no address of the user is a recipient of the message")
+}
+
+// returnPath strips the angle brackets of a return path
+func returnPath(sender string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(sender), "<"), ">")
+}
+
+// vacationSchema describes the arguments of vacation (RFC 5230, section 4, RFC 6131 and RFC 8580):
+//
+//	vacation [":days" number | ":seconds" number] [":subject" string] [":from" string]
+//		[":addresses" string-list] [":mime"] [":handle" string] [":fcc" string ...] <reason: string>
+var vacationSchema = &TestSchema{
+	Tags: map[string]TagSchema{
+		":days":       {Group: "period", Value: ArgNumber},
+		":seconds":    {Group: "period", Value: ArgNumber},
+		":subject":    {Value: ArgString},
+		":from":       {Value: ArgString},
+		":addresses":  {Value: ArgStringList},
+		":mime":       {},
+		":handle":     {Value: ArgString},
+		":fcc":        {Value: ArgString},
+		":copy":       {},
+		":create":     {},
+		":flags":      {Value: ArgStringList},
+		":specialuse": {Value: ArgString},
+	},
+	Positional: []ArgumentKind{ArgString},
+}
+
+// VacationHandle returns the handle of a vacation command, which identifies its response in
+// the tracking of the senders that got it (RFC 5230, section 4.2): the value of :handle, or
+// else a handle derived from :subject, :from, :mime and the reason, so that a changed
+// response is sent again. Derived handles start with "auto-" and are the same for equal
+// arguments in every process.
+func VacationHandle(command *GenericCommandNode) (string, error) {
+	if !isKeyword(command.Name, "vacation") {
+		return "", fmt.Errorf("%s is not a vacation command", command.Name)
+	}
+	args, err := command.Bind()
+	if err != nil {
+		return "", fmt.Errorf("vacation: %w", err)
+	}
+	if handle, ok := args.Value(":handle").(*StringNode); ok {
+		return handle.Text, nil
+	}
+
+	reason, _ := args.String(0)
+	var subject, from string
+	if s, ok := args.Value(":subject").(*StringNode); ok {
+		subject = s.Text
+	}
+	if s, ok := args.Value(":from").(*StringNode); ok {
+		from = s.Text
+	}
+	return "auto-" + digest(subject, from, strconv.FormatBool(args.Has(":mime")), reason), nil
+}
+
+// VacationKey identifies the record of a vacation response sent to a sender, e.g. in a store
+// shared by the nodes of a cluster: responses are tracked per user, sender and handle (RFC
+// 5230, section 4.2). Use NewVacationKey to get the normalized form.
+type VacationKey struct {
+	User   string // owner of the script, normalized as Sender
+	Sender string // return path of the message, lower-case with an ASCII domain
+	Handle string // handle of the vacation command (see VacationHandle)
+}
+
+// NewVacationKey returns the key of a response to sender: the addresses lose their angle
+// brackets and are compared ignoring case and with internationalized domains in their ASCII
+// form, as CheckVacation compares them
+func NewVacationKey(user, sender, handle string) VacationKey {
+	normalize := func(addr string) string {
+		return asciiLower(normalizeAddress(returnPath(addr)))
+	}
+	return VacationKey{User: normalize(user), Sender: normalize(sender), Handle: handle}
+}
+
+// String returns the key as a hex SHA-256 digest of its fields, for stores with string keys
+func (k VacationKey) String() string {
+	return digest(k.User, k.Sender, k.Handle)
+}
+
+// digest returns the hex SHA-256 digest of strings, each preceded by its length, so that
+// the boundaries between them are part of the digest
+func digest(values ...string) string {
+	h := sha256.New()
+	for _, value := range values {
+		fmt.Fprintf(h, "%d:%s", len(value), value)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// isAutomatedSender reports whether the local part of a return path is that of a mailer daemon
+// or a mailing list manager, which must not get automatic responses (RFC 5230, section 4.6)
+func isAutomatedSender(local string) bool {
+	local = asciiLower(local)
+	switch local {
+	case "mailer-daemon", "postmaster", "listserv", "majordomo", "mailman":
+		return true
+	}
+	return strings.HasPrefix(local, "owner-") || strings.HasSuffix(local, "-request") || strings.HasSuffix(local, "-owner") || strings.HasSuffix(local, "-bounces")
+}
+
+// fieldKeyword returns the first word of a structured field value, before any parameters
+// (`;`) or comment (`(...)`)
+func fieldKeyword(value string) string {
+	if i := strings.IndexAny(value, ";("); i >= 0 {
+		value = value[:i]
+	}
+	if fields := strings.Fields(value); len(fields) > 0 {
+		return fields[0]
+	}
+	return ""
+}
+
+// headerAddresses returns the addresses of an address list field; values that aren't valid
+// RFC 5322 address lists, which are common, are split on commas and the address of each part
+// is taken from angle brackets or the word containing an `@`
+func headerAddresses(value string) []string {
+	var addresses []string
+	if list, err := (&mail.AddressParser{}).ParseList(value); err == nil {
+		for _, addr := range list {
+			addresses = append(addresses, addr.Address)
+		}
+		return addresses
+	}
+	for _, part := range strings.Split(value, ",") {
+		if i := strings.LastIndex(part, "<"); i >= 0 {
+			if j := strings.Index(part[i:], ">"); j >= 0 {
+				addresses = append(addresses, strings.TrimSpace(part[i+1:i+j]))
+				continue
+			}
+		}
+		for _, word := range strings.Fields(part) {
+			if strings.Contains(word, "@") {
+				addresses = append(addresses, strings.Trim(word, `"'<>;`))
+			}
+		}
+	}
+	return addresses
+}
+
+// containsAddress reports whether an address is in a list, comparing local parts case-insensitively
+// and domains normalized
+func containsAddress(list []string, addr string) bool {
+	addr = normalizeAddress(addr)
+	for _, a := range list {
+		if equalFoldASCII(normalizeAddress(strings.TrimSpace(a)), addr) {
+			return true
+		}
+	}
+	return false
+}
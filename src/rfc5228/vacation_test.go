@@ -0,0 +1,103 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckVacation(t *testing.T) {
+	user := []string{"user@example.com", "alias@example.org"}
+	tests := []struct {
+		sender string
+		header []HeaderField
+		reason string // part of the error; empty if a response may be sent
+	}{
+		{"friend@example.net", []HeaderField{{"To", `"User, Example" <USER@Example.COM>`}}, ""},
+		{"friend@example.net", []HeaderField{{"To", "someone@example.net"}, {"Cc", "Alias@example.org (the alias)"}}, ""},
+		{"friend@example.net", []HeaderField{{"To", "undisclosed-recipients:;"}, {"Resent-To", "user@example.com"}}, ""},
+		// not valid RFC 5322: unquoted comma in the display name
+		{"friend@example.net", []HeaderField{{"To", "Doe, John <user@example.com>"}}, ""},
+		{"friend@example.net", []HeaderField{{"To", "user@example.com"}, {"Auto-Submitted", "no"}}, ""},
+		{"friend@example.net", []HeaderField{{"To", "other@example.com"}}, "no address"},
+		{"friend@example.net", []HeaderField{{"To", "Team: user@example.com;"}, {"Auto-Submitted", "auto-replied (vacation)"}}, "auto-submitted (auto-replied)"},
+		{"friend@example.net", []HeaderField{{"To", "user@example.com"}, {"Precedence", " Bulk"}}, "precedence bulk"},
+		{"friend@example.net", []HeaderField{{"To", "user@example.com"}, {"List-Id", "<dev.lists.example.net>"}}, "List-Id"},
+		{"friend@example.net", []HeaderField{{"To", "user@example.com"}, {"X-Auto-Response-Suppress", "DR, OOF"}}, "OOF"},
+		{"<>", []HeaderField{{"To", "user@example.com"}}, "empty return path"},
+		{"MAILER-DAEMON@example.net", []HeaderField{{"To", "user@example.com"}}, "automated sender"},
+		{"dev-request@lists.example.net", []HeaderField{{"To", "user@example.com"}}, "automated sender"},
+		{"<user@EXAMPLE.com>", []HeaderField{{"To", "user@example.com"}}, "address of the user"},
+	}
+	for _, test := range tests {
+		err := CheckVacation(NewHeaderIndex(test.header), test.sender, user)
+		switch {
+		case test.reason == "" && err != nil:
+			t.Errorf("%s %v: unexpected error %s", test.sender, test.header, err)
+		case test.reason != "" && (err == nil || !strings.Contains(err.Error(), test.reason)):
+			t.Errorf("%s %v: expected an error with %q, got %v", test.sender, test.header, test.reason, err)
+		}
+	}
+}
+
+func TestVacationHandle(t *testing.T) {
+	vacation := func(arguments string) *GenericCommandNode {
+		t.Helper()
+		tree := parse(t, "require \"vacation\";\r\nvacation "+arguments+";\r\n")
+		return tree.Commands()[1].(*GenericCommandNode)
+	}
+
+	if handle, err := VacationHandle(vacation(":days 7 :handle \"summer\" \"I'm away\"")); err != nil || handle != "summer" {
+		t.Errorf("unexpected handle %q (%v)", handle, err)
+	}
+
+	derived, err := VacationHandle(vacation(":subject \"Away\" \"I'm away\""))
+	if err != nil || !strings.HasPrefix(derived, "auto-") {
+		t.Fatalf("unexpected handle %q (%v)", derived, err)
+	}
+	for arguments, same := range map[string]bool{
+		":days 3 :subject \"Away\" \"I'm away\"": true, // :days isn't part of the handle
+		":subject \"Away\" :mime \"I'm away\"":   false,
+		":subject \"Awa\" \"yI'm away\"":         false,
+		"\"I'm away\"":                           false,
+	} {
+		if handle, err := VacationHandle(vacation(arguments)); err != nil || (handle == derived) != same {
+			t.Errorf("%s: unexpected handle %q (%v)", arguments, handle, err)
+		}
+	}
+	if _, err := VacationHandle(vacation(":days \"7\" \"I'm away\"")); err == nil {
+		t.Error("expected an error for invalid arguments")
+	}
+
+	key := NewVacationKey("Me@Example.com", "<Sender@BÜCHER.example>", "summer")
+	if key != (VacationKey{User: "me@example.com", Sender: "sender@xn--bcher-kva.example", Handle: "summer"}) {
+		t.Errorf("unexpected key %+v", key)
+	}
+	if key.String() != NewVacationKey("me@example.com", "sender@bücher.example", "summer").String() || key.String() == NewVacationKey("me@example.com", "sender@bücher.example", "winter").String() {
+		t.Errorf("unexpected key string %s", key)
+	}
+}
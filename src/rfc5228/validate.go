@@ -0,0 +1,290 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Warning is a finding for a construct that is valid but most likely unintended
+type Warning struct {
+	Pos      Pos      `json:"pos"`               // The starting position, in bytes, of the construct in the input string.
+	Line     int      `json:"line,omitempty"`    // The line of the construct, starting at 1; 0 if the tree has no source.
+	Column   int      `json:"column,omitempty"`  // The column, in bytes, of the construct, starting at 1.
+	Code     Code     `json:"code"`              // The stable identifier of the finding.
+	Severity Severity `json:"severity"`          // The severity of the finding (see SeverityOf and WithWarningsAsErrors).
+	Message  string   `json:"message"`           // The description of the finding in the requested locale.
+	Related  []Pos    `json:"related,omitempty"` // The positions of other constructs involved in the finding.
+	Args     []any    `json:"-"`                 // The arguments of the message, e.g. to render it in another locale.
+}
+
+func (w Warning) String() string {
+	if len(w.Related) > 0 {
+		return fmt.Sprintf("pos = [%d], warning = [%s], related = %v", w.Pos, w.Message, w.Related)
+	}
+	return fmt.Sprintf("pos = [%d], warning = [%s]", w.Pos, w.Message)
+}
+
+// Localize renders the message of the warning for a language tag
+func (w Warning) Localize(tag string) string {
+	return Localize(tag, w.Code, w.Args...)
+}
+
+// Validate inspects a parsed script with the built-in checks and the registered validation
+// passes (see RegisterValidation) and returns warnings in lexical order
+func Validate(tree *Tree, opts ...Option) []Warning {
+	v := &validator{options: newOptions(opts), source: tree.Source, required: map[string]Pos{}}
+	for _, require := range tree.Requires() {
+		for _, capability := range require.Capabilities {
+			if _, ok := v.required[capability]; !ok {
+				v.required[capability] = require.Pos
+			}
+		}
+	}
+	v.sequence(tree.Commands(), true)
+	v.missing(tree)
+
+	report := &Report{options: v.options, source: tree.Source, warnings: v.warnings}
+	for _, pass := range validationPasses() {
+		pass(tree, report)
+	}
+	sort.SliceStable(report.warnings, func(i, j int) bool { return report.warnings[i].Pos < report.warnings[j].Pos })
+	return report.warnings
+}
+
+// ValidationPass is a custom check run by Validate, e.g. to enforce house rules such as
+// "redirect only to addresses of the organization"; it adds its findings to the report
+type ValidationPass func(tree *Tree, report *Report)
+
+var (
+	validationsMu sync.RWMutex
+	validations   = map[string]ValidationPass{}
+)
+
+// RegisterValidation adds or replaces (by name) a validation pass run by Validate after the
+// built-in checks; passes run in the order of their names. Nil removes the pass.
+func RegisterValidation(name string, pass ValidationPass) {
+	validationsMu.Lock()
+	defer validationsMu.Unlock()
+	if pass == nil {
+		delete(validations, name)
+		return
+	}
+	validations[name] = pass
+}
+
+// validationPasses returns the registered passes in the order of their names
+func validationPasses() []ValidationPass {
+	validationsMu.RLock()
+	defer validationsMu.RUnlock()
+	names := make([]string, 0, len(validations))
+	for name := range validations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	passes := make([]ValidationPass, len(names))
+	for i, name := range names {
+		passes[i] = validations[name]
+	}
+	return passes
+}
+
+// Report collects the warnings of a validation. Messages are looked up by code in the catalogs
+// (see RegisterCatalog), so a pass registers the messages of its codes, and suppressed codes
+// (see WithSuppressed) are dropped.
+type Report struct {
+	options  options
+	source   *SourceFile
+	warnings []Warning
+}
+
+// Warn adds a warning for the construct at pos
+func (r *Report) Warn(pos Pos, code Code, args ...any) {
+	r.warnings = r.options.warn(r.warnings, r.source, pos, nil, code, args...)
+}
+
+// WarnRelated adds a warning for the construct at pos that involves constructs at other positions
+func (r *Report) WarnRelated(pos Pos, related []Pos, code Code, args ...any) {
+	r.warnings = r.options.warn(r.warnings, r.source, pos, related, code, args...)
+}
+
+// Warnings returns the warnings reported so far, including those of the built-in checks
+func (r *Report) Warnings() []Warning {
+	return append([]Warning{}, r.warnings...)
+}
+
+type validator struct {
+	warnings []Warning
+	started  bool // a command other than require has been visited
+	options  options
+	source   *SourceFile
+	required map[string]Pos // the capabilities required by the script and the first require of each
+}
+
+func (v *validator) warnf(pos Pos, code Code, args ...any) {
+	v.warnings = v.options.warn(v.warnings, v.source, pos, nil, code, args...)
+}
+
+// relatedf adds a warning that involves constructs at other positions
+func (v *validator) relatedf(pos Pos, related []Pos, code Code, args ...any) {
+	v.warnings = v.options.warn(v.warnings, v.source, pos, related, code, args...)
+}
+
+func (v *validator) commands(block *CommandsNode) {
+	v.sequence(block.Commands(), false)
+}
+
+// sequence visits the commands of the script (top) or of a block
+func (v *validator) sequence(commands []Command, top bool) {
+	var stops []*IfNode // preceding rules that end with stop
+	for _, node := range commands {
+		n, ok := node.(*IfNode)
+		if ok {
+			v.shadowedByStop(n, stops)
+		}
+
+		v.command(node)
+		if _, ok := node.(*RequireNode); top && !ok {
+			v.started = true
+		}
+
+		if ok && len(n.ElseIfs) == 0 && n.Else == nil && endsWithStop(n.Body) {
+			stops = append(stops, n)
+		}
+	}
+}
+
+// duplicates warns about the capabilities of a require command that are required before, by
+// an earlier require or earlier in the same one
+func (v *validator) duplicates(require *RequireNode) {
+	seen := map[string]bool{}
+	for _, capability := range require.Capabilities {
+		if pos := v.required[capability]; seen[capability] || pos < require.Pos {
+			v.relatedf(require.Pos, []Pos{pos}, CodeDuplicateCapability, capability, v.source.Position(pos))
+		}
+		seen[capability] = true
+	}
+}
+
+func (v *validator) command(node Command) {
+	switch n := node.(type) {
+	case *RequireNode:
+		// require must come before any other command (RFC 5228, section 3.2);
+		// the parser only rejects this in strict mode
+		if v.started {
+			v.warnf(n.Pos, CodeRequirePlacement, n.Name)
+		}
+		v.duplicates(n)
+		v.interactions(n)
+	case *RedirectNode:
+		// the parser only rejects invalid addresses in strict mode
+		if _, _, err := SplitAddress(n.Address); err != nil {
+			v.warnf(n.Pos, CodeRedirectAddress, n.Name, err)
+		}
+	case *GenericCommandNode:
+		if n.Block != nil {
+			v.commands(n.Block)
+		}
+	case *IfNode:
+		v.started = true
+		unreachable := v.condition(n.Name, n.Test)
+		v.contradictions(n.Test)
+		v.commands(n.Body)
+
+		conditions := n.Conditions()
+		for i, elsif := range n.ElseIfs {
+			if unreachable {
+				v.warnf(elsif.Pos, CodeUnreachable, elsif.Name)
+			} else {
+				v.shadowed(elsif, conditions[:i+1])
+				unreachable = v.condition(elsif.Name, elsif.Test)
+			}
+			v.contradictions(elsif.Test)
+			v.commands(elsif.Body)
+		}
+		if n.Else != nil {
+			if unreachable {
+				v.warnf(n.Else.Pos, CodeUnreachable, n.Else.Name)
+			}
+			v.commands(n.Else.Body)
+		}
+	}
+}
+
+// condition warns about a constant condition and reports whether it is always true,
+// in which case the remaining elsif/else blocks of the chain are unreachable
+func (v *validator) condition(name string, test *TestNode) bool {
+	value, ok := constant(test)
+	if !ok {
+		return false
+	}
+	if value {
+		v.warnf(test.Pos, CodeAlwaysTrue, name)
+	} else {
+		v.warnf(test.Pos, CodeAlwaysFalse, name)
+	}
+	return value
+}
+
+// constant evaluates a test that does not depend on the message, i.e. tests composed
+// of true, false, not, allof and anyof only; ok is false if the test is not constant
+func constant(test *TestNode) (value bool, ok bool) {
+	switch strings.ToLower(test.Name) {
+	case TRUE:
+		return true, true
+	case FALSE:
+		return false, true
+	case NOT:
+		if len(test.Tests) != 1 {
+			return false, false
+		}
+		if value, ok := constant(test.Tests[0]); ok {
+			return !value, true
+		}
+		return false, false
+	case ALLOF, ANYOF:
+		// allof is true unless a test is false, anyof is false unless a test is true;
+		// a single constant test decides the outcome regardless of the other tests
+		decisive := isKeyword(test.Name, ANYOF)
+		all := true
+		for _, t := range test.Tests {
+			value, ok := constant(t)
+			if ok && value == decisive {
+				return decisive, true
+			}
+			all = all && ok
+		}
+		if !all {
+			return false, false
+		}
+		return !decisive, true
+	default:
+		return false, false
+	}
+}
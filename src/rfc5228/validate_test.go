@@ -0,0 +1,161 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConstantTests(t *testing.T) {
+	for _, expected := range []struct {
+		input    string
+		value    bool
+		constant bool
+	}{
+		{"true", true, true},
+		{"false", false, true},
+		{"not true", false, true},
+		{"allof()", true, true},
+		{"anyof()", false, true},
+		{"allof(true, not false)", true, true},
+		{"allof(exists \"X\", false)", false, true},
+		{"anyof(exists \"X\", not false)", true, true},
+		{"anyof(false, exists \"X\")", false, false},
+		{"not exists \"X\"", false, false},
+	} {
+		parser, err := newParser(lex("test", expected.input+" "))
+		if err != nil {
+			t.Fatal(err)
+		}
+		test, err := parser.parseTest(newTree("test"))
+		if err != nil {
+			t.Fatalf("%s: %s", expected.input, err)
+		}
+
+		value, ok := constant(test)
+		if ok != expected.constant || value != expected.value {
+			t.Errorf("%s: expected %t (constant = %t), got %t (constant = %t)",
+				expected.input, expected.value, expected.constant, value, ok)
+		}
+	}
+	// not without its test, as a tree built or decoded elsewhere may have it
+	if _, ok := constant(&TestNode{Name: NOT}); ok {
+		t.Error("expected not without a test not to be constant")
+	}
+}
+
+func TestValidateConstantConditions(t *testing.T) {
+	tree := parse(t, "if true {\r\n  keep;\r\n} elsif exists \"X\" {\r\n  discard;\r\n} else {\r\n  stop;\r\n}\r\n"+
+		"if anyof() {\r\n  discard;\r\n}\r\n")
+
+	warnings := Validate(tree)
+	if len(warnings) != 4 {
+		t.Fatalf("expected 4 warnings, got %v", warnings)
+	}
+	for i, expected := range []string{
+		"`if` condition is always true",
+		"`elsif` is unreachable",
+		"`else` is unreachable",
+		"`if` condition is always false",
+	} {
+		if warnings[i].Message != expected {
+			t.Errorf("expected warning %q, got %q", expected, warnings[i].Message)
+		}
+	}
+}
+
+func TestValidateRequirePlacement(t *testing.T) {
+	tree := parse(t, "require \"fileinto\";\r\nkeep;\r\nrequire \"envelope\";\r\nif exists \"X\" {\r\n  require \"reject\";\r\n}\r\n")
+
+	warnings := Validate(tree)
+	if len(warnings) != 2 {
+		t.Fatalf("expected 2 warnings, got %v", warnings)
+	}
+	for _, w := range warnings {
+		if w.Message != "`require` must come before any other command" {
+			t.Errorf("unexpected warning %s", w)
+		}
+	}
+}
+
+func TestRegisterValidation(t *testing.T) {
+	const (
+		codeNoFinalKeep   Code = "CORP0001"
+		codeForeignDomain Code = "CORP0002"
+	)
+	RegisterCatalog("en", map[Code]string{
+		codeNoFinalKeep:   "script must end with an explicit `keep`",
+		codeForeignDomain: "`%s` to %s outside corp.example",
+	})
+	RegisterValidation("corp-final-keep", func(tree *Tree, report *Report) {
+		commands := tree.Commands()
+		if len(commands) == 0 {
+			return
+		}
+		if _, ok := commands[len(commands)-1].(*KeepNode); !ok {
+			report.Warn(commands[len(commands)-1].Position(), codeNoFinalKeep)
+		}
+	})
+	RegisterValidation("corp-redirect", func(tree *Tree, report *Report) {
+		var walk func(commands []Command)
+		walk = func(commands []Command) {
+			for _, node := range commands {
+				switch n := node.(type) {
+				case *RedirectNode:
+					if _, domain, err := SplitAddress(n.Address); err == nil && !strings.HasSuffix(asciiLower(domain), ".corp.example") {
+						report.Warn(n.Pos, codeForeignDomain, n.Name, n.Address)
+					}
+				case *IfNode:
+					for _, block := range n.Blocks() {
+						walk(block.Commands())
+					}
+				}
+			}
+		}
+		walk(tree.Commands())
+	})
+	defer RegisterValidation("corp-final-keep", nil)
+	defer RegisterValidation("corp-redirect", nil)
+
+	tree := parse(t, "if true {\r\n  redirect \"a@mail.corp.example\";\r\n  redirect \"b@example.com\";\r\n}\r\n")
+	warnings := Validate(tree)
+	expected := []Code{codeNoFinalKeep, CodeAlwaysTrue, codeForeignDomain}
+	if len(warnings) != len(expected) {
+		t.Fatalf("unexpected warnings %v", warnings)
+	}
+	for i, code := range expected {
+		if warnings[i].Code != code {
+			t.Errorf("expected %s, got %v", code, warnings[i])
+		}
+	}
+	if warnings[2].Message != "`redirect` to b@example.com outside corp.example" || !IsWarning(codeForeignDomain) {
+		t.Errorf("unexpected warning %v", warnings[2])
+	}
+	if warnings := Validate(tree, WithSuppressed(codeNoFinalKeep, CodeAlwaysTrue)); len(warnings) != 1 {
+		t.Errorf("unexpected warnings %v", warnings)
+	}
+}
@@ -0,0 +1,286 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DefaultMaxVariableLength is the number of bytes a variable holds by default; RFC 5229,
+// section 6, requires at least 4000 characters
+const DefaultMaxVariableLength = 4000
+
+// VariableLimits are the implementation limits of the variables of an evaluation
+type VariableLimits struct {
+	MaxValueLength int // bytes a variable holds, longer values are truncated; DefaultMaxVariableLength if 0
+	MaxMemory      int // bytes of all variables and the string being expanded together; unlimited if 0
+}
+
+// MemoryLimitError is returned when the variables of an evaluation would use more memory
+// than VariableLimits.MaxMemory
+type MemoryLimitError struct {
+	Limit int // the ceiling in bytes
+	Size  int // the bytes that would have been used
+}
+
+func (e *MemoryLimitError) Error() string {
+	return fmt.Sprintf("variables need %d bytes, more than the limit of %d", e.Size, e.Limit)
+}
+
+// Variables holds the variables of an evaluation (RFC 5229) and accounts for their memory,
+// so that scripts building large strings with set are cut off at a configured ceiling.
+// Names are case-insensitive. A Variables is used by a single evaluation.
+type Variables struct {
+	limits VariableLimits
+	values map[string]string
+	match  []string // the match variables ${0} to ${9}
+	used   int      // bytes held by values and match
+}
+
+// NewVariables returns an empty set of variables enforcing limits
+func NewVariables(limits VariableLimits) *Variables {
+	if limits.MaxValueLength == 0 {
+		limits.MaxValueLength = DefaultMaxVariableLength
+	}
+	return &Variables{limits: limits, values: map[string]string{}}
+}
+
+// Used returns the bytes held by the variables
+func (v *Variables) Used() int {
+	return v.used
+}
+
+// Get returns the value of a variable
+func (v *Variables) Get(name string) (string, bool) {
+	value, ok := v.values[strings.ToLower(name)]
+	return value, ok
+}
+
+// Set implements the set action (RFC 5229, section 4): the modifiers (e.g. ":lower") are
+// applied to the value in order of precedence, and the result is truncated to MaxValueLength
+func (v *Variables) Set(name, value string, modifiers ...string) error {
+	if !isVariableName(name) {
+		return fmt.Errorf("invalid variable name %q", name)
+	}
+	value, err := applyModifiers(value, modifiers)
+	if err != nil {
+		return err
+	}
+	value = v.truncate(value)
+	key := strings.ToLower(name)
+	if err := v.account(len(value) - len(v.values[key])); err != nil {
+		return err
+	}
+	v.values[key] = value
+	return nil
+}
+
+// SetMatch sets the match variables ${0} to ${9} from the match of a test, replacing the
+// previous ones; values beyond ${9} are not kept
+func (v *Variables) SetMatch(values []string) error {
+	if len(values) > 10 {
+		values = values[:10]
+	}
+	match := make([]string, len(values))
+	size := 0
+	for i, value := range values {
+		match[i] = v.truncate(value)
+		size += len(match[i])
+	}
+	old := 0
+	for _, value := range v.match {
+		old += len(value)
+	}
+	if err := v.account(size - old); err != nil {
+		return err
+	}
+	v.match = match
+	return nil
+}
+
+// Expand replaces the variable references (`${name}`, `${1}`) of a string by their values
+// (RFC 5229, section 3); unknown variables and namespaces expand to the empty string and text
+// that isn't a reference is kept. The expansion is accounted for while it is built.
+func (v *Variables) Expand(s string) (string, error) {
+	if !strings.Contains(s, "${") {
+		return s, nil
+	}
+	var b strings.Builder
+	write := func(text string) error {
+		if size := v.used + b.Len() + len(text); v.limits.MaxMemory > 0 && size > v.limits.MaxMemory {
+			return &MemoryLimitError{Limit: v.limits.MaxMemory, Size: size}
+		}
+		b.WriteString(text)
+		return nil
+	}
+	for {
+		start := strings.Index(s, "${")
+		end := -1
+		if start >= 0 {
+			end = strings.IndexByte(s[start:], '}')
+		}
+		if end < 0 {
+			if err := write(s); err != nil {
+				return "", err
+			}
+			return b.String(), nil
+		}
+		text := s[:start+2] // not a reference: `${` is kept and the scan continues after it
+		next := start + 2
+		if value, ok := v.reference(s[start+2 : start+end]); ok {
+			text, next = s[:start]+value, start+end+1
+		}
+		if err := write(text); err != nil {
+			return "", err
+		}
+		s = s[next:]
+	}
+}
+
+// reference returns the value of a reference; ok is false if name isn't one
+func (v *Variables) reference(name string) (string, bool) {
+	if name != "" && strings.Trim(name, "0123456789") == "" {
+		i, err := strconv.Atoi(name)
+		if err != nil || i >= len(v.match) {
+			return "", true
+		}
+		return v.match[i], true
+	}
+	if !isVariableRef(name) {
+		return "", false
+	}
+	return v.values[strings.ToLower(name)], true
+}
+
+func (v *Variables) account(delta int) error {
+	if size := v.used + delta; v.limits.MaxMemory > 0 && size > v.limits.MaxMemory {
+		return &MemoryLimitError{Limit: v.limits.MaxMemory, Size: size}
+	}
+	v.used += delta
+	return nil
+}
+
+// truncate cuts a value to MaxValueLength bytes, without splitting a UTF-8 sequence
+func (v *Variables) truncate(value string) string {
+	if len(value) <= v.limits.MaxValueLength {
+		return value
+	}
+	end := v.limits.MaxValueLength
+	for end > 0 && !utf8.RuneStart(value[end]) {
+		end--
+	}
+	return value[:end]
+}
+
+// isVariableName reports whether name is an identifier, the name of a variable set by set
+func isVariableName(name string) bool {
+	if name == "" || !isAlpha(rune(name[0])) && name[0] != '_' {
+		return false
+	}
+	for _, r := range name {
+		if !isAlpha(r) && !isDigit(r) && r != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+// isVariableRef reports whether name is a variable name, possibly with namespaces (`env.x`)
+func isVariableRef(name string) bool {
+	for _, part := range strings.Split(name, ".") {
+		if !isVariableName(part) {
+			return false
+		}
+	}
+	return true
+}
+
+// modifierPrecedence is the precedence of the modifiers of set (RFC 5229, section 4.1, and
+// :encodeurl of RFC 5435, section 7); higher precedence is applied first
+var modifierPrecedence = map[string]int{
+	":lower":         40,
+	":upper":         40,
+	":lowerfirst":    30,
+	":upperfirst":    30,
+	":quotewildcard": 20,
+	":encodeurl":     15,
+	":length":        10,
+}
+
+func applyModifiers(value string, modifiers []string) (string, error) {
+	sorted := make([]string, len(modifiers))
+	for i, modifier := range modifiers {
+		sorted[i] = strings.ToLower(modifier)
+		if _, ok := modifierPrecedence[sorted[i]]; !ok {
+			return "", fmt.Errorf("unknown modifier %s", modifier)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool { return modifierPrecedence[sorted[i]] > modifierPrecedence[sorted[j]] })
+	for i := 1; i < len(sorted); i++ {
+		if modifierPrecedence[sorted[i]] == modifierPrecedence[sorted[i-1]] {
+			return "", fmt.Errorf("modifiers %s and %s have the same precedence", sorted[i-1], sorted[i])
+		}
+	}
+
+	for _, modifier := range sorted {
+		switch modifier {
+		case ":lower":
+			value = strings.ToLower(value)
+		case ":upper":
+			value = strings.ToUpper(value)
+		case ":lowerfirst", ":upperfirst":
+			r, size := utf8.DecodeRuneInString(value)
+			if size > 0 {
+				if modifier == ":lowerfirst" {
+					r = unicode.ToLower(r)
+				} else {
+					r = unicode.ToUpper(r)
+				}
+				value = string(r) + value[size:]
+			}
+		case ":quotewildcard":
+			var b strings.Builder
+			for _, r := range value {
+				if r == '*' || r == '?' || r == '\\' {
+					b.WriteByte('\\')
+				}
+				b.WriteRune(r)
+			}
+			value = b.String()
+		case ":encodeurl":
+			value = strings.ReplaceAll(url.QueryEscape(value), "+", "%20")
+		case ":length":
+			value = strconv.Itoa(utf8.RuneCountInString(value))
+		}
+	}
+	return value, nil
+}
@@ -0,0 +1,120 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package rfc5228
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestVariablesExpand(t *testing.T) {
+	v := NewVariables(VariableLimits{})
+	if err := v.Set("Company", "ACME"); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.SetMatch([]string{"all", "first"}); err != nil {
+		t.Fatal(err)
+	}
+	for input, expected := range map[string]string{
+		"no references":          "no references",
+		"${company} ${COMPANY}":  "ACME ACME",
+		"${1}/${01}/${2}":        "first/first/",
+		"${unknown}${env.user}x": "x",
+		"${a-b} ${ ${company}":   "${a-b} ${ ACME",
+		"${company":              "${company",
+		"$${company}}":           "$ACME}",
+	} {
+		if output, err := v.Expand(input); err != nil || output != expected {
+			t.Errorf("%q: expected %q, got %q, %v", input, expected, output, err)
+		}
+	}
+}
+
+func TestVariablesModifiers(t *testing.T) {
+	v := NewVariables(VariableLimits{})
+	for _, test := range []struct {
+		value     string
+		modifiers []string
+		expected  string
+	}{
+		{"Juliet", []string{":lower"}, "juliet"},
+		{"juliet", []string{":upperfirst"}, "Juliet"},
+		// :lower is applied before :upperfirst
+		{"JULIET", []string{":upperfirst", ":lower"}, "Juliet"},
+		{"a*b?", []string{":quotewildcard"}, `a\*b\?`},
+		{"café", []string{":length"}, "4"},
+		{"a b&c", []string{":encodeurl"}, "a%20b%26c"},
+	} {
+		if err := v.Set("x", test.value, test.modifiers...); err != nil {
+			t.Fatal(err)
+		}
+		if value, _ := v.Get("X"); value != test.expected {
+			t.Errorf("%q %v: expected %q, got %q", test.value, test.modifiers, test.expected, value)
+		}
+	}
+	if err := v.Set("x", "a", ":lower", ":upper"); err == nil {
+		t.Error("expected an error for modifiers of the same precedence")
+	}
+	if err := v.Set("x", "a", ":reverse"); err == nil {
+		t.Error("expected an error for an unknown modifier")
+	}
+	if err := v.Set("1x", "a"); err == nil {
+		t.Error("expected an error for an invalid name")
+	}
+}
+
+func TestVariablesLimits(t *testing.T) {
+	v := NewVariables(VariableLimits{MaxValueLength: 5, MaxMemory: 12})
+	// truncated without splitting é
+	if err := v.Set("a", "abcdé"); err != nil {
+		t.Fatal(err)
+	}
+	if value, _ := v.Get("a"); value != "abcd" || v.Used() != 4 {
+		t.Errorf("expected the value to be truncated to 4 bytes, got %q, %d", value, v.Used())
+	}
+	if err := v.Set("b", "12345"); err != nil {
+		t.Fatal(err)
+	}
+	// replacing a value accounts for the difference
+	if err := v.Set("b", "1"); err != nil || v.Used() != 5 {
+		t.Errorf("expected 5 bytes in use, got %d, %v", v.Used(), err)
+	}
+
+	var limit *MemoryLimitError
+	if _, err := v.Expand("${a}${a}"); !errors.As(err, &limit) || limit.Limit != 12 || limit.Size != 13 {
+		t.Errorf("expected a *MemoryLimitError for the expansion, got %v", err)
+	}
+	if err := v.Set("c", "12345"); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.Set("d", "123"); !errors.As(err, &limit) || !strings.Contains(err.Error(), "more than the limit of 12") {
+		t.Errorf("expected a *MemoryLimitError for set, got %v", err)
+	}
+	if _, ok := v.Get("d"); ok || v.Used() != 10 {
+		t.Errorf("expected the failed set to be left out, got %d bytes", v.Used())
+	}
+}
@@ -0,0 +1,170 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package scripts
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// binaryMagic starts the header of a compiled script of Dovecot Pigeonhole, in the byte
+// order of the host that compiled it, followed by the major and minor version of the format
+const binaryMagic = 0xcafebabe
+
+// activeBinary is the compiled active script of Dovecot when the active script is a file
+const activeBinary = ".dovecot.svbin"
+
+// Binary is a compiled script of Dovecot Pigeonhole in the directory of a user
+type Binary struct {
+	Name    string `json:"name"`              // the script it was compiled from; empty for .dovecot.svbin
+	Path    string `json:"path"`              // the file of the binary
+	Version string `json:"version,omitempty"` // the version of the format, e.g. "1.4"; empty if it isn't a binary of Pigeonhole
+	Stale   bool   `json:"stale"`             // the script is newer, or no longer exists
+}
+
+// Layout describes the directory of a user as Dovecot Pigeonhole uses it
+type Layout struct {
+	Active   string   `json:"active,omitempty"` // the active script of the store (see FileStore)
+	Foreign  bool     `json:"foreign"`          // .dovecot.sieve is a file instead of a link, written by the user or an administrator
+	Binaries []Binary `json:"binaries"`
+}
+
+// Inspect returns the layout of the directory of a user, e.g. to check a Pigeonhole
+// deployment before gosieve tools manage it: the active script and the compiled scripts, of
+// which those older than their scripts or without one are stale
+func (s *FileStore) Inspect(user string) (*Layout, error) {
+	home, err := s.home(user)
+	if err != nil {
+		return nil, err
+	}
+	layout := &Layout{Binaries: []Binary{}}
+	if layout.Active, err = s.active(home); err != nil {
+		return nil, err
+	}
+	link := filepath.Join(home, activeLink)
+	if info, err := os.Lstat(link); err == nil && info.Mode()&fs.ModeSymlink == 0 {
+		layout.Foreign = true
+	}
+
+	paths, err := filepath.Glob(filepath.Join(home, scriptDir, "*"+binaryExt))
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), binaryExt)
+		b, err := inspectBinary(name, path, filepath.Join(home, scriptDir, name+scriptExt))
+		if err != nil {
+			return nil, err
+		}
+		layout.Binaries = append(layout.Binaries, b)
+	}
+	// the binary of a foreign active script, which Dovecot keeps next to it
+	if path := filepath.Join(home, activeBinary); exists(path) {
+		b, err := inspectBinary("", path, link)
+		if err != nil {
+			return nil, err
+		}
+		layout.Binaries = append(layout.Binaries, b)
+	}
+	sort.Slice(layout.Binaries, func(i, j int) bool { return layout.Binaries[i].Path < layout.Binaries[j].Path })
+	return layout, nil
+}
+
+// Invalidate removes the stale compiled scripts of a user (see Inspect), so that Dovecot
+// compiles their scripts again, and returns their paths
+func (s *FileStore) Invalidate(user string) ([]string, error) {
+	layout, err := s.Inspect(user)
+	if err != nil {
+		return nil, err
+	}
+	removed := []string{}
+	for _, b := range layout.Binaries {
+		if !b.Stale {
+			continue
+		}
+		if err := os.Remove(b.Path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return removed, err
+		}
+		removed = append(removed, b.Path)
+	}
+	return removed, nil
+}
+
+// removeBinary removes the compiled script of a script, which Dovecot would otherwise keep
+// using if the script is replaced within the resolution of modification times
+func removeBinary(script string) error {
+	binary := strings.TrimSuffix(script, scriptExt) + binaryExt
+	if err := os.Remove(binary); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// inspectBinary reads the header of a compiled script and compares its modification time
+// with that of its script; a binary is stale if it isn't newer than its script
+func inspectBinary(name, path, script string) (Binary, error) {
+	b := Binary{Name: name, Path: path}
+	f, err := os.Open(path)
+	if err != nil {
+		return b, err
+	}
+	defer f.Close()
+	var header [8]byte
+	if _, err := io.ReadFull(f, header[:]); err == nil {
+		for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+			if order.Uint32(header[:4]) == binaryMagic {
+				b.Version = fmt.Sprintf("%d.%d", order.Uint16(header[4:6]), order.Uint16(header[6:8]))
+			}
+		}
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		return b, err
+	}
+	scriptInfo, err := os.Stat(script)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		b.Stale = true
+	case err != nil:
+		return b, err
+	default:
+		b.Stale = !info.ModTime().After(scriptInfo.ModTime())
+	}
+	return b, nil
+}
+
+func exists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
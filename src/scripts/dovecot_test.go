@@ -0,0 +1,116 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package scripts
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFileStoreInspect(t *testing.T) {
+	root := t.TempDir()
+	store := &FileStore{Root: root}
+	for _, name := range []string{"main", "old"} {
+		if err := store.Put("alice", name, "keep;\r\n"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.SetActive("alice", "main"); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := filepath.Join(root, "alice", "sieve")
+	script := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	for name, compiled := range map[string]time.Time{
+		"main.svbin": script.Add(time.Minute), // compiled after the script was written
+		"old.svbin":  script,                  // not newer than the script
+		"gone.svbin": script,                  // the script was removed by hand
+	} {
+		path := filepath.Join(dir, name)
+		// the header of a binary compiled on a little-endian host, version 1.4
+		if err := os.WriteFile(path, []byte{0xbe, 0xba, 0xfe, 0xca, 1, 0, 4, 0}, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, compiled, compiled); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{"main.sieve", "old.sieve"} {
+		if err := os.Chtimes(filepath.Join(dir, name), script, script); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	layout, err := store.Inspect("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Binary{
+		{Name: "gone", Path: filepath.Join(dir, "gone.svbin"), Version: "1.4", Stale: true},
+		{Name: "main", Path: filepath.Join(dir, "main.svbin"), Version: "1.4"},
+		{Name: "old", Path: filepath.Join(dir, "old.svbin"), Version: "1.4", Stale: true},
+	}
+	if layout.Active != "main" || layout.Foreign || !reflect.DeepEqual(layout.Binaries, expected) {
+		t.Errorf("unexpected layout %+v", layout)
+	}
+
+	removed, err := store.Invalidate("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(removed, []string{expected[0].Path, expected[2].Path}) {
+		t.Errorf("unexpected removed binaries %v", removed)
+	}
+
+	// replacing a script removes its binary, whatever the modification times
+	if err := store.Put("alice", "main", "discard;\r\n"); err != nil {
+		t.Fatal(err)
+	}
+	if layout, err := store.Inspect("alice"); err != nil || len(layout.Binaries) != 0 {
+		t.Errorf("unexpected layout %+v (%v)", layout, err)
+	}
+
+	// an active script written by hand, compiled next to it
+	home := filepath.Join(root, "alice")
+	if err := os.Remove(filepath.Join(home, ".dovecot.sieve")); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{".dovecot.sieve", ".dovecot.svbin"} {
+		if err := os.WriteFile(filepath.Join(home, name), []byte("keep;\r\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	layout, err = store.Inspect("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if layout.Active != "" || !layout.Foreign || len(layout.Binaries) != 1 || layout.Binaries[0].Name != "" || layout.Binaries[0].Version != "" {
+		t.Errorf("unexpected layout %+v", layout)
+	}
+}
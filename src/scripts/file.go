@@ -0,0 +1,245 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package scripts
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gosieve/src/rfc5228"
+)
+
+// The layout of the directory of a user in a FileStore, that of Dovecot Pigeonhole with its
+// default sieve = file:~/sieve;active=~/.dovecot.sieve
+const (
+	scriptDir  = "sieve"          // the scripts, as <name>.sieve
+	tempDir    = "sieve/tmp"      // scripts and links being written
+	activeLink = ".dovecot.sieve" // symbolic link to the active script, relative to the directory of the user
+	scriptExt  = ".sieve"
+	binaryExt  = ".svbin" // compiled scripts of Dovecot, next to their scripts
+)
+
+// FileStore keeps the scripts of a user in <Root>/<user>/sieve/<name>.sieve, with a symbolic
+// link <Root>/<user>/.dovecot.sieve to the active script, as Dovecot Pigeonhole does when
+// the home directories of its users are <Root>/<user>.
+//
+// Scripts and the link are written aside and renamed into place, so delivery never reads a
+// half-written script. The store doesn't compile scripts: Dovecot compiles a script into a
+// .svbin file when it runs it, and recompiles it when the script is newer; Put and Delete
+// remove the .svbin file of a script, and Inspect and Invalidate find stale ones.
+type FileStore struct {
+	Root    string
+	Mode    rfc5228.Mode // the mode scripts are parsed in by Put
+	Options []rfc5228.Option
+}
+
+// home returns the directory of a user
+func (s *FileStore) home(user string) (string, error) {
+	if err := ValidName(user); err != nil {
+		return "", err
+	}
+	return filepath.Join(s.Root, user), nil
+}
+
+// path returns the file of a script
+func (s *FileStore) path(user, name string) (string, error) {
+	home, err := s.home(user)
+	if err != nil {
+		return "", err
+	}
+	if err := ValidName(name); err != nil {
+		return "", err
+	}
+	return filepath.Join(home, scriptDir, name+scriptExt), nil
+}
+
+// active returns the name of the active script of a user, or "" if no script of the store
+// is active; an active script that isn't a link isn't one of the store (see SetActive)
+func (s *FileStore) active(home string) (string, error) {
+	link := filepath.Join(home, activeLink)
+	info, err := os.Lstat(link)
+	if errors.Is(err, fs.ErrNotExist) || err == nil && info.Mode()&fs.ModeSymlink == 0 {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	target, err := os.Readlink(link)
+	if err != nil {
+		return "", err
+	}
+	if filepath.Dir(target) != scriptDir || !strings.HasSuffix(target, scriptExt) {
+		return "", fmt.Errorf("%s links to %s, outside the scripts of the store", activeLink, target)
+	}
+	return strings.TrimSuffix(filepath.Base(target), scriptExt), nil
+}
+
+func (s *FileStore) ListScripts(user string) ([]ScriptInfo, error) {
+	home, err := s.home(user)
+	if err != nil {
+		return nil, err
+	}
+	active, err := s.active(home)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(filepath.Join(home, scriptDir))
+	if errors.Is(err, fs.ErrNotExist) {
+		return []ScriptInfo{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	infos := []ScriptInfo{}
+	for _, entry := range entries {
+		name := strings.TrimSuffix(entry.Name(), scriptExt)
+		if !entry.Type().IsRegular() || !strings.HasSuffix(entry.Name(), scriptExt) || ValidName(name) != nil {
+			continue // .svbin files, the tmp directory and files not written by a store
+		}
+		infos = append(infos, ScriptInfo{Name: name, Active: name == active})
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos, nil
+}
+
+func (s *FileStore) Get(user, name string) (string, error) {
+	path, err := s.path(user, name)
+	if err != nil {
+		return "", err
+	}
+	content, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", ErrNotFound
+	}
+	return string(content), err
+}
+
+func (s *FileStore) Put(user, name, content string) error {
+	path, err := s.path(user, name)
+	if err != nil {
+		return err
+	}
+	if err := check(name, content, s.Mode, s.Options); err != nil {
+		return err
+	}
+	tmp, err := s.temp(user)
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(tmp, name+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(content)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		return err
+	}
+	return removeBinary(path)
+}
+
+// temp returns the directory for files being written, creating the directories of the user
+func (s *FileStore) temp(user string) (string, error) {
+	home, err := s.home(user)
+	if err != nil {
+		return "", err
+	}
+	tmp := filepath.Join(home, tempDir)
+	return tmp, os.MkdirAll(tmp, 0o700)
+}
+
+func (s *FileStore) SetActive(user, name string) error {
+	home, err := s.home(user)
+	if err != nil {
+		return err
+	}
+	link := filepath.Join(home, activeLink)
+	if info, err := os.Lstat(link); err == nil && info.Mode()&fs.ModeSymlink == 0 {
+		// a script that isn't managed by the store, e.g. written by the user or an administrator
+		return fmt.Errorf("%s is not a symbolic link", link)
+	}
+	if name == "" {
+		if err := os.Remove(link); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+
+	if _, err := s.Get(user, name); err != nil {
+		return err
+	}
+	tmp, err := s.temp(user)
+	if err != nil {
+		return err
+	}
+	// a new link renamed over the old one replaces it atomically
+	f, err := os.CreateTemp(tmp, activeLink+".*")
+	if err != nil {
+		return err
+	}
+	f.Close()
+	os.Remove(f.Name())
+	if err := os.Symlink(filepath.Join(scriptDir, name+scriptExt), f.Name()); err != nil {
+		return err
+	}
+	if err := os.Rename(f.Name(), link); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return nil
+}
+
+func (s *FileStore) Delete(user, name string) error {
+	path, err := s.path(user, name)
+	if err != nil {
+		return err
+	}
+	home, _ := s.home(user)
+	active, err := s.active(home)
+	if err != nil {
+		return err
+	}
+	if name == active {
+		return ErrActive
+	}
+	if err := os.Remove(path); errors.Is(err, fs.ErrNotExist) {
+		return ErrNotFound
+	} else if err != nil {
+		return err
+	}
+	return removeBinary(path)
+}
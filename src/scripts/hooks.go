@@ -0,0 +1,143 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package scripts
+
+import (
+	"fmt"
+
+	"gosieve/src/rfc5228"
+)
+
+// Veto is the error of a hook that rejects a script; a ManageSieve server answers it with a
+// NO response holding Code, if any, and Message (RFC 5804, section 1.3)
+type Veto struct {
+	Code    string // the response code, e.g. "QUOTA/MAXSCRIPTS"; empty for none
+	Message string // the reason, for the user
+}
+
+func (v *Veto) Error() string {
+	return v.Message
+}
+
+// Event describes an operation on a HookedStore, e.g. for an audit log
+type Event struct {
+	Op   string // "put", "activate", "deactivate" or "delete"
+	User string
+	Name string
+	Err  error // the veto of a hook or the error of the store; nil if the operation succeeded
+}
+
+// Hooks are called by a HookedStore around the operations of its store
+type Hooks struct {
+	// BeforePut is called with a parsed script before it is stored (PUTSCRIPT); an error,
+	// typically a *Veto, rejects the script
+	BeforePut func(user, name string, tree *rfc5228.Tree) error
+	// BeforeActivate is called with the stored script before it becomes the active one
+	// (SETACTIVE); an error rejects the activation
+	BeforeActivate func(user, name string, tree *rfc5228.Tree) error
+	// After is called after every operation that changes the store, including rejected ones
+	After func(event Event)
+}
+
+// HookedStore is a Store that calls hooks, e.g. to enforce a validation profile, run a
+// policy engine or record an audit log
+type HookedStore struct {
+	Store   Store
+	Hooks   Hooks
+	Mode    rfc5228.Mode // the mode scripts are parsed in for the hooks
+	Options []rfc5228.Option
+}
+
+func (s *HookedStore) ListScripts(user string) ([]ScriptInfo, error) {
+	return s.Store.ListScripts(user)
+}
+
+func (s *HookedStore) Get(user, name string) (string, error) {
+	return s.Store.Get(user, name)
+}
+
+func (s *HookedStore) Put(user, name, content string) error {
+	err := s.call(s.Hooks.BeforePut, user, name, content)
+	if err == nil {
+		err = s.Store.Put(user, name, content)
+	}
+	s.after("put", user, name, err)
+	return err
+}
+
+func (s *HookedStore) SetActive(user, name string) error {
+	if name == "" {
+		err := s.Store.SetActive(user, name)
+		s.after("deactivate", user, name, err)
+		return err
+	}
+	content, err := s.Store.Get(user, name)
+	if err == nil {
+		err = s.call(s.Hooks.BeforeActivate, user, name, content)
+	}
+	if err == nil {
+		err = s.Store.SetActive(user, name)
+	}
+	s.after("activate", user, name, err)
+	return err
+}
+
+func (s *HookedStore) Delete(user, name string) error {
+	err := s.Store.Delete(user, name)
+	s.after("delete", user, name, err)
+	return err
+}
+
+// call parses a script and passes it to a hook
+func (s *HookedStore) call(hook func(user, name string, tree *rfc5228.Tree) error, user, name, content string) error {
+	if hook == nil {
+		return nil
+	}
+	tree, err := rfc5228.Parse(name, content, s.Mode, s.Options...)
+	if err != nil {
+		return err
+	}
+	return hook(user, name, tree)
+}
+
+func (s *HookedStore) after(op, user, name string, err error) {
+	if s.Hooks.After != nil {
+		s.Hooks.After(Event{Op: op, User: user, Name: name, Err: err})
+	}
+}
+
+// RejectWarnings returns a hook that vetoes scripts with findings of the validator of
+// rfc5228.SeverityError, e.g. with rfc5228.WithWarningsAsErrors for a strict profile
+func RejectWarnings(opts ...rfc5228.Option) func(user, name string, tree *rfc5228.Tree) error {
+	return func(user, name string, tree *rfc5228.Tree) error {
+		for _, w := range rfc5228.Validate(tree, opts...) {
+			if w.Severity == rfc5228.SeverityError {
+				return &Veto{Message: fmt.Sprintf("line %d: %s (%s)", w.Line, w.Message, w.Code)}
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,191 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+// Package scripts stores the sieve scripts of many users, with at most one active script per
+// user, for the upload of scripts (e.g. by ManageSieve, RFC 5804) and for delivery, which runs
+// the active script of the recipient.
+//
+// FileStore is compatible with the layout of Dovecot Pigeonhole, so both can serve the same
+// directories; MemoryStore is meant for tests.
+package scripts
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"gosieve/src/rfc5228"
+)
+
+var (
+	// ErrNotFound is returned for a script that doesn't exist
+	ErrNotFound = errors.New("script not found")
+	// ErrActive is returned when deleting the active script, which must be deactivated first
+	// (RFC 5804, section 2.10)
+	ErrActive = errors.New("script is active")
+)
+
+// ScriptInfo describes a stored script
+type ScriptInfo struct {
+	Name   string `json:"name"`
+	Active bool   `json:"active"`
+}
+
+// Store keeps the scripts of users
+type Store interface {
+	// ListScripts returns the scripts of a user sorted by name
+	ListScripts(user string) ([]ScriptInfo, error)
+	// Get returns the content of a script
+	Get(user, name string) (string, error)
+	// Put creates or replaces a script; a script that doesn't parse is rejected with the
+	// *rfc5228.SyntaxError and not stored
+	Put(user, name, content string) error
+	// SetActive makes a script the active one of a user; the empty name deactivates the
+	// active script
+	SetActive(user, name string) error
+	// Delete removes a script that isn't active
+	Delete(user, name string) error
+}
+
+// Active returns the name and content of the active script of a user, e.g. for delivery;
+// the name is empty if no script is active
+func Active(s Store, user string) (string, string, error) {
+	infos, err := s.ListScripts(user)
+	if err != nil {
+		return "", "", err
+	}
+	for _, info := range infos {
+		if info.Active {
+			content, err := s.Get(user, info.Name)
+			return info.Name, content, err
+		}
+	}
+	return "", "", nil
+}
+
+// ValidName rejects names of users and scripts that can't be stored: empty names, names
+// with a control character or a slash, and names starting with a dot, which would escape the
+// directory of a FileStore or be hidden in it (RFC 5804, section 1.6, leaves the allowed
+// characters to the server)
+func ValidName(name string) error {
+	if name == "" || strings.HasPrefix(name, ".") || strings.ContainsAny(name, `/\`) {
+		return fmt.Errorf("invalid name %q", name)
+	}
+	for _, r := range name {
+		if unicode.IsControl(r) {
+			return fmt.Errorf("invalid name %q", name)
+		}
+	}
+	return nil
+}
+
+// check parses a script, as every store does before storing it
+func check(name, content string, mode rfc5228.Mode, opts []rfc5228.Option) error {
+	_, err := rfc5228.Parse(name, content, mode, opts...)
+	return err
+}
+
+// MemoryStore keeps scripts in memory, e.g. for tests
+type MemoryStore struct {
+	Mode    rfc5228.Mode // the mode scripts are parsed in by Put
+	Options []rfc5228.Option
+
+	mu      sync.Mutex
+	scripts map[string]map[string]string // contents by user and name
+	active  map[string]string            // names of the active scripts by user
+}
+
+func (s *MemoryStore) ListScripts(user string) ([]ScriptInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	infos := []ScriptInfo{}
+	for name := range s.scripts[user] {
+		infos = append(infos, ScriptInfo{Name: name, Active: s.active[user] == name})
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos, nil
+}
+
+func (s *MemoryStore) Get(user, name string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	content, ok := s.scripts[user][name]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return content, nil
+}
+
+func (s *MemoryStore) Put(user, name, content string) error {
+	if err := ValidName(user); err != nil {
+		return err
+	}
+	if err := ValidName(name); err != nil {
+		return err
+	}
+	if err := check(name, content, s.Mode, s.Options); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.scripts == nil {
+		s.scripts, s.active = map[string]map[string]string{}, map[string]string{}
+	}
+	if s.scripts[user] == nil {
+		s.scripts[user] = map[string]string{}
+	}
+	s.scripts[user][name] = content
+	return nil
+}
+
+func (s *MemoryStore) SetActive(user, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if name == "" {
+		delete(s.active, user)
+		return nil
+	}
+	if _, ok := s.scripts[user][name]; !ok {
+		return ErrNotFound
+	}
+	s.active[user] = name
+	return nil
+}
+
+func (s *MemoryStore) Delete(user, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.scripts[user][name]; !ok {
+		return ErrNotFound
+	}
+	if s.active[user] == name {
+		return ErrActive
+	}
+	delete(s.scripts[user], name)
+	return nil
+}
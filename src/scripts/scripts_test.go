@@ -0,0 +1,211 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package scripts
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"gosieve/src/rfc5228"
+)
+
+func TestStore(t *testing.T) {
+	for name, store := range map[string]Store{
+		"file":   &FileStore{Root: t.TempDir()},
+		"memory": &MemoryStore{},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if infos, err := store.ListScripts("alice"); err != nil || len(infos) != 0 {
+				t.Errorf("unexpected scripts %v (%v)", infos, err)
+			}
+			for _, name := range []string{"vacation", "main"} {
+				if err := store.Put("alice", name, "keep;\r\n"); err != nil {
+					t.Fatal(err)
+				}
+			}
+			var syntax *rfc5228.SyntaxError
+			if err := store.Put("alice", "broken", "if {"); !errors.As(err, &syntax) {
+				t.Errorf("expected a syntax error, got %v", err)
+			}
+			for _, name := range []string{"", ".hidden", "../bob", "a/b", "tab\t"} {
+				if err := store.Put("alice", name, "keep;\r\n"); err == nil {
+					t.Errorf("%q: expected an invalid name", name)
+				}
+			}
+
+			if err := store.SetActive("alice", "missing"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("expected ErrNotFound, got %v", err)
+			}
+			if err := store.SetActive("alice", "vacation"); err != nil {
+				t.Fatal(err)
+			}
+			if err := store.SetActive("alice", "main"); err != nil {
+				t.Fatal(err)
+			}
+			expected := []ScriptInfo{{Name: "main", Active: true}, {Name: "vacation"}}
+			if infos, err := store.ListScripts("alice"); err != nil || !reflect.DeepEqual(infos, expected) {
+				t.Errorf("unexpected scripts %v (%v)", infos, err)
+			}
+			if infos, err := store.ListScripts("bob"); err != nil || len(infos) != 0 {
+				t.Errorf("unexpected scripts of another user %v (%v)", infos, err)
+			}
+
+			if err := store.Put("alice", "main", "discard;\r\n"); err != nil {
+				t.Fatal(err)
+			}
+			if name, content, err := Active(store, "alice"); err != nil || name != "main" || content != "discard;\r\n" {
+				t.Errorf("unexpected active script %q %q (%v)", name, content, err)
+			}
+
+			if err := store.Delete("alice", "main"); !errors.Is(err, ErrActive) {
+				t.Errorf("expected ErrActive, got %v", err)
+			}
+			if err := store.Delete("alice", "vacation"); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := store.Get("alice", "vacation"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("expected ErrNotFound, got %v", err)
+			}
+			if err := store.Delete("alice", "vacation"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("expected ErrNotFound, got %v", err)
+			}
+
+			if err := store.SetActive("alice", ""); err != nil {
+				t.Fatal(err)
+			}
+			if name, _, err := Active(store, "alice"); err != nil || name != "" {
+				t.Errorf("unexpected active script %q (%v)", name, err)
+			}
+			if err := store.Delete("alice", "main"); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestFileStoreLayout(t *testing.T) {
+	root := t.TempDir()
+	store := &FileStore{Root: root}
+	if err := store.Put("alice", "main", "keep;\r\n"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Put("alice", "old", "keep;\r\n"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetActive("alice", "main"); err != nil {
+		t.Fatal(err)
+	}
+
+	home := filepath.Join(root, "alice")
+	if target, err := os.Readlink(filepath.Join(home, ".dovecot.sieve")); err != nil || target != filepath.Join("sieve", "main.sieve") {
+		t.Errorf("unexpected link %q (%v)", target, err)
+	}
+	// compiled scripts of Dovecot aren't scripts, and are removed with their script
+	for _, name := range []string{"main.svbin", "old.svbin"} {
+		if err := os.WriteFile(filepath.Join(home, "sieve", name), []byte{0}, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if infos, err := store.ListScripts("alice"); err != nil || len(infos) != 2 {
+		t.Errorf("unexpected scripts %v (%v)", infos, err)
+	}
+	if err := store.Delete("alice", "old"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(home, "sieve", "old.svbin")); !os.IsNotExist(err) {
+		t.Errorf("expected the compiled script to be removed, got %v", err)
+	}
+
+	// an active script that isn't a link isn't managed by the store
+	if err := os.Remove(filepath.Join(home, ".dovecot.sieve")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(home, ".dovecot.sieve"), []byte("keep;\r\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetActive("alice", "main"); err == nil {
+		t.Error("expected an error for an active script that isn't a link")
+	}
+	if infos, err := store.ListScripts("alice"); err != nil || len(infos) != 1 || infos[0].Active {
+		t.Errorf("unexpected scripts %v (%v)", infos, err)
+	}
+}
+
+func TestHookedStore(t *testing.T) {
+	var events []Event
+	store := &HookedStore{
+		Store: &MemoryStore{},
+		Hooks: Hooks{
+			BeforePut: RejectWarnings(rfc5228.WithWarningsAsErrors()),
+			BeforeActivate: func(user, name string, tree *rfc5228.Tree) error {
+				if len(tree.Commands()) > 1 {
+					return &Veto{Code: "QUOTA", Message: "too many rules"}
+				}
+				return nil
+			},
+			After: func(event Event) { events = append(events, event) },
+		},
+	}
+
+	var veto *Veto
+	if err := store.Put("alice", "bad", "redirect \"postmaster\";\r\n"); !errors.As(err, &veto) || veto.Code != "" {
+		t.Errorf("expected a veto, got %v", err)
+	}
+	if _, err := store.Get("alice", "bad"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected a rejected script not to be stored, got %v", err)
+	}
+	for _, name := range []string{"one", "two"} {
+		script := "keep;\r\n"
+		if name == "two" {
+			script += "stop;\r\n"
+		}
+		if err := store.Put("alice", name, script); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.SetActive("alice", "two"); !errors.As(err, &veto) || veto.Code != "QUOTA" {
+		t.Errorf("expected a veto, got %v", err)
+	}
+	if err := store.SetActive("alice", "one"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetActive("alice", "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+
+	var ops []string
+	for _, event := range events {
+		ops = append(ops, fmt.Sprintf("%s %s %v", event.Op, event.Name, event.Err != nil))
+	}
+	expected := []string{"put bad true", "put one false", "put two false", "activate two true", "activate one false", "activate missing true"}
+	if !reflect.DeepEqual(ops, expected) {
+		t.Errorf("unexpected events %v", ops)
+	}
+}
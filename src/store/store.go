@@ -0,0 +1,194 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+// Package store persists the state that sieve actions keep between messages: the senders
+// that got a vacation response (see rfc5228.VacationKey and RFC 5230, section 4.2) and the
+// messages seen by the duplicate test (RFC 7352). Both record a key for a period and ask
+// whether a key is recorded, so a store maps keys to the time they expire.
+//
+// Implementations exist for the file system (FileStore) and memory (MemoryStore); other
+// stores (e.g. Redis or SQL) can be plugged in.
+package store
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Store records keys until they expire
+type Store interface {
+	// Track reports whether key is recorded and hasn't expired at now; if it isn't, key is
+	// recorded until now plus period. The check and the update are a single operation, so
+	// that of two deliveries at the same time only one sends a response.
+	Track(key string, now time.Time, period time.Duration) (bool, error)
+	// Prune removes the keys that have expired at now
+	Prune(now time.Time) error
+}
+
+// MemoryStore keeps keys in memory, e.g. for tests or a single process
+type MemoryStore struct {
+	mu      sync.Mutex
+	expires map[string]time.Time
+}
+
+func (s *MemoryStore) Track(key string, now time.Time, period time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if expires, ok := s.expires[key]; ok && now.Before(expires) {
+		return true, nil
+	}
+	if s.expires == nil {
+		s.expires = map[string]time.Time{}
+	}
+	s.expires[key] = now.Add(period)
+	return false, nil
+}
+
+func (s *MemoryStore) Prune(now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, expires := range s.expires {
+		if !now.Before(expires) {
+			delete(s.expires, key)
+		}
+	}
+	return nil
+}
+
+// FileStore keeps a file per key in a directory, named by the SHA-256 digest of the key and
+// holding the time it expires:
+//
+//	<Dir>/<digest>
+//
+// A key is recorded by linking its file into place, which fails if it exists, so processes
+// sharing the directory agree on which of them recorded it first.
+type FileStore struct {
+	Dir string
+}
+
+func (s *FileStore) path(key string) string {
+	digest := sha256.Sum256([]byte(key))
+	return filepath.Join(s.Dir, hex.EncodeToString(digest[:]))
+}
+
+func (s *FileStore) Track(key string, now time.Time, period time.Duration) (bool, error) {
+	if err := os.MkdirAll(s.Dir, 0o700); err != nil {
+		return false, err
+	}
+	path := s.path(key)
+	for {
+		err := s.create(path, now.Add(period))
+		if err == nil {
+			return false, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return false, err
+		}
+
+		expires, err := readExpiry(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue // removed by Prune in the meantime
+		}
+		if err != nil {
+			return false, err
+		}
+		if now.Before(expires) {
+			return true, nil
+		}
+		// expired: remove it and record the key again; processes doing so at the same time
+		// may both record it, which is the only case in which two of them report false
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return false, err
+		}
+	}
+}
+
+// create writes the file of a key unless it exists; the file is written aside and linked
+// into place, so that it is never read half-written
+func (s *FileStore) create(path string, expires time.Time) error {
+	f, err := os.CreateTemp(s.Dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(expires.UTC().Format(time.RFC3339Nano))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Link(f.Name(), path)
+}
+
+func (s *FileStore) Prune(now time.Time) error {
+	entries, err := os.ReadDir(s.Dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue // being written by Track
+		}
+		path := filepath.Join(s.Dir, entry.Name())
+		expires, err := readExpiry(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if !now.Before(expires) {
+			if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// readExpiry reads the time a key expires from its file
+func readExpiry(path string) (time.Time, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	expires, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(string(data)))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return expires, nil
+}
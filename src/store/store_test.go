@@ -0,0 +1,107 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2023 Erik-Paul Dittmer (epdittmer@s114.nl)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NON INFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR
+ * ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package store
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"gosieve/src/rfc5228"
+)
+
+func TestStore(t *testing.T) {
+	for name, store := range map[string]Store{
+		"file":   &FileStore{Dir: t.TempDir()},
+		"memory": &MemoryStore{},
+	} {
+		t.Run(name, func(t *testing.T) {
+			now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+			key := rfc5228.NewVacationKey("me@example.com", "<sender@example.com>", "summer").String()
+			for _, test := range []struct {
+				at   time.Duration
+				seen bool
+			}{
+				{0, false},
+				{time.Hour, true},
+				{7*24*time.Hour - time.Nanosecond, true},
+				{7 * 24 * time.Hour, false}, // expired and recorded again
+				{8 * 24 * time.Hour, true},
+			} {
+				seen, err := store.Track(key, now.Add(test.at), 7*24*time.Hour)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if seen != test.seen {
+					t.Errorf("%s: expected seen %v, got %v", test.at, test.seen, seen)
+				}
+			}
+			if seen, err := store.Track("other", now, time.Hour); err != nil || seen {
+				t.Errorf("unexpected result %v (%v) for another key", seen, err)
+			}
+
+			if err := store.Prune(now.Add(2 * time.Hour)); err != nil {
+				t.Fatal(err)
+			}
+			if seen, err := store.Track("other", now.Add(2*time.Hour), time.Hour); err != nil || seen {
+				t.Errorf("expected a pruned key, got %v (%v)", seen, err)
+			}
+			if seen, err := store.Track(key, now.Add(8*24*time.Hour), time.Hour); err != nil || !seen {
+				t.Errorf("expected an unexpired key to survive pruning, got %v (%v)", seen, err)
+			}
+		})
+	}
+}
+
+func TestFileStoreConcurrent(t *testing.T) {
+	store := &FileStore{Dir: t.TempDir()}
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	var wg sync.WaitGroup
+	results := make([]bool, 20)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			seen, err := store.Track("key", now, time.Hour)
+			if err != nil {
+				t.Error(err)
+			}
+			results[i] = seen
+		}(i)
+	}
+	wg.Wait()
+
+	recorded := 0
+	for _, seen := range results {
+		if !seen {
+			recorded++
+		}
+	}
+	if recorded != 1 {
+		t.Errorf("expected the key to be recorded once, got %d", recorded)
+	}
+}